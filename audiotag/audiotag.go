@@ -0,0 +1,123 @@
+package audiotag
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrNoTags is returned when a file carries no supported tags.
+var ErrNoTags = errors.New("no supported tags found")
+
+// Extensions lists the file extensions ReadFile understands.
+var Extensions = []string{".mp3", ".flac", ".ogg", ".oga", ".opus"}
+
+// Tags is the metadata read from an audio file: ID3v2 and ID3v1 tags of MP3 files
+// and Vorbis comments of FLAC, Ogg Vorbis and Opus files.
+type Tags struct {
+	Artist string
+	Title  string
+	Album  string
+	// Date is the recording date as written in the tag: a year or YYYY-MM-DD.
+	Date   string
+	Lyrics string
+}
+
+// Supported reports whether path has one of the supported extensions.
+func Supported(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, supported := range Extensions {
+		if ext == supported {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadFile reads the tags of the audio file at path.
+func ReadFile(path string) (Tags, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Tags{}, err
+	}
+	defer file.Close()
+
+	return Read(file)
+}
+
+// Read detects the container format of r and reads its tags.
+func Read(r io.ReadSeeker) (Tags, error) {
+	var tags Tags
+
+	magic := make([]byte, 4)
+	_, err := io.ReadFull(r, magic)
+	if err != nil {
+		return tags, fmt.Errorf("error reading file header: %v", err)
+	}
+
+	switch {
+	case bytes.HasPrefix(magic, []byte("ID3")):
+		tags, err = readID3v2(r)
+		if err != nil {
+			return tags, err
+		}
+
+		// Some FLAC files carry an ID3v2 tag in front of the stream.
+		_, err = io.ReadFull(r, magic)
+		if err == nil && bytes.Equal(magic, []byte("fLaC")) {
+			flacTags, err := readFLAC(r)
+			if err == nil {
+				tags = merge(tags, flacTags)
+			}
+		}
+	case bytes.Equal(magic, []byte("fLaC")):
+		tags, err = readFLAC(r)
+		if err != nil {
+			return tags, err
+		}
+	case bytes.Equal(magic, []byte("OggS")):
+		tags, err = readOgg(r)
+		if err != nil {
+			return tags, err
+		}
+	}
+
+	v1, err := readID3v1(r)
+	if err == nil {
+		tags = merge(tags, v1)
+	}
+
+	if tags == (Tags{}) {
+		return tags, ErrNoTags
+	}
+	return tags, nil
+}
+
+// merge fills the empty fields of tags from fallback.
+func merge(tags, fallback Tags) Tags {
+	if tags.Artist == "" {
+		tags.Artist = fallback.Artist
+	}
+	if tags.Title == "" {
+		tags.Title = fallback.Title
+	}
+	if tags.Album == "" {
+		tags.Album = fallback.Album
+	}
+	if tags.Date == "" {
+		tags.Date = fallback.Date
+	}
+	if tags.Lyrics == "" {
+		tags.Lyrics = fallback.Lyrics
+	}
+	return tags
+}
+
+// clean trims whitespace and the NUL padding used by some taggers.
+func clean(value string) string {
+	return strings.TrimSpace(strings.Trim(value, "\x00"))
+}
@@ -0,0 +1,283 @@
+package audiotag
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf16"
+)
+
+// ID3 text encodings.
+const (
+	encodingLatin1  = 0
+	encodingUTF16   = 1
+	encodingUTF16BE = 2
+	encodingUTF8    = 3
+)
+
+// id3Frames maps ID3v2.2 and ID3v2.3/2.4 frame ids to the tag fields they fill.
+var id3Frames = map[string]func(*Tags) *string{
+	"TP1":  func(t *Tags) *string { return &t.Artist },
+	"TPE1": func(t *Tags) *string { return &t.Artist },
+	"TT2":  func(t *Tags) *string { return &t.Title },
+	"TIT2": func(t *Tags) *string { return &t.Title },
+	"TAL":  func(t *Tags) *string { return &t.Album },
+	"TALB": func(t *Tags) *string { return &t.Album },
+	"TYE":  func(t *Tags) *string { return &t.Date },
+	"TYER": func(t *Tags) *string { return &t.Date },
+	"TDRC": func(t *Tags) *string { return &t.Date },
+	"ULT":  func(t *Tags) *string { return &t.Lyrics },
+	"USLT": func(t *Tags) *string { return &t.Lyrics },
+}
+
+// id3AlbumArtist holds the band frames used when a file has no lead artist.
+var id3AlbumArtist = map[string]bool{"TP2": true, "TPE2": true}
+
+// readID3v2 reads the ID3v2 tag at the start of r and leaves r positioned after it.
+func readID3v2(r io.ReadSeeker) (Tags, error) {
+	var tags Tags
+
+	_, err := r.Seek(0, io.SeekStart)
+	if err != nil {
+		return tags, err
+	}
+
+	header := make([]byte, 10)
+	_, err = io.ReadFull(r, header)
+	if err != nil {
+		return tags, fmt.Errorf("error reading ID3v2 header: %v", err)
+	}
+
+	major := header[3]
+	flags := header[5]
+	if major < 2 || major > 4 {
+		return tags, fmt.Errorf("unsupported ID3v2 version 2.%d", major)
+	}
+
+	data := make([]byte, syncsafe(header[6:10]))
+	_, err = io.ReadFull(r, data)
+	if err != nil {
+		return tags, fmt.Errorf("error reading ID3v2 tag: %v", err)
+	}
+
+	if flags&0x80 != 0 && major < 4 {
+		data = removeUnsync(data)
+	}
+
+	pos := 0
+	if flags&0x40 != 0 && len(data) >= 4 {
+		switch major {
+		case 3:
+			pos = 4 + int(binary.BigEndian.Uint32(data))
+		case 4:
+			pos = syncsafe(data[:4])
+		}
+	}
+
+	var albumArtist string
+	for pos < len(data) {
+		id, body, next, ok := nextID3Frame(data, pos, major)
+		if !ok {
+			break
+		}
+		pos = next
+
+		if id3AlbumArtist[id] {
+			albumArtist = decodeTextFrame(body)
+			continue
+		}
+
+		field, ok := id3Frames[id]
+		if !ok || *field(&tags) != "" {
+			continue
+		}
+		if strings.HasPrefix(id, "U") {
+			*field(&tags) = decodeLyricsFrame(body)
+		} else {
+			*field(&tags) = decodeTextFrame(body)
+		}
+	}
+
+	if tags.Artist == "" {
+		tags.Artist = albumArtist
+	}
+	return tags, nil
+}
+
+// nextID3Frame parses the frame at pos and returns its id, its decoded body and the
+// position of the following frame. ok is false at the padding or a malformed frame.
+func nextID3Frame(data []byte, pos int, major byte) (id string, body []byte, next int, ok bool) {
+	var size int
+	var flags uint16
+
+	if major == 2 {
+		if pos+6 > len(data) {
+			return "", nil, 0, false
+		}
+		id = string(data[pos : pos+3])
+		size = int(data[pos+3])<<16 | int(data[pos+4])<<8 | int(data[pos+5])
+		pos += 6
+	} else {
+		if pos+10 > len(data) {
+			return "", nil, 0, false
+		}
+		id = string(data[pos : pos+4])
+		if major == 4 {
+			size = syncsafe(data[pos+4 : pos+8])
+		} else {
+			size = int(binary.BigEndian.Uint32(data[pos+4 : pos+8]))
+		}
+		flags = binary.BigEndian.Uint16(data[pos+8 : pos+10])
+		pos += 10
+	}
+
+	if id[0] == 0 || size <= 0 || pos+size > len(data) {
+		return "", nil, 0, false
+	}
+	body = data[pos : pos+size]
+	next = pos + size
+
+	switch major {
+	case 3:
+		// Compressed or encrypted frames are skipped; grouped frames carry an extra byte.
+		if flags&0x00c0 != 0 {
+			return id, nil, next, true
+		}
+		if flags&0x0020 != 0 && len(body) > 0 {
+			body = body[1:]
+		}
+	case 4:
+		if flags&0x000c != 0 {
+			return id, nil, next, true
+		}
+		if flags&0x0040 != 0 && len(body) > 0 {
+			body = body[1:]
+		}
+		if flags&0x0001 != 0 && len(body) >= 4 {
+			body = body[4:]
+		}
+		if flags&0x0002 != 0 {
+			body = removeUnsync(body)
+		}
+	}
+
+	return id, body, next, true
+}
+
+// decodeTextFrame decodes a text information frame. Only the first of several values is kept.
+func decodeTextFrame(body []byte) string {
+	if len(body) < 2 {
+		return ""
+	}
+
+	text, _ := splitTerminated(body[0], body[1:])
+	return clean(decodeString(body[0], text))
+}
+
+// decodeLyricsFrame decodes an unsynchronised lyrics frame: encoding, language,
+// content descriptor and the lyrics themselves.
+func decodeLyricsFrame(body []byte) string {
+	if len(body) < 5 {
+		return ""
+	}
+
+	_, lyrics := splitTerminated(body[0], body[4:])
+	return clean(decodeString(body[0], lyrics))
+}
+
+// splitTerminated splits b at the first string terminator of the encoding.
+func splitTerminated(encoding byte, b []byte) ([]byte, []byte) {
+	if encoding == encodingUTF16 || encoding == encodingUTF16BE {
+		for i := 0; i+1 < len(b); i += 2 {
+			if b[i] == 0 && b[i+1] == 0 {
+				return b[:i], b[i+2:]
+			}
+		}
+		return b, nil
+	}
+
+	i := bytes.IndexByte(b, 0)
+	if i < 0 {
+		return b, nil
+	}
+	return b[:i], b[i+1:]
+}
+
+// decodeString converts an ID3 encoded string to UTF-8.
+func decodeString(encoding byte, b []byte) string {
+	switch encoding {
+	case encodingUTF16:
+		if len(b) >= 2 && b[0] == 0xfe && b[1] == 0xff {
+			return decodeUTF16(b[2:], binary.BigEndian)
+		}
+		if len(b) >= 2 && b[0] == 0xff && b[1] == 0xfe {
+			b = b[2:]
+		}
+		return decodeUTF16(b, binary.LittleEndian)
+	case encodingUTF16BE:
+		return decodeUTF16(b, binary.BigEndian)
+	case encodingUTF8:
+		return string(b)
+	default:
+		return decodeLatin1(b)
+	}
+}
+
+func decodeUTF16(b []byte, order binary.ByteOrder) string {
+	units := make([]uint16, 0, len(b)/2)
+	for i := 0; i+1 < len(b); i += 2 {
+		units = append(units, order.Uint16(b[i:]))
+	}
+	return string(utf16.Decode(units))
+}
+
+func decodeLatin1(b []byte) string {
+	runes := make([]rune, len(b))
+	for i, c := range b {
+		runes[i] = rune(c)
+	}
+	return string(runes)
+}
+
+// syncsafe decodes a 28-bit synchsafe integer.
+func syncsafe(b []byte) int {
+	return int(b[0]&0x7f)<<21 | int(b[1]&0x7f)<<14 | int(b[2]&0x7f)<<7 | int(b[3]&0x7f)
+}
+
+// removeUnsync reverses the ID3 unsynchronisation scheme, which inserts 0x00 after every 0xff.
+func removeUnsync(b []byte) []byte {
+	return bytes.ReplaceAll(b, []byte{0xff, 0x00}, []byte{0xff})
+}
+
+// readID3v1 reads the ID3v1 tag in the last 128 bytes of r.
+func readID3v1(r io.ReadSeeker) (Tags, error) {
+	var tags Tags
+
+	_, err := r.Seek(-128, io.SeekEnd)
+	if err != nil {
+		return tags, err
+	}
+
+	data := make([]byte, 128)
+	_, err = io.ReadFull(r, data)
+	if err != nil {
+		return tags, err
+	}
+	if string(data[:3]) != "TAG" {
+		return tags, ErrNoTags
+	}
+
+	tags.Title = id3v1Field(data[3:33])
+	tags.Artist = id3v1Field(data[33:63])
+	tags.Album = id3v1Field(data[63:93])
+	tags.Date = id3v1Field(data[93:97])
+	return tags, nil
+}
+
+// id3v1Field decodes a fixed-size, NUL padded ID3v1 field.
+func id3v1Field(b []byte) string {
+	text, _ := splitTerminated(encodingLatin1, b)
+	return clean(decodeLatin1(text))
+}
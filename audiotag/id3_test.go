@@ -0,0 +1,286 @@
+package audiotag
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+// id3v2 builds an ID3v2 tag of a major version with the given flags around data.
+func id3v2(major, flags byte, data []byte) []byte {
+	tag := []byte{'I', 'D', '3', major, 0, flags}
+	tag = append(tag, syncsafeBytes(len(data))...)
+	return append(tag, data...)
+}
+
+func syncsafeBytes(n int) []byte {
+	return []byte{byte(n >> 21 & 0x7f), byte(n >> 14 & 0x7f), byte(n >> 7 & 0x7f), byte(n & 0x7f)}
+}
+
+// frame22 builds an ID3v2.2 frame.
+func frame22(id string, body []byte) []byte {
+	n := len(body)
+	return append(append([]byte(id), byte(n>>16), byte(n>>8), byte(n)), body...)
+}
+
+// frame23 builds an ID3v2.3 frame, whose size is a plain integer.
+func frame23(id string, flags uint16, body []byte) []byte {
+	frame := binary.BigEndian.AppendUint32([]byte(id), uint32(len(body)))
+	frame = binary.BigEndian.AppendUint16(frame, flags)
+	return append(frame, body...)
+}
+
+// frame24 builds an ID3v2.4 frame, whose size is synchsafe.
+func frame24(id string, flags uint16, body []byte) []byte {
+	frame := append([]byte(id), syncsafeBytes(len(body))...)
+	frame = binary.BigEndian.AppendUint16(frame, flags)
+	return append(frame, body...)
+}
+
+// text builds the body of a text frame.
+func text(encoding byte, value []byte) []byte {
+	return append([]byte{encoding}, value...)
+}
+
+// lyrics builds the body of an unsynchronised lyrics frame with an empty descriptor.
+func lyrics(encoding byte, value []byte) []byte {
+	body := append([]byte{encoding}, "eng"...)
+	if encoding == encodingUTF16 || encoding == encodingUTF16BE {
+		body = append(body, 0, 0)
+	} else {
+		body = append(body, 0)
+	}
+	return append(body, value...)
+}
+
+func latin1(s string) []byte {
+	b := make([]byte, 0, len(s))
+	for _, r := range s {
+		b = append(b, byte(r))
+	}
+	return b
+}
+
+// utf16Bytes encodes s as UTF-16 in the given byte order, with a byte order mark if bom is set.
+func utf16Bytes(s string, order binary.AppendByteOrder, bom bool) []byte {
+	var b []byte
+	units := utf16.Encode([]rune(s))
+	if bom {
+		units = append([]uint16{0xfeff}, units...)
+	}
+	for _, unit := range units {
+		b = order.AppendUint16(b, unit)
+	}
+	return b
+}
+
+// id3v1 builds an ID3v1 tag.
+func id3v1(title, artist, album, year string) []byte {
+	tag := make([]byte, 128)
+	copy(tag, "TAG")
+	copy(tag[3:33], title)
+	copy(tag[33:63], artist)
+	copy(tag[63:93], album)
+	copy(tag[93:97], year)
+	return tag
+}
+
+// audio stands for the audio frames following a tag.
+var audio = bytes.Repeat([]byte{0xff, 0xfb, 0x90, 0x64}, 64)
+
+func join(parts ...[]byte) []byte {
+	return bytes.Join(parts, nil)
+}
+
+func TestReadID3(t *testing.T) {
+	longLyrics := strings.Repeat("Yellow submarine, ", 20)
+
+	tests := []struct {
+		name string
+		file []byte
+		tags Tags
+	}{
+		{
+			name: "ID3v2.2",
+			file: join(id3v2(2, 0, join(
+				frame22("TP1", text(encodingLatin1, []byte("The Beatles"))),
+				frame22("TT2", text(encodingLatin1, []byte("Yellow Submarine"))),
+				frame22("TAL", text(encodingLatin1, []byte("Revolver"))),
+				frame22("TYE", text(encodingLatin1, []byte("1966"))),
+				frame22("ULT", lyrics(encodingLatin1, []byte("In the town where I was born"))),
+			)), audio),
+			tags: Tags{Artist: "The Beatles", Title: "Yellow Submarine", Album: "Revolver", Date: "1966", Lyrics: "In the town where I was born"},
+		},
+		{
+			name: "ID3v2.3 Latin-1 with padding",
+			file: join(id3v2(3, 0, join(
+				frame23("TPE1", 0, text(encodingLatin1, latin1("Motörhead"))),
+				frame23("TIT2", 0, text(encodingLatin1, []byte("Ace of Spades\x00"))),
+				frame23("TYER", 0, text(encodingLatin1, []byte("1980"))),
+				make([]byte, 64),
+			)), audio),
+			tags: Tags{Artist: "Motörhead", Title: "Ace of Spades", Date: "1980"},
+		},
+		{
+			name: "ID3v2.3 UTF-16 with byte order marks",
+			file: join(id3v2(3, 0, join(
+				frame23("TPE1", 0, text(encodingUTF16, utf16Bytes("Кино", binary.LittleEndian, true))),
+				frame23("TIT2", 0, text(encodingUTF16, utf16Bytes("Группа крови", binary.BigEndian, true))),
+				frame23("USLT", 0, lyrics(encodingUTF16, utf16Bytes("Тёплое место", binary.LittleEndian, true))),
+			)), audio),
+			tags: Tags{Artist: "Кино", Title: "Группа крови", Lyrics: "Тёплое место"},
+		},
+		{
+			name: "ID3v2.3 extended header",
+			file: join(id3v2(3, 0x40, join(
+				[]byte{0, 0, 0, 6, 0, 0, 0, 0, 0, 0},
+				frame23("TIT2", 0, text(encodingLatin1, []byte("Uprising"))),
+			)), audio),
+			tags: Tags{Title: "Uprising"},
+		},
+		{
+			name: "ID3v2.3 unsynchronisation",
+			file: join(id3v2(3, 0x80, unsync(join(
+				frame23("TPE1", 0, text(encodingLatin1, latin1("ÿÿ"))),
+				frame23("TIT2", 0, text(encodingLatin1, []byte("Song"))),
+			))), audio),
+			tags: Tags{Artist: "ÿÿ", Title: "Song"},
+		},
+		{
+			name: "ID3v2.3 band as artist",
+			file: join(id3v2(3, 0, join(
+				frame23("TPE2", 0, text(encodingLatin1, []byte("Muse"))),
+				frame23("TIT2", 0, text(encodingLatin1, []byte("Uprising"))),
+			)), audio),
+			tags: Tags{Artist: "Muse", Title: "Uprising"},
+		},
+		{
+			name: "ID3v2.3 compressed frame skipped",
+			file: join(id3v2(3, 0, join(
+				frame23("TIT2", 0x0080, text(encodingLatin1, []byte("compressed"))),
+				frame23("TALB", 0, text(encodingLatin1, []byte("Album"))),
+			)), audio),
+			tags: Tags{Album: "Album"},
+		},
+		{
+			name: "ID3v2.4 UTF-8 with a synchsafe frame size",
+			file: join(id3v2(4, 0, join(
+				frame24("TPE1", 0, text(encodingUTF8, []byte("Sigur Rós"))),
+				frame24("TDRC", 0, text(encodingUTF8, []byte("2005-09-12"))),
+				frame24("USLT", 0, lyrics(encodingUTF8, []byte(longLyrics))),
+			)), audio),
+			tags: Tags{Artist: "Sigur Rós", Date: "2005-09-12", Lyrics: strings.TrimSpace(longLyrics)},
+		},
+		{
+			name: "ID3v2.4 UTF-16BE and multiple values",
+			file: join(id3v2(4, 0, join(
+				frame24("TIT2", 0, text(encodingUTF16BE, append(utf16Bytes("Первый", binary.BigEndian, false), append([]byte{0, 0}, utf16Bytes("Второй", binary.BigEndian, false)...)...))),
+			)), audio),
+			tags: Tags{Title: "Первый"},
+		},
+		{
+			name: "ID3v2.4 extended header",
+			file: join(id3v2(4, 0x40, join(
+				[]byte{0, 0, 0, 6, 1, 0},
+				frame24("TIT2", 0, text(encodingLatin1, []byte("Song"))),
+			)), audio),
+			tags: Tags{Title: "Song"},
+		},
+		{
+			name: "ID3v2.4 frame unsynchronisation and data length",
+			file: join(id3v2(4, 0, join(
+				frame24("TPE1", 0x0003, join([]byte{0, 0, 0, 3}, unsync(text(encodingLatin1, latin1("ÿÿ"))))),
+				frame24("TIT2", 0x0040, join([]byte{1}, text(encodingLatin1, []byte("Grouped")))),
+			)), audio),
+			tags: Tags{Artist: "ÿÿ", Title: "Grouped"},
+		},
+		{
+			name: "ID3v1",
+			file: join(audio, id3v1("Smells Like Teen Spirit", "Nirvana", "Nevermind", "1991")),
+			tags: Tags{Artist: "Nirvana", Title: "Smells Like Teen Spirit", Album: "Nevermind", Date: "1991"},
+		},
+		{
+			name: "ID3v2 completed by ID3v1",
+			file: join(id3v2(3, 0, frame23("TIT2", 0, text(encodingLatin1, []byte("Longer Title From ID3v2")))), audio,
+				id3v1("Longer Title From", "Artist", "Album", "2001")),
+			tags: Tags{Artist: "Artist", Title: "Longer Title From ID3v2", Album: "Album", Date: "2001"},
+		},
+		{
+			name: "truncated frame",
+			file: join(id3v2(3, 0, join(
+				frame23("TIT2", 0, text(encodingLatin1, []byte("Kept"))),
+				frame23("TALB", 0, text(encodingLatin1, []byte("Cut off")))[:14],
+			)), audio),
+			tags: Tags{Title: "Kept"},
+		},
+		{
+			name: "frame larger than the tag",
+			file: join(id3v2(3, 0, join(
+				frame23("TIT2", 0, text(encodingLatin1, []byte("Kept"))),
+				binary.BigEndian.AppendUint32([]byte("TALB"), 1<<30), []byte{0, 0, 0, 'x'},
+			)), audio),
+			tags: Tags{Title: "Kept"},
+		},
+		{
+			name: "empty frame",
+			file: join(id3v2(3, 0, join(
+				frame23("TIT2", 0, nil),
+				frame23("TALB", 0, text(encodingLatin1, []byte("Unreached"))),
+			)), audio, id3v1("Fallback", "", "", "")),
+			tags: Tags{Title: "Fallback"},
+		},
+		{
+			name: "short text and lyrics frames",
+			file: join(id3v2(3, 0, join(
+				frame23("TPE1", 0, []byte{encodingUTF16}),
+				frame23("USLT", 0, []byte{encodingLatin1, 'e', 'n'}),
+				frame23("TIT2", 0, text(encodingUTF16, []byte{0xff})),
+			)), audio, id3v1("Fallback", "", "", "")),
+			tags: Tags{Title: "Fallback"},
+		},
+	}
+
+	for _, test := range tests {
+		tags, err := Read(bytes.NewReader(test.file))
+		if err != nil {
+			t.Errorf("%s: Read: %v", test.name, err)
+			continue
+		}
+		if tags != test.tags {
+			t.Errorf("%s: Read = %+v, want %+v", test.name, tags, test.tags)
+		}
+	}
+}
+
+// unsync applies the ID3 unsynchronisation scheme, inserting 0x00 after every 0xff.
+func unsync(b []byte) []byte {
+	return bytes.ReplaceAll(b, []byte{0xff}, []byte{0xff, 0x00})
+}
+
+func TestReadID3Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		file []byte
+	}{
+		{"unsupported version", join(id3v2(5, 0, frame23("TIT2", 0, text(encodingLatin1, []byte("Song")))), audio)},
+		{"tag larger than the file", id3v2(3, 0, frame23("TIT2", 0, text(encodingLatin1, []byte("Song"))))[:20]},
+		{"truncated header", []byte("ID3\x03\x00")},
+		{"no tags", audio},
+		{"empty tags", join(id3v2(3, 0, make([]byte, 32)), audio)},
+	}
+
+	for _, test := range tests {
+		_, err := Read(bytes.NewReader(test.file))
+		if err == nil {
+			t.Errorf("%s: Read returned no error", test.name)
+		}
+	}
+
+	_, err := Read(bytes.NewReader(audio))
+	if !errors.Is(err, ErrNoTags) {
+		t.Errorf("Read of a file without tags = %v, want ErrNoTags", err)
+	}
+}
@@ -0,0 +1,172 @@
+package audiotag
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// flacVorbisComment is the FLAC metadata block type holding Vorbis comments.
+const flacVorbisComment = 4
+
+// maxOggPages bounds the pages read while looking for the comment header.
+const maxOggPages = 512
+
+// vorbisFields maps Vorbis comment names to the tag fields they fill.
+var vorbisFields = map[string]func(*Tags) *string{
+	"ARTIST":          func(t *Tags) *string { return &t.Artist },
+	"TITLE":           func(t *Tags) *string { return &t.Title },
+	"ALBUM":           func(t *Tags) *string { return &t.Album },
+	"DATE":            func(t *Tags) *string { return &t.Date },
+	"YEAR":            func(t *Tags) *string { return &t.Date },
+	"LYRICS":          func(t *Tags) *string { return &t.Lyrics },
+	"UNSYNCEDLYRICS":  func(t *Tags) *string { return &t.Lyrics },
+	"UNSYNCED LYRICS": func(t *Tags) *string { return &t.Lyrics },
+	"UNSYNCED_LYRICS": func(t *Tags) *string { return &t.Lyrics },
+}
+
+// vorbisAlbumArtist holds the comment names used when a file has no artist.
+var vorbisAlbumArtist = map[string]bool{"ALBUMARTIST": true, "ALBUM ARTIST": true, "ALBUM_ARTIST": true}
+
+// readFLAC reads the Vorbis comment block of a FLAC stream; r is positioned after the "fLaC" marker.
+func readFLAC(r io.Reader) (Tags, error) {
+	header := make([]byte, 4)
+	for {
+		_, err := io.ReadFull(r, header)
+		if err != nil {
+			return Tags{}, fmt.Errorf("error reading FLAC metadata: %v", err)
+		}
+
+		last := header[0]&0x80 != 0
+		blockType := header[0] & 0x7f
+		size := int(header[1])<<16 | int(header[2])<<8 | int(header[3])
+
+		block := make([]byte, size)
+		_, err = io.ReadFull(r, block)
+		if err != nil {
+			return Tags{}, fmt.Errorf("error reading FLAC metadata: %v", err)
+		}
+
+		if blockType == flacVorbisComment {
+			return parseVorbisComment(block)
+		}
+		if last {
+			return Tags{}, ErrNoTags
+		}
+	}
+}
+
+// readOgg reads the comment header of the first logical stream of an Ogg Vorbis or Opus file.
+func readOgg(r io.ReadSeeker) (Tags, error) {
+	_, err := r.Seek(0, io.SeekStart)
+	if err != nil {
+		return Tags{}, err
+	}
+
+	var packets [][]byte
+	var packet []byte
+	header := make([]byte, 27)
+
+	for page := 0; page < maxOggPages && len(packets) < 2; page++ {
+		_, err = io.ReadFull(r, header)
+		if err != nil {
+			return Tags{}, fmt.Errorf("error reading Ogg page: %v", err)
+		}
+		if string(header[:4]) != "OggS" {
+			return Tags{}, fmt.Errorf("invalid Ogg page")
+		}
+
+		segments := make([]byte, header[26])
+		_, err = io.ReadFull(r, segments)
+		if err != nil {
+			return Tags{}, fmt.Errorf("error reading Ogg page: %v", err)
+		}
+
+		for _, size := range segments {
+			data := make([]byte, size)
+			_, err = io.ReadFull(r, data)
+			if err != nil {
+				return Tags{}, fmt.Errorf("error reading Ogg page: %v", err)
+			}
+
+			packet = append(packet, data...)
+			if size < 255 {
+				packets = append(packets, packet)
+				packet = nil
+			}
+		}
+	}
+
+	if len(packets) < 2 {
+		return Tags{}, ErrNoTags
+	}
+
+	comment := packets[1]
+	switch {
+	case bytes.HasPrefix(comment, []byte("\x03vorbis")):
+		return parseVorbisComment(comment[7:])
+	case bytes.HasPrefix(comment, []byte("OpusTags")):
+		return parseVorbisComment(comment[8:])
+	default:
+		return Tags{}, ErrNoTags
+	}
+}
+
+// parseVorbisComment parses a Vorbis comment structure: a vendor string followed by
+// NAME=value comments, all prefixed by little-endian 32-bit lengths.
+func parseVorbisComment(b []byte) (Tags, error) {
+	var tags Tags
+
+	next := func() (string, bool) {
+		if len(b) < 4 {
+			return "", false
+		}
+		size := int(binary.LittleEndian.Uint32(b))
+		if size < 0 || 4+size > len(b) {
+			return "", false
+		}
+		value := string(b[4 : 4+size])
+		b = b[4+size:]
+		return value, true
+	}
+
+	_, ok := next()
+	if !ok || len(b) < 4 {
+		return tags, fmt.Errorf("invalid Vorbis comment")
+	}
+	count := int(binary.LittleEndian.Uint32(b))
+	b = b[4:]
+
+	var albumArtist string
+	for i := 0; i < count; i++ {
+		comment, ok := next()
+		if !ok {
+			break
+		}
+
+		name, value, found := strings.Cut(comment, "=")
+		if !found {
+			continue
+		}
+		name = strings.ToUpper(name)
+		value = clean(value)
+
+		if vorbisAlbumArtist[name] {
+			albumArtist = value
+			continue
+		}
+
+		// Repeated comments hold additional values; the first one is kept.
+		field, ok := vorbisFields[name]
+		if ok && *field(&tags) == "" {
+			*field(&tags) = value
+		}
+	}
+
+	if tags.Artist == "" {
+		tags.Artist = albumArtist
+	}
+	return tags, nil
+}
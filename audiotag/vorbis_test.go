@@ -0,0 +1,169 @@
+package audiotag
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// vorbisComment builds a Vorbis comment structure.
+func vorbisComment(vendor string, comments ...string) []byte {
+	b := binary.LittleEndian.AppendUint32(nil, uint32(len(vendor)))
+	b = append(b, vendor...)
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(comments)))
+	for _, comment := range comments {
+		b = binary.LittleEndian.AppendUint32(b, uint32(len(comment)))
+		b = append(b, comment...)
+	}
+	return b
+}
+
+// flacBlock builds a FLAC metadata block.
+func flacBlock(blockType byte, last bool, data []byte) []byte {
+	if last {
+		blockType |= 0x80
+	}
+	n := len(data)
+	return append([]byte{blockType, byte(n >> 16), byte(n >> 8), byte(n)}, data...)
+}
+
+// streamInfo stands for the STREAMINFO block every FLAC stream starts with.
+var streamInfo = make([]byte, 34)
+
+// oggPage builds an Ogg page holding the given segments. The checksum is not verified and left zero.
+func oggPage(sequence uint32, segments []byte, data []byte) []byte {
+	page := []byte("OggS\x00\x00")
+	page = append(page, make([]byte, 8)...)
+	page = binary.LittleEndian.AppendUint32(page, 1)
+	page = binary.LittleEndian.AppendUint32(page, sequence)
+	page = append(page, 0, 0, 0, 0, byte(len(segments)))
+	page = append(page, segments...)
+	return append(page, data...)
+}
+
+// lacing returns the segment table of a packet of size bytes.
+func lacing(size int) []byte {
+	segments := bytes.Repeat([]byte{255}, size/255)
+	return append(segments, byte(size%255))
+}
+
+// oggFile builds an Ogg stream of an identification packet and a comment packet. The comment
+// packet continues on a second page after split bytes, or fits the first page when split is 0.
+func oggFile(identification, comment []byte, split int) []byte {
+	if split == 0 {
+		return oggPage(0, append(lacing(len(identification)), lacing(len(comment))...), join(identification, comment))
+	}
+
+	// The first page ends with full segments of the comment, which continue on the next page.
+	first := append(lacing(len(identification)), bytes.Repeat([]byte{255}, split/255)...)
+	rest := comment[split/255*255:]
+	return join(
+		oggPage(0, first, join(identification, comment[:split/255*255])),
+		oggPage(1, lacing(len(rest)), rest),
+	)
+}
+
+func TestReadVorbis(t *testing.T) {
+	longLyrics := strings.Repeat("We all live in a yellow submarine. ", 30)
+
+	tests := []struct {
+		name string
+		file []byte
+		tags Tags
+	}{
+		{
+			name: "FLAC",
+			file: join([]byte("fLaC"), flacBlock(0, false, streamInfo), flacBlock(1, false, make([]byte, 16)),
+				flacBlock(flacVorbisComment, true, vorbisComment("reference libFLAC 1.4.3",
+					"ARTIST=Кино", "title=Группа крови", "Album=Группа крови", "DATE=1988", "LYRICS=Тёплое место"))),
+			tags: Tags{Artist: "Кино", Title: "Группа крови", Album: "Группа крови", Date: "1988", Lyrics: "Тёплое место"},
+		},
+		{
+			name: "FLAC after an ID3v2 tag",
+			file: join(id3v2(3, 0, frame23("TIT2", 0, text(encodingLatin1, []byte("From ID3")))), []byte("fLaC"),
+				flacBlock(0, false, streamInfo), flacBlock(flacVorbisComment, true, vorbisComment("vendor", "TITLE=From FLAC", "ARTIST=Muse"))),
+			tags: Tags{Artist: "Muse", Title: "From ID3"},
+		},
+		{
+			name: "FLAC with repeated and unknown comments",
+			file: join([]byte("fLaC"), flacBlock(flacVorbisComment, true, vorbisComment("vendor",
+				"ALBUMARTIST=Various Artists", "TITLE= Padded \x00", "TITLE=Second", "GENRE=Rock", "no separator", "YEAR=2001"))),
+			tags: Tags{Artist: "Various Artists", Title: "Padded", Date: "2001"},
+		},
+		{
+			name: "FLAC with fewer comments than counted",
+			// The count of two comments is followed by the first one only.
+			file: join([]byte("fLaC"), flacBlock(flacVorbisComment, true,
+				vorbisComment("vendor", "TITLE=Kept", "ARTIST=Cut off")[:len(vorbisComment("vendor", "TITLE=Kept"))])),
+			tags: Tags{Title: "Kept"},
+		},
+		{
+			name: "FLAC with an oversize comment",
+			// The second comment claims 4 GiB, past the end of the block.
+			file: join([]byte("fLaC"), flacBlock(flacVorbisComment, true, join(
+				vorbisComment("vendor", "TITLE=Kept", "")[:len(vorbisComment("vendor", "TITLE=Kept", ""))-4],
+				[]byte{0xff, 0xff, 0xff, 0xff, 'A', 'R'},
+			))),
+			tags: Tags{Title: "Kept"},
+		},
+		{
+			name: "Ogg Vorbis",
+			file: oggFile([]byte("\x01vorbis identification"),
+				join([]byte("\x03vorbis"), vorbisComment("Xiph.Org libVorbis", "ARTIST=Sigur Rós", "TITLE=Hoppípolla"), []byte{1}), 0),
+			tags: Tags{Artist: "Sigur Rós", Title: "Hoppípolla"},
+		},
+		{
+			name: "Ogg Vorbis comment across pages",
+			file: oggFile([]byte("\x01vorbis identification"),
+				join([]byte("\x03vorbis"), vorbisComment("vendor", "TITLE=Yellow Submarine", "UNSYNCEDLYRICS="+longLyrics), []byte{1}), 600),
+			tags: Tags{Title: "Yellow Submarine", Lyrics: strings.TrimSpace(longLyrics)},
+		},
+		{
+			name: "Opus",
+			file: oggFile([]byte("OpusHead\x01\x02"), join([]byte("OpusTags"), vorbisComment("libopus 1.4", "ARTIST=Muse", "TITLE=Uprising", "DATE=2009-09-07")), 0),
+			tags: Tags{Artist: "Muse", Title: "Uprising", Date: "2009-09-07"},
+		},
+	}
+
+	for _, test := range tests {
+		tags, err := Read(bytes.NewReader(test.file))
+		if err != nil {
+			t.Errorf("%s: Read: %v", test.name, err)
+			continue
+		}
+		if tags != test.tags {
+			t.Errorf("%s: Read = %+v, want %+v", test.name, tags, test.tags)
+		}
+	}
+}
+
+func TestReadVorbisErrors(t *testing.T) {
+	comment := vorbisComment("vendor", "TITLE=Song")
+
+	tests := []struct {
+		name  string
+		file  []byte
+		noTag bool
+	}{
+		{"FLAC without comments", join([]byte("fLaC"), flacBlock(0, true, streamInfo)), true},
+		{"truncated FLAC block", join([]byte("fLaC"), flacBlock(flacVorbisComment, true, comment)[:20]), false},
+		{"FLAC comment with a truncated vendor", join([]byte("fLaC"), flacBlock(flacVorbisComment, true, []byte{10, 0, 0, 0, 'v'})), false},
+		{"FLAC comment without a count", join([]byte("fLaC"), flacBlock(flacVorbisComment, true, comment[:10])), false},
+		{"Ogg without a comment packet", oggPage(0, lacing(10), []byte("\x01vorbis...")), false},
+		{"Ogg with an unknown codec", oggFile([]byte("\x80theora"), join([]byte("\x81theora"), comment), 0), true},
+		{"truncated Ogg page", oggFile([]byte("\x01vorbis"), join([]byte("\x03vorbis"), comment), 0)[:40], false},
+	}
+
+	for _, test := range tests {
+		_, err := Read(bytes.NewReader(test.file))
+		if err == nil {
+			t.Errorf("%s: Read returned no error", test.name)
+			continue
+		}
+		if test.noTag && !errors.Is(err, ErrNoTags) {
+			t.Errorf("%s: Read = %v, want ErrNoTags", test.name, err)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package auth
+
+import "slices"
+
+// Roles of a user, from the least to the most privileged.
+const (
+	RoleReader = "reader"
+	RoleEditor = "editor"
+	RoleAdmin  = "admin"
+)
+
+// Roles lists the valid roles.
+var Roles = []string{RoleReader, RoleEditor, RoleAdmin}
+
+// Permission is an action on the catalogue that a role may be granted.
+type Permission string
+
+// Permissions checked by the routes.
+const (
+	PermSongsCreate  Permission = "songs:create"
+	PermSongsEdit    Permission = "songs:edit"
+	PermSongsDelete  Permission = "songs:delete"
+	PermSongsImport  Permission = "songs:import"
+	PermGroupsDelete Permission = "groups:delete"
+	// PermGroupsAny lifts the per-group ownership check: without it, songs can only be
+	// created and edited for the groups the user maintains.
+	PermGroupsAny    Permission = "groups:any"
+	PermUsersManage  Permission = "users:manage"
+	PermGenresManage Permission = "genres:manage"
+	PermGroupsMerge  Permission = "groups:merge"
+	PermSongsMerge   Permission = "songs:merge"
+)
+
+// rolePermissions maps each role to the permissions it grants. Readers can only read
+// the catalogue and manage their own playlists.
+var rolePermissions = map[string][]Permission{
+	RoleReader: {},
+	RoleEditor: {PermSongsCreate, PermSongsEdit},
+	RoleAdmin: {
+		PermSongsCreate, PermSongsEdit, PermSongsDelete, PermSongsImport,
+		PermGroupsDelete, PermGroupsAny, PermUsersManage, PermGenresManage,
+		PermGroupsMerge, PermSongsMerge,
+	},
+}
+
+// ValidRole reports whether role is one of Roles.
+func ValidRole(role string) bool {
+	return slices.Contains(Roles, role)
+}
+
+// Can reports whether a role grants a permission.
+func Can(role string, permission Permission) bool {
+	return slices.Contains(rolePermissions[role], permission)
+}
+
+// Can reports whether the principal's role grants a permission.
+func (p *Principal) Can(permission Permission) bool {
+	return p != nil && Can(p.Role, permission)
+}
@@ -0,0 +1,228 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Issuer is the iss claim of the tokens issued by the library.
+const Issuer = "music"
+
+// Token types, stored in the typ claim so that a refresh token cannot be used as an access token.
+const (
+	TypeAccess  = "access"
+	TypeRefresh = "refresh"
+)
+
+// Default token lifetimes.
+const (
+	DefaultAccessTTL  = 15 * time.Minute
+	DefaultRefreshTTL = 30 * 24 * time.Hour
+)
+
+// ErrInvalidToken is returned for tokens that are malformed, expired, of the wrong type or not signed by the issuer.
+var ErrInvalidToken = errors.New("invalid token")
+
+// Claims are the claims of an access or refresh token. The subject is the user ID.
+// The role is informational: requests are authorized with the current role of the user.
+type Claims struct {
+	Name string `json:"name"`
+	Role string `json:"role"`
+	Type string `json:"typ"`
+	jwt.RegisteredClaims
+}
+
+// UserID returns the user ID in the subject of the claims.
+func (c *Claims) UserID() (int, error) {
+	return strconv.Atoi(c.Subject)
+}
+
+// TokenPair is the result of a login or a refresh.
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type" example:"Bearer"`
+	// ExpiresIn is the lifetime of the access token in seconds.
+	ExpiresIn int `json:"expires_in"`
+}
+
+// Signer issues and verifies tokens signed with Ed25519. Verification only needs the
+// public key, published as a JWK set, so other services can check tokens offline.
+type Signer struct {
+	privateKey ed25519.PrivateKey
+	publicKey  ed25519.PublicKey
+	keyID      string
+	AccessTTL  time.Duration
+	RefreshTTL time.Duration
+}
+
+// NewSigner creates a Signer for an Ed25519 private key.
+func NewSigner(privateKey ed25519.PrivateKey) *Signer {
+	publicKey := privateKey.Public().(ed25519.PublicKey)
+	sum := sha256.Sum256(publicKey)
+
+	return &Signer{
+		privateKey: privateKey,
+		publicKey:  publicKey,
+		keyID:      hex.EncodeToString(sum[:8]),
+		AccessTTL:  DefaultAccessTTL,
+		RefreshTTL: DefaultRefreshTTL,
+	}
+}
+
+// NewSignerFromEnv creates a Signer from JWT_PRIVATE_KEY, a base64 encoded Ed25519 seed
+// or private key, and the optional JWT_ACCESS_TTL and JWT_REFRESH_TTL durations.
+// Without a key a random one is generated, so tokens do not survive a restart.
+func NewSignerFromEnv() (*Signer, error) {
+	var privateKey ed25519.PrivateKey
+
+	encoded := os.Getenv("JWT_PRIVATE_KEY")
+	if encoded == "" {
+		log.Printf("JWT_PRIVATE_KEY is not set, tokens are signed with a temporary key")
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, fmt.Errorf("error generating signing key: %v", err)
+		}
+		privateKey = key
+	} else {
+		raw, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid JWT_PRIVATE_KEY: %v", err)
+		}
+		switch len(raw) {
+		case ed25519.SeedSize:
+			privateKey = ed25519.NewKeyFromSeed(raw)
+		case ed25519.PrivateKeySize:
+			privateKey = ed25519.PrivateKey(raw)
+		default:
+			return nil, fmt.Errorf("invalid JWT_PRIVATE_KEY: expected %d or %d bytes, got %d", ed25519.SeedSize, ed25519.PrivateKeySize, len(raw))
+		}
+	}
+
+	signer := NewSigner(privateKey)
+
+	for name, ttl := range map[string]*time.Duration{"JWT_ACCESS_TTL": &signer.AccessTTL, "JWT_REFRESH_TTL": &signer.RefreshTTL} {
+		value := os.Getenv(name)
+		if value == "" {
+			continue
+		}
+		duration, err := time.ParseDuration(value)
+		if err != nil || duration <= 0 {
+			return nil, fmt.Errorf("invalid %s: %v", name, value)
+		}
+		*ttl = duration
+	}
+
+	return signer, nil
+}
+
+// Issue creates an access and a refresh token for a user.
+func (s *Signer) Issue(userID int, name, role string) (TokenPair, error) {
+	access, err := s.sign(userID, name, role, TypeAccess, s.AccessTTL)
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	refresh, err := s.sign(userID, name, role, TypeRefresh, s.RefreshTTL)
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	return TokenPair{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(s.AccessTTL.Seconds()),
+	}, nil
+}
+
+func (s *Signer) sign(userID int, name, role, tokenType string, ttl time.Duration) (string, error) {
+	id := make([]byte, 16)
+	_, err := rand.Read(id)
+	if err != nil {
+		return "", fmt.Errorf("error generating token id: %v", err)
+	}
+
+	now := time.Now()
+	claims := Claims{
+		Name: name,
+		Role: role,
+		Type: tokenType,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    Issuer,
+			Subject:   strconv.Itoa(userID),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			ID:        hex.EncodeToString(id),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+	token.Header["kid"] = s.keyID
+
+	signed, err := token.SignedString(s.privateKey)
+	if err != nil {
+		return "", fmt.Errorf("error signing token: %v", err)
+	}
+	return signed, nil
+}
+
+// Verify checks the signature, expiry, issuer and type of a token and returns its claims.
+func (s *Signer) Verify(token, tokenType string) (*Claims, error) {
+	var claims Claims
+
+	_, err := jwt.ParseWithClaims(token, &claims, func(*jwt.Token) (interface{}, error) {
+		return s.publicKey, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodEdDSA.Alg()}), jwt.WithIssuer(Issuer), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	if claims.Type != tokenType {
+		return nil, fmt.Errorf("%w: expected %s token, got %q", ErrInvalidToken, tokenType, claims.Type)
+	}
+	_, err = claims.UserID()
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid subject", ErrInvalidToken)
+	}
+
+	return &claims, nil
+}
+
+// JWK is a public key in JSON Web Key format.
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Curve     string `json:"crv"`
+	X         string `json:"x"`
+	KeyID     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+}
+
+// JWKSet is a set of public keys in JSON Web Key format.
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public key that verifies the issued tokens.
+func (s *Signer) JWKS() JWKSet {
+	return JWKSet{Keys: []JWK{{
+		KeyType:   "OKP",
+		Curve:     "Ed25519",
+		X:         base64.RawURLEncoding.EncodeToString(s.publicKey),
+		KeyID:     s.keyID,
+		Algorithm: jwt.SigningMethodEdDSA.Alg(),
+		Use:       "sig",
+	}}}
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/noctusha/music/auth"
+	"github.com/noctusha/music/connection"
+	"github.com/noctusha/music/exporter"
+	"github.com/noctusha/music/handlers"
+	"github.com/noctusha/music/importer"
+	"github.com/noctusha/music/models"
+	"github.com/noctusha/music/musicinfo"
+	"github.com/noctusha/music/scanner"
+	"github.com/noctusha/music/songbook"
+)
+
+// songFilterFlags registers the ListSongs filters on a command's flag set.
+func songFilterFlags(fs *flag.FlagSet) *models.SongFilter {
+	var filter models.SongFilter
+	fs.StringVar(&filter.Group, "group", "", "filter by group name")
+	fs.StringVar(&filter.Name, "name", "", "filter by song name")
+	fs.StringVar(&filter.ReleaseDate, "release-date", "", "filter by release date")
+	fs.StringVar(&filter.Text, "text", "", "filter by song text")
+	fs.StringVar(&filter.Link, "link", "", "filter by song link")
+	fs.IntVar(&filter.Limit, "limit", 0, "maximum number of songs")
+	fs.IntVar(&filter.Offset, "offset", 0, "number of songs to skip")
+	return &filter
+}
+
+// createOutput opens the output file of a command, using stdout for "-" or an empty path.
+func createOutput(path string) (io.WriteCloser, error) {
+	if path == "" || path == "-" {
+		return os.Stdout, nil
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("error creating output file: %v", err)
+	}
+	return file, nil
+}
+
+// runSongbook renders a songbook for songs selected by ids, by a playlist or by the ListSongs filters.
+// Private playlists are only rendered for their owner given by -user.
+func runSongbook(repo *connection.Repository, args []string) error {
+	fs := flag.NewFlagSet("songbook", flag.ExitOnError)
+	format := fs.String("format", songbook.FormatHTML, "output format: html or pdf")
+	title := fs.String("title", "", "songbook title")
+	ids := fs.String("ids", "", "comma separated song ids, in songbook order")
+	playlistID := fs.Int("playlist", 0, "playlist ID, in playlist order")
+	userName := fs.String("user", "", "user name, to render a private playlist of the user")
+	output := fs.String("o", "", "output file, stdout by default")
+	font := fs.String("font", os.Getenv("SONGBOOK_FONT"), "TrueType font for the PDF output")
+	filter := songFilterFlags(fs)
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	var songs []models.DetailedSong
+	switch {
+	case *playlistID != 0:
+		userID := 0
+		if *userName != "" {
+			user, _, err := repo.UserCredentials(*userName)
+			if err != nil {
+				return err
+			}
+			if user == nil {
+				return fmt.Errorf("no such user: %v", *userName)
+			}
+			userID = user.ID
+		}
+
+		playlist, err := repo.GetPlaylist(*playlistID)
+		if err != nil {
+			return err
+		}
+		if playlist == nil || !playlist.ReadableBy(userID) {
+			return fmt.Errorf("no such playlist with playlist_id: %v", *playlistID)
+		}
+		if *title == "" {
+			*title = playlist.Name
+		}
+		songs, err = repo.PlaylistSongs(playlist.ID)
+		if err != nil {
+			return err
+		}
+	case *ids != "":
+		songIDs, err := handlers.ParseIDs(*ids)
+		if err != nil {
+			return err
+		}
+		songs, err = repo.DetailedSongsByIDs(songIDs)
+		if err != nil {
+			return err
+		}
+	default:
+		if filter.Limit == 0 {
+			filter.Limit = songbook.MaxSongs
+		}
+		songs, err = repo.DetailedSongList(*filter)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(songs) == 0 {
+		return fmt.Errorf("no songs found for the songbook")
+	}
+
+	book := songbook.New(*title, songs)
+	book.FontPath = *font
+
+	out, err := createOutput(*output)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	return book.Write(out, *format)
+}
+
+// runImport imports songs in bulk from a CSV, JSON or NDJSON file, or restores a bundle,
+// and writes the report as JSON.
+func runImport(repo *connection.Repository, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	format := fs.String("format", "", "input format: csv, json, ndjson or bundle (guessed from the file name by default)")
+	dryRun := fs.Bool("dry-run", false, "validate and report without saving")
+	upsert := fs.Bool("upsert", false, "update existing songs instead of skipping them")
+	enrich := fs.Bool("enrich", false, "fill in missing details from the external API")
+	batchSize := fs.Int("batch-size", importer.DefaultBatchSize, "rows per transaction")
+	output := fs.String("o", "", "report file, stdout by default")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: import [flags] <file|->")
+	}
+
+	input := os.Stdin
+	if fs.Arg(0) != "-" {
+		input, err = os.Open(fs.Arg(0))
+		if err != nil {
+			return fmt.Errorf("error opening input file: %v", err)
+		}
+		defer input.Close()
+	}
+
+	if *format == "" {
+		*format = importer.FormatFromFileName(fs.Arg(0))
+	}
+
+	if *format == importer.FormatBundle {
+		if input == os.Stdin {
+			return fmt.Errorf("bundles must be imported from a file")
+		}
+		info, err := input.Stat()
+		if err != nil {
+			return fmt.Errorf("error reading input file: %v", err)
+		}
+		report, err := importer.RestoreBundle(repo, input, info.Size(), *dryRun)
+		return writeReport(report, err, *output)
+	}
+
+	imp := importer.Importer{
+		Repo: repo,
+		Options: importer.Options{
+			Format:    *format,
+			BatchSize: *batchSize,
+			DryRun:    *dryRun,
+			Upsert:    *upsert,
+		},
+	}
+	if *enrich {
+		imp.Client, err = musicinfo.NewClient()
+		if err != nil {
+			return err
+		}
+	}
+
+	report, err := imp.Import(input)
+	return writeReport(report, err, *output)
+}
+
+// writeReport writes an import report as JSON and returns the import error.
+func writeReport(report *models.ImportReport, importErr error, output string) error {
+	out, err := createOutput(output)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	err = encoder.Encode(report)
+	if err != nil {
+		return fmt.Errorf("error writing report: %v", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "created %d, updated %d, skipped %d, failed %d\n", report.Created, report.Updated, report.Skipped, report.Failed)
+	return importErr
+}
+
+// runExport streams the catalogue as NDJSON, CSV or a zipped JSON bundle.
+func runExport(repo *connection.Repository, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	format := fs.String("format", exporter.FormatNDJSON, "output format: ndjson, csv or bundle")
+	output := fs.String("o", "", "output file, stdout by default")
+	filter := songFilterFlags(fs)
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	out, err := createOutput(*output)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	return exporter.Export(repo, out, *format, *filter)
+}
+
+// runScan imports songs from the tags of the audio files in a directory and writes the report as JSON.
+func runScan(repo *connection.Repository, args []string) error {
+	fs := flag.NewFlagSet("scan", flag.ExitOnError)
+	statePath := fs.String("state", ".music-scan.json", "file remembering scanned files between runs")
+	full := fs.Bool("full", false, "rescan files that did not change since the previous scan")
+	dryRun := fs.Bool("dry-run", false, "report what would change without saving")
+	overwrite := fs.Bool("overwrite", false, "replace stored values with tag values instead of reporting conflicts")
+	output := fs.String("o", "", "report file, stdout by default")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: scan [flags] <directory>")
+	}
+
+	state, err := scanner.LoadState(*statePath)
+	if err != nil {
+		return err
+	}
+	s := scanner.Scanner{Repo: repo, State: state, Full: *full, DryRun: *dryRun, Overwrite: *overwrite}
+	report, scanErr := s.Scan(fs.Arg(0))
+
+	if !*dryRun {
+		err = state.Save()
+		if err != nil {
+			return err
+		}
+	}
+
+	out, err := createOutput(*output)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	err = encoder.Encode(report)
+	if err != nil {
+		return fmt.Errorf("error writing report: %v", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "created %d, updated %d, unchanged %d, conflicts %d, failed %d, skipped %d\n",
+		report.Created, report.Updated, report.Unchanged, report.Conflicts, report.Failed, report.Skipped)
+	return scanErr
+}
+
+// runRole changes the role of a user. It bootstraps the first admin, who can then
+// manage roles through the API.
+func runRole(repo *connection.Repository, args []string) error {
+	fs := flag.NewFlagSet("role", flag.ExitOnError)
+	name := fs.String("user", "", "user name")
+	role := fs.String("role", "", fmt.Sprintf("new role, one of %v", auth.Roles))
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	if *name == "" || !auth.ValidRole(*role) {
+		return fmt.Errorf("usage: role -user <name> -role <%s|%s|%s>", auth.RoleReader, auth.RoleEditor, auth.RoleAdmin)
+	}
+
+	user, _, err := repo.UserCredentials(*name)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return fmt.Errorf("no such user: %v", *name)
+	}
+
+	_, err = repo.SetUserRole(user.ID, *role)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "%s is now %s\n", user.Name, *role)
+	return nil
+}
@@ -0,0 +1,115 @@
+package connection
+
+import (
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/noctusha/music/models"
+)
+
+// songLyricsFrom joins the songs with their groups and details; the default text is selected as empty.
+const songLyricsFrom = `
+FROM
+	songs
+JOIN
+	groups
+ON
+	groups.id = songs.group_id
+LEFT JOIN
+	song_details
+ON
+	song_details.song_id = songs.id`
+
+// songLyricsHash is a hash of the names and the text of a song, which changes when any of them does.
+const songLyricsHash = `md5(groups.name || chr(31) || songs.name || chr(31) || COALESCE(NULLIF(song_details.text, $1), ''))`
+
+// songLyricsQuery selects the lyrics of songs with their names and hash.
+const songLyricsQuery = `
+SELECT
+	songs.id,
+	songs.group_id,
+	groups.name,
+	songs.name,
+	COALESCE(NULLIF(song_details.text, $1), ''),
+	` + songLyricsHash + songLyricsFrom
+
+// SongLyrics retrieves the lyrics of a song. It returns nil when the song does not exist.
+func (r *Repository) SongLyrics(songID int) (*models.SongLyrics, error) {
+	songs, err := r.queryLyrics(songLyricsQuery+` WHERE songs.id = $2`, models.UnknownValue, songID)
+	if err != nil {
+		return nil, err
+	}
+	if len(songs) == 0 {
+		return nil, nil
+	}
+	return &songs[0], nil
+}
+
+// GroupLyrics retrieves the lyrics of all songs of a group.
+func (r *Repository) GroupLyrics(groupID int) ([]models.SongLyrics, error) {
+	return r.queryLyrics(songLyricsQuery+` WHERE songs.group_id = $2 ORDER BY songs.id`, models.UnknownValue, groupID)
+}
+
+// AllSongLyrics retrieves the lyrics of all songs.
+func (r *Repository) AllSongLyrics() ([]models.SongLyrics, error) {
+	return r.queryLyrics(songLyricsQuery+` ORDER BY songs.id`, models.UnknownValue)
+}
+
+// SongLyricsByIDs retrieves the lyrics of the songs with the given ids, ordered by id.
+func (r *Repository) SongLyricsByIDs(ids []int) ([]models.SongLyrics, error) {
+	return r.queryLyrics(songLyricsQuery+` WHERE songs.id = ANY($2) ORDER BY songs.id`, models.UnknownValue, pq.Array(ids))
+}
+
+// SongLyricsHashes retrieves the hash of the lyrics of every song by song id, without the lyrics.
+func (r *Repository) SongLyricsHashes() (map[int]string, error) {
+	rows, err := r.db.Query(`SELECT songs.id, `+songLyricsHash+songLyricsFrom, models.UnknownValue)
+	if err != nil {
+		return nil, fmt.Errorf("error executing query: %v", err)
+	}
+	defer rows.Close()
+
+	hashes := make(map[int]string)
+	for rows.Next() {
+		var (
+			songID int
+			hash   string
+		)
+		err = rows.Scan(&songID, &hash)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning song hash: %v", err)
+		}
+		hashes[songID] = hash
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("rows iteration error: %v", err)
+	}
+
+	return hashes, nil
+}
+
+func (r *Repository) queryLyrics(query string, params ...interface{}) ([]models.SongLyrics, error) {
+	rows, err := r.db.Query(query, params...)
+	if err != nil {
+		return nil, fmt.Errorf("error executing query: %v", err)
+	}
+	defer rows.Close()
+
+	songs := []models.SongLyrics{}
+	for rows.Next() {
+		var song models.SongLyrics
+		err = rows.Scan(&song.SongID, &song.GroupID, &song.Group, &song.Song, &song.Text, &song.Hash)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning song lyrics: %v", err)
+		}
+		songs = append(songs, song)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("rows iteration error: %v", err)
+	}
+
+	return songs, nil
+}
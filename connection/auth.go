@@ -0,0 +1,197 @@
+package connection
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/noctusha/music/models"
+)
+
+// CreateUser creates a new user with a password hash and returns it.
+func (r *Repository) CreateUser(name, passwordHash string) (*models.User, error) {
+	user := models.User{Name: name}
+	err := r.db.QueryRow("INSERT INTO users (name, password_hash) VALUES ($1, $2) RETURNING id, role, created_at", name, passwordHash).Scan(&user.ID, &user.Role, &user.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("error inserting user: %v", err)
+	}
+	return &user, nil
+}
+
+// GetUserByID retrieves a user by its ID.
+func (r *Repository) GetUserByID(id int) (*models.User, error) {
+	var user models.User
+	err := r.db.QueryRow("SELECT id, name, role, created_at FROM users WHERE id = $1", id).Scan(&user.ID, &user.Name, &user.Role, &user.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error scanning user: %v", err)
+	}
+	return &user, nil
+}
+
+// UserCredentials retrieves a user and their password hash by name.
+func (r *Repository) UserCredentials(name string) (*models.User, string, error) {
+	var user models.User
+	var hash string
+	err := r.db.QueryRow("SELECT id, name, role, created_at, password_hash FROM users WHERE name = $1", name).Scan(&user.ID, &user.Name, &user.Role, &user.CreatedAt, &hash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, "", nil
+		}
+		return nil, "", fmt.Errorf("error scanning user: %v", err)
+	}
+	return &user, hash, nil
+}
+
+// CreateAPIKey stores the hash of a new API key of a user.
+func (r *Repository) CreateAPIKey(userID int, name, prefix, hash string) (*models.APIKey, error) {
+	key := models.APIKey{Name: name, Prefix: prefix}
+	err := r.db.QueryRow("INSERT INTO api_keys (user_id, name, prefix, key_hash) VALUES ($1, $2, $3, $4) RETURNING id, created_at",
+		userID, name, prefix, hash).Scan(&key.ID, &key.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("error inserting API key: %v", err)
+	}
+	return &key, nil
+}
+
+// ListAPIKeys retrieves the API keys of a user without the keys themselves.
+func (r *Repository) ListAPIKeys(userID int) ([]models.APIKey, error) {
+	rows, err := r.db.Query("SELECT id, name, prefix, created_at, last_used_at FROM api_keys WHERE user_id = $1 ORDER BY id", userID)
+	if err != nil {
+		return nil, fmt.Errorf("error executing query: %v", err)
+	}
+	defer rows.Close()
+
+	keys := []models.APIKey{}
+	for rows.Next() {
+		var key models.APIKey
+		err = rows.Scan(&key.ID, &key.Name, &key.Prefix, &key.CreatedAt, &key.LastUsedAt)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning API key: %v", err)
+		}
+		keys = append(keys, key)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("rows iteration error: %v", err)
+	}
+
+	return keys, nil
+}
+
+// DeleteAPIKey deletes an API key of a user and reports whether it existed.
+func (r *Repository) DeleteAPIKey(userID, keyID int) (bool, error) {
+	result, err := r.db.Exec("DELETE FROM api_keys WHERE id = $1 AND user_id = $2", keyID, userID)
+	if err != nil {
+		return false, fmt.Errorf("error deleting API key: %v", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error deleting API key: %v", err)
+	}
+	return deleted > 0, nil
+}
+
+// UserByAPIKey retrieves the owner of an API key by the key hash and records its use.
+func (r *Repository) UserByAPIKey(hash string) (*models.User, error) {
+	var user models.User
+	err := r.db.QueryRow(`
+UPDATE api_keys SET last_used_at = now()
+FROM users
+WHERE api_keys.key_hash = $1 AND users.id = api_keys.user_id
+RETURNING users.id, users.name, users.role, users.created_at`, hash).Scan(&user.ID, &user.Name, &user.Role, &user.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error scanning user: %v", err)
+	}
+	return &user, nil
+}
+
+// SetUserRole changes the role of a user and reports whether the user exists.
+func (r *Repository) SetUserRole(userID int, role string) (bool, error) {
+	result, err := r.db.Exec("UPDATE users SET role = $1 WHERE id = $2", role, userID)
+	if err != nil {
+		return false, fmt.Errorf("error updating user: %v", err)
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error updating user: %v", err)
+	}
+	return updated > 0, nil
+}
+
+// IsGroupMaintainer reports whether a user maintains a group.
+func (r *Repository) IsGroupMaintainer(groupID, userID int) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow("SELECT EXISTS (SELECT 1 FROM group_maintainers WHERE group_id = $1 AND user_id = $2)", groupID, userID).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("error scanning group maintainer: %v", err)
+	}
+	return exists, nil
+}
+
+// GroupMaintainers retrieves the users maintaining a group.
+func (r *Repository) GroupMaintainers(groupID int) ([]models.User, error) {
+	rows, err := r.db.Query(`
+SELECT
+	users.id,
+	users.name,
+	users.role,
+	users.created_at
+FROM
+	group_maintainers
+JOIN
+	users
+ON
+	users.id = group_maintainers.user_id
+WHERE
+	group_maintainers.group_id = $1
+ORDER BY
+	users.id`, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("error executing query: %v", err)
+	}
+	defer rows.Close()
+
+	users := []models.User{}
+	for rows.Next() {
+		var user models.User
+		err = rows.Scan(&user.ID, &user.Name, &user.Role, &user.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning user: %v", err)
+		}
+		users = append(users, user)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("rows iteration error: %v", err)
+	}
+
+	return users, nil
+}
+
+// AddGroupMaintainer makes a user a maintainer of a group.
+func (r *Repository) AddGroupMaintainer(groupID, userID int) error {
+	_, err := r.db.Exec("INSERT INTO group_maintainers (group_id, user_id) VALUES ($1, $2) ON CONFLICT DO NOTHING", groupID, userID)
+	if err != nil {
+		return fmt.Errorf("error inserting group maintainer: %v", err)
+	}
+	return nil
+}
+
+// RemoveGroupMaintainer removes a user from the maintainers of a group.
+func (r *Repository) RemoveGroupMaintainer(groupID, userID int) error {
+	_, err := r.db.Exec("DELETE FROM group_maintainers WHERE group_id = $1 AND user_id = $2", groupID, userID)
+	if err != nil {
+		return fmt.Errorf("error deleting group maintainer: %v", err)
+	}
+	return nil
+}
@@ -4,16 +4,19 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"github.com/noctusha/music/lyrics"
 	"github.com/noctusha/music/models"
 	"os"
 	"strings"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 // Repository provides methods to interact with the database.
 type Repository struct {
 	db *sql.DB
+	// Explicit flags the songs whose lyrics it matches when they are written.
+	Explicit *lyrics.WordList
 }
 
 // NewRepository creates a new Repository with a database connection.
@@ -42,69 +45,53 @@ func (r *Repository) Close() {
 }
 
 // SongList retrieves a list of songs from the database with optional filters and pagination.
-func (r *Repository) SongList(group, name, releaseDate, text, link string, limit, offset int) ([]models.Song, error) {
+func (r *Repository) SongList(filter models.SongFilter) ([]models.Song, error) {
 	var songs []models.Song
 	var (
-		rows         *sql.Rows
-		err          error
-		params       []interface{}
-		whereClauses []string
+		rows   *sql.Rows
+		err    error
+		params []interface{}
 	)
 
-	if limit == 0 {
-		limit = 25
+	if filter.Limit == 0 {
+		filter.Limit = 25
 	}
 
 	query := `
 SELECT
 	songs.id,
 	songs.name,
-	songs.group_id
+	songs.group_id,
+	songs.explicit,
+	song_stats.average_rating,
+	song_stats.ratings,
+	song_stats.plays,
+	song_stats.favorites,
+	EXISTS (SELECT 1 FROM song_favorites WHERE song_favorites.song_id = songs.id AND song_favorites.user_id = $1),
+	COALESCE((SELECT rating FROM song_ratings WHERE song_ratings.song_id = songs.id AND song_ratings.user_id = $1), 0)
 FROM
     songs
 JOIN
 	song_details
 ON
-	songs.id = song_details.song_id`
-
-	if group != "" {
-		whereClauses = append(whereClauses, "songs.group_id = (SELECT id FROM groups WHERE name ILIKE $"+fmt.Sprint(len(params)+1)+")")
-		params = append(params, "%"+group+"%")
-	}
-
-	if name != "" {
-		whereClauses = append(whereClauses, "songs.name ILIKE $"+fmt.Sprint(len(params)+1))
-		params = append(params, "%"+name+"%")
-	}
-
-	if releaseDate != "" {
-		whereClauses = append(whereClauses, "song_details.release_date = $"+fmt.Sprint(len(params)+1))
-		params = append(params, releaseDate)
-	}
-
-	if text != "" {
-		whereClauses = append(whereClauses, "song_details.text ILIKE $"+fmt.Sprint(len(params)+1))
-		params = append(params, "%"+text+"%")
-	}
-
-	if link != "" {
-		whereClauses = append(whereClauses, "song_details.link = $"+fmt.Sprint(len(params)+1))
-		params = append(params, link)
-	}
+	songs.id = song_details.song_id
+JOIN
+	song_stats
+ON
+	song_stats.song_id = songs.id`
 
-	if len(whereClauses) > 0 {
-		query += " WHERE " + strings.Join(whereClauses, " AND ")
-	}
+	params = append(params, filter.UserID)
+	query, params = applySongFilter(query, params, filter)
 
 	query += `
 ORDER BY
-	songs.name
+	` + songOrder(filter) + `
 LIMIT
 	$` + fmt.Sprint(len(params)+1) + `
 OFFSET
 	$` + fmt.Sprint(len(params)+2)
 
-	params = append(params, limit, offset)
+	params = append(params, filter.Limit, filter.Offset)
 
 	rows, err = r.db.Query(query, params...)
 	if err != nil {
@@ -113,8 +100,9 @@ OFFSET
 	defer rows.Close()
 
 	for rows.Next() {
-		song := models.Song{}
-		err = rows.Scan(&song.ID, &song.Name, &song.GroupID)
+		song := models.Song{Stats: &models.SongStats{}}
+		err = rows.Scan(&song.ID, &song.Name, &song.GroupID, &song.Explicit, &song.Stats.AverageRating, &song.Stats.Ratings,
+			&song.Stats.Plays, &song.Stats.Favorites, &song.Stats.Favorite, &song.Stats.Rating)
 		if err != nil {
 			return nil, fmt.Errorf("error scanning song: %v", err)
 		}
@@ -133,6 +121,169 @@ OFFSET
 	return songs, nil
 }
 
+// GroupSongLists retrieves a page of the songs of each of the given groups by name, with their stats for
+// a user, in a single query.
+func (r *Repository) GroupSongLists(groupIDs []int, userID, limit, offset int) (map[int][]models.Song, error) {
+	rows, err := r.db.Query(`
+SELECT
+	id, name, group_id, explicit, average_rating, ratings, plays, favorites, favorite, rating
+FROM (
+	SELECT
+		songs.id,
+		songs.name,
+		songs.group_id,
+		songs.explicit,
+		song_stats.average_rating,
+		song_stats.ratings,
+		song_stats.plays,
+		song_stats.favorites,
+		EXISTS (SELECT 1 FROM song_favorites WHERE song_favorites.song_id = songs.id AND song_favorites.user_id = $1) AS favorite,
+		COALESCE((SELECT rating FROM song_ratings WHERE song_ratings.song_id = songs.id AND song_ratings.user_id = $1), 0) AS rating,
+		ROW_NUMBER() OVER (PARTITION BY songs.group_id ORDER BY songs.name, songs.id) AS position
+	FROM
+		songs
+	JOIN
+		song_details
+	ON
+		songs.id = song_details.song_id
+	JOIN
+		song_stats
+	ON
+		song_stats.song_id = songs.id
+	WHERE
+		songs.group_id = ANY($2)
+) AS ranked
+WHERE
+	position > $3 AND position <= $3 + $4
+ORDER BY
+	group_id, position`, userID, pq.Array(groupIDs), offset, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error executing query: %v", err)
+	}
+	defer rows.Close()
+
+	songs := make(map[int][]models.Song)
+	for rows.Next() {
+		song := models.Song{Stats: &models.SongStats{}}
+		err = rows.Scan(&song.ID, &song.Name, &song.GroupID, &song.Explicit, &song.Stats.AverageRating, &song.Stats.Ratings,
+			&song.Stats.Plays, &song.Stats.Favorites, &song.Stats.Favorite, &song.Stats.Rating)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning song: %v", err)
+		}
+		songs[song.GroupID] = append(songs[song.GroupID], song)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("rows iteration error: %v", err)
+	}
+
+	return songs, nil
+}
+
+// searchKeyMatch is the condition that column contains the search key of the parameter with the given number.
+func searchKeyMatch(column, param string) string {
+	return "(search_key($" + param + "::text) <> '' AND " + column + " LIKE '%' || search_key($" + param + "::text) || '%')"
+}
+
+// applySongFilter appends the WHERE clause for the filter to a query over songs joined with song_details.
+func applySongFilter(query string, params []interface{}, filter models.SongFilter) (string, []interface{}) {
+	var whereClauses []string
+
+	if filter.GroupID != 0 {
+		whereClauses = append(whereClauses, "songs.group_id = $"+fmt.Sprint(len(params)+1))
+		params = append(params, filter.GroupID)
+	}
+
+	// Names match as ILIKE patterns, or by a search key containing the search key of the filter.
+	if filter.Group != "" {
+		pattern, key := fmt.Sprint(len(params)+1), fmt.Sprint(len(params)+2)
+		whereClauses = append(whereClauses, "songs.group_id IN (SELECT id FROM groups WHERE name ILIKE $"+pattern+" OR "+searchKeyMatch("search_key", key)+
+			" UNION SELECT group_id FROM group_aliases WHERE name ILIKE $"+pattern+" OR "+searchKeyMatch("search_key", key)+")")
+		params = append(params, "%"+filter.Group+"%", filter.Group)
+	}
+
+	if filter.Name != "" {
+		pattern, key := fmt.Sprint(len(params)+1), fmt.Sprint(len(params)+2)
+		whereClauses = append(whereClauses, "(songs.name ILIKE $"+pattern+" OR "+searchKeyMatch("songs.search_key", key)+")")
+		params = append(params, "%"+filter.Name+"%", filter.Name)
+	}
+
+	if filter.ReleaseDate != "" {
+		whereClauses = append(whereClauses, "song_details.release_date = $"+fmt.Sprint(len(params)+1))
+		params = append(params, filter.ReleaseDate)
+	}
+
+	if filter.Text != "" {
+		whereClauses = append(whereClauses, "song_details.text ILIKE $"+fmt.Sprint(len(params)+1))
+		params = append(params, "%"+filter.Text+"%")
+	}
+
+	if filter.Link != "" {
+		whereClauses = append(whereClauses, "song_details.link = $"+fmt.Sprint(len(params)+1))
+		params = append(params, filter.Link)
+	}
+
+	if filter.FavoritesOnly {
+		whereClauses = append(whereClauses, "songs.id IN (SELECT song_id FROM song_favorites WHERE user_id = $"+fmt.Sprint(len(params)+1)+")")
+		params = append(params, filter.UserID)
+	}
+
+	if filter.MinRating > 0 {
+		whereClauses = append(whereClauses, "songs.id IN (SELECT song_id FROM song_stats WHERE average_rating >= $"+fmt.Sprint(len(params)+1)+")")
+		params = append(params, filter.MinRating)
+	}
+
+	if filter.ExcludeExplicit {
+		whereClauses = append(whereClauses, "NOT songs.explicit")
+	}
+
+	for _, labels := range []struct {
+		kind  string
+		names []string
+	}{{models.TagKindTag, filter.Tags}, {models.TagKindMood, filter.Moods}} {
+		if len(labels.names) == 0 {
+			continue
+		}
+
+		clause := fmt.Sprintf(`songs.id IN (SELECT song_effective_tags.song_id FROM song_effective_tags
+JOIN tags ON tags.id = song_effective_tags.tag_id WHERE tags.kind = $%d AND tags.name = ANY($%d)`, len(params)+1, len(params)+2)
+		params = append(params, labels.kind, pq.Array(labels.names))
+		if filter.TagMatch != models.TagMatchAny {
+			// The names are distinct, so a song has all of them when it has as many.
+			clause += fmt.Sprintf(" GROUP BY song_effective_tags.song_id HAVING count(*) = $%d", len(params)+1)
+			params = append(params, len(labels.names))
+		}
+		whereClauses = append(whereClauses, clause+")")
+	}
+
+	if filter.Genre != "" {
+		whereClauses = append(whereClauses, `songs.id IN (SELECT song_effective_genres.song_id FROM song_effective_genres
+JOIN genre_ancestors ON genre_ancestors.genre_id = song_effective_genres.genre_id
+JOIN genres ON genres.id = genre_ancestors.ancestor_id WHERE lower(genres.name) = lower($`+fmt.Sprint(len(params)+1)+`))`)
+		params = append(params, filter.Genre)
+	}
+
+	if len(whereClauses) > 0 {
+		query += " WHERE " + strings.Join(whereClauses, " AND ")
+	}
+
+	return query, params
+}
+
+// songOrder returns the ORDER BY expressions of a song query joined with song_stats for the sort order
+// of a filter. Popular songs are the most played, then the most favourited and the best rated.
+func songOrder(filter models.SongFilter) string {
+	switch filter.Sort {
+	case models.SortPopularity:
+		return "song_stats.plays DESC, song_stats.favorites DESC, song_stats.average_rating DESC, songs.name"
+	case models.SortRating:
+		return "song_stats.average_rating DESC, song_stats.ratings DESC, songs.name"
+	default:
+		return "songs.name"
+	}
+}
+
 // TextListByID retrieves the text of a song by its ID.
 func (r *Repository) TextListByID(id string) (string, bool, error) {
 	var text string
@@ -156,11 +307,27 @@ func (r *Repository) SongDelete(songID string) error {
 	return nil
 }
 
-// GetGroupID retrieves the ID of a group by its name.
+// groupByNameQuery selects the ID of the group with the exact name $1, or else with an alias equal to it
+// regardless of case, or else with a name or alias of the same search key: regardless of accents,
+// punctuation and whether it is spelled in Cyrillic or Latin.
+const groupByNameQuery = `
+SELECT id FROM (
+	SELECT id, 0 AS priority FROM groups WHERE name = $1
+	UNION ALL
+	SELECT group_id, 1 FROM group_aliases WHERE lower(name) = lower($1)
+	UNION ALL
+	SELECT id, 2 FROM groups WHERE search_key = search_key($1) AND search_key <> ''
+	UNION ALL
+	SELECT group_id, 3 FROM group_aliases WHERE search_key = search_key($1) AND search_key <> ''
+) AS found
+ORDER BY priority, id
+LIMIT 1`
+
+// GetGroupID retrieves the ID of a group by its name or one of its aliases.
 func (r *Repository) GetGroupID(group string) (int, error) {
 	var id int
 
-	err := r.db.QueryRow("SELECT id FROM groups WHERE name = $1", group).Scan(&id)
+	err := r.db.QueryRow(groupByNameQuery, group).Scan(&id)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return 0, nil
@@ -174,7 +341,7 @@ func (r *Repository) GetGroupID(group string) (int, error) {
 func (r *Repository) GetSongByID(songID string) (*models.Song, error) {
 	var song models.Song
 
-	err := r.db.QueryRow("SELECT id, name, group_id FROM songs WHERE id = $1", songID).Scan(&song.ID, &song.Name, &song.GroupID)
+	err := r.db.QueryRow("SELECT id, name, group_id, explicit FROM songs WHERE id = $1", songID).Scan(&song.ID, &song.Name, &song.GroupID, &song.Explicit)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
@@ -185,6 +352,30 @@ func (r *Repository) GetSongByID(songID string) (*models.Song, error) {
 	return &song, nil
 }
 
+// GetGroupByID retrieves a group by its ID.
+func (r *Repository) GetGroupByID(groupID int) (*models.Group, error) {
+	var group models.Group
+
+	err := r.db.QueryRow("SELECT id, name FROM groups WHERE id = $1", groupID).Scan(&group.ID, &group.Name)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error scanning group: %v", err)
+	}
+
+	return &group, nil
+}
+
+// DeleteGroup deletes a group with its songs.
+func (r *Repository) DeleteGroup(groupID int) error {
+	_, err := r.db.Exec("DELETE FROM groups WHERE id = $1", groupID)
+	if err != nil {
+		return fmt.Errorf("error deleting group: %v", err)
+	}
+	return nil
+}
+
 // NewGroup creates a new group in the database.
 func (r *Repository) NewGroup(name string) (int, error) {
 	var id int
@@ -200,7 +391,7 @@ func (r *Repository) NewGroup(name string) (int, error) {
 func (r *Repository) GetSongDetailsByID(songID string) (*models.SongDetails, error) {
 	var songDetails models.SongDetails
 
-	err := r.db.QueryRow("SELECT id, song_id, release_date, text, link FROM song_details WHERE song_id = $1", songID).Scan(&songDetails.ID, &songDetails.SongID, &songDetails.ReleaseDate, &songDetails.Text, &songDetails.Link)
+	err := r.db.QueryRow("SELECT id, song_id, release_date, text, link, album FROM song_details WHERE song_id = $1", songID).Scan(&songDetails.ID, &songDetails.SongID, &songDetails.ReleaseDate, &songDetails.Text, &songDetails.Link, &songDetails.Album)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
@@ -229,12 +420,29 @@ func (r *Repository) UpdateSong(song *models.Song, songDetails *models.SongDetai
 		}
 	}()
 
-	_, err = tx.Exec(`UPDATE songs SET name = $1, group_id = $2 WHERE id = $3`, song.Name, song.GroupID, song.ID)
+	err = updateSong(tx, r.Explicit, song, songDetails)
+	return err
+}
+
+// updateSong updates a song and its details in a transaction.
+func updateSong(tx *sql.Tx, explicit *lyrics.WordList, song *models.Song, songDetails *models.SongDetails) error {
+	// A pending duplicate stays exempt from the unique name only while its name and group are unchanged.
+	_, err := tx.Exec(`
+UPDATE songs SET
+	name = $1,
+	group_id = $2,
+	explicit_detected = COALESCE($3, explicit_detected),
+	pending_duplicate = pending_duplicate AND group_id = $2 AND name_key = song_name_key($1)
+WHERE id = $4`,
+		song.Name, song.GroupID, explicit.Detect(songDetails.Text), song.ID)
 	if err != nil {
+		if violates(err, uniqueViolation) {
+			return ErrSongExists
+		}
 		return fmt.Errorf("error updating song: %v", err)
 	}
 
-	_, err = tx.Exec(`UPDATE song_details SET release_date = $1, text = $2, link = $3 WHERE song_id = $4`, songDetails.ReleaseDate, songDetails.Text, songDetails.Link, songDetails.SongID)
+	_, err = tx.Exec(`UPDATE song_details SET release_date = $1, text = $2, link = $3, album = $4 WHERE song_id = $5`, songDetails.ReleaseDate, songDetails.Text, songDetails.Link, songDetails.Album, songDetails.SongID)
 	if err != nil {
 		return fmt.Errorf("error updating song_details: %v", err)
 	}
@@ -242,11 +450,11 @@ func (r *Repository) UpdateSong(song *models.Song, songDetails *models.SongDetai
 	return nil
 }
 
-// CreateSongWithDetails creates a new song and its details in the database.
-func (r *Repository) CreateSongWithDetails(song models.Song, details models.SongDetails) error {
+// CreateSongWithDetails creates a new song and its details in the database and returns the song ID.
+func (r *Repository) CreateSongWithDetails(song models.Song, details models.SongDetails) (int, error) {
 	tx, err := r.db.Begin()
 	if err != nil {
-		return fmt.Errorf("failed to start transaction: %v", err)
+		return 0, fmt.Errorf("failed to start transaction: %v", err)
 	}
 
 	defer func() {
@@ -261,16 +469,217 @@ func (r *Repository) CreateSongWithDetails(song models.Song, details models.Song
 	}()
 
 	var songID int
-	err = tx.QueryRow(`INSERT INTO songs (name, group_id) VALUES ($1, $2) RETURNING id`, song.Name, song.GroupID).Scan(&songID)
+	err = tx.QueryRow(`INSERT INTO songs (name, group_id, explicit_detected) VALUES ($1, $2, COALESCE($3::boolean, FALSE)) RETURNING id`,
+		song.Name, song.GroupID, r.Explicit.Detect(details.Text)).Scan(&songID)
 	if err != nil {
-		return fmt.Errorf("failed to insert song: %v", err)
+		if violates(err, uniqueViolation) {
+			return 0, ErrSongExists
+		}
+		return 0, fmt.Errorf("failed to insert song: %v", err)
 	}
 
-	_, err = tx.Exec(`INSERT INTO song_details (song_id, release_date, text, link) VALUES ($1, $2, $3, $4)`,
-		songID, details.ReleaseDate, details.Text, details.Link)
+	_, err = tx.Exec(`INSERT INTO song_details (song_id, release_date, text, link, album) VALUES ($1, $2, $3, $4, $5)`,
+		songID, details.ReleaseDate, details.Text, details.Link, details.Album)
 	if err != nil {
-		return fmt.Errorf("failed to insert song details: %v", err)
+		return 0, fmt.Errorf("failed to insert song details: %v", err)
 	}
 
-	return nil
+	return songID, nil
+}
+
+const detailedSongQuery = `
+SELECT
+	songs.id,
+	songs.name,
+	songs.group_id,
+	songs.explicit,
+	groups.name,
+	song_details.id,
+	song_details.song_id,
+	song_details.release_date,
+	song_details.text,
+	song_details.link,
+	song_details.album
+FROM
+	songs
+JOIN
+	groups
+ON
+	groups.id = songs.group_id
+JOIN
+	song_details
+ON
+	songs.id = song_details.song_id`
+
+// DetailedSongList retrieves songs with their group names and details using the ListSongs filters.
+func (r *Repository) DetailedSongList(filter models.SongFilter) ([]models.DetailedSong, error) {
+	var params []interface{}
+
+	if filter.Limit == 0 {
+		filter.Limit = 25
+	}
+
+	query, params := applySongFilter(detailedSongQuery+`
+JOIN
+	song_stats
+ON
+	song_stats.song_id = songs.id`, params, filter)
+
+	query += `
+ORDER BY
+	` + songOrder(filter) + `
+LIMIT
+	$` + fmt.Sprint(len(params)+1) + `
+OFFSET
+	$` + fmt.Sprint(len(params)+2)
+
+	params = append(params, filter.Limit, filter.Offset)
+
+	return r.queryDetailedSongs(query, params...)
+}
+
+// DetailedSongsByIDs retrieves songs with their group names and details, keeping the order of ids.
+func (r *Repository) DetailedSongsByIDs(ids []int) ([]models.DetailedSong, error) {
+	songs, err := r.queryDetailedSongs(detailedSongQuery+` WHERE songs.id = ANY($1)`, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[int]models.DetailedSong, len(songs))
+	for _, song := range songs {
+		byID[song.Song.ID] = song
+	}
+
+	ordered := make([]models.DetailedSong, 0, len(songs))
+	for _, id := range ids {
+		song, ok := byID[id]
+		if !ok {
+			continue
+		}
+		ordered = append(ordered, song)
+		delete(byID, id)
+	}
+
+	return ordered, nil
+}
+
+// FindSong retrieves a song with its group name and details by the group name or alias and the
+// song name, which match regardless of case, spaces, punctuation, accents and Cyrillic or Latin spelling.
+func (r *Repository) FindSong(group, name string) (*models.DetailedSong, error) {
+	songs, err := r.queryDetailedSongs(detailedSongQuery+`
+WHERE
+	songs.group_id = (`+groupByNameQuery+`) AND songs.name_key = song_name_key($2)
+ORDER BY
+	songs.pending_duplicate, songs.id
+LIMIT 1`, group, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(songs) == 0 {
+		return nil, nil
+	}
+	return &songs[0], nil
+}
+
+// songNameQuery selects the columns of models.SongName.
+const songNameQuery = `
+SELECT
+	songs.id,
+	songs.group_id,
+	groups.name,
+	songs.name
+FROM
+	songs
+JOIN
+	groups
+ON
+	groups.id = songs.group_id`
+
+// SongNames retrieves the id, group and name of every song, ordered by id.
+func (r *Repository) SongNames() ([]models.SongName, error) {
+	return r.querySongNames(songNameQuery + `
+ORDER BY
+	songs.id`)
+}
+
+// SongNamesByIDs retrieves the id, group and name of the songs with the given ids, ordered by id.
+func (r *Repository) SongNamesByIDs(ids []int) ([]models.SongName, error) {
+	return r.querySongNames(songNameQuery+`
+WHERE
+	songs.id = ANY($1)
+ORDER BY
+	songs.id`, pq.Array(ids))
+}
+
+// GroupSongNames retrieves the id, group and name of the songs of a group, ordered by id.
+func (r *Repository) GroupSongNames(groupID int) ([]models.SongName, error) {
+	return r.querySongNames(songNameQuery+`
+WHERE
+	songs.group_id = $1
+ORDER BY
+	songs.id`, groupID)
+}
+
+// SongNamesByName retrieves the id, group and name of the songs of any group whose name matches one of
+// names regardless of case, spaces, punctuation, accents and Cyrillic or Latin spelling.
+func (r *Repository) SongNamesByName(names ...string) ([]models.SongName, error) {
+	return r.querySongNames(songNameQuery+`
+WHERE
+	songs.name_key IN (SELECT song_name_key(name) FROM unnest($1::text[]) AS name)
+ORDER BY
+	songs.pending_duplicate, songs.id`, pq.Array(names))
+}
+
+// querySongNames runs a query selecting the songNameQuery columns and scans the result.
+func (r *Repository) querySongNames(query string, params ...interface{}) ([]models.SongName, error) {
+	rows, err := r.db.Query(query, params...)
+	if err != nil {
+		return nil, fmt.Errorf("error executing query: %v", err)
+	}
+	defer rows.Close()
+
+	var names []models.SongName
+	for rows.Next() {
+		var name models.SongName
+		err = rows.Scan(&name.ID, &name.GroupID, &name.Group, &name.Name)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning song: %v", err)
+		}
+		names = append(names, name)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("rows iteration error: %v", err)
+	}
+
+	return names, nil
+}
+
+// queryDetailedSongs runs a query selecting the detailedSongQuery columns and scans the result.
+func (r *Repository) queryDetailedSongs(query string, params ...interface{}) ([]models.DetailedSong, error) {
+	rows, err := r.db.Query(query, params...)
+	if err != nil {
+		return nil, fmt.Errorf("error executing query: %v", err)
+	}
+	defer rows.Close()
+
+	var songs []models.DetailedSong
+	for rows.Next() {
+		var song models.DetailedSong
+		err = rows.Scan(&song.Song.ID, &song.Song.Name, &song.Song.GroupID, &song.Song.Explicit, &song.Group,
+			&song.SongDetails.ID, &song.SongDetails.SongID, &song.SongDetails.ReleaseDate, &song.SongDetails.Text, &song.SongDetails.Link, &song.SongDetails.Album)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning song: %v", err)
+		}
+		songs = append(songs, song)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("rows iteration error: %v", err)
+	}
+
+	return songs, nil
 }
@@ -0,0 +1,83 @@
+package connection
+
+import (
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/noctusha/music/models"
+)
+
+// SongCredits retrieves the credits of the given songs in their order, by song ID.
+// Songs without credits have none in the map.
+func (r *Repository) SongCredits(songIDs []int) (map[int][]models.SongCredit, error) {
+	rows, err := r.db.Query(`
+SELECT
+	song_id,
+	name,
+	role
+FROM
+	song_credits
+WHERE
+	song_id = ANY($1)
+ORDER BY
+	song_id, position, id`, pq.Array(songIDs))
+	if err != nil {
+		return nil, fmt.Errorf("error executing query: %v", err)
+	}
+	defer rows.Close()
+
+	credits := make(map[int][]models.SongCredit)
+	for rows.Next() {
+		var (
+			songID int
+			credit models.SongCredit
+		)
+		err = rows.Scan(&songID, &credit.Name, &credit.Role)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning song credit: %v", err)
+		}
+		credits[songID] = append(credits[songID], credit)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("rows iteration error: %v", err)
+	}
+
+	return credits, nil
+}
+
+// SetSongCredits replaces the credits of a song, keeping their order. A name credited twice in the
+// same role is kept once.
+func (r *Repository) SetSongCredits(songID int, credits []models.SongCredit) (err error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %v", err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			tx.Rollback()
+		} else if err = tx.Commit(); err != nil {
+			err = fmt.Errorf("failed to commit transaction: %v", err)
+		}
+	}()
+
+	_, err = tx.Exec(`DELETE FROM song_credits WHERE song_id = $1`, songID)
+	if err != nil {
+		return fmt.Errorf("error deleting song credits: %v", err)
+	}
+
+	for i, credit := range credits {
+		_, err = tx.Exec(`INSERT INTO song_credits (song_id, name, role, position) VALUES ($1, $2, $3, $4) ON CONFLICT DO NOTHING`,
+			songID, credit.Name, credit.Role, i+1)
+		if err != nil {
+			return fmt.Errorf("error inserting song credit: %v", err)
+		}
+	}
+
+	return nil
+}
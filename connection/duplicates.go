@@ -0,0 +1,164 @@
+package connection
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/noctusha/music/models"
+)
+
+// Errors returned by the duplicate song methods.
+var (
+	ErrSongExists        = errors.New("the group already has a song with this name")
+	ErrSongNotFound      = errors.New("song not found")
+	ErrSongMergeIntoSelf = errors.New("a song cannot be merged into itself")
+)
+
+// FindSongInGroup retrieves the song of a group whose name matches name regardless of case, spaces,
+// punctuation, accents and Cyrillic or Latin spelling. It returns nil when the group has no such song.
+func (r *Repository) FindSongInGroup(groupID int, name string) (*models.Song, error) {
+	var song models.Song
+
+	err := r.db.QueryRow(`
+SELECT id, name, group_id, explicit FROM songs
+WHERE group_id = $1 AND name_key = song_name_key($2)
+ORDER BY pending_duplicate, id
+LIMIT 1`, groupID, name).Scan(&song.ID, &song.Name, &song.GroupID, &song.Explicit)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error scanning song: %v", err)
+	}
+
+	return &song, nil
+}
+
+// MergeSongs moves the playlist entries, favorites, ratings, plays, suggestions, genres and tags of
+// the source songs to the target song, deletes the sources and stores the details returned by merge as
+// the target's details. The songs are locked while merge combines their details. The ratings of the
+// target win over those of the sources by the same user. It returns the groups of the merged songs.
+func (r *Repository) MergeSongs(targetID int, sourceIDs []int, merge func(target models.SongDetails, sources []models.SongDetails) models.SongDetails) (groupIDs []int, err error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %v", err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			tx.Rollback()
+		} else if err = tx.Commit(); err != nil {
+			err = fmt.Errorf("failed to commit transaction: %v", err)
+		}
+	}()
+
+	for _, sourceID := range sourceIDs {
+		if sourceID == targetID {
+			return nil, ErrSongMergeIntoSelf
+		}
+	}
+
+	songs, err := lockSongDetails(tx, append([]int{targetID}, sourceIDs...))
+	if err != nil {
+		return nil, err
+	}
+
+	target, ok := songs[targetID]
+	if !ok {
+		return nil, ErrSongNotFound
+	}
+	groupIDs = []int{target.Song.GroupID}
+	sources := make([]models.SongDetails, len(sourceIDs))
+	for i, sourceID := range sourceIDs {
+		source, ok := songs[sourceID]
+		if !ok {
+			return nil, ErrSongNotFound
+		}
+		sources[i] = source.SongDetails
+		groupIDs = append(groupIDs, source.Song.GroupID)
+	}
+	details := merge(target.SongDetails, sources)
+
+	for _, sourceID := range sourceIDs {
+		for _, query := range []string{
+			`UPDATE playlist_entries SET song_id = $1 WHERE song_id = $2`,
+			`INSERT INTO song_favorites (user_id, song_id, created_at) SELECT user_id, $1, created_at FROM song_favorites WHERE song_id = $2
+ON CONFLICT DO NOTHING`,
+			`INSERT INTO song_ratings (user_id, song_id, rating, updated_at) SELECT user_id, $1, rating, updated_at FROM song_ratings WHERE song_id = $2
+ON CONFLICT DO NOTHING`,
+			// A play scrobbled for both songs at the same time is counted once.
+			`UPDATE song_plays SET song_id = $1 WHERE song_id = $2 AND NOT EXISTS (
+	SELECT 1 FROM song_plays AS kept WHERE kept.song_id = $1 AND kept.user_id = song_plays.user_id AND kept.played_at = song_plays.played_at
+)`,
+			`UPDATE song_suggestions SET song_id = $1 WHERE song_id = $2`,
+			`INSERT INTO song_genres (song_id, genre_id) SELECT $1, genre_id FROM song_genres WHERE song_id = $2 ON CONFLICT DO NOTHING`,
+			`INSERT INTO song_tags (song_id, tag_id) SELECT $1, tag_id FROM song_tags WHERE song_id = $2 ON CONFLICT DO NOTHING`,
+			// The credits of the source follow those of the target.
+			`INSERT INTO song_credits (song_id, name, role, position)
+SELECT $1, name, role, position + (SELECT COALESCE(max(position), 0) FROM song_credits WHERE song_id = $1)
+FROM song_credits WHERE song_id = $2 ON CONFLICT DO NOTHING`,
+			`DELETE FROM songs WHERE id = $2`,
+		} {
+			_, err = tx.Exec(query, targetID, sourceID)
+			if err != nil {
+				return nil, fmt.Errorf("error merging song %d: %v", sourceID, err)
+			}
+		}
+	}
+
+	_, err = tx.Exec(`UPDATE song_details SET release_date = $1, text = $2, link = $3, album = $4 WHERE song_id = $5`,
+		details.ReleaseDate, details.Text, details.Link, details.Album, targetID)
+	if err != nil {
+		return nil, fmt.Errorf("error updating song_details: %v", err)
+	}
+
+	// The target is no longer a pending duplicate once the song it duplicated is merged into it.
+	_, err = tx.Exec(`
+UPDATE songs SET
+	explicit_detected = COALESCE($1, explicit_detected),
+	pending_duplicate = EXISTS (
+		SELECT 1 FROM songs AS other
+		WHERE other.group_id = songs.group_id AND other.name_key = songs.name_key AND other.id <> songs.id AND NOT other.pending_duplicate
+	)
+WHERE id = $2`, r.Explicit.Detect(details.Text), targetID)
+	if err != nil {
+		return nil, fmt.Errorf("error updating song: %v", err)
+	}
+
+	return groupIDs, nil
+}
+
+// lockSongDetails locks the songs with the given ids and their details and returns them by song id.
+func lockSongDetails(tx *sql.Tx, ids []int) (map[int]models.DetailedSong, error) {
+	rows, err := tx.Query(detailedSongQuery+`
+WHERE songs.id = ANY($1)
+ORDER BY songs.id
+FOR UPDATE OF songs, song_details`, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("error locking songs: %v", err)
+	}
+	defer rows.Close()
+
+	songs := make(map[int]models.DetailedSong, len(ids))
+	for rows.Next() {
+		var song models.DetailedSong
+		err = rows.Scan(&song.Song.ID, &song.Song.Name, &song.Song.GroupID, &song.Song.Explicit, &song.Group,
+			&song.SongDetails.ID, &song.SongDetails.SongID, &song.SongDetails.ReleaseDate, &song.SongDetails.Text, &song.SongDetails.Link, &song.SongDetails.Album)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning song: %v", err)
+		}
+		songs[song.Song.ID] = song
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("rows iteration error: %v", err)
+	}
+
+	return songs, nil
+}
@@ -0,0 +1,78 @@
+package connection
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/noctusha/music/models"
+)
+
+// SongExplicit retrieves the explicit flag of a song. It returns nil when the song does not exist.
+func (r *Repository) SongExplicit(songID int) (*models.SongExplicit, error) {
+	explicit := models.SongExplicit{SongID: songID}
+
+	err := r.db.QueryRow(`SELECT explicit, explicit_detected, explicit_override FROM songs WHERE id = $1`, songID).
+		Scan(&explicit.Explicit, &explicit.Detected, &explicit.Override)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error scanning song: %v", err)
+	}
+
+	return &explicit, nil
+}
+
+// SetExplicitOverride overrides the explicit flag of a song, or with nil removes the override.
+func (r *Repository) SetExplicitOverride(songID int, override *bool) error {
+	_, err := r.db.Exec(`UPDATE songs SET explicit_override = $1 WHERE id = $2`, override, songID)
+	if err != nil {
+		return fmt.Errorf("error updating song: %v", err)
+	}
+	return nil
+}
+
+// DetectExplicit flags the songs whose lyrics match the explicit words again, for songs
+// written before the words changed, and returns the number of songs whose flag changed.
+func (r *Repository) DetectExplicit() (int, error) {
+	rows, err := r.db.Query(`SELECT song_id, text FROM song_details`)
+	if err != nil {
+		return 0, fmt.Errorf("error executing query: %v", err)
+	}
+	defer rows.Close()
+
+	ids := []int64{}
+	for rows.Next() {
+		var (
+			songID int64
+			text   string
+		)
+		err = rows.Scan(&songID, &text)
+		if err != nil {
+			return 0, fmt.Errorf("error scanning song text: %v", err)
+		}
+		if r.Explicit.Explicit(text) {
+			ids = append(ids, songID)
+		}
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return 0, fmt.Errorf("rows iteration error: %v", err)
+	}
+
+	result, err := r.db.Exec(`
+UPDATE songs SET explicit_detected = NOT explicit_detected
+WHERE explicit_detected <> (id = ANY($1::integer[]))`, pq.Array(ids))
+	if err != nil {
+		return 0, fmt.Errorf("error updating songs: %v", err)
+	}
+
+	changed, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("error counting updated songs: %v", err)
+	}
+	return int(changed), nil
+}
@@ -0,0 +1,132 @@
+package connection
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/noctusha/music/models"
+)
+
+// exportFetchSize is the number of rows fetched from the server-side cursor at once.
+const exportFetchSize = 500
+
+// ExportSongs streams the songs matching filter to fn, ordered by id. Rows are read
+// through a server-side cursor, so memory use does not depend on the catalogue size.
+// A zero limit exports every matching song.
+func (r *Repository) ExportSongs(filter models.SongFilter, fn func(models.ExportSong) error) error {
+	var params []interface{}
+
+	query := `
+SELECT
+	songs.id,
+	songs.group_id,
+	song_details.id,
+	groups.name,
+	songs.name,
+	COALESCE(to_char(song_details.release_date, 'YYYY-MM-DD'), ''),
+	song_details.text,
+	song_details.link,
+	song_details.album
+FROM
+	songs
+JOIN
+	groups
+ON
+	groups.id = songs.group_id
+JOIN
+	song_details
+ON
+	songs.id = song_details.song_id`
+
+	query, params = applySongFilter(query, params, filter)
+
+	query += `
+ORDER BY
+	songs.id`
+
+	if filter.Limit > 0 {
+		query += ` LIMIT $` + fmt.Sprint(len(params)+1)
+		params = append(params, filter.Limit)
+	}
+	if filter.Offset > 0 {
+		query += ` OFFSET $` + fmt.Sprint(len(params)+1)
+		params = append(params, filter.Offset)
+	}
+
+	return r.withCursor(query, params, func(rows *sql.Rows) error {
+		var song models.ExportSong
+		err := rows.Scan(&song.SongID, &song.GroupID, &song.DetailsID, &song.Group, &song.Song, &song.ReleaseDate, &song.Text, &song.Link, &song.Album)
+		if err != nil {
+			return fmt.Errorf("error scanning song: %v", err)
+		}
+		return fn(song)
+	})
+}
+
+// ExportGroups streams groups to fn, ordered by id. A nil ids exports every group.
+func (r *Repository) ExportGroups(ids []int, fn func(models.Group) error) error {
+	query := `SELECT id, name FROM groups`
+	var params []interface{}
+	if ids != nil {
+		query += ` WHERE id = ANY($1)`
+		params = append(params, pq.Array(ids))
+	}
+	query += ` ORDER BY id`
+
+	return r.withCursor(query, params, func(rows *sql.Rows) error {
+		var group models.Group
+		err := rows.Scan(&group.ID, &group.Name)
+		if err != nil {
+			return fmt.Errorf("error scanning group: %v", err)
+		}
+		return fn(group)
+	})
+}
+
+// withCursor declares a server-side cursor for query in a read-only transaction and
+// calls scan for every row, fetching exportFetchSize rows at a time.
+func (r *Repository) withCursor(query string, params []interface{}, scan func(*sql.Rows) error) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`SET TRANSACTION READ ONLY`)
+	if err != nil {
+		return fmt.Errorf("error setting transaction read only: %v", err)
+	}
+
+	_, err = tx.Exec(`DECLARE export_cursor NO SCROLL CURSOR FOR `+query, params...)
+	if err != nil {
+		return fmt.Errorf("error declaring cursor: %v", err)
+	}
+
+	for {
+		rows, err := tx.Query(fmt.Sprintf(`FETCH %d FROM export_cursor`, exportFetchSize))
+		if err != nil {
+			return fmt.Errorf("error fetching from cursor: %v", err)
+		}
+
+		fetched := 0
+		for rows.Next() {
+			fetched++
+			err = scan(rows)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+		}
+
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return fmt.Errorf("rows iteration error: %v", err)
+		}
+
+		if fetched < exportFetchSize {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package connection
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/noctusha/music/models"
+)
+
+// SongStats retrieves the ratings, plays and favorites of a song, with the favorite and rating of a user.
+// It returns nil when the song does not exist.
+func (r *Repository) SongStats(songID, userID int) (*models.SongStats, error) {
+	var stats models.SongStats
+
+	err := r.db.QueryRow(`
+SELECT
+	song_stats.average_rating,
+	song_stats.ratings,
+	song_stats.plays,
+	song_stats.favorites,
+	EXISTS (SELECT 1 FROM song_favorites WHERE song_favorites.song_id = song_stats.song_id AND song_favorites.user_id = $2),
+	COALESCE((SELECT rating FROM song_ratings WHERE song_ratings.song_id = song_stats.song_id AND song_ratings.user_id = $2), 0)
+FROM
+	song_stats
+WHERE
+	song_stats.song_id = $1`, songID, userID).Scan(&stats.AverageRating, &stats.Ratings, &stats.Plays, &stats.Favorites, &stats.Favorite, &stats.Rating)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error scanning song stats: %v", err)
+	}
+
+	return &stats, nil
+}
+
+// AddFavorite adds a song to the favorites of a user.
+func (r *Repository) AddFavorite(userID, songID int) error {
+	_, err := r.db.Exec("INSERT INTO song_favorites (user_id, song_id) VALUES ($1, $2) ON CONFLICT DO NOTHING", userID, songID)
+	if err != nil {
+		return fmt.Errorf("error inserting favorite: %v", err)
+	}
+	return nil
+}
+
+// RemoveFavorite removes a song from the favorites of a user.
+func (r *Repository) RemoveFavorite(userID, songID int) error {
+	_, err := r.db.Exec("DELETE FROM song_favorites WHERE user_id = $1 AND song_id = $2", userID, songID)
+	if err != nil {
+		return fmt.Errorf("error deleting favorite: %v", err)
+	}
+	return nil
+}
+
+// SetRating sets the rating of a song by a user, replacing a previous rating.
+func (r *Repository) SetRating(userID, songID, rating int) error {
+	_, err := r.db.Exec(`
+INSERT INTO song_ratings (user_id, song_id, rating) VALUES ($1, $2, $3)
+ON CONFLICT (user_id, song_id) DO UPDATE SET rating = EXCLUDED.rating, updated_at = now()`, userID, songID, rating)
+	if err != nil {
+		return fmt.Errorf("error inserting rating: %v", err)
+	}
+	return nil
+}
+
+// RemoveRating removes the rating of a song by a user.
+func (r *Repository) RemoveRating(userID, songID int) error {
+	_, err := r.db.Exec("DELETE FROM song_ratings WHERE user_id = $1 AND song_id = $2", userID, songID)
+	if err != nil {
+		return fmt.Errorf("error deleting rating: %v", err)
+	}
+	return nil
+}
+
+// RecordPlay records that a user played a song.
+func (r *Repository) RecordPlay(userID, songID int, playedAt time.Time) error {
+	_, err := r.db.Exec("INSERT INTO song_plays (user_id, song_id, played_at) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING", userID, songID, playedAt)
+	if err != nil {
+		return fmt.Errorf("error inserting play: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,351 @@
+package connection
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/noctusha/music/models"
+)
+
+// Errors returned by the group alias methods.
+var (
+	ErrGroupNotFound = errors.New("group not found")
+	ErrAliasNotFound = errors.New("alias not found")
+	ErrAliasExists   = errors.New("the name is already used by a group or an alias")
+	ErrMergeIntoSelf = errors.New("a group cannot be merged into itself")
+)
+
+// GroupAliases retrieves the aliases of a group by name, or of every group when groupID is 0.
+func (r *Repository) GroupAliases(groupID int) ([]models.GroupAlias, error) {
+	rows, err := r.db.Query(`
+SELECT
+	group_aliases.id,
+	group_aliases.group_id,
+	groups.name,
+	group_aliases.name
+FROM
+	group_aliases
+JOIN
+	groups
+ON
+	groups.id = group_aliases.group_id
+WHERE
+	$1 = 0 OR group_aliases.group_id = $1
+ORDER BY
+	lower(group_aliases.name)`, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("error executing query: %v", err)
+	}
+	defer rows.Close()
+
+	aliases := []models.GroupAlias{}
+	for rows.Next() {
+		var alias models.GroupAlias
+		err = rows.Scan(&alias.ID, &alias.GroupID, &alias.Group, &alias.Name)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning alias: %v", err)
+		}
+		aliases = append(aliases, alias)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("rows iteration error: %v", err)
+	}
+
+	return aliases, nil
+}
+
+// GroupAliasNames retrieves the alias names of the given groups by group ID, in alphabetical order.
+func (r *Repository) GroupAliasNames(groupIDs []int) (map[int][]string, error) {
+	rows, err := r.db.Query(`
+SELECT
+	group_id,
+	name
+FROM
+	group_aliases
+WHERE
+	group_id = ANY($1)
+ORDER BY
+	lower(name)`, pq.Array(groupIDs))
+	if err != nil {
+		return nil, fmt.Errorf("error executing query: %v", err)
+	}
+	defer rows.Close()
+
+	aliases := make(map[int][]string)
+	for rows.Next() {
+		var (
+			groupID int
+			name    string
+		)
+		err = rows.Scan(&groupID, &name)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning alias: %v", err)
+		}
+		aliases[groupID] = append(aliases[groupID], name)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("rows iteration error: %v", err)
+	}
+
+	return aliases, nil
+}
+
+// AddGroupAlias adds an alias to a group and returns its ID. The alias may not be the name of another group.
+func (r *Repository) AddGroupAlias(groupID int, name string) (int, error) {
+	var id int
+	err := r.db.QueryRow(`
+INSERT INTO group_aliases (group_id, name)
+SELECT $1, $2::varchar
+WHERE NOT EXISTS (SELECT 1 FROM groups WHERE lower(name) = lower($2) AND id <> $1)
+RETURNING id`, groupID, name).Scan(&id)
+	switch {
+	case errors.Is(err, sql.ErrNoRows), violates(err, uniqueViolation):
+		return 0, ErrAliasExists
+	case err != nil:
+		return 0, fmt.Errorf("error inserting alias: %v", err)
+	}
+	return id, nil
+}
+
+// DeleteGroupAlias deletes an alias of a group.
+func (r *Repository) DeleteGroupAlias(groupID, aliasID int) error {
+	result, err := r.db.Exec(`DELETE FROM group_aliases WHERE id = $1 AND group_id = $2`, aliasID, groupID)
+	if err != nil {
+		return fmt.Errorf("error deleting alias: %v", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error counting deleted aliases: %v", err)
+	}
+	if deleted == 0 {
+		return ErrAliasNotFound
+	}
+	return nil
+}
+
+// RenameGroup changes the name of a group. The name may not be the name or an alias of another group.
+func (r *Repository) RenameGroup(groupID int, name string) error {
+	result, err := r.db.Exec(`
+UPDATE groups SET name = $2
+WHERE id = $1 AND NOT EXISTS (
+	SELECT 1 FROM groups WHERE lower(name) = lower($2::varchar) AND id <> $1
+	UNION ALL
+	SELECT 1 FROM group_aliases WHERE lower(name) = lower($2::varchar) AND group_id <> $1
+)`, groupID, name)
+	if err != nil {
+		return fmt.Errorf("error renaming group: %v", err)
+	}
+
+	renamed, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error counting renamed groups: %v", err)
+	}
+	if renamed > 0 {
+		return nil
+	}
+
+	group, err := r.GetGroupByID(groupID)
+	if err != nil {
+		return err
+	}
+	if group == nil {
+		return fmt.Errorf("%w: %d", ErrGroupNotFound, groupID)
+	}
+	return ErrAliasExists
+}
+
+// GroupRedirect retrieves the ID of the group a merged group was merged into, or 0 when groupID was not merged.
+func (r *Repository) GroupRedirect(groupID int) (int, error) {
+	var id int
+	err := r.db.QueryRow(`SELECT group_id FROM group_redirects WHERE from_id = $1`, groupID).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("error scanning redirect: %v", err)
+	}
+	return id, nil
+}
+
+// GroupList retrieves a page of groups ordered by name.
+func (r *Repository) GroupList(limit, offset int) ([]models.Group, error) {
+	return r.queryGroups(`SELECT id, name FROM groups ORDER BY lower(name), id LIMIT $1 OFFSET $2`, limit, offset)
+}
+
+// GroupsByIDs retrieves the given groups by ID. Missing groups are not in the map.
+func (r *Repository) GroupsByIDs(ids []int) (map[int]models.Group, error) {
+	groups, err := r.queryGroups(`SELECT id, name FROM groups WHERE id = ANY($1)`, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[int]models.Group, len(groups))
+	for _, group := range groups {
+		byID[group.ID] = group
+	}
+	return byID, nil
+}
+
+func (r *Repository) queryGroups(query string, params ...interface{}) ([]models.Group, error) {
+	rows, err := r.db.Query(query, params...)
+	if err != nil {
+		return nil, fmt.Errorf("error executing query: %v", err)
+	}
+	defer rows.Close()
+
+	groups := []models.Group{}
+	for rows.Next() {
+		var group models.Group
+		err = rows.Scan(&group.ID, &group.Name)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning group: %v", err)
+		}
+		groups = append(groups, group)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("rows iteration error: %v", err)
+	}
+
+	return groups, nil
+}
+
+// GroupSummaries retrieves every group with the number of its songs.
+func (r *Repository) GroupSummaries() ([]models.GroupSummary, error) {
+	rows, err := r.db.Query(`
+SELECT
+	groups.id,
+	groups.name,
+	(SELECT count(*) FROM songs WHERE songs.group_id = groups.id)
+FROM
+	groups
+ORDER BY
+	groups.id`)
+	if err != nil {
+		return nil, fmt.Errorf("error executing query: %v", err)
+	}
+	defer rows.Close()
+
+	groups := []models.GroupSummary{}
+	for rows.Next() {
+		var group models.GroupSummary
+		err = rows.Scan(&group.ID, &group.Name, &group.Songs)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning group: %v", err)
+		}
+		groups = append(groups, group)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("rows iteration error: %v", err)
+	}
+
+	return groups, nil
+}
+
+// MergeGroups moves the songs of the source groups into the target group and deletes the sources.
+// Their names become aliases of the target and their ids redirect to it; their maintainers, genres,
+// tags and aliases move along. It returns the number of songs moved.
+func (r *Repository) MergeGroups(targetID int, sourceIDs []int) (moved int, err error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to start transaction: %v", err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			tx.Rollback()
+		} else if err = tx.Commit(); err != nil {
+			moved, err = 0, fmt.Errorf("failed to commit transaction: %v", err)
+		}
+	}()
+
+	ids := append([]int{targetID}, sourceIDs...)
+	rows, err := tx.Query(`SELECT id FROM groups WHERE id = ANY($1) ORDER BY id FOR UPDATE`, pq.Array(ids))
+	if err != nil {
+		return 0, fmt.Errorf("error locking groups: %v", err)
+	}
+	found := make(map[int]bool, len(ids))
+	for rows.Next() {
+		var id int
+		err = rows.Scan(&id)
+		if err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("error scanning group: %v", err)
+		}
+		found[id] = true
+	}
+	rows.Close()
+	err = rows.Err()
+	if err != nil {
+		return 0, fmt.Errorf("rows iteration error: %v", err)
+	}
+
+	for _, id := range ids {
+		if !found[id] {
+			return 0, fmt.Errorf("%w: %d", ErrGroupNotFound, id)
+		}
+	}
+
+	for _, sourceID := range sourceIDs {
+		if sourceID == targetID {
+			return 0, ErrMergeIntoSelf
+		}
+
+		var (
+			result sql.Result
+			songs  int64
+		)
+		// Songs already in the target group are kept as pending duplicates, to be merged later.
+		_, err = tx.Exec(`
+UPDATE songs SET pending_duplicate = TRUE
+WHERE group_id = $2 AND name_key IN (SELECT name_key FROM songs WHERE group_id = $1)`, targetID, sourceID)
+		if err != nil {
+			return 0, fmt.Errorf("error marking duplicate songs: %v", err)
+		}
+
+		result, err = tx.Exec(`UPDATE songs SET group_id = $1 WHERE group_id = $2`, targetID, sourceID)
+		if err != nil {
+			return 0, fmt.Errorf("error moving songs: %v", err)
+		}
+		songs, err = result.RowsAffected()
+		if err != nil {
+			return 0, fmt.Errorf("error counting moved songs: %v", err)
+		}
+		moved += int(songs)
+
+		for _, query := range []string{
+			// Suggestions would be deleted with the group, and their snapshots would no longer match.
+			`UPDATE song_suggestions SET group_id = $1 WHERE group_id = $2`,
+			`UPDATE song_suggestions SET base_group_id = $1 WHERE base_group_id = $2`,
+			`INSERT INTO group_maintainers (group_id, user_id) SELECT $1, user_id FROM group_maintainers WHERE group_id = $2 ON CONFLICT DO NOTHING`,
+			`INSERT INTO group_genres (group_id, genre_id) SELECT $1, genre_id FROM group_genres WHERE group_id = $2 ON CONFLICT DO NOTHING`,
+			`INSERT INTO group_tags (group_id, tag_id) SELECT $1, tag_id FROM group_tags WHERE group_id = $2 ON CONFLICT DO NOTHING`,
+			`UPDATE group_aliases SET group_id = $1 WHERE group_id = $2`,
+			`INSERT INTO group_aliases (group_id, name) SELECT $1, name FROM groups WHERE id = $2
+ON CONFLICT (lower(name)) DO UPDATE SET group_id = EXCLUDED.group_id`,
+			`UPDATE group_redirects SET group_id = $1 WHERE group_id = $2`,
+			`INSERT INTO group_redirects (from_id, group_id) VALUES ($2, $1)`,
+			`DELETE FROM groups WHERE id = $2`,
+		} {
+			_, err = tx.Exec(query, targetID, sourceID)
+			if err != nil {
+				return 0, fmt.Errorf("error merging group %d: %v", sourceID, err)
+			}
+		}
+	}
+
+	return moved, nil
+}
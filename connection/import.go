@@ -0,0 +1,126 @@
+package connection
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/noctusha/music/lyrics"
+	"github.com/noctusha/music/models"
+)
+
+// ImportSongs stores a batch of imported songs in a single transaction. Songs that already
+// exist for the group are skipped or, with upsert, get their non-empty fields updated.
+// Every row runs in its own savepoint so that a failing row does not abort the batch.
+// With dryRun the transaction is rolled back after the rows have been processed.
+// The returned results are in the order of rows.
+func (r *Repository) ImportSongs(rows []models.ImportRow, upsert, dryRun bool) (results []models.ImportRowResult, err error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %v", err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		} else if err != nil || dryRun {
+			tx.Rollback()
+		} else if err = tx.Commit(); err != nil {
+			results, err = nil, fmt.Errorf("failed to commit transaction: %v", err)
+		}
+	}()
+
+	results = make([]models.ImportRowResult, len(rows))
+	for i, row := range rows {
+		result := models.ImportRowResult{Group: row.Group, Song: row.Song}
+
+		_, err = tx.Exec("SAVEPOINT import_row")
+		if err != nil {
+			return nil, fmt.Errorf("error creating savepoint: %v", err)
+		}
+
+		result.SongID, result.Status, err = importSong(tx, row, upsert, r.Explicit)
+		if err != nil {
+			result.Status = models.ImportFailed
+			result.Error = err.Error()
+			_, err = tx.Exec("ROLLBACK TO SAVEPOINT import_row")
+			if err != nil {
+				return nil, fmt.Errorf("error rolling back to savepoint: %v", err)
+			}
+		}
+
+		if dryRun {
+			result.SongID = 0
+		}
+		results[i] = result
+	}
+
+	return results, nil
+}
+
+// importSong creates or updates a single imported song inside tx, flagging it explicit when its lyrics match explicit.
+func importSong(tx *sql.Tx, row models.ImportRow, upsert bool, explicit *lyrics.WordList) (int, string, error) {
+	var groupID int
+	err := tx.QueryRow(groupByNameQuery, row.Group).Scan(&groupID)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = tx.QueryRow(`INSERT INTO groups (name) VALUES ($1) ON CONFLICT (name) DO NOTHING RETURNING id`, row.Group).Scan(&groupID)
+		if errors.Is(err, sql.ErrNoRows) {
+			err = tx.QueryRow(`SELECT id FROM groups WHERE name = $1`, row.Group).Scan(&groupID)
+		}
+	}
+	if err != nil {
+		return 0, "", fmt.Errorf("error resolving group: %v", err)
+	}
+
+	var songID int
+	err = tx.QueryRow(`SELECT id FROM songs WHERE group_id = $1 AND name_key = song_name_key($2) ORDER BY pending_duplicate, id LIMIT 1`, groupID, row.Song).Scan(&songID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return 0, "", fmt.Errorf("error looking up song: %v", err)
+	}
+
+	if songID != 0 {
+		if !upsert {
+			return songID, models.ImportSkipped, nil
+		}
+
+		_, err = tx.Exec(`
+UPDATE song_details SET
+	release_date = COALESCE(NULLIF($1, '')::date, release_date),
+	text = COALESCE(NULLIF($2, ''), text),
+	link = COALESCE(NULLIF($3, ''), link),
+	album = COALESCE(NULLIF($4, ''), album)
+WHERE song_id = $5`, row.ReleaseDate, row.Text, row.Link, row.Album, songID)
+		if err != nil {
+			return 0, "", fmt.Errorf("error updating song_details: %v", err)
+		}
+
+		if row.Text != "" {
+			_, err = tx.Exec(`UPDATE songs SET explicit_detected = COALESCE($1, explicit_detected) WHERE id = $2`, explicit.Detect(row.Text), songID)
+			if err != nil {
+				return 0, "", fmt.Errorf("error updating song: %v", err)
+			}
+		}
+		return songID, models.ImportUpdated, nil
+	}
+
+	err = tx.QueryRow(`INSERT INTO songs (name, group_id, explicit_detected) VALUES ($1, $2, COALESCE($3::boolean, FALSE)) RETURNING id`,
+		row.Song, groupID, explicit.Detect(row.Text)).Scan(&songID)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to insert song: %v", err)
+	}
+
+	_, err = tx.Exec(`
+INSERT INTO song_details (song_id, release_date, text, link, album) VALUES (
+	$1,
+	COALESCE(NULLIF($2, ''), $6)::date,
+	COALESCE(NULLIF($3, ''), $7),
+	COALESCE(NULLIF($4, ''), $7),
+	$5
+)`, songID, row.ReleaseDate, row.Text, row.Link, row.Album, models.DefaultReleaseDate, models.UnknownValue)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to insert song details: %v", err)
+	}
+
+	return songID, models.ImportCreated, nil
+}
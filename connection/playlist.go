@@ -0,0 +1,475 @@
+package connection
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/lib/pq"
+	"github.com/noctusha/music/models"
+)
+
+// Errors returned by the playlist methods.
+var (
+	ErrPlaylistNotFound      = errors.New("playlist not found")
+	ErrPlaylistEntryNotFound = errors.New("playlist entry not found")
+	ErrInvalidPosition       = errors.New("position is out of range")
+)
+
+const playlistQuery = `
+SELECT
+	playlists.id,
+	playlists.owner_id,
+	playlists.name,
+	playlists.description,
+	playlists.visibility,
+	COALESCE(playlists.share_token, ''),
+	(SELECT count(*) FROM playlist_entries WHERE playlist_entries.playlist_id = playlists.id),
+	playlists.created_at,
+	playlists.updated_at
+FROM
+	playlists`
+
+// CreatePlaylist creates a new playlist without entries.
+func (r *Repository) CreatePlaylist(playlist models.Playlist) (*models.Playlist, error) {
+	err := r.db.QueryRow(`INSERT INTO playlists (owner_id, name, description, visibility) VALUES ($1, $2, $3, $4) RETURNING id, created_at, updated_at`,
+		playlist.OwnerID, playlist.Name, playlist.Description, playlist.Visibility).Scan(&playlist.ID, &playlist.CreatedAt, &playlist.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("error inserting playlist: %v", err)
+	}
+	return &playlist, nil
+}
+
+// GetPlaylist retrieves a playlist without its entries by its ID.
+func (r *Repository) GetPlaylist(id int) (*models.Playlist, error) {
+	return r.queryPlaylist(playlistQuery+` WHERE playlists.id = $1`, id)
+}
+
+// GetPlaylistByShareToken retrieves a playlist without its entries by its share token.
+func (r *Repository) GetPlaylistByShareToken(token string) (*models.Playlist, error) {
+	return r.queryPlaylist(playlistQuery+` WHERE playlists.share_token = $1`, token)
+}
+
+func (r *Repository) queryPlaylist(query string, params ...interface{}) (*models.Playlist, error) {
+	playlists, err := r.queryPlaylists(query, params...)
+	if err != nil {
+		return nil, err
+	}
+	if len(playlists) == 0 {
+		return nil, nil
+	}
+	return &playlists[0], nil
+}
+
+// ListPlaylists retrieves the playlists visible to a user: their own and those that
+// are not private, most recently updated first. A zero userID lists only the latter.
+func (r *Repository) ListPlaylists(userID, limit, offset int) ([]models.Playlist, error) {
+	if limit == 0 {
+		limit = 25
+	}
+
+	return r.queryPlaylists(playlistQuery+`
+WHERE
+	playlists.owner_id = $1 OR playlists.visibility <> 'private'
+ORDER BY
+	playlists.updated_at DESC, playlists.id
+LIMIT
+	$2
+OFFSET
+	$3`, userID, limit, offset)
+}
+
+func (r *Repository) queryPlaylists(query string, params ...interface{}) ([]models.Playlist, error) {
+	rows, err := r.db.Query(query, params...)
+	if err != nil {
+		return nil, fmt.Errorf("error executing query: %v", err)
+	}
+	defer rows.Close()
+
+	playlists := []models.Playlist{}
+	for rows.Next() {
+		var p models.Playlist
+		err = rows.Scan(&p.ID, &p.OwnerID, &p.Name, &p.Description, &p.Visibility, &p.ShareToken, &p.Size, &p.CreatedAt, &p.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning playlist: %v", err)
+		}
+		playlists = append(playlists, p)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("rows iteration error: %v", err)
+	}
+
+	return playlists, nil
+}
+
+// PlaylistEntries retrieves the entries of a playlist in order.
+func (r *Repository) PlaylistEntries(playlistID int) ([]models.PlaylistEntry, error) {
+	rows, err := r.db.Query(`
+SELECT
+	playlist_entries.id,
+	playlist_entries.song_id,
+	groups.name,
+	songs.name,
+	COALESCE(playlist_entries.added_by, 0),
+	playlist_entries.added_at
+FROM
+	playlist_entries
+JOIN
+	songs
+ON
+	songs.id = playlist_entries.song_id
+JOIN
+	groups
+ON
+	groups.id = songs.group_id
+WHERE
+	playlist_entries.playlist_id = $1
+ORDER BY
+	playlist_entries.position, playlist_entries.id`, playlistID)
+	if err != nil {
+		return nil, fmt.Errorf("error executing query: %v", err)
+	}
+	defer rows.Close()
+
+	entries := []models.PlaylistEntry{}
+	for rows.Next() {
+		entry := models.PlaylistEntry{Position: len(entries) + 1}
+		err = rows.Scan(&entry.ID, &entry.SongID, &entry.Group, &entry.Song, &entry.AddedBy, &entry.AddedAt)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning playlist entry: %v", err)
+		}
+		entries = append(entries, entry)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("rows iteration error: %v", err)
+	}
+
+	return entries, nil
+}
+
+// PlaylistSongs retrieves the songs of a playlist with their group names and details, in playlist order.
+func (r *Repository) PlaylistSongs(playlistID int) ([]models.DetailedSong, error) {
+	return r.queryDetailedSongs(detailedSongQuery+`
+JOIN
+	playlist_entries
+ON
+	playlist_entries.song_id = songs.id
+WHERE
+	playlist_entries.playlist_id = $1
+ORDER BY
+	playlist_entries.position, playlist_entries.id`, playlistID)
+}
+
+// UpdatePlaylist updates the name, description and visibility of a playlist.
+func (r *Repository) UpdatePlaylist(playlist *models.Playlist) error {
+	err := r.db.QueryRow(`UPDATE playlists SET name = $1, description = $2, visibility = $3, updated_at = now() WHERE id = $4 RETURNING updated_at`,
+		playlist.Name, playlist.Description, playlist.Visibility, playlist.ID).Scan(&playlist.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrPlaylistNotFound
+		}
+		return fmt.Errorf("error updating playlist: %v", err)
+	}
+	return nil
+}
+
+// SetPlaylistShareToken sets the share token of a playlist; an empty token revokes sharing.
+func (r *Repository) SetPlaylistShareToken(playlistID int, token string) error {
+	_, err := r.db.Exec(`UPDATE playlists SET share_token = NULLIF($1, '') WHERE id = $2`, token, playlistID)
+	if err != nil {
+		return fmt.Errorf("error updating playlist: %v", err)
+	}
+	return nil
+}
+
+// DeletePlaylist deletes a playlist with its entries.
+func (r *Repository) DeletePlaylist(playlistID int) error {
+	_, err := r.db.Exec("DELETE FROM playlists WHERE id = $1", playlistID)
+	if err != nil {
+		return fmt.Errorf("error deleting playlist: %v", err)
+	}
+	return nil
+}
+
+// InsertPlaylistEntries inserts songs at a 1-based position of a playlist, shifting the
+// following entries down. A zero position appends the songs.
+func (r *Repository) InsertPlaylistEntries(playlistID, userID int, songIDs []int, position int) error {
+	return r.editPlaylist(playlistID, func(tx *sql.Tx, entries []int) ([]int, error) {
+		if position == 0 {
+			position = len(entries) + 1
+		}
+		if position < 1 || position > len(entries)+1 {
+			return nil, ErrInvalidPosition
+		}
+
+		added, err := insertEntries(tx, playlistID, userID, songIDs)
+		if err != nil {
+			return nil, err
+		}
+
+		return slices.Insert(entries, position-1, added...), nil
+	})
+}
+
+// MovePlaylistEntry moves an entry to a 1-based position of its playlist.
+func (r *Repository) MovePlaylistEntry(playlistID, entryID, position int) error {
+	return r.editPlaylist(playlistID, func(tx *sql.Tx, entries []int) ([]int, error) {
+		from := slices.Index(entries, entryID)
+		if from < 0 {
+			return nil, ErrPlaylistEntryNotFound
+		}
+		if position < 1 || position > len(entries) {
+			return nil, ErrInvalidPosition
+		}
+
+		ordered := slices.Delete(entries, from, from+1)
+		return slices.Insert(ordered, position-1, entryID), nil
+	})
+}
+
+// RemovePlaylistEntry removes an entry from its playlist.
+func (r *Repository) RemovePlaylistEntry(playlistID, entryID int) error {
+	return r.editPlaylist(playlistID, func(tx *sql.Tx, entries []int) ([]int, error) {
+		i := slices.Index(entries, entryID)
+		if i < 0 {
+			return nil, ErrPlaylistEntryNotFound
+		}
+
+		_, err := tx.Exec("DELETE FROM playlist_entries WHERE id = $1", entryID)
+		if err != nil {
+			return nil, fmt.Errorf("error deleting playlist entry: %v", err)
+		}
+		return slices.Delete(entries, i, i+1), nil
+	})
+}
+
+// MergePlaylists appends the songs of source to target and returns how many were added
+// and skipped. With skipDuplicates songs already in target are not added again.
+func (r *Repository) MergePlaylists(targetID, sourceID, userID int, skipDuplicates bool) (added, skipped int, err error) {
+	err = r.editPlaylist(targetID, func(tx *sql.Tx, entries []int) ([]int, error) {
+		songIDs, err := queryIDs(tx, `SELECT song_id FROM playlist_entries WHERE playlist_id = $1 ORDER BY position, id`, sourceID)
+		if err != nil {
+			return nil, err
+		}
+
+		if skipDuplicates {
+			present, err := queryIDs(tx, `SELECT song_id FROM playlist_entries WHERE id = ANY($1)`, pq.Array(entries))
+			if err != nil {
+				return nil, err
+			}
+
+			seen := make(map[int]bool, len(present))
+			for _, id := range present {
+				seen[id] = true
+			}
+
+			unique := songIDs[:0]
+			for _, id := range songIDs {
+				if !seen[id] {
+					seen[id] = true
+					unique = append(unique, id)
+				}
+			}
+			skipped = len(songIDs) - len(unique)
+			songIDs = unique
+		}
+
+		ids, err := insertEntries(tx, targetID, userID, songIDs)
+		if err != nil {
+			return nil, err
+		}
+		added = len(ids)
+		return append(entries, ids...), nil
+	})
+	return added, skipped, err
+}
+
+// DuplicatePlaylist copies a playlist with its entries to a new private playlist owned
+// by ownerID and returns the ID of the copy.
+func (r *Repository) DuplicatePlaylist(playlistID, ownerID int, name string) (id int, err error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to start transaction: %v", err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	err = tx.QueryRow(`
+INSERT INTO playlists (owner_id, name, description, visibility)
+SELECT $1, $2, description, 'private' FROM playlists WHERE id = $3
+RETURNING id`, ownerID, name, playlistID).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrPlaylistNotFound
+		}
+		return 0, fmt.Errorf("error inserting playlist: %v", err)
+	}
+
+	_, err = tx.Exec(`
+INSERT INTO playlist_entries (playlist_id, song_id, position, added_by)
+SELECT $1, song_id, row_number() OVER (ORDER BY position, id), $2 FROM playlist_entries WHERE playlist_id = $3`,
+		id, ownerID, playlistID)
+	if err != nil {
+		return 0, fmt.Errorf("error copying playlist entries: %v", err)
+	}
+
+	return id, nil
+}
+
+// CreatePlaylistWithEntries creates a playlist with songs added by userID, in order, in one transaction.
+func (r *Repository) CreatePlaylistWithEntries(playlist models.Playlist, userID int, songIDs []int) (created *models.Playlist, err error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %v", err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	err = tx.QueryRow(`INSERT INTO playlists (owner_id, name, description, visibility) VALUES ($1, $2, $3, $4) RETURNING id, created_at, updated_at`,
+		playlist.OwnerID, playlist.Name, playlist.Description, playlist.Visibility).Scan(&playlist.ID, &playlist.CreatedAt, &playlist.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("error inserting playlist: %v", err)
+	}
+
+	ids, err := insertEntries(tx, playlist.ID, userID, songIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(`
+UPDATE playlist_entries
+SET position = data.position
+FROM unnest($1::int[]) WITH ORDINALITY AS data(id, position)
+WHERE playlist_entries.id = data.id`, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("error numbering playlist entries: %v", err)
+	}
+
+	return &playlist, nil
+}
+
+// editPlaylist locks a playlist, passes the ids of its entries in order to edit and
+// renumbers the entries in the order edit returns, all in one transaction. Locking the
+// playlist row serializes concurrent edits by collaborators.
+func (r *Repository) editPlaylist(playlistID int, edit func(tx *sql.Tx, entries []int) ([]int, error)) (err error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %v", err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	var id int
+	err = tx.QueryRow("SELECT id FROM playlists WHERE id = $1 FOR UPDATE", playlistID).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrPlaylistNotFound
+		}
+		return fmt.Errorf("error locking playlist: %v", err)
+	}
+
+	entries, err := queryIDs(tx, `SELECT id FROM playlist_entries WHERE playlist_id = $1 ORDER BY position, id`, playlistID)
+	if err != nil {
+		return err
+	}
+
+	ordered, err := edit(tx, entries)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+UPDATE playlist_entries
+SET position = data.position
+FROM unnest($1::int[]) WITH ORDINALITY AS data(id, position)
+WHERE playlist_entries.id = data.id`, pq.Array(ordered))
+	if err != nil {
+		return fmt.Errorf("error renumbering playlist entries: %v", err)
+	}
+
+	_, err = tx.Exec("UPDATE playlists SET updated_at = now() WHERE id = $1", playlistID)
+	if err != nil {
+		return fmt.Errorf("error updating playlist: %v", err)
+	}
+
+	return nil
+}
+
+// insertEntries inserts songs into a playlist and returns the new entry ids. The
+// entries get placeholder positions which the caller renumbers.
+func insertEntries(tx *sql.Tx, playlistID, userID int, songIDs []int) ([]int, error) {
+	var addedBy interface{}
+	if userID != 0 {
+		addedBy = userID
+	}
+
+	ids := make([]int, 0, len(songIDs))
+	for _, songID := range songIDs {
+		var id int
+		err := tx.QueryRow(`INSERT INTO playlist_entries (playlist_id, song_id, position, added_by) VALUES ($1, $2, 0, $3) RETURNING id`,
+			playlistID, songID, addedBy).Scan(&id)
+		if err != nil {
+			return nil, fmt.Errorf("error inserting playlist entry: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// queryIDs runs a query selecting a single integer column inside a transaction.
+func queryIDs(tx *sql.Tx, query string, params ...interface{}) ([]int, error) {
+	rows, err := tx.Query(query, params...)
+	if err != nil {
+		return nil, fmt.Errorf("error executing query: %v", err)
+	}
+	defer rows.Close()
+
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		err = rows.Scan(&id)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning id: %v", err)
+		}
+		ids = append(ids, id)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("rows iteration error: %v", err)
+	}
+
+	return ids, nil
+}
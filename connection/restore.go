@@ -0,0 +1,104 @@
+package connection
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/noctusha/music/lyrics"
+	"github.com/noctusha/music/models"
+)
+
+// Restore writes exported groups and songs back with their original ids inside a
+// single transaction. Existing rows with the same ids are overwritten.
+type Restore struct {
+	tx       *sql.Tx
+	explicit *lyrics.WordList
+}
+
+// BeginRestore starts a restore transaction.
+func (r *Repository) BeginRestore() (*Restore, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %v", err)
+	}
+	return &Restore{tx: tx, explicit: r.Explicit}, nil
+}
+
+// Group restores a group and returns models.ImportCreated or models.ImportUpdated.
+func (rs *Restore) Group(group models.Group) (string, error) {
+	var inserted bool
+	err := rs.tx.QueryRow(`
+INSERT INTO groups (id, name) VALUES ($1, $2)
+ON CONFLICT (id) DO UPDATE SET name = EXCLUDED.name
+RETURNING xmax = 0`, group.ID, group.Name).Scan(&inserted)
+	if err != nil {
+		return "", fmt.Errorf("error restoring group %d: %v", group.ID, err)
+	}
+	return restoreStatus(inserted), nil
+}
+
+// Song restores a song with its details and returns models.ImportCreated or models.ImportUpdated.
+func (rs *Restore) Song(song models.ExportSong) (string, error) {
+	var inserted bool
+	err := rs.tx.QueryRow(`
+INSERT INTO songs (id, name, group_id, explicit_detected, pending_duplicate)
+VALUES ($1, $2::varchar, $3, COALESCE($4::boolean, FALSE), EXISTS (
+	SELECT 1 FROM songs WHERE group_id = $3 AND name_key = song_name_key($2) AND id <> $1 AND NOT pending_duplicate
+))
+ON CONFLICT (id) DO UPDATE SET
+	name = EXCLUDED.name,
+	group_id = EXCLUDED.group_id,
+	explicit_detected = COALESCE($4::boolean, songs.explicit_detected),
+	pending_duplicate = EXCLUDED.pending_duplicate
+RETURNING xmax = 0`, song.SongID, song.Song, song.GroupID, rs.explicit.Detect(song.Text)).Scan(&inserted)
+	if err != nil {
+		return "", fmt.Errorf("error restoring song %d: %v", song.SongID, err)
+	}
+
+	// Details already stored for the song keep their id, and new details whose exported id is
+	// taken by the details of another song get a fresh one.
+	_, err = rs.tx.Exec(`
+INSERT INTO song_details (id, song_id, release_date, text, link, album)
+SELECT
+	CASE WHEN EXISTS (SELECT 1 FROM song_details WHERE id = $1) THEN nextval(pg_get_serial_sequence('song_details', 'id')) ELSE $1 END,
+	$2, NULLIF($3, '')::date, $4, $5, $6
+ON CONFLICT (song_id) DO UPDATE SET
+	release_date = EXCLUDED.release_date,
+	text = EXCLUDED.text,
+	link = EXCLUDED.link,
+	album = EXCLUDED.album`, song.DetailsID, song.SongID, song.ReleaseDate, song.Text, song.Link, song.Album)
+	if err != nil {
+		return "", fmt.Errorf("error restoring details of song %d: %v", song.SongID, err)
+	}
+
+	return restoreStatus(inserted), nil
+}
+
+// Commit moves the id sequences past the restored ids and commits the restore.
+func (rs *Restore) Commit() error {
+	for _, table := range []string{"groups", "songs", "song_details"} {
+		_, err := rs.tx.Exec(fmt.Sprintf(`SELECT setval(pg_get_serial_sequence('%[1]s', 'id'), COALESCE((SELECT MAX(id) FROM %[1]s), 0) + 1, false)`, table))
+		if err != nil {
+			rs.tx.Rollback()
+			return fmt.Errorf("error resetting %s sequence: %v", table, err)
+		}
+	}
+
+	err := rs.tx.Commit()
+	if err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return nil
+}
+
+// Rollback discards the restore.
+func (rs *Restore) Rollback() error {
+	return rs.tx.Rollback()
+}
+
+func restoreStatus(inserted bool) string {
+	if inserted {
+		return models.ImportCreated
+	}
+	return models.ImportUpdated
+}
@@ -0,0 +1,121 @@
+package connection
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/noctusha/music/models"
+)
+
+// RecordPlays stores the plays of a user in one transaction and reports for each play whether
+// it was new. Plays with the same song and time as a stored play are duplicates and skipped.
+func (r *Repository) RecordPlays(userID int, plays []models.Play) (inserted []bool, err error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %v", err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	stmt, err := tx.Prepare(`
+INSERT INTO song_plays (user_id, song_id, played_at, duration)
+VALUES ($1, $2, $3, NULLIF($4, 0))
+ON CONFLICT (user_id, song_id, played_at) DO NOTHING
+RETURNING id`)
+	if err != nil {
+		return nil, fmt.Errorf("error preparing statement: %v", err)
+	}
+	defer stmt.Close()
+
+	inserted = make([]bool, len(plays))
+	for i, play := range plays {
+		var id int64
+		err = stmt.QueryRow(userID, play.SongID, play.PlayedAt, play.Duration).Scan(&id)
+		if errors.Is(err, sql.ErrNoRows) {
+			err = nil
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error inserting play: %v", err)
+		}
+		inserted[i] = true
+	}
+
+	return inserted, nil
+}
+
+// ListPlays retrieves the listening history of a user, the latest first. Zero times do not limit the period.
+func (r *Repository) ListPlays(userID int, from, to time.Time, limit, offset int) ([]models.Play, error) {
+	if limit == 0 {
+		limit = 50
+	}
+
+	var fromParam, toParam interface{}
+	if !from.IsZero() {
+		fromParam = from
+	}
+	if !to.IsZero() {
+		toParam = to
+	}
+
+	rows, err := r.db.Query(`
+SELECT
+	song_plays.id,
+	song_plays.song_id,
+	groups.name,
+	songs.name,
+	song_plays.played_at,
+	COALESCE(song_plays.duration, 0)
+FROM
+	song_plays
+JOIN
+	songs
+ON
+	songs.id = song_plays.song_id
+JOIN
+	groups
+ON
+	groups.id = songs.group_id
+WHERE
+	song_plays.user_id = $1
+	AND ($2::timestamptz IS NULL OR song_plays.played_at >= $2)
+	AND ($3::timestamptz IS NULL OR song_plays.played_at < $3)
+ORDER BY
+	song_plays.played_at DESC, song_plays.id DESC
+LIMIT
+	$4
+OFFSET
+	$5`, userID, fromParam, toParam, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("error executing query: %v", err)
+	}
+	defer rows.Close()
+
+	plays := []models.Play{}
+	for rows.Next() {
+		var play models.Play
+		err = rows.Scan(&play.ID, &play.SongID, &play.Group, &play.Song, &play.PlayedAt, &play.Duration)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning play: %v", err)
+		}
+		plays = append(plays, play)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("rows iteration error: %v", err)
+	}
+
+	return plays, nil
+}
@@ -0,0 +1,209 @@
+package connection
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/noctusha/music/models"
+)
+
+// CatalogueStats computes the statistics of the catalogue, with the groups with the most songs first.
+func (r *Repository) CatalogueStats(groups int) (*models.CatalogueStats, error) {
+	var stats models.CatalogueStats
+
+	err := r.db.QueryRow(`
+SELECT
+	count(*),
+	(SELECT count(*) FROM groups),
+	count(*) FILTER (WHERE COALESCE(song_details.text, '') NOT IN ('', $1)),
+	count(*) FILTER (WHERE COALESCE(song_details.link, '') IN ('', $1)),
+	count(*) FILTER (WHERE song_details.album = ''),
+	count(*) FILTER (WHERE song_details.release_date IS NULL OR song_details.release_date = $2::date)
+FROM
+	songs
+JOIN
+	song_details
+ON
+	song_details.song_id = songs.id`, models.UnknownValue, models.DefaultReleaseDate).Scan(&stats.Songs, &stats.Groups, &stats.WithLyrics, &stats.MissingLinks, &stats.MissingAlbums, &stats.Undated)
+	if err != nil {
+		return nil, fmt.Errorf("error scanning catalogue stats: %v", err)
+	}
+	if stats.Songs > 0 {
+		stats.LyricsCoverage = float64(stats.WithLyrics) / float64(stats.Songs)
+	}
+
+	stats.PerGroup, err = r.songsPerGroup(groups)
+	if err != nil {
+		return nil, err
+	}
+
+	stats.PerYear, err = r.songsPerPeriod(1)
+	if err != nil {
+		return nil, err
+	}
+
+	stats.PerDecade, err = r.songsPerPeriod(10)
+	if err != nil {
+		return nil, err
+	}
+
+	return &stats, nil
+}
+
+func (r *Repository) songsPerGroup(limit int) ([]models.GroupCount, error) {
+	rows, err := r.db.Query(`
+SELECT
+	groups.id,
+	groups.name,
+	count(songs.id)
+FROM
+	groups
+LEFT JOIN
+	songs
+ON
+	songs.group_id = groups.id
+GROUP BY
+	groups.id
+ORDER BY
+	count(songs.id) DESC, groups.name
+LIMIT
+	$1`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error executing query: %v", err)
+	}
+	defer rows.Close()
+
+	counts := []models.GroupCount{}
+	for rows.Next() {
+		var count models.GroupCount
+		err = rows.Scan(&count.GroupID, &count.Group, &count.Songs)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning group count: %v", err)
+		}
+		counts = append(counts, count)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("rows iteration error: %v", err)
+	}
+
+	return counts, nil
+}
+
+// songsPerPeriod counts the dated songs per release year rounded down to a multiple of years.
+func (r *Repository) songsPerPeriod(years int) ([]models.PeriodCount, error) {
+	rows, err := r.db.Query(`
+SELECT
+	(extract(year FROM release_date)::integer / $1) * $1 AS period,
+	count(*)
+FROM
+	song_details
+WHERE
+	release_date IS NOT NULL AND release_date <> $2::date
+GROUP BY
+	period
+ORDER BY
+	period`, years, models.DefaultReleaseDate)
+	if err != nil {
+		return nil, fmt.Errorf("error executing query: %v", err)
+	}
+	defer rows.Close()
+
+	counts := []models.PeriodCount{}
+	for rows.Next() {
+		var count models.PeriodCount
+		err = rows.Scan(&count.Period, &count.Songs)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning period count: %v", err)
+		}
+		counts = append(counts, count)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("rows iteration error: %v", err)
+	}
+
+	return counts, nil
+}
+
+const chartQuery = `
+SELECT
+	songs.id,
+	groups.name,
+	songs.name,
+	sum(song_daily_stats.plays),
+	sum(song_daily_stats.ratings),
+	COALESCE(sum(song_daily_stats.rating_sum)::float8 / NULLIF(sum(song_daily_stats.ratings), 0), 0)
+FROM
+	song_daily_stats
+JOIN
+	songs
+ON
+	songs.id = song_daily_stats.song_id
+JOIN
+	groups
+ON
+	groups.id = songs.group_id
+WHERE
+	song_daily_stats.day >= $1 AND song_daily_stats.day < $2
+GROUP BY
+	songs.id, groups.name`
+
+// TopPlayed ranks the songs by their plays in a period of days, from inclusive to exclusive.
+func (r *Repository) TopPlayed(from, to time.Time, limit int) ([]models.ChartEntry, error) {
+	return r.queryChart(chartQuery+`
+HAVING
+	sum(song_daily_stats.plays) > 0
+ORDER BY
+	4 DESC, 6 DESC, songs.name
+LIMIT
+	$3`, from.Format(time.DateOnly), to.Format(time.DateOnly), limit)
+}
+
+// TopRated ranks the songs by the average of the ratings given in a period of days,
+// leaving out songs with fewer than minRatings ratings.
+func (r *Repository) TopRated(from, to time.Time, minRatings, limit int) ([]models.ChartEntry, error) {
+	return r.queryChart(chartQuery+`
+HAVING
+	sum(song_daily_stats.ratings) >= GREATEST($4, 1)
+ORDER BY
+	6 DESC, 5 DESC, songs.name
+LIMIT
+	$3`, from.Format(time.DateOnly), to.Format(time.DateOnly), limit, minRatings)
+}
+
+func (r *Repository) queryChart(query string, params ...interface{}) ([]models.ChartEntry, error) {
+	rows, err := r.db.Query(query, params...)
+	if err != nil {
+		return nil, fmt.Errorf("error executing query: %v", err)
+	}
+	defer rows.Close()
+
+	entries := []models.ChartEntry{}
+	for rows.Next() {
+		entry := models.ChartEntry{Rank: len(entries) + 1}
+		err = rows.Scan(&entry.SongID, &entry.Group, &entry.Song, &entry.Plays, &entry.Ratings, &entry.AverageRating)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning chart entry: %v", err)
+		}
+		entries = append(entries, entry)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("rows iteration error: %v", err)
+	}
+
+	return entries, nil
+}
+
+// RefreshCharts recomputes the materialized view the charts are read from.
+func (r *Repository) RefreshCharts() error {
+	_, err := r.db.Exec("REFRESH MATERIALIZED VIEW CONCURRENTLY song_daily_stats")
+	if err != nil {
+		return fmt.Errorf("error refreshing charts: %v", err)
+	}
+	return nil
+}
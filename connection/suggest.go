@@ -0,0 +1,107 @@
+package connection
+
+import (
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/noctusha/music/models"
+)
+
+// SearchSuggestions retrieves the groups, songs and albums with their popularity and search keys:
+// every one, or those of the given groups. Groups are also keyed by their aliases. Albums are told
+// apart by group, and the unknown album is left out.
+func (r *Repository) SearchSuggestions(groupIDs ...int) ([]models.SearchSuggestion, error) {
+	rows, err := r.db.Query(`
+SELECT
+	'group',
+	groups.id,
+	groups.name,
+	0,
+	'',
+	COALESCE(sum(song_stats.plays + song_stats.favorites), 0)::bigint,
+	ARRAY[groups.search_key] || ARRAY(SELECT search_key FROM group_aliases WHERE group_aliases.group_id = groups.id)
+FROM
+	groups
+LEFT JOIN
+	songs
+ON
+	songs.group_id = groups.id
+LEFT JOIN
+	song_stats
+ON
+	song_stats.song_id = songs.id
+WHERE
+	cardinality($2::int[]) = 0 OR groups.id = ANY($2)
+GROUP BY
+	groups.id
+UNION ALL
+SELECT
+	'song',
+	songs.id,
+	songs.name,
+	songs.group_id,
+	groups.name,
+	(song_stats.plays + song_stats.favorites)::bigint,
+	ARRAY[songs.search_key]
+FROM
+	songs
+JOIN
+	groups
+ON
+	groups.id = songs.group_id
+JOIN
+	song_stats
+ON
+	song_stats.song_id = songs.id
+WHERE
+	cardinality($2::int[]) = 0 OR songs.group_id = ANY($2)
+UNION ALL
+SELECT
+	'album',
+	0,
+	song_details.album,
+	songs.group_id,
+	groups.name,
+	sum(song_stats.plays + song_stats.favorites)::bigint,
+	ARRAY[search_key(song_details.album)]
+FROM
+	songs
+JOIN
+	groups
+ON
+	groups.id = songs.group_id
+JOIN
+	song_details
+ON
+	song_details.song_id = songs.id
+JOIN
+	song_stats
+ON
+	song_stats.song_id = songs.id
+WHERE
+	song_details.album NOT IN ('', $1)
+	AND (cardinality($2::int[]) = 0 OR songs.group_id = ANY($2))
+GROUP BY
+	song_details.album, songs.group_id, groups.name`, models.UnknownValue, pq.Array(groupIDs))
+	if err != nil {
+		return nil, fmt.Errorf("error executing query: %v", err)
+	}
+	defer rows.Close()
+
+	suggestions := []models.SearchSuggestion{}
+	for rows.Next() {
+		var suggestion models.SearchSuggestion
+		err = rows.Scan(&suggestion.Kind, &suggestion.ID, &suggestion.Name, &suggestion.GroupID, &suggestion.Group, &suggestion.Popularity, pq.Array(&suggestion.Keys))
+		if err != nil {
+			return nil, fmt.Errorf("error scanning suggestion: %v", err)
+		}
+		suggestions = append(suggestions, suggestion)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("rows iteration error: %v", err)
+	}
+
+	return suggestions, nil
+}
@@ -0,0 +1,304 @@
+package connection
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/noctusha/music/models"
+)
+
+// StaleSuggestionReason is the reason of suggestions rejected because the song changed after they were made.
+const StaleSuggestionReason = "the song changed since the edit was suggested"
+
+const suggestionQuery = `
+SELECT
+	song_suggestions.id,
+	song_suggestions.song_id,
+	groups.name,
+	songs.name,
+	COALESCE(song_suggestions.user_id, 0),
+	COALESCE(users.name, ''),
+	song_suggestions.status,
+	song_suggestions.comment,
+	song_suggestions.name,
+	COALESCE(song_suggestions.group_id, 0),
+	song_suggestions.release_date,
+	song_suggestions.text,
+	song_suggestions.link,
+	song_suggestions.album,
+	song_suggestions.base_name,
+	song_suggestions.base_group_id,
+	song_suggestions.base_release_date,
+	song_suggestions.base_text,
+	song_suggestions.base_link,
+	song_suggestions.base_album,
+	song_suggestions.reason,
+	COALESCE(song_suggestions.reviewed_by, 0),
+	song_suggestions.created_at,
+	song_suggestions.reviewed_at
+FROM
+	song_suggestions
+JOIN
+	songs
+ON
+	songs.id = song_suggestions.song_id
+JOIN
+	groups
+ON
+	groups.id = songs.group_id
+LEFT JOIN
+	users
+ON
+	users.id = song_suggestions.user_id`
+
+// suggestionSong joins a suggestion with the current state of its song, and suggestionUnchanged
+// is true while that state still matches the snapshot taken when the edit was suggested.
+const (
+	suggestionSong = `
+FROM
+	songs
+JOIN
+	song_details
+ON
+	song_details.song_id = songs.id
+WHERE
+	songs.id = song_suggestions.song_id`
+
+	suggestionUnchanged = `
+	(songs.name, songs.group_id, to_char(song_details.release_date, 'YYYY-MM-DD'), COALESCE(song_details.text, ''), COALESCE(song_details.link, ''), song_details.album)
+	IS NOT DISTINCT FROM
+	(song_suggestions.base_name, song_suggestions.base_group_id, song_suggestions.base_release_date, song_suggestions.base_text, song_suggestions.base_link, song_suggestions.base_album)`
+)
+
+// CreateSuggestion stores a pending suggested edit of a song together with a snapshot of
+// the song, and returns its ID. It returns 0 when the song does not exist.
+func (r *Repository) CreateSuggestion(songID, userID int, payload models.SuggestionPayload) (int, error) {
+	var id int
+
+	err := r.db.QueryRow(`
+INSERT INTO song_suggestions (
+	song_id, user_id, comment, name, group_id, release_date, text, link, album,
+	base_name, base_group_id, base_release_date, base_text, base_link, base_album
+)
+SELECT
+	songs.id, $2::integer, $3::text, $4::text, NULLIF($5::integer, 0), $6::text, $7::text, $8::text, $9::text,
+	songs.name, songs.group_id, to_char(song_details.release_date, 'YYYY-MM-DD'),
+	COALESCE(song_details.text, ''), COALESCE(song_details.link, ''), song_details.album
+FROM
+	songs
+JOIN
+	song_details
+ON
+	song_details.song_id = songs.id
+WHERE
+	songs.id = $1
+RETURNING
+	id`, songID, userID, payload.Comment, payload.Song.Name, payload.Song.GroupID,
+		payload.SongDetails.ReleaseDate, payload.SongDetails.Text, payload.SongDetails.Link, payload.SongDetails.Album).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("error inserting suggestion: %v", err)
+	}
+
+	return id, nil
+}
+
+// GetSuggestion retrieves a suggested edit by its ID.
+func (r *Repository) GetSuggestion(id int) (*models.Suggestion, error) {
+	suggestions, err := r.querySuggestions(suggestionQuery+`
+WHERE
+	song_suggestions.id = $1`, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(suggestions) == 0 {
+		return nil, nil
+	}
+	return &suggestions[0], nil
+}
+
+// ListSuggestions retrieves suggested edits, the oldest first.
+func (r *Repository) ListSuggestions(filter models.SuggestionFilter) ([]models.Suggestion, error) {
+	if filter.Limit == 0 {
+		filter.Limit = 25
+	}
+
+	return r.querySuggestions(suggestionQuery+`
+WHERE
+	($1 = '' OR song_suggestions.status = $1)
+	AND ($2 = 0 OR song_suggestions.song_id = $2)
+	AND ($3 = 0 OR song_suggestions.user_id = $3)
+	AND ($4 = 0 OR songs.group_id IN (SELECT group_id FROM group_maintainers WHERE user_id = $4))
+ORDER BY
+	song_suggestions.created_at, song_suggestions.id
+LIMIT
+	$5
+OFFSET
+	$6`, filter.Status, filter.SongID, filter.UserID, filter.MaintainerID, filter.Limit, filter.Offset)
+}
+
+func (r *Repository) querySuggestions(query string, params ...interface{}) ([]models.Suggestion, error) {
+	rows, err := r.db.Query(query, params...)
+	if err != nil {
+		return nil, fmt.Errorf("error executing query: %v", err)
+	}
+	defer rows.Close()
+
+	suggestions := []models.Suggestion{}
+	for rows.Next() {
+		var s models.Suggestion
+		var reviewedAt sql.NullTime
+		err = rows.Scan(&s.ID, &s.SongID, &s.Group, &s.Song, &s.UserID, &s.Author, &s.Status, &s.Comment,
+			&s.Proposed.Song.Name, &s.Proposed.Song.GroupID, &s.Proposed.SongDetails.ReleaseDate,
+			&s.Proposed.SongDetails.Text, &s.Proposed.SongDetails.Link, &s.Proposed.SongDetails.Album,
+			&s.Base.Song.Name, &s.Base.Song.GroupID, &s.Base.SongDetails.ReleaseDate,
+			&s.Base.SongDetails.Text, &s.Base.SongDetails.Link, &s.Base.SongDetails.Album,
+			&s.Reason, &s.ReviewedBy, &s.CreatedAt, &reviewedAt)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning suggestion: %v", err)
+		}
+		s.Proposed.Song.ID = s.SongID
+		s.Base.Song.ID = s.SongID
+		if reviewedAt.Valid {
+			s.ReviewedAt = &reviewedAt.Time
+		}
+		suggestions = append(suggestions, s)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("rows iteration error: %v", err)
+	}
+
+	return suggestions, nil
+}
+
+// RejectStaleSuggestions rejects the pending suggestions whose song changed since they were
+// made, for one song or, with a zero songID, for all songs. It returns the number rejected.
+func (r *Repository) RejectStaleSuggestions(songID int) (int64, error) {
+	result, err := r.db.Exec(`
+UPDATE
+	song_suggestions
+SET
+	status = 'rejected',
+	reason = $1,
+	reviewed_at = now()`+suggestionSong+`
+	AND song_suggestions.status = 'pending'
+	AND ($2 = 0 OR song_suggestions.song_id = $2)
+	AND NOT`+suggestionUnchanged, StaleSuggestionReason, songID)
+	if err != nil {
+		return 0, fmt.Errorf("error rejecting suggestions: %v", err)
+	}
+
+	rejected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("error rejecting suggestions: %v", err)
+	}
+	return rejected, nil
+}
+
+// ApproveSuggestion marks a pending suggestion as approved and applies its edit to the song in one
+// transaction, if the song did not change since the suggestion was made. It reports whether the
+// suggestion was approved; nothing is changed when the edit cannot be applied.
+func (r *Repository) ApproveSuggestion(id, reviewerID int, edit func(*models.Song, *models.SongDetails)) (approved bool, err error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to start transaction: %v", err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		} else if err != nil || !approved {
+			tx.Rollback()
+		} else if err = tx.Commit(); err != nil {
+			approved = false
+			err = fmt.Errorf("failed to commit transaction: %v", err)
+		}
+	}()
+
+	// The song is locked so that no other edit lands between the check and the update.
+	var song models.Song
+	var songDetails models.SongDetails
+	err = tx.QueryRow(`
+SELECT
+	songs.id, songs.name, songs.group_id, songs.explicit,
+	song_details.id, song_details.song_id, song_details.release_date, song_details.text, song_details.link, song_details.album
+FROM
+	song_suggestions
+JOIN
+	songs
+ON
+	songs.id = song_suggestions.song_id
+JOIN
+	song_details
+ON
+	song_details.song_id = songs.id
+WHERE
+	song_suggestions.id = $1
+FOR UPDATE OF songs, song_details`, id).Scan(&song.ID, &song.Name, &song.GroupID, &song.Explicit,
+		&songDetails.ID, &songDetails.SongID, &songDetails.ReleaseDate, &songDetails.Text, &songDetails.Link, &songDetails.Album)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("error scanning song: %v", err)
+	}
+
+	result, err := tx.Exec(`
+UPDATE
+	song_suggestions
+SET
+	status = 'approved',
+	reviewed_by = $2,
+	reviewed_at = now()`+suggestionSong+`
+	AND song_suggestions.id = $1
+	AND song_suggestions.status = 'pending'
+	AND`+suggestionUnchanged, id, reviewerID)
+	if err != nil {
+		return false, fmt.Errorf("error approving suggestion: %v", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error approving suggestion: %v", err)
+	}
+	if rows == 0 {
+		return false, nil
+	}
+
+	edit(&song, &songDetails)
+
+	err = updateSong(tx, r.Explicit, &song, &songDetails)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// RejectSuggestion rejects a pending suggestion with a reason and reports whether it was pending.
+func (r *Repository) RejectSuggestion(id, reviewerID int, reason string) (bool, error) {
+	result, err := r.db.Exec(`
+UPDATE
+	song_suggestions
+SET
+	status = 'rejected',
+	reason = $3,
+	reviewed_by = $2,
+	reviewed_at = now()
+WHERE
+	id = $1 AND status = 'pending'`, id, reviewerID, reason)
+	if err != nil {
+		return false, fmt.Errorf("error rejecting suggestion: %v", err)
+	}
+
+	rejected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error rejecting suggestion: %v", err)
+	}
+	return rejected > 0, nil
+}
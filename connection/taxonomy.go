@@ -0,0 +1,425 @@
+package connection
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/noctusha/music/models"
+)
+
+// Errors returned by the genre methods.
+var (
+	ErrGenreNotFound     = errors.New("genre not found")
+	ErrGenreExists       = errors.New("a genre with this name already exists")
+	ErrGenreCycle        = errors.New("a genre cannot be moved under itself or its subgenres")
+	ErrGenreHasSubgenres = errors.New("genre has subgenres")
+)
+
+// PostgreSQL error codes of constraint violations.
+const (
+	foreignKeyViolation = "23503"
+	uniqueViolation     = "23505"
+)
+
+// violates reports whether err is the violation of a constraint with the given code.
+func violates(err error, code string) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && string(pqErr.Code) == code
+}
+
+// Genres retrieves all genres by name, without their subgenres.
+func (r *Repository) Genres() ([]models.Genre, error) {
+	return r.queryGenres(`SELECT id, name, parent_id FROM genres ORDER BY lower(name)`)
+}
+
+// GetGenre retrieves a genre by its ID. It returns nil when the genre does not exist.
+func (r *Repository) GetGenre(genreID int) (*models.Genre, error) {
+	genres, err := r.queryGenres(`SELECT id, name, parent_id FROM genres WHERE id = $1`, genreID)
+	if err != nil {
+		return nil, err
+	}
+	if len(genres) == 0 {
+		return nil, nil
+	}
+	return &genres[0], nil
+}
+
+// CreateGenre creates a genre and returns its ID.
+func (r *Repository) CreateGenre(genre models.GenrePayload) (int, error) {
+	var id int
+	err := r.db.QueryRow(`INSERT INTO genres (name, parent_id) VALUES ($1, $2) RETURNING id`, genre.Name, genre.ParentID).Scan(&id)
+	switch {
+	case violates(err, uniqueViolation):
+		return 0, ErrGenreExists
+	case violates(err, foreignKeyViolation):
+		return 0, fmt.Errorf("parent %w", ErrGenreNotFound)
+	case err != nil:
+		return 0, fmt.Errorf("error inserting genre: %v", err)
+	}
+	return id, nil
+}
+
+// UpdateGenre renames a genre and moves it under another parent, refusing to make it its own ancestor.
+func (r *Repository) UpdateGenre(genreID int, genre models.GenrePayload) (err error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %v", err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			tx.Rollback()
+		} else if err = tx.Commit(); err != nil {
+			err = fmt.Errorf("failed to commit transaction: %v", err)
+		}
+	}()
+
+	// Concurrent moves could otherwise form a cycle that neither sees.
+	_, err = tx.Exec(`LOCK TABLE genres IN SHARE ROW EXCLUSIVE MODE`)
+	if err != nil {
+		return fmt.Errorf("error locking genres: %v", err)
+	}
+
+	if genre.ParentID != nil {
+		var cycle bool
+		err = tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM genre_ancestors WHERE ancestor_id = $1 AND genre_id = $2)`,
+			genreID, *genre.ParentID).Scan(&cycle)
+		if err != nil {
+			return fmt.Errorf("error checking genre ancestors: %v", err)
+		}
+		if cycle {
+			return ErrGenreCycle
+		}
+	}
+
+	result, err := tx.Exec(`UPDATE genres SET name = $1, parent_id = $2 WHERE id = $3`, genre.Name, genre.ParentID, genreID)
+	switch {
+	case violates(err, uniqueViolation):
+		return ErrGenreExists
+	case violates(err, foreignKeyViolation):
+		return fmt.Errorf("parent %w", ErrGenreNotFound)
+	case err != nil:
+		return fmt.Errorf("error updating genre: %v", err)
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error counting updated genres: %v", err)
+	}
+	if updated == 0 {
+		return ErrGenreNotFound
+	}
+	return nil
+}
+
+// DeleteGenre deletes a genre without subgenres, unlinking it from songs and groups.
+func (r *Repository) DeleteGenre(genreID int) error {
+	result, err := r.db.Exec(`DELETE FROM genres WHERE id = $1`, genreID)
+	switch {
+	case violates(err, foreignKeyViolation):
+		return ErrGenreHasSubgenres
+	case err != nil:
+		return fmt.Errorf("error deleting genre: %v", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error counting deleted genres: %v", err)
+	}
+	if deleted == 0 {
+		return ErrGenreNotFound
+	}
+	return nil
+}
+
+func (r *Repository) queryGenres(query string, params ...interface{}) ([]models.Genre, error) {
+	rows, err := r.db.Query(query, params...)
+	if err != nil {
+		return nil, fmt.Errorf("error executing query: %v", err)
+	}
+	defer rows.Close()
+
+	genres := []models.Genre{}
+	for rows.Next() {
+		var (
+			genre    models.Genre
+			parentID sql.NullInt64
+		)
+		err = rows.Scan(&genre.ID, &genre.Name, &parentID)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning genre: %v", err)
+		}
+		if parentID.Valid {
+			id := int(parentID.Int64)
+			genre.ParentID = &id
+		}
+		genres = append(genres, genre)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("rows iteration error: %v", err)
+	}
+
+	return genres, nil
+}
+
+// Tags retrieves the tags and moods in use, optionally of one kind. A query keeps those whose
+// name or one of its words starts with it, the names starting with it first; then the most used come first.
+func (r *Repository) Tags(kind, query string, limit, offset int) ([]models.Tag, error) {
+	if limit == 0 {
+		limit = 25
+	}
+
+	prefix := escapeLike(strings.ToLower(query)) + "%"
+
+	rows, err := r.db.Query(`
+SELECT
+	id,
+	kind,
+	name,
+	songs,
+	groups
+FROM
+	(SELECT
+		tags.id,
+		tags.kind,
+		tags.name,
+		(SELECT count(*) FROM song_tags WHERE song_tags.tag_id = tags.id) AS songs,
+		(SELECT count(*) FROM group_tags WHERE group_tags.tag_id = tags.id) AS groups
+	FROM
+		tags
+	WHERE
+		($1 = '' OR tags.kind = $1)
+		AND (tags.name LIKE $2 OR tags.name LIKE '% ' || $2)) AS used
+WHERE
+	songs + groups > 0
+ORDER BY
+	name LIKE $2 DESC,
+	songs + groups DESC,
+	name
+LIMIT $3 OFFSET $4`, kind, prefix, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("error executing query: %v", err)
+	}
+	defer rows.Close()
+
+	tags := []models.Tag{}
+	for rows.Next() {
+		var tag models.Tag
+		err = rows.Scan(&tag.ID, &tag.Kind, &tag.Name, &tag.Songs, &tag.Groups)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning tag: %v", err)
+		}
+		tags = append(tags, tag)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("rows iteration error: %v", err)
+	}
+
+	return tags, nil
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// SongTaxonomy retrieves the genres, tags and moods of a song, with the genres of its group when it has none.
+func (r *Repository) SongTaxonomy(songID int) (*models.Taxonomy, error) {
+	taxonomy := &models.Taxonomy{}
+
+	rows, err := r.db.Query(`
+SELECT
+	genres.id,
+	genres.name,
+	genres.parent_id,
+	song_effective_genres.inherited
+FROM
+	song_effective_genres
+JOIN
+	genres
+ON
+	genres.id = song_effective_genres.genre_id
+WHERE
+	song_effective_genres.song_id = $1
+ORDER BY
+	lower(genres.name)`, songID)
+	if err != nil {
+		return nil, fmt.Errorf("error executing query: %v", err)
+	}
+	defer rows.Close()
+
+	taxonomy.Genres = []models.Genre{}
+	for rows.Next() {
+		var (
+			genre    models.Genre
+			parentID sql.NullInt64
+		)
+		err = rows.Scan(&genre.ID, &genre.Name, &parentID, &taxonomy.GenresInherited)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning genre: %v", err)
+		}
+		if parentID.Valid {
+			id := int(parentID.Int64)
+			genre.ParentID = &id
+		}
+		taxonomy.Genres = append(taxonomy.Genres, genre)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("rows iteration error: %v", err)
+	}
+
+	err = r.taxonomyTags(taxonomy, `
+SELECT tags.kind, tags.name, FALSE FROM song_tags JOIN tags ON tags.id = song_tags.tag_id WHERE song_tags.song_id = $1
+UNION ALL
+SELECT tags.kind, tags.name, TRUE FROM songs JOIN group_tags ON group_tags.group_id = songs.group_id JOIN tags ON tags.id = group_tags.tag_id
+WHERE songs.id = $1 AND NOT EXISTS (SELECT 1 FROM song_tags WHERE song_tags.song_id = $1 AND song_tags.tag_id = tags.id)
+ORDER BY 2`, songID)
+	if err != nil {
+		return nil, err
+	}
+
+	return taxonomy, nil
+}
+
+// GroupTaxonomy retrieves the genres, tags and moods of a group.
+func (r *Repository) GroupTaxonomy(groupID int) (*models.Taxonomy, error) {
+	genres, err := r.queryGenres(`
+SELECT genres.id, genres.name, genres.parent_id FROM group_genres JOIN genres ON genres.id = group_genres.genre_id
+WHERE group_genres.group_id = $1 ORDER BY lower(genres.name)`, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	taxonomy := &models.Taxonomy{Genres: genres}
+	err = r.taxonomyTags(taxonomy, `
+SELECT tags.kind, tags.name, FALSE FROM group_tags JOIN tags ON tags.id = group_tags.tag_id
+WHERE group_tags.group_id = $1 ORDER BY tags.name`, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	return taxonomy, nil
+}
+
+// taxonomyTags adds the tags and moods selected by a query of kind, name and whether they come from the group.
+func (r *Repository) taxonomyTags(taxonomy *models.Taxonomy, query string, id int) error {
+	rows, err := r.db.Query(query, id)
+	if err != nil {
+		return fmt.Errorf("error executing query: %v", err)
+	}
+	defer rows.Close()
+
+	taxonomy.Tags, taxonomy.Moods = []string{}, []string{}
+	for rows.Next() {
+		var (
+			kind, name string
+			inherited  bool
+		)
+		err = rows.Scan(&kind, &name, &inherited)
+		if err != nil {
+			return fmt.Errorf("error scanning tag: %v", err)
+		}
+
+		switch {
+		case kind == models.TagKindTag && inherited:
+			taxonomy.InheritedTags = append(taxonomy.InheritedTags, name)
+		case kind == models.TagKindTag:
+			taxonomy.Tags = append(taxonomy.Tags, name)
+		case inherited:
+			taxonomy.InheritedMoods = append(taxonomy.InheritedMoods, name)
+		default:
+			taxonomy.Moods = append(taxonomy.Moods, name)
+		}
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return fmt.Errorf("rows iteration error: %v", err)
+	}
+	return nil
+}
+
+// SetSongTaxonomy replaces the genres, tags and moods of a song given in the payload.
+func (r *Repository) SetSongTaxonomy(songID int, payload models.TaxonomyPayload) error {
+	return r.setTaxonomy("song", songID, payload)
+}
+
+// SetGroupTaxonomy replaces the genres, tags and moods of a group given in the payload.
+func (r *Repository) SetGroupTaxonomy(groupID int, payload models.TaxonomyPayload) error {
+	return r.setTaxonomy("group", groupID, payload)
+}
+
+// setTaxonomy replaces the links of a song or a group, the owner, in the <owner>_genres and <owner>_tags tables.
+// Tags and moods are created when first used.
+func (r *Repository) setTaxonomy(owner string, id int, payload models.TaxonomyPayload) (err error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %v", err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			tx.Rollback()
+		} else if err = tx.Commit(); err != nil {
+			err = fmt.Errorf("failed to commit transaction: %v", err)
+		}
+	}()
+
+	if payload.GenreIDs != nil {
+		_, err = tx.Exec(`DELETE FROM `+owner+`_genres WHERE `+owner+`_id = $1`, id)
+		if err != nil {
+			return fmt.Errorf("error deleting genres: %v", err)
+		}
+
+		_, err = tx.Exec(`INSERT INTO `+owner+`_genres (`+owner+`_id, genre_id) SELECT $1, unnest($2::integer[]) ON CONFLICT DO NOTHING`,
+			id, pq.Array(*payload.GenreIDs))
+		switch {
+		case violates(err, foreignKeyViolation):
+			return ErrGenreNotFound
+		case err != nil:
+			return fmt.Errorf("error inserting genres: %v", err)
+		}
+	}
+
+	for _, labels := range []struct {
+		kind  string
+		names *[]string
+	}{{models.TagKindTag, payload.Tags}, {models.TagKindMood, payload.Moods}} {
+		if labels.names == nil {
+			continue
+		}
+
+		_, err = tx.Exec(`INSERT INTO tags (kind, name) SELECT $1, unnest($2::text[]) ON CONFLICT DO NOTHING`, labels.kind, pq.Array(*labels.names))
+		if err != nil {
+			return fmt.Errorf("error inserting tags: %v", err)
+		}
+
+		_, err = tx.Exec(`DELETE FROM `+owner+`_tags WHERE `+owner+`_id = $1 AND tag_id IN (SELECT id FROM tags WHERE kind = $2)`, id, labels.kind)
+		if err != nil {
+			return fmt.Errorf("error deleting tags: %v", err)
+		}
+
+		_, err = tx.Exec(`INSERT INTO `+owner+`_tags (`+owner+`_id, tag_id) SELECT $1, id FROM tags WHERE kind = $2 AND name = ANY($3)`,
+			id, labels.kind, pq.Array(*labels.names))
+		if err != nil {
+			return fmt.Errorf("error inserting tags: %v", err)
+		}
+	}
+
+	return nil
+}
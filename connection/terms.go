@@ -0,0 +1,108 @@
+package connection
+
+import (
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/noctusha/music/models"
+)
+
+// ChangedSongTexts retrieves the texts of the songs whose terms are missing or were counted from another text.
+// The default text counts as empty.
+func (r *Repository) ChangedSongTexts() ([]models.SongText, error) {
+	rows, err := r.db.Query(`
+SELECT
+	songs.id,
+	texts.text,
+	md5(texts.text)
+FROM
+	songs
+JOIN
+	song_details
+ON
+	song_details.song_id = songs.id
+CROSS JOIN LATERAL
+	(SELECT COALESCE(NULLIF(song_details.text, $1), '') AS text) AS texts
+LEFT JOIN
+	song_terms
+ON
+	song_terms.song_id = songs.id
+WHERE
+	song_terms.text_hash IS DISTINCT FROM md5(texts.text)`, models.UnknownValue)
+	if err != nil {
+		return nil, fmt.Errorf("error executing query: %v", err)
+	}
+	defer rows.Close()
+
+	texts := []models.SongText{}
+	for rows.Next() {
+		var text models.SongText
+		err = rows.Scan(&text.SongID, &text.Text, &text.Hash)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning song text: %v", err)
+		}
+		texts = append(texts, text)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("rows iteration error: %v", err)
+	}
+
+	return texts, nil
+}
+
+// SaveSongTerms stores the term counts of a song's lyrics.
+func (r *Repository) SaveSongTerms(songID int, hash string, terms []string, counts []int64) error {
+	_, err := r.db.Exec(`
+INSERT INTO song_terms (song_id, text_hash, terms, counts) VALUES ($1, $2, $3, $4)
+ON CONFLICT (song_id) DO UPDATE SET text_hash = EXCLUDED.text_hash, terms = EXCLUDED.terms, counts = EXCLUDED.counts, updated_at = now()`,
+		songID, hash, pq.Array(terms), pq.Array(counts))
+	if err != nil {
+		return fmt.Errorf("error saving song terms: %v", err)
+	}
+	return nil
+}
+
+// AllSongTerms retrieves the term counts of all songs with their groups and release years.
+func (r *Repository) AllSongTerms() ([]models.SongTerms, error) {
+	rows, err := r.db.Query(`
+SELECT
+	songs.id,
+	songs.group_id,
+	CASE WHEN song_details.release_date IS NULL OR song_details.release_date = $1::date THEN 0
+		ELSE extract(year FROM song_details.release_date)::integer END,
+	song_terms.terms,
+	song_terms.counts
+FROM
+	song_terms
+JOIN
+	songs
+ON
+	songs.id = song_terms.song_id
+JOIN
+	song_details
+ON
+	song_details.song_id = songs.id`, models.DefaultReleaseDate)
+	if err != nil {
+		return nil, fmt.Errorf("error executing query: %v", err)
+	}
+	defer rows.Close()
+
+	all := []models.SongTerms{}
+	for rows.Next() {
+		var terms models.SongTerms
+		err = rows.Scan(&terms.SongID, &terms.GroupID, &terms.Year, pq.Array(&terms.Terms), pq.Array(&terms.Counts))
+		if err != nil {
+			return nil, fmt.Errorf("error scanning song terms: %v", err)
+		}
+		all = append(all, terms)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("rows iteration error: %v", err)
+	}
+
+	return all, nil
+}
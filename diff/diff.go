@@ -0,0 +1,88 @@
+// Package diff compares texts line by line.
+package diff
+
+import (
+	"strings"
+
+	"github.com/noctusha/music/models"
+)
+
+// Line operations.
+const (
+	OpEqual  = "="
+	OpDelete = "-"
+	OpInsert = "+"
+)
+
+// Lines returns the line diff turning old into new, based on their longest common subsequence.
+// Removed lines come before the added lines that replace them.
+func Lines(old, new string) []models.DiffLine {
+	a := splitLines(old)
+	b := splitLines(new)
+
+	// Common leading and trailing lines are kept out of the quadratic part.
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	lines := make([]models.DiffLine, 0, len(a)+len(b))
+	for _, line := range a[:prefix] {
+		lines = append(lines, models.DiffLine{Op: OpEqual, Text: line})
+	}
+	lines = append(lines, middle(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		lines = append(lines, models.DiffLine{Op: OpEqual, Text: line})
+	}
+	return lines
+}
+
+// middle diffs the lines between the common prefix and suffix.
+func middle(a, b []string) []models.DiffLine {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var lines []models.DiffLine
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, models.DiffLine{Op: OpEqual, Text: a[i]})
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, models.DiffLine{Op: OpDelete, Text: a[i]})
+			i++
+		default:
+			lines = append(lines, models.DiffLine{Op: OpInsert, Text: b[j]})
+			j++
+		}
+	}
+	return lines
+}
+
+// splitLines splits a text into lines, ignoring the line ending style and a final newline.
+func splitLines(text string) []string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.TrimSuffix(text, "\n")
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
@@ -0,0 +1,217 @@
+package exporter
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/noctusha/music/connection"
+	"github.com/noctusha/music/models"
+)
+
+// Supported export formats.
+const (
+	FormatNDJSON = "ndjson"
+	FormatCSV    = "csv"
+	FormatBundle = "bundle"
+)
+
+// Files of a bundle.
+const (
+	BundleManifest = "manifest.json"
+	BundleGroups   = "groups.json"
+	BundleSongs    = "songs.json"
+)
+
+// BundleVersion is the version of the bundle layout written by Export.
+const BundleVersion = 1
+
+// CSVHeader lists the columns of a CSV export.
+var CSVHeader = []string{"song_id", "group_id", "details_id", "group", "song", "release_date", "text", "link", "album"}
+
+// Manifest describes the contents of a bundle.
+type Manifest struct {
+	Version    int       `json:"version"`
+	ExportedAt time.Time `json:"exported_at"`
+	Groups     int       `json:"groups"`
+	Songs      int       `json:"songs"`
+}
+
+// Export streams the songs matching filter to w in the given format.
+func Export(repo *connection.Repository, w io.Writer, format string, filter models.SongFilter) error {
+	switch format {
+	case FormatNDJSON:
+		return exportNDJSON(repo, w, filter)
+	case FormatCSV:
+		return exportCSV(repo, w, filter)
+	case FormatBundle:
+		return exportBundle(repo, w, filter)
+	default:
+		return fmt.Errorf("unsupported export format: %v", format)
+	}
+}
+
+// ContentType returns the MIME type of an export format.
+func ContentType(format string) string {
+	switch format {
+	case FormatCSV:
+		return "text/csv; charset=UTF-8"
+	case FormatBundle:
+		return "application/zip"
+	default:
+		return "application/x-ndjson"
+	}
+}
+
+// FileName returns the suggested file name of an export.
+func FileName(format string) string {
+	if format == FormatBundle {
+		return "catalogue.zip"
+	}
+	return "catalogue." + format
+}
+
+func exportNDJSON(repo *connection.Repository, w io.Writer, filter models.SongFilter) error {
+	encoder := json.NewEncoder(w)
+	return repo.ExportSongs(filter, func(song models.ExportSong) error {
+		return encoder.Encode(song)
+	})
+}
+
+func exportCSV(repo *connection.Repository, w io.Writer, filter models.SongFilter) error {
+	writer := csv.NewWriter(w)
+
+	err := writer.Write(CSVHeader)
+	if err != nil {
+		return fmt.Errorf("error writing CSV header: %v", err)
+	}
+
+	err = repo.ExportSongs(filter, func(song models.ExportSong) error {
+		return writer.Write([]string{
+			strconv.Itoa(song.SongID),
+			strconv.Itoa(song.GroupID),
+			strconv.Itoa(song.DetailsID),
+			song.Group,
+			song.Song,
+			song.ReleaseDate,
+			song.Text,
+			song.Link,
+			song.Album,
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// exportBundle writes a zip with the songs, their groups and a manifest. Without a
+// filter every group is included, so that groups without songs survive a round trip.
+func exportBundle(repo *connection.Repository, w io.Writer, filter models.SongFilter) error {
+	zw := zip.NewWriter(w)
+	manifest := Manifest{Version: BundleVersion, ExportedAt: time.Now().UTC()}
+
+	file, err := zw.Create(BundleSongs)
+	if err != nil {
+		return fmt.Errorf("error creating %s: %v", BundleSongs, err)
+	}
+
+	groupIDs := make(map[int]bool)
+	songs := newArrayWriter(file)
+	err = repo.ExportSongs(filter, func(song models.ExportSong) error {
+		groupIDs[song.GroupID] = true
+		manifest.Songs++
+		return songs.write(song)
+	})
+	if err != nil {
+		return err
+	}
+	err = songs.close()
+	if err != nil {
+		return err
+	}
+
+	file, err = zw.Create(BundleGroups)
+	if err != nil {
+		return fmt.Errorf("error creating %s: %v", BundleGroups, err)
+	}
+
+	var ids []int
+	if filter.Filtered() {
+		ids = make([]int, 0, len(groupIDs))
+		for id := range groupIDs {
+			ids = append(ids, id)
+		}
+		sort.Ints(ids)
+	}
+
+	groups := newArrayWriter(file)
+	err = repo.ExportGroups(ids, func(group models.Group) error {
+		manifest.Groups++
+		return groups.write(group)
+	})
+	if err != nil {
+		return err
+	}
+	err = groups.close()
+	if err != nil {
+		return err
+	}
+
+	file, err = zw.Create(BundleManifest)
+	if err != nil {
+		return fmt.Errorf("error creating %s: %v", BundleManifest, err)
+	}
+	err = json.NewEncoder(file).Encode(manifest)
+	if err != nil {
+		return fmt.Errorf("error writing %s: %v", BundleManifest, err)
+	}
+
+	return zw.Close()
+}
+
+// arrayWriter writes a JSON array one element at a time.
+type arrayWriter struct {
+	w     io.Writer
+	count int
+}
+
+func newArrayWriter(w io.Writer) *arrayWriter {
+	return &arrayWriter{w: w}
+}
+
+func (a *arrayWriter) write(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("error encoding element: %v", err)
+	}
+
+	separator := ",\n"
+	if a.count == 0 {
+		separator = "[\n"
+	}
+	a.count++
+
+	_, err = io.WriteString(a.w, separator)
+	if err != nil {
+		return err
+	}
+	_, err = a.w.Write(data)
+	return err
+}
+
+func (a *arrayWriter) close() error {
+	end := "\n]\n"
+	if a.count == 0 {
+		end = "[]\n"
+	}
+	_, err := io.WriteString(a.w, end)
+	return err
+}
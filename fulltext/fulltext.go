@@ -0,0 +1,379 @@
+// Package fulltext searches group names, song names and lyrics with an inverted index held in
+// memory. Words are stemmed for English and Russian, results are ranked by BM25, and quoted
+// phrases must match word for word. The index is saved to a snapshot file and reloaded on
+// startup, so it needs no search features of the database.
+package fulltext
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/noctusha/music/lyrics"
+	"github.com/noctusha/music/models"
+)
+
+// Fields of a song, indexed separately.
+const (
+	fieldGroup = iota
+	fieldSong
+	fieldText
+	numFields
+)
+
+var fieldNames = [numFields]string{"group", "song", "text"}
+
+// fieldWeights weight the BM25 scores of the fields: a match in a name counts more than one in the lyrics.
+var fieldWeights = [numFields]float64{2, 3, 1}
+
+// Parameters of BM25: k1 saturates the term frequency, b normalizes it by the field length.
+const (
+	k1 = 1.2
+	b  = 0.75
+)
+
+// Source is the part of the repository the index reads songs from.
+type Source interface {
+	SongLyrics(songID int) (*models.SongLyrics, error)
+	SongLyricsByIDs(ids []int) ([]models.SongLyrics, error)
+	SongLyricsHashes() (map[int]string, error)
+}
+
+// Index is the inverted index of all songs. Sync brings it up to date with the repository,
+// reindexing only the songs whose names or lyrics changed; Update does so for a single song.
+type Index struct {
+	Repo Source
+	// Path is the snapshot file; the index is not saved when it is empty.
+	Path string
+
+	// syncMu serializes the updates of the index and its saving, mu guards the index.
+	syncMu sync.Mutex
+	mu     sync.RWMutex
+	synced bool
+	dirty  bool
+	docs   map[int]*document
+	// postings maps each term to the songs containing it.
+	postings map[string]map[int]*posting
+	// lengths sums the number of terms of each field over all songs.
+	lengths [numFields]int
+}
+
+// document is an indexed song. Its fields are exported for the snapshot.
+type document struct {
+	GroupID int
+	Group   string
+	Song    string
+	Hash    string
+	Terms   [numFields][]string
+}
+
+// posting lists the positions of a term in each field of a song.
+type posting [numFields][]int
+
+// NewIndex creates an empty Index saved to path. It is filled by Load and Sync.
+func NewIndex(repo Source, path string) *Index {
+	return &Index{Repo: repo, Path: path, docs: make(map[int]*document), postings: make(map[string]map[int]*posting)}
+}
+
+// analyze splits a text into stemmed terms. Stop words are kept, so that phrases made of them match.
+func analyze(text string) []string {
+	words := lyrics.Words(text)
+	for i, word := range words {
+		words[i] = lyrics.Stem(word)
+	}
+	return words
+}
+
+// Sync reindexes the songs added or changed since the last Sync, drops the deleted ones, and
+// saves the index when it changed. Only the hashes of the songs are read to find the changed ones.
+func (x *Index) Sync() error {
+	x.syncMu.Lock()
+	defer x.syncMu.Unlock()
+
+	hashes, err := x.Repo.SongLyricsHashes()
+	if err != nil {
+		return err
+	}
+
+	x.mu.RLock()
+	var changed []int
+	for songID, hash := range hashes {
+		if doc, ok := x.docs[songID]; !ok || doc.Hash != hash {
+			changed = append(changed, songID)
+		}
+	}
+	x.mu.RUnlock()
+
+	var songs []models.SongLyrics
+	if len(changed) > 0 {
+		songs, err = x.Repo.SongLyricsByIDs(changed)
+		if err != nil {
+			return err
+		}
+	}
+
+	x.mu.Lock()
+	for _, song := range songs {
+		x.put(song)
+	}
+	for id := range x.docs {
+		if _, ok := hashes[id]; !ok {
+			x.remove(id)
+		}
+	}
+	x.synced = true
+	x.mu.Unlock()
+
+	return x.save()
+}
+
+// Update reindexes songs after they were created or changed, or drops them after they were
+// deleted, and saves the index.
+func (x *Index) Update(songIDs ...int) error {
+	x.syncMu.Lock()
+	defer x.syncMu.Unlock()
+
+	for _, songID := range songIDs {
+		song, err := x.Repo.SongLyrics(songID)
+		if err != nil {
+			return err
+		}
+
+		x.mu.Lock()
+		if song == nil {
+			x.remove(songID)
+		} else {
+			x.put(*song)
+		}
+		x.mu.Unlock()
+	}
+
+	return x.save()
+}
+
+// put indexes a song, replacing its previous version. The caller holds mu.
+func (x *Index) put(song models.SongLyrics) {
+	x.remove(song.SongID)
+
+	doc := &document{GroupID: song.GroupID, Group: song.Group, Song: song.Song, Hash: song.Hash}
+	doc.Terms[fieldGroup] = analyze(song.Group)
+	doc.Terms[fieldSong] = analyze(song.Song)
+	doc.Terms[fieldText] = analyze(song.Text)
+
+	x.add(song.SongID, doc)
+	x.dirty = true
+}
+
+// add adds the postings of a document. The caller holds mu.
+func (x *Index) add(songID int, doc *document) {
+	x.docs[songID] = doc
+	for field, terms := range doc.Terms {
+		x.lengths[field] += len(terms)
+		for position, term := range terms {
+			songs, ok := x.postings[term]
+			if !ok {
+				songs = make(map[int]*posting)
+				x.postings[term] = songs
+			}
+			p, ok := songs[songID]
+			if !ok {
+				p = &posting{}
+				songs[songID] = p
+			}
+			p[field] = append(p[field], position)
+		}
+	}
+}
+
+// remove drops a song from the index. The caller holds mu.
+func (x *Index) remove(songID int) {
+	doc, ok := x.docs[songID]
+	if !ok {
+		return
+	}
+
+	for field, terms := range doc.Terms {
+		x.lengths[field] -= len(terms)
+		for _, term := range terms {
+			songs := x.postings[term]
+			delete(songs, songID)
+			if len(songs) == 0 {
+				delete(x.postings, term)
+			}
+		}
+	}
+	delete(x.docs, songID)
+	x.dirty = true
+}
+
+// Search finds the songs whose group, name or lyrics contain any of the words of the query and
+// every phrase of it in double quotes, the best BM25 scores first. It syncs the index first if
+// it is empty and never was.
+func (x *Index) Search(query string, limit, offset int) (models.SearchResults, error) {
+	x.mu.RLock()
+	empty := !x.synced && len(x.docs) == 0
+	x.mu.RUnlock()
+
+	if empty {
+		err := x.Sync()
+		if err != nil {
+			return models.SearchResults{}, fmt.Errorf("error building the search index: %v", err)
+		}
+	}
+
+	results := models.SearchResults{Results: []models.SearchResult{}}
+
+	terms, phrases := parseQuery(query)
+	if len(terms) == 0 {
+		return results, nil
+	}
+
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	candidates := x.candidates(terms, phrases)
+
+	var average [numFields]float64
+	for field, length := range x.lengths {
+		average[field] = math.Max(float64(length)/float64(len(x.docs)), 1)
+	}
+
+	found := make([]models.SearchResult, 0, len(candidates))
+	for songID := range candidates {
+		doc := x.docs[songID]
+		result := models.SearchResult{SongID: songID, GroupID: doc.GroupID, Group: doc.Group, Song: doc.Song, Fields: []string{}}
+
+		var matched [numFields]bool
+		for _, term := range terms {
+			songs := x.postings[term]
+			p, ok := songs[songID]
+			if !ok {
+				continue
+			}
+
+			df := float64(len(songs))
+			idf := math.Log(1 + (float64(len(x.docs))-df+0.5)/(df+0.5))
+			for field, positions := range p {
+				if len(positions) == 0 {
+					continue
+				}
+				tf := float64(len(positions))
+				norm := 1 - b + b*float64(len(doc.Terms[field]))/average[field]
+				result.Score += fieldWeights[field] * idf * tf * (k1 + 1) / (tf + k1*norm)
+				matched[field] = true
+			}
+		}
+
+		for field, ok := range matched {
+			if ok {
+				result.Fields = append(result.Fields, fieldNames[field])
+			}
+		}
+		result.Score = math.Round(result.Score*1000) / 1000
+		found = append(found, result)
+	}
+
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].Score != found[j].Score {
+			return found[i].Score > found[j].Score
+		}
+		return found[i].SongID < found[j].SongID
+	})
+
+	results.Total = len(found)
+	if offset < len(found) {
+		found = found[offset:]
+		if len(found) > limit {
+			found = found[:limit]
+		}
+		results.Results = found
+	}
+	return results, nil
+}
+
+// candidates returns the songs containing every phrase, or else any of the terms. The caller holds mu.
+func (x *Index) candidates(terms []string, phrases [][]string) map[int]bool {
+	candidates := make(map[int]bool)
+
+	if len(phrases) == 0 {
+		for _, term := range terms {
+			for songID := range x.postings[term] {
+				candidates[songID] = true
+			}
+		}
+		return candidates
+	}
+
+	// The songs containing the first word of the first phrase are checked against every phrase.
+	for songID := range x.postings[phrases[0][0]] {
+		all := true
+		for _, phrase := range phrases {
+			if !x.containsPhrase(songID, phrase) {
+				all = false
+				break
+			}
+		}
+		if all {
+			candidates[songID] = true
+		}
+	}
+	return candidates
+}
+
+// containsPhrase reports whether a field of a song has the terms of a phrase one after another.
+// The caller holds mu.
+func (x *Index) containsPhrase(songID int, phrase []string) bool {
+	postings := make([]*posting, len(phrase))
+	for i, term := range phrase {
+		p, ok := x.postings[term][songID]
+		if !ok {
+			return false
+		}
+		postings[i] = p
+	}
+
+	for field := 0; field < numFields; field++ {
+		for _, start := range postings[0][field] {
+			all := true
+			for i := 1; i < len(phrase); i++ {
+				positions := postings[i][field]
+				j := sort.SearchInts(positions, start+i)
+				if j == len(positions) || positions[j] != start+i {
+					all = false
+					break
+				}
+			}
+			if all {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// parseQuery splits a query into its distinct terms and the terms of its phrases in double quotes.
+func parseQuery(query string) ([]string, [][]string) {
+	var (
+		terms   []string
+		phrases [][]string
+	)
+	seen := make(map[string]bool)
+
+	for i, part := range strings.Split(query, `"`) {
+		words := analyze(part)
+		// Odd parts are quoted; an unterminated quote runs to the end of the query.
+		if i%2 == 1 && len(words) > 0 {
+			phrases = append(phrases, words)
+		}
+		for _, word := range words {
+			if !seen[word] {
+				seen[word] = true
+				terms = append(terms, word)
+			}
+		}
+	}
+
+	return terms, phrases
+}
@@ -0,0 +1,220 @@
+package fulltext
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/noctusha/music/models"
+)
+
+// songSource is a Source over songs held in memory.
+type songSource map[int]models.SongLyrics
+
+func (s songSource) SongLyrics(songID int) (*models.SongLyrics, error) {
+	song, ok := s[songID]
+	if !ok {
+		return nil, nil
+	}
+	return &song, nil
+}
+
+func (s songSource) SongLyricsByIDs(ids []int) ([]models.SongLyrics, error) {
+	var songs []models.SongLyrics
+	for _, id := range ids {
+		if song, ok := s[id]; ok {
+			songs = append(songs, song)
+		}
+	}
+	return songs, nil
+}
+
+func (s songSource) SongLyricsHashes() (map[int]string, error) {
+	hashes := make(map[int]string, len(s))
+	for id, song := range s {
+		hashes[id] = song.Hash
+	}
+	return hashes, nil
+}
+
+// testSongs are the songs of the tests. The hash of a song joins its names and text, so that it changes with them.
+func testSongs() songSource {
+	songs := songSource{}
+	for _, song := range []models.SongLyrics{
+		{SongID: 1, GroupID: 1, Group: "The Beatles", Song: "Yellow Submarine", Text: "In the town where I was born lived a man who sailed to sea"},
+		{SongID: 2, GroupID: 2, Group: "Kino", Song: "Morning Song", Text: "We all live in a yellow submarine, sang the radio"},
+		{SongID: 3, GroupID: 3, Group: "Muse", Song: "Uprising", Text: "They will not force us, they will stop degrading us"},
+		{SongID: 4, GroupID: 4, Group: "Кино", Song: "Группа крови", Text: "Тёплое место, но улицы ждут отпечатков наших ног"},
+	} {
+		song.Hash = fmt.Sprintf("%s|%s|%s", song.Group, song.Song, song.Text)
+		songs[song.SongID] = song
+	}
+	return songs
+}
+
+func newTestIndex(t *testing.T, songs songSource, path string) *Index {
+	t.Helper()
+
+	x := NewIndex(songs, path)
+	err := x.Sync()
+	if err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	return x
+}
+
+func songIDs(results models.SearchResults) []int {
+	ids := []int{}
+	for _, result := range results.Results {
+		ids = append(ids, result.SongID)
+	}
+	return ids
+}
+
+func TestSearch(t *testing.T) {
+	x := newTestIndex(t, testSongs(), "")
+
+	tests := []struct {
+		query string
+		ids   []int
+	}{
+		// A match in the song name ranks above one in the lyrics only.
+		{"submarine", []int{1, 2}},
+		{"yellow submarine", []int{1, 2}},
+		// Stems match other forms of a word.
+		{"sailing", []int{1}},
+		{"улица", []int{4}},
+		// A phrase must match word for word, in one field, and restricts the words around it.
+		{`"we all live" submarine`, []int{2}},
+		{`"submarine yellow"`, []int{}},
+		{`"will stop"`, []int{3}},
+		{`"will force"`, []int{}},
+		// Group names are searched as written, not transliterated.
+		{"кино", []int{4}},
+		{"nothing", []int{}},
+		{"", []int{}},
+	}
+
+	for _, test := range tests {
+		results, err := x.Search(test.query, 10, 0)
+		if err != nil {
+			t.Fatalf("Search(%q): %v", test.query, err)
+		}
+		got := songIDs(results)
+		if !reflect.DeepEqual(got, test.ids) {
+			t.Errorf("Search(%q) = %v, want %v", test.query, got, test.ids)
+		}
+		if results.Total != len(test.ids) {
+			t.Errorf("Search(%q).Total = %d, want %d", test.query, results.Total, len(test.ids))
+		}
+	}
+}
+
+func TestSearchFields(t *testing.T) {
+	x := newTestIndex(t, testSongs(), "")
+
+	results, err := x.Search("submarine", 10, 0)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(results.Results) != 2 {
+		t.Fatalf("Search returned %d results, want 2", len(results.Results))
+	}
+
+	title, lyrics := results.Results[0], results.Results[1]
+	if !reflect.DeepEqual(title.Fields, []string{"song"}) || !reflect.DeepEqual(lyrics.Fields, []string{"text"}) {
+		t.Errorf("fields = %v and %v, want [song] and [text]", title.Fields, lyrics.Fields)
+	}
+	if title.Score <= lyrics.Score {
+		t.Errorf("score of the title match %v is not above the lyrics match %v", title.Score, lyrics.Score)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	songs := testSongs()
+	x := newTestIndex(t, songs, "")
+
+	// A deleted song is dropped by Update, which finds no such song.
+	delete(songs, 3)
+	// A changed song is reindexed.
+	song := songs[2]
+	song.Text, song.Hash = "Nothing but the rain", "changed"
+	songs[2] = song
+
+	err := x.Update(2, 3)
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	for query, ids := range map[string][]int{
+		"uprising":  {},
+		"submarine": {1},
+		"rain":      {2},
+	} {
+		results, err := x.Search(query, 10, 0)
+		if err != nil {
+			t.Fatalf("Search(%q): %v", query, err)
+		}
+		if got := songIDs(results); !reflect.DeepEqual(got, ids) {
+			t.Errorf("after Update, Search(%q) = %v, want %v", query, got, ids)
+		}
+	}
+
+	// Updating a song that is not indexed and does not exist changes nothing.
+	err = x.Update(99)
+	if err != nil {
+		t.Fatalf("Update of a missing song: %v", err)
+	}
+	if len(x.docs) != 3 {
+		t.Errorf("index has %d songs, want 3", len(x.docs))
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fulltext.idx")
+	songs := testSongs()
+	x := newTestIndex(t, songs, path)
+
+	// Update saves the index without waiting for the next Sync.
+	delete(songs, 1)
+	err := x.Update(1)
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	loaded := NewIndex(songs, path)
+	err = loaded.Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if !reflect.DeepEqual(loaded.docs, x.docs) || !reflect.DeepEqual(loaded.postings, x.postings) || loaded.lengths != x.lengths {
+		t.Errorf("loaded index differs from the saved one")
+	}
+
+	for _, query := range []string{"submarine", `"will stop"`, "кино"} {
+		want, err := x.Search(query, 10, 0)
+		if err != nil {
+			t.Fatalf("Search(%q): %v", query, err)
+		}
+		got, err := loaded.Search(query, 10, 0)
+		if err != nil {
+			t.Fatalf("Search(%q) of the loaded index: %v", query, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Search(%q) of the loaded index = %+v, want %+v", query, got, want)
+		}
+	}
+}
+
+func TestLoadMissing(t *testing.T) {
+	x := NewIndex(testSongs(), filepath.Join(t.TempDir(), "missing.idx"))
+
+	err := x.Load()
+	if err != nil {
+		t.Fatalf("Load of a missing snapshot: %v", err)
+	}
+	if len(x.docs) != 0 {
+		t.Errorf("index has %d songs, want none", len(x.docs))
+	}
+}
@@ -0,0 +1,96 @@
+package fulltext
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// snapshotVersion changes with the tokenizer, the stemmers and the song hashes, so that a snapshot
+// of terms analyzed differently is not loaded.
+const snapshotVersion = 2
+
+// snapshot is the content of the snapshot file: the analyzed songs, from which the postings are rebuilt.
+type snapshot struct {
+	Version int
+	Docs    map[int]*document
+}
+
+// Load reads the index saved by the last Sync. A missing snapshot, or one of another version,
+// leaves the index empty until the next Sync.
+func (x *Index) Load() error {
+	if x.Path == "" {
+		return nil
+	}
+
+	file, err := os.Open(x.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error opening search index: %v", err)
+	}
+	defer file.Close()
+
+	var saved snapshot
+	err = gob.NewDecoder(file).Decode(&saved)
+	if err != nil {
+		return fmt.Errorf("error reading search index: %v", err)
+	}
+	if saved.Version != snapshotVersion {
+		return nil
+	}
+
+	x.syncMu.Lock()
+	defer x.syncMu.Unlock()
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	x.docs = make(map[int]*document, len(saved.Docs))
+	x.postings = make(map[string]map[int]*posting)
+	x.lengths = [numFields]int{}
+	for songID, doc := range saved.Docs {
+		x.add(songID, doc)
+	}
+	x.dirty = false
+	return nil
+}
+
+// save writes the index to a temporary file replacing the snapshot, if it changed since it was
+// last saved. The caller holds syncMu.
+func (x *Index) save() error {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	if x.Path == "" || !x.dirty {
+		return nil
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(x.Path), filepath.Base(x.Path)+".*")
+	if err != nil {
+		return fmt.Errorf("error creating search index: %v", err)
+	}
+	defer os.Remove(file.Name())
+
+	err = gob.NewEncoder(file).Encode(snapshot{Version: snapshotVersion, Docs: x.docs})
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("error writing search index: %v", err)
+	}
+	err = file.Close()
+	if err != nil {
+		return fmt.Errorf("error writing search index: %v", err)
+	}
+
+	err = os.Rename(file.Name(), x.Path)
+	if err != nil {
+		return fmt.Errorf("error saving search index: %v", err)
+	}
+
+	// Only Load, Update and Sync change the index, and all hold syncMu, so nothing changed while saving.
+	x.dirty = false
+	return nil
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/noctusha/music/lyrics"
+)
+
+// GetSongAnalytics godoc
+// @Summary Get lyrics analytics of a song
+// @Description Returns the word count, vocabulary size, most frequent words without stop words, average line and word
+// @Description length, share of repeated lines, readability and language of the lyrics of a song. Annotations such as
+// @Description [Chorus] are skipped.
+// @Tags analytics
+// @Produce json
+// @Param song_id path int true "Song ID"
+// @Param top query int false "Number of most frequent words, 20 by default"
+// @Success 200 {object} models.LyricsAnalytics
+// @Failure 400 {object} JSON
+// @Failure 404 {object} JSON
+// @Failure 500 {object} JSON
+// @Router /api/songs/{song_id}/analytics [get]
+// GetSongAnalytics handles the request to analyse the lyrics of a song.
+func (h *Handler) GetSongAnalytics(w http.ResponseWriter, r *http.Request) {
+	songID, err := pathID(r, "song_id")
+	if err != nil {
+		respondJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	top, err := parseTop(r)
+	if err != nil {
+		respondJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	song, err := h.Repo.SongLyrics(songID)
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to retrieve text: %v", err))
+		return
+	}
+	if song == nil {
+		respondJSONError(w, http.StatusNotFound, fmt.Sprintf("no such song with song_id: %v", songID))
+		return
+	}
+
+	analytics := lyrics.Analyze([]string{song.Text}, top)
+	analytics.SongID, analytics.Song, analytics.GroupID, analytics.Group = song.SongID, song.Song, song.GroupID, song.Group
+
+	RespondJSON(w, http.StatusOK, analytics)
+}
+
+// GetGroupAnalytics godoc
+// @Summary Get lyrics analytics of a group
+// @Description Returns the lyrics analytics of all songs of a group together. Repeated lines are counted
+// @Description within each song; the language is the one of most songs, with the songs per language. The readability
+// @Description of the songs is weighted by their words.
+// @Tags analytics
+// @Produce json
+// @Param group_id path int true "Group ID"
+// @Param top query int false "Number of most frequent words, 20 by default"
+// @Success 200 {object} models.LyricsAnalytics
+// @Failure 400 {object} JSON
+// @Failure 404 {object} JSON
+// @Failure 500 {object} JSON
+// @Router /api/groups/{group_id}/analytics [get]
+// GetGroupAnalytics handles the request to analyse the lyrics of the songs of a group.
+func (h *Handler) GetGroupAnalytics(w http.ResponseWriter, r *http.Request) {
+	top, err := parseTop(r)
+	if err != nil {
+		respondJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	group, ok := h.pathGroup(w, r)
+	if !ok {
+		return
+	}
+
+	songs, err := h.Repo.GroupLyrics(group.ID)
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to select texts from database: %v", err))
+		return
+	}
+
+	texts := make([]string, len(songs))
+	for i, song := range songs {
+		texts[i] = song.Text
+	}
+
+	analytics := lyrics.Analyze(texts, top)
+	analytics.GroupID, analytics.Group = group.ID, group.Name
+
+	RespondJSON(w, http.StatusOK, analytics)
+}
+
+// parseTop reads the number of most frequent words to list.
+func parseTop(r *http.Request) (int, error) {
+	value := r.URL.Query().Get("top")
+	if value == "" {
+		return 20, nil
+	}
+
+	top, err := strconv.Atoi(value)
+	if err != nil || top < 0 || top > 100 {
+		return 0, fmt.Errorf("invalid top: %v, expected 0 to 100", value)
+	}
+	return top, nil
+}
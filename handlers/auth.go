@@ -0,0 +1,431 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/noctusha/music/auth"
+	"github.com/noctusha/music/models"
+)
+
+// apiKeyHeader is an alternative to "Authorization: Bearer" for API keys.
+const apiKeyHeader = "X-API-Key"
+
+// Register godoc
+// @Summary Register a user
+// @Description Creates a user account with a password of at least 8 characters
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param credentials body models.CredentialsPayload true "Name and password"
+// @Success 201 {object} models.User
+// @Failure 400 {object} JSON
+// @Failure 500 {object} JSON
+// @Router /api/auth/register [post]
+// Register handles the request to create a user account.
+func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
+	var payload models.CredentialsPayload
+
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		respondJSONError(w, http.StatusBadRequest, fmt.Sprintf("failed to decode credentials: %v", err))
+		return
+	}
+	if payload.Name == "" {
+		respondJSONError(w, http.StatusBadRequest, "no user name")
+		return
+	}
+	if len([]rune(payload.Password)) < auth.MinPasswordLength {
+		respondJSONError(w, http.StatusBadRequest, fmt.Sprintf("password must have at least %d characters", auth.MinPasswordLength))
+		return
+	}
+
+	existing, _, err := h.Repo.UserCredentials(payload.Name)
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to retrieve user: %v", err))
+		return
+	}
+	if existing != nil {
+		respondJSONError(w, http.StatusBadRequest, fmt.Sprintf("user %v already exists", payload.Name))
+		return
+	}
+
+	hash, err := auth.HashPassword(payload.Password)
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	user, err := h.Repo.CreateUser(payload.Name, hash)
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to create user: %v", err))
+		return
+	}
+
+	RespondJSON(w, http.StatusCreated, user)
+}
+
+// Login godoc
+// @Summary Log in
+// @Description Checks a name and password and issues a short-lived access token and a long-lived refresh token.
+// @Description The tokens carry the role of the user. Both are Ed25519 signed JWTs that can be verified offline with the keys from /.well-known/jwks.json.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param credentials body models.CredentialsPayload true "Name and password"
+// @Success 200 {object} auth.TokenPair
+// @Failure 400 {object} JSON
+// @Failure 401 {object} JSON
+// @Failure 500 {object} JSON
+// @Router /api/auth/login [post]
+// Login handles the request to exchange credentials for tokens.
+func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
+	var payload models.CredentialsPayload
+
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		respondJSONError(w, http.StatusBadRequest, fmt.Sprintf("failed to decode credentials: %v", err))
+		return
+	}
+
+	user, hash, err := h.Repo.UserCredentials(payload.Name)
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to retrieve user: %v", err))
+		return
+	}
+	if user == nil || !auth.CheckPassword(hash, payload.Password) {
+		respondUnauthorized(w, "invalid name or password")
+		return
+	}
+
+	h.respondTokens(w, user)
+}
+
+// Refresh godoc
+// @Summary Refresh tokens
+// @Description Exchanges a refresh token for a new access and refresh token pair with the current role of the user
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param refresh body models.RefreshPayload true "Refresh token"
+// @Success 200 {object} auth.TokenPair
+// @Failure 400 {object} JSON
+// @Failure 401 {object} JSON
+// @Failure 500 {object} JSON
+// @Router /api/auth/refresh [post]
+// Refresh handles the request to refresh tokens.
+func (h *Handler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var payload models.RefreshPayload
+
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		respondJSONError(w, http.StatusBadRequest, fmt.Sprintf("failed to decode refresh token: %v", err))
+		return
+	}
+
+	claims, err := h.Auth.Verify(payload.RefreshToken, auth.TypeRefresh)
+	if err != nil {
+		respondUnauthorized(w, err.Error())
+		return
+	}
+
+	userID, _ := claims.UserID()
+	user, err := h.Repo.GetUserByID(userID)
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to retrieve user: %v", err))
+		return
+	}
+	if user == nil {
+		respondUnauthorized(w, "user no longer exists")
+		return
+	}
+
+	h.respondTokens(w, user)
+}
+
+// Me godoc
+// @Summary Get the current user
+// @Description Returns the authenticated user
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.User
+// @Failure 401 {object} JSON
+// @Failure 500 {object} JSON
+// @Router /api/auth/me [get]
+// Me handles the request to get the authenticated user.
+func (h *Handler) Me(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	user, err := h.Repo.GetUserByID(userID)
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to retrieve user: %v", err))
+		return
+	}
+
+	RespondJSON(w, http.StatusOK, user)
+}
+
+// ListAPIKeys godoc
+// @Summary Get API keys
+// @Description Returns the API keys of the current user without the keys themselves
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.APIKey
+// @Failure 401 {object} JSON
+// @Failure 500 {object} JSON
+// @Router /api/auth/keys [get]
+// ListAPIKeys handles the request to list the API keys of the current user.
+func (h *Handler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	keys, err := h.Repo.ListAPIKeys(userID)
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to select API keys from database: %v", err))
+		return
+	}
+
+	RespondJSON(w, http.StatusOK, keys)
+}
+
+// CreateAPIKey godoc
+// @Summary Create an API key
+// @Description Creates a long-lived API key for scripts. The key is only shown in this response;
+// @Description send it as "Authorization: Bearer <key>" or in the X-API-Key header.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param key body models.NewAPIKeyPayload true "Key name"
+// @Success 201 {object} models.APIKey
+// @Failure 400 {object} JSON
+// @Failure 401 {object} JSON
+// @Failure 500 {object} JSON
+// @Router /api/auth/keys [post]
+// CreateAPIKey handles the request to create an API key.
+func (h *Handler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	var payload models.NewAPIKeyPayload
+
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		respondJSONError(w, http.StatusBadRequest, fmt.Sprintf("failed to decode API key: %v", err))
+		return
+	}
+	if payload.Name == "" {
+		respondJSONError(w, http.StatusBadRequest, "no API key name")
+		return
+	}
+
+	key, prefix, hash, err := auth.NewAPIKey()
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	created, err := h.Repo.CreateAPIKey(userID, payload.Name, prefix, hash)
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to create API key: %v", err))
+		return
+	}
+
+	created.Key = key
+	RespondJSON(w, http.StatusCreated, created)
+}
+
+// DeleteAPIKey godoc
+// @Summary Revoke an API key
+// @Description Deletes an API key of the current user
+// @Tags auth
+// @Security BearerAuth
+// @Param key_id path int true "API key ID"
+// @Success 200 {object} JSON
+// @Failure 400 {object} JSON
+// @Failure 401 {object} JSON
+// @Failure 404 {object} JSON
+// @Failure 500 {object} JSON
+// @Router /api/auth/keys/{key_id} [delete]
+// DeleteAPIKey handles the request to revoke an API key.
+func (h *Handler) DeleteAPIKey(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	keyID, err := pathID(r, "key_id")
+	if err != nil {
+		respondJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	deleted, err := h.Repo.DeleteAPIKey(userID, keyID)
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to delete API key: %v", err))
+		return
+	}
+	if !deleted {
+		respondJSONError(w, http.StatusNotFound, fmt.Sprintf("no such API key with key_id: %v", keyID))
+		return
+	}
+
+	RespondJSON(w, http.StatusOK, nil)
+}
+
+// JWKS godoc
+// @Summary Get token signing keys
+// @Description Returns the public keys that verify access and refresh tokens, as a JSON Web Key set
+// @Tags auth
+// @Produce json
+// @Success 200 {object} auth.JWKSet
+// @Router /.well-known/jwks.json [get]
+// JWKS handles the request to get the public keys of the token signer.
+func (h *Handler) JWKS(w http.ResponseWriter, r *http.Request) {
+	RespondJSON(w, http.StatusOK, h.Auth.JWKS())
+}
+
+// Authenticate is a middleware that identifies the user of a request from a bearer
+// access token or an API key. Requests without credentials pass through anonymously;
+// requests with invalid credentials are rejected.
+func (h *Handler) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		credential, err := requestCredential(r)
+		if err != nil {
+			respondUnauthorized(w, err.Error())
+			return
+		}
+		if credential == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		principal, status, err := h.principal(credential)
+		if status == http.StatusUnauthorized {
+			respondUnauthorized(w, err.Error())
+			return
+		}
+		if err != nil {
+			respondJSONError(w, status, err.Error())
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+	})
+}
+
+// RequireUser is a middleware that rejects anonymous requests.
+func (h *Handler) RequireUser(next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if auth.PrincipalFromContext(r.Context()) == nil {
+			respondUnauthorized(w, "authentication required")
+			return
+		}
+		next(w, r)
+	})
+}
+
+// RequirePermission is a middleware that rejects anonymous requests and requests of
+// users whose role does not grant a permission.
+func (h *Handler) RequirePermission(permission auth.Permission, next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal := auth.PrincipalFromContext(r.Context())
+		if principal == nil {
+			respondUnauthorized(w, "authentication required")
+			return
+		}
+		if !principal.Can(permission) {
+			respondForbidden(w, fmt.Sprintf("missing permission: %s", permission))
+			return
+		}
+		next(w, r)
+	})
+}
+
+// requestCredential returns the bearer token or API key of a request, or "" when it has none.
+func requestCredential(r *http.Request) (string, error) {
+	return parseCredential(r.Header.Get(apiKeyHeader), r.Header.Get("Authorization"))
+}
+
+// parseCredential returns the API key, or else the credential of a bearer authorization, or "" when
+// there is neither.
+func parseCredential(apiKey, authorization string) (string, error) {
+	if apiKey != "" {
+		return apiKey, nil
+	}
+	if authorization == "" {
+		return "", nil
+	}
+
+	scheme, credential, found := strings.Cut(authorization, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(credential) == "" {
+		return "", errors.New("unsupported authorization scheme, expected Bearer")
+	}
+	return strings.TrimSpace(credential), nil
+}
+
+// principal identifies the user of an access token or an API key, returning the status to respond
+// with when the credential is invalid.
+func (h *Handler) principal(credential string) (*auth.Principal, int, error) {
+	if auth.IsAPIKey(credential) {
+		user, err := h.Repo.UserByAPIKey(auth.HashAPIKey(credential))
+		if err != nil {
+			return nil, http.StatusInternalServerError, fmt.Errorf("failed to retrieve API key: %v", err)
+		}
+		if user == nil {
+			return nil, http.StatusUnauthorized, errors.New("invalid API key")
+		}
+		return &auth.Principal{UserID: user.ID, Name: user.Name, Role: user.Role, Method: auth.MethodAPIKey}, 0, nil
+	}
+
+	claims, err := h.Auth.Verify(credential, auth.TypeAccess)
+	if err != nil {
+		return nil, http.StatusUnauthorized, err
+	}
+
+	// The role is reloaded rather than taken from the token, so that a role change or a
+	// deleted user takes effect before the token expires.
+	userID, _ := claims.UserID()
+	user, err := h.Repo.GetUserByID(userID)
+	if err != nil {
+		return nil, http.StatusInternalServerError, fmt.Errorf("failed to retrieve user: %v", err)
+	}
+	if user == nil {
+		return nil, http.StatusUnauthorized, errors.New("user no longer exists")
+	}
+	return &auth.Principal{UserID: user.ID, Name: user.Name, Role: user.Role, Method: auth.MethodToken}, 0, nil
+}
+
+// respondTokens responds with a new token pair for a user.
+func (h *Handler) respondTokens(w http.ResponseWriter, user *models.User) {
+	tokens, err := h.Auth.Issue(user.ID, user.Name, user.Role)
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	RespondJSON(w, http.StatusOK, tokens)
+}
+
+// respondUnauthorized responds with 401 and the authentication scheme to use.
+func respondUnauthorized(w http.ResponseWriter, message string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="music"`)
+	respondJSONError(w, http.StatusUnauthorized, message)
+}
+
+// respondForbidden responds with 403 for an authenticated user lacking a permission.
+func respondForbidden(w http.ResponseWriter, message string) {
+	respondJSONError(w, http.StatusForbidden, message)
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/noctusha/music/suggest"
+)
+
+// Autocomplete godoc
+// @Summary Complete a search
+// @Description Returns groups, songs and albums with a name or a word of it starting with q, regardless of case,
+// @Description accents and Cyrillic or Latin spelling. Names starting with q come first, then the more popular ones
+// @Description by plays and favorites. Groups are also found by their aliases.
+// @Tags search
+// @Produce json
+// @Param q query string true "Beginning of the search"
+// @Param limit query int false "Number of suggestions, from 1 to 50, 10 by default"
+// @Success 200 {array} models.SearchSuggestion
+// @Failure 400 {object} JSON
+// @Failure 500 {object} JSON
+// @Router /api/suggest [get]
+// Autocomplete handles the request to complete a search.
+func (h *Handler) Autocomplete(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	limit := 10
+	if value := query.Get("limit"); value != "" {
+		var err error
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > suggest.MaxLimit {
+			respondJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid limit: %v, expected 1 to %d", value, suggest.MaxLimit))
+			return
+		}
+	}
+
+	suggestions, err := h.Suggest.Suggest(query.Get("q"), limit)
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to complete search: %v", err))
+		return
+	}
+
+	RespondJSON(w, http.StatusOK, suggestions)
+}
+
+// syncAutocomplete updates the search suggestions of the changed groups in the background, or
+// rebuilds all of them when no groups are given.
+func (h *Handler) syncAutocomplete(groupIDs ...int) {
+	go func() {
+		if len(groupIDs) == 0 {
+			err := h.Suggest.Sync()
+			if err != nil {
+				log.Printf("error syncing search suggestions: %v", err)
+			}
+			return
+		}
+
+		err := h.Suggest.SyncGroups(groupIDs...)
+		if err != nil {
+			log.Printf("error updating search suggestions of groups %v: %v", groupIDs, err)
+		}
+	}()
+}
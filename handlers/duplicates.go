@@ -0,0 +1,272 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/noctusha/music/connection"
+	"github.com/noctusha/music/models"
+	"github.com/noctusha/music/playlist"
+)
+
+// Weights of the name and lyrics similarities in the score of a duplicate song.
+const (
+	duplicateNameWeight   = 0.6
+	duplicateLyricsWeight = 0.4
+)
+
+// ListDuplicateSongs godoc
+// @Summary Find duplicate songs
+// @Description Returns the pairs of songs of the same group that are likely the same song. The score is the
+// @Description similarity of their names after normalization (60%) and of their lyrics (40%), or of their names
+// @Description alone when either has no lyrics. The older song comes first.
+// @Tags songs
+// @Produce json
+// @Security BearerAuth
+// @Param threshold query number false "Minimal score, from 0.5 to 1, 0.85 by default"
+// @Success 200 {array} models.SongDuplicate
+// @Failure 400 {object} JSON
+// @Failure 401 {object} JSON
+// @Failure 403 {object} JSON
+// @Failure 500 {object} JSON
+// @Router /api/songs/duplicates [get]
+// ListDuplicateSongs handles the request to find songs that are likely the same.
+func (h *Handler) ListDuplicateSongs(w http.ResponseWriter, r *http.Request) {
+	threshold := 0.85
+	if value := r.URL.Query().Get("threshold"); value != "" {
+		var err error
+		threshold, err = strconv.ParseFloat(value, 64)
+		if err != nil || threshold < 0.5 || threshold > 1 {
+			respondJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid threshold: %v, expected 0.5 to 1", value))
+			return
+		}
+	}
+
+	songs, err := h.Repo.SongNames()
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to select songs from database: %v", err))
+		return
+	}
+
+	duplicates, err := songDuplicates(songs, threshold, h.Similar.LyricsSimilarity)
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to compare lyrics: %v", err))
+		return
+	}
+
+	RespondJSON(w, http.StatusOK, duplicates)
+}
+
+// MergeSongs godoc
+// @Summary Merge songs
+// @Description Merges the given songs into this song and deletes them. Their playlist entries, favorites,
+// @Description ratings, plays, suggested edits, genres, tags and credits move to this song; its own ratings win.
+// @Description Each detail is the best known one among the songs: the longest lyrics, the earliest release
+// @Description date, and this song's link and album unless unknown.
+// @Tags songs
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param song_id path int true "ID of the song to merge into"
+// @Param songs body models.MergeSongsPayload true "Songs to merge"
+// @Success 200 {object} models.SongMerge
+// @Failure 400 {object} JSON
+// @Failure 401 {object} JSON
+// @Failure 403 {object} JSON
+// @Failure 404 {object} JSON
+// @Failure 500 {object} JSON
+// @Router /api/songs/{song_id}/merge [post]
+// MergeSongs handles the request to merge songs into another one.
+func (h *Handler) MergeSongs(w http.ResponseWriter, r *http.Request) {
+	song, ok := h.pathSong(w, r)
+	if !ok {
+		return
+	}
+
+	var payload models.MergeSongsPayload
+
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		respondJSONError(w, http.StatusBadRequest, fmt.Sprintf("failed to decode songs: %v", err))
+		return
+	}
+
+	merged := []int{}
+	for _, id := range payload.SongIDs {
+		if id == song.ID {
+			respondSongError(w, connection.ErrSongMergeIntoSelf)
+			return
+		}
+		if !slices.Contains(merged, id) {
+			merged = append(merged, id)
+		}
+	}
+	if len(merged) == 0 {
+		respondJSONError(w, http.StatusBadRequest, "no songs to merge")
+		return
+	}
+
+	groupIDs, err := h.Repo.MergeSongs(song.ID, merged, mergeDetails)
+	if err != nil {
+		respondSongError(w, err)
+		return
+	}
+
+	_, err = h.Repo.RejectStaleSuggestions(song.ID)
+	if err != nil {
+		log.Printf("error rejecting suggestions of song %d: %v", song.ID, err)
+	}
+	h.syncSimilar()
+	h.syncAutocomplete(groupIDs...)
+	h.syncSearch(append([]int{song.ID}, merged...)...)
+
+	id := strconv.Itoa(song.ID)
+	song, err = h.Repo.GetSongByID(id)
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to retrieve song: %v", err))
+		return
+	}
+	songDetails, err := h.Repo.GetSongDetailsByID(id)
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to retrieve song: %v", err))
+		return
+	}
+	if song == nil || songDetails == nil {
+		respondSongError(w, connection.ErrSongNotFound)
+		return
+	}
+
+	RespondJSON(w, http.StatusOK, models.SongMerge{Song: *song, SongDetails: *songDetails, Merged: merged})
+}
+
+// songDuplicates pairs the songs of each group that score at least threshold, the highest scores first.
+// lyricsSimilarity reports the similarity of the lyrics of two songs, or false when it is unknown.
+func songDuplicates(songs []models.SongName, threshold float64, lyricsSimilarity func(a, b int) (float64, bool, error)) ([]models.SongDuplicate, error) {
+	type normalized struct {
+		song   models.SongName
+		name   string
+		length int
+	}
+
+	byGroup := make(map[int][]normalized)
+	for _, song := range songs {
+		name := playlist.Normalize(song.Name)
+		if name != "" {
+			byGroup[song.GroupID] = append(byGroup[song.GroupID], normalized{song: song, name: name, length: utf8.RuneCountInString(name)})
+		}
+	}
+
+	// Even identical lyrics cannot lift names less similar than this to the threshold.
+	minNameScore := (threshold - duplicateLyricsWeight) / duplicateNameWeight
+
+	duplicates := []models.SongDuplicate{}
+	for _, names := range byGroup {
+		sort.SliceStable(names, func(i, j int) bool { return names[i].length < names[j].length })
+
+		for i, a := range names {
+			for _, b := range names[i+1:] {
+				if float64(a.length) < minNameScore*float64(b.length) {
+					break
+				}
+
+				nameScore := playlist.Similarity(a.name, b.name)
+				if nameScore < minNameScore {
+					continue
+				}
+
+				lyricsScore, known, err := lyricsSimilarity(a.song.ID, b.song.ID)
+				if err != nil {
+					return nil, err
+				}
+
+				duplicate := models.SongDuplicate{Song: a.song, Duplicate: b.song, Score: nameScore, NameScore: round(nameScore)}
+				if known {
+					duplicate.Score = duplicateNameWeight*nameScore + duplicateLyricsWeight*lyricsScore
+					lyricsScore = round(lyricsScore)
+					duplicate.LyricsScore = &lyricsScore
+				}
+				if duplicate.Score < threshold {
+					continue
+				}
+				duplicate.Score = round(duplicate.Score)
+
+				if duplicate.Duplicate.ID < duplicate.Song.ID {
+					duplicate.Song, duplicate.Duplicate = duplicate.Duplicate, duplicate.Song
+				}
+				duplicates = append(duplicates, duplicate)
+			}
+		}
+	}
+
+	sort.Slice(duplicates, func(i, j int) bool {
+		if duplicates[i].Score != duplicates[j].Score {
+			return duplicates[i].Score > duplicates[j].Score
+		}
+		if duplicates[i].Song.ID != duplicates[j].Song.ID {
+			return duplicates[i].Song.ID < duplicates[j].Song.ID
+		}
+		return duplicates[i].Duplicate.ID < duplicates[j].Duplicate.ID
+	})
+
+	return duplicates, nil
+}
+
+func round(score float64) float64 {
+	return math.Round(score*1000) / 1000
+}
+
+// mergeDetails picks the best known value of each detail of a song and its duplicates: the longest
+// lyrics, the earliest release date, and the song's own link and album unless they are unknown.
+func mergeDetails(target models.SongDetails, sources []models.SongDetails) models.SongDetails {
+	details := target
+
+	for _, source := range sources {
+		if knownDetail(source.Text) && (!knownDetail(details.Text) || utf8.RuneCountInString(source.Text) > utf8.RuneCountInString(details.Text)) {
+			details.Text = source.Text
+		}
+		if knownDate(source.ReleaseDate) && (!knownDate(details.ReleaseDate) || source.ReleaseDate < details.ReleaseDate) {
+			details.ReleaseDate = source.ReleaseDate
+		}
+		if !knownDetail(details.Link) && knownDetail(source.Link) {
+			details.Link = source.Link
+		}
+		if !knownDetail(details.Album) && knownDetail(source.Album) {
+			details.Album = source.Album
+		}
+	}
+
+	return details
+}
+
+func knownDetail(value string) bool {
+	value = strings.TrimSpace(value)
+	return value != "" && value != models.UnknownValue
+}
+
+// knownDate reports whether a release date is set; dates are scanned as RFC 3339 timestamps.
+func knownDate(value string) bool {
+	return value != "" && !strings.HasPrefix(value, models.DefaultReleaseDate)
+}
+
+// respondSongError maps the duplicate song errors of the repository to status codes.
+func respondSongError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, connection.ErrSongNotFound):
+		respondJSONError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, connection.ErrSongMergeIntoSelf):
+		respondJSONError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, connection.ErrSongExists):
+		respondJSONError(w, http.StatusConflict, err.Error())
+	default:
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to update songs: %v", err))
+	}
+}
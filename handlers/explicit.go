@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/noctusha/music/auth"
+	"github.com/noctusha/music/models"
+)
+
+// SetSongExplicit godoc
+// @Summary Override the explicit flag of a song
+// @Description Marks a song as explicit or not regardless of its lyrics. A null explicit removes the
+// @Description override, so the flag is detected from the lyrics again. Editors can only override
+// @Description the songs of the groups they maintain.
+// @Tags songs
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param song_id path int true "Song ID"
+// @Param explicit body models.ExplicitPayload true "Explicit flag"
+// @Success 200 {object} models.SongExplicit
+// @Failure 400 {object} JSON
+// @Failure 401 {object} JSON
+// @Failure 403 {object} JSON
+// @Failure 404 {object} JSON
+// @Failure 500 {object} JSON
+// @Router /api/songs/{song_id}/explicit [put]
+// SetSongExplicit handles the request to override the explicit flag of a song.
+func (h *Handler) SetSongExplicit(w http.ResponseWriter, r *http.Request) {
+	song, ok := h.pathSong(w, r)
+	if !ok {
+		return
+	}
+
+	var payload models.ExplicitPayload
+
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		respondJSONError(w, http.StatusBadRequest, fmt.Sprintf("failed to decode explicit flag: %v", err))
+		return
+	}
+
+	if !h.requireGroup(w, r, song.GroupID, auth.PermSongsEdit) {
+		return
+	}
+
+	err = h.Repo.SetExplicitOverride(song.ID, payload.Explicit)
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to update song: %v", err))
+		return
+	}
+
+	explicit, err := h.Repo.SongExplicit(song.ID)
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to retrieve song: %v", err))
+		return
+	}
+	if explicit == nil {
+		respondJSONError(w, http.StatusNotFound, fmt.Sprintf("no such song with song_id: %v", song.ID))
+		return
+	}
+
+	RespondJSON(w, http.StatusOK, explicit)
+}
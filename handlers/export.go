@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/noctusha/music/exporter"
+	"github.com/noctusha/music/importer"
+)
+
+// ExportCatalogue godoc
+// @Summary Export the catalogue
+// @Description Streams groups, songs and details as NDJSON, CSV or a zipped JSON bundle.
+// @Description Without limit every song matching the ListSongs filters is exported.
+// @Tags export
+// @Produce application/x-ndjson
+// @Produce text/csv
+// @Produce application/zip
+// @Param format query string false "Export format" Enums(ndjson, csv, bundle)
+// @Param group query string false "Group name or alias, also matched regardless of accents and Cyrillic or Latin spelling"
+// @Param name query string false "Song name, also matched regardless of accents and Cyrillic or Latin spelling"
+// @Param releaseDate query string false "Release date"
+// @Param text query string false "Song text"
+// @Param link query string false "Song link"
+// @Param favoritesOnly query bool false "Only the favorites of the current user"
+// @Param minRating query number false "Minimal average rating, from 1 to 5"
+// @Param excludeExplicit query bool false "Leave out explicit songs"
+// @Param tags query string false "Comma-separated tags of the song or its group"
+// @Param moods query string false "Comma-separated moods of the song or its group"
+// @Param tagMatch query string false "Whether songs need all the tags and moods or any of them, all by default" Enums(all, any)
+// @Param genre query string false "Genre, including its subgenres and the genres inherited from the group"
+// @Param limit query int false "Limit"
+// @Param offset query int false "Offset"
+// @Success 200 {file} file
+// @Failure 400 {object} JSON
+// @Router /api/export [get]
+// ExportCatalogue handles the request to stream the catalogue.
+func (h *Handler) ExportCatalogue(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	format := query.Get("format")
+	if format == "" {
+		format = exporter.FormatNDJSON
+	}
+	if format != exporter.FormatNDJSON && format != exporter.FormatCSV && format != exporter.FormatBundle {
+		respondJSONError(w, http.StatusBadRequest, fmt.Sprintf("unsupported format: %v", format))
+		return
+	}
+
+	filter, err := parseSongFilter(r, "format")
+	if err != nil {
+		respondJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// The response is streamed, so errors after this point can only be logged.
+	w.Header().Set("Content-Type", exporter.ContentType(format))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", exporter.FileName(format)))
+	w.WriteHeader(http.StatusOK)
+
+	err = exporter.Export(h.Repo, w, format, filter)
+	if err != nil {
+		log.Printf("error exporting catalogue: %v", err)
+	}
+}
+
+// restoreBundle spools an uploaded bundle to a temporary file and restores it.
+func (h *Handler) restoreBundle(w http.ResponseWriter, body io.Reader, dryRun bool) {
+	file, err := os.CreateTemp("", "music-bundle-*.zip")
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to store bundle: %v", err))
+		return
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	size, err := io.Copy(file, body)
+	if err != nil {
+		respondJSONError(w, http.StatusBadRequest, fmt.Sprintf("failed to read bundle: %v", err))
+		return
+	}
+
+	report, err := importer.RestoreBundle(h.Repo, file, size, dryRun)
+	if err != nil {
+		respondJSONError(w, importStatus(err), fmt.Sprintf("failed to restore bundle: %v", err))
+		return
+	}
+	if !dryRun {
+		h.syncAutocomplete()
+		h.syncSearch()
+	}
+
+	RespondJSON(w, http.StatusOK, report)
+}
@@ -0,0 +1,263 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/noctusha/music/models"
+)
+
+// GetSongStats godoc
+// @Summary Get song statistics
+// @Description Returns the average rating, plays and favorites of a song, and for authenticated users their own favorite and rating
+// @Tags favorites
+// @Produce json
+// @Param song_id path int true "Song ID"
+// @Success 200 {object} models.SongStats
+// @Failure 400 {object} JSON
+// @Failure 404 {object} JSON
+// @Failure 500 {object} JSON
+// @Router /api/songs/{song_id}/stats [get]
+// GetSongStats handles the request to get the statistics of a song.
+func (h *Handler) GetSongStats(w http.ResponseWriter, r *http.Request) {
+	songID, err := pathID(r, "song_id")
+	if err != nil {
+		respondJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	h.respondSongStats(w, songID, currentUserID(r))
+}
+
+// AddFavorite godoc
+// @Summary Add a song to favorites
+// @Description Adds a song to the favorites of the current user
+// @Tags favorites
+// @Produce json
+// @Security BearerAuth
+// @Param song_id path int true "Song ID"
+// @Success 200 {object} models.SongStats
+// @Failure 400 {object} JSON
+// @Failure 401 {object} JSON
+// @Failure 404 {object} JSON
+// @Failure 500 {object} JSON
+// @Router /api/songs/{song_id}/favorite [put]
+// AddFavorite handles the request to add a song to the favorites of the current user.
+func (h *Handler) AddFavorite(w http.ResponseWriter, r *http.Request) {
+	userID, songID, ok := h.userSong(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.Repo.AddFavorite(userID, songID)
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to add favorite: %v", err))
+		return
+	}
+
+	h.respondSongStats(w, songID, userID)
+}
+
+// RemoveFavorite godoc
+// @Summary Remove a song from favorites
+// @Description Removes a song from the favorites of the current user
+// @Tags favorites
+// @Produce json
+// @Security BearerAuth
+// @Param song_id path int true "Song ID"
+// @Success 200 {object} models.SongStats
+// @Failure 400 {object} JSON
+// @Failure 401 {object} JSON
+// @Failure 404 {object} JSON
+// @Failure 500 {object} JSON
+// @Router /api/songs/{song_id}/favorite [delete]
+// RemoveFavorite handles the request to remove a song from the favorites of the current user.
+func (h *Handler) RemoveFavorite(w http.ResponseWriter, r *http.Request) {
+	userID, songID, ok := h.userSong(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.Repo.RemoveFavorite(userID, songID)
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to remove favorite: %v", err))
+		return
+	}
+
+	h.respondSongStats(w, songID, userID)
+}
+
+// RateSong godoc
+// @Summary Rate a song
+// @Description Sets the rating of a song by the current user, from 1 to 5, replacing a previous rating
+// @Tags favorites
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param song_id path int true "Song ID"
+// @Param rating body models.RatingPayload true "Rating"
+// @Success 200 {object} models.SongStats
+// @Failure 400 {object} JSON
+// @Failure 401 {object} JSON
+// @Failure 404 {object} JSON
+// @Failure 500 {object} JSON
+// @Router /api/songs/{song_id}/rating [put]
+// RateSong handles the request to rate a song.
+func (h *Handler) RateSong(w http.ResponseWriter, r *http.Request) {
+	userID, songID, ok := h.userSong(w, r)
+	if !ok {
+		return
+	}
+
+	var payload models.RatingPayload
+
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		respondJSONError(w, http.StatusBadRequest, fmt.Sprintf("failed to decode rating: %v", err))
+		return
+	}
+	if payload.Rating < 1 || payload.Rating > 5 {
+		respondJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid rating: %v, expected 1 to 5", payload.Rating))
+		return
+	}
+
+	err = h.Repo.SetRating(userID, songID, payload.Rating)
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to rate song: %v", err))
+		return
+	}
+
+	h.respondSongStats(w, songID, userID)
+}
+
+// RemoveRating godoc
+// @Summary Remove a song rating
+// @Description Removes the rating of a song by the current user
+// @Tags favorites
+// @Produce json
+// @Security BearerAuth
+// @Param song_id path int true "Song ID"
+// @Success 200 {object} models.SongStats
+// @Failure 400 {object} JSON
+// @Failure 401 {object} JSON
+// @Failure 404 {object} JSON
+// @Failure 500 {object} JSON
+// @Router /api/songs/{song_id}/rating [delete]
+// RemoveRating handles the request to remove the rating of a song.
+func (h *Handler) RemoveRating(w http.ResponseWriter, r *http.Request) {
+	userID, songID, ok := h.userSong(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.Repo.RemoveRating(userID, songID)
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to remove rating: %v", err))
+		return
+	}
+
+	h.respondSongStats(w, songID, userID)
+}
+
+// RecordPlay godoc
+// @Summary Record a play
+// @Description Records that the current user played a song, now or at played_at
+// @Tags favorites
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param song_id path int true "Song ID"
+// @Param play body models.PlayPayload false "Play time"
+// @Success 200 {object} models.SongStats
+// @Failure 400 {object} JSON
+// @Failure 401 {object} JSON
+// @Failure 404 {object} JSON
+// @Failure 500 {object} JSON
+// @Router /api/songs/{song_id}/plays [post]
+// RecordPlay handles the request to record a play of a song.
+func (h *Handler) RecordPlay(w http.ResponseWriter, r *http.Request) {
+	userID, songID, ok := h.userSong(w, r)
+	if !ok {
+		return
+	}
+
+	var payload models.PlayPayload
+
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil && err != io.EOF {
+		respondJSONError(w, http.StatusBadRequest, fmt.Sprintf("failed to decode play: %v", err))
+		return
+	}
+
+	playedAt := time.Now()
+	if payload.PlayedAt != nil {
+		if payload.PlayedAt.After(playedAt) {
+			respondJSONError(w, http.StatusBadRequest, "played_at is in the future")
+			return
+		}
+		playedAt = *payload.PlayedAt
+	}
+
+	err = h.Repo.RecordPlay(userID, songID, playedAt)
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to record play: %v", err))
+		return
+	}
+
+	h.respondSongStats(w, songID, userID)
+}
+
+// userSong returns the current user and the existing song in the song_id path variable.
+func (h *Handler) userSong(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	userID, ok := h.requireUser(w, r)
+	if !ok {
+		return 0, 0, false
+	}
+
+	song, ok := h.pathSong(w, r)
+	if !ok {
+		return 0, 0, false
+	}
+
+	return userID, song.ID, true
+}
+
+// pathSong loads the song in the song_id path variable, responding with 404 when it does not exist.
+func (h *Handler) pathSong(w http.ResponseWriter, r *http.Request) (*models.Song, bool) {
+	songID, err := pathID(r, "song_id")
+	if err != nil {
+		respondJSONError(w, http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+
+	song, err := h.Repo.GetSongByID(strconv.Itoa(songID))
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to retrieve song: %v", err))
+		return nil, false
+	}
+	if song == nil {
+		respondJSONError(w, http.StatusNotFound, fmt.Sprintf("no such song with song_id: %v", songID))
+		return nil, false
+	}
+
+	return song, true
+}
+
+// respondSongStats responds with the statistics of a song as seen by a user.
+func (h *Handler) respondSongStats(w http.ResponseWriter, songID, userID int) {
+	stats, err := h.Repo.SongStats(songID, userID)
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to retrieve song stats: %v", err))
+		return
+	}
+	if stats == nil {
+		respondJSONError(w, http.StatusNotFound, fmt.Sprintf("no such song with song_id: %v", songID))
+		return
+	}
+
+	RespondJSON(w, http.StatusOK, stats)
+}
@@ -0,0 +1,279 @@
+package handlers
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/graph-gophers/graphql-go"
+	"github.com/noctusha/music/auth"
+	"github.com/noctusha/music/connection"
+	"github.com/noctusha/music/models"
+)
+
+//go:embed schema.graphql
+var graphqlSchema string
+
+// Limits of a GraphQL query: the deepest nesting of its fields, its length in bytes, and the number of
+// songs and groups its connections may return, which also bounds the queries to the database.
+const (
+	maxGraphQLDepth  = 10
+	maxGraphQLLength = 10000
+	maxGraphQLNodes  = 5000
+)
+
+// GraphQL parses the GraphQL schema and returns the handler of the GraphQL endpoint.
+func (h *Handler) GraphQL() (http.Handler, error) {
+	schema, err := graphql.ParseSchema(graphqlSchema, &graphqlResolver{h: h},
+		graphql.MaxDepth(maxGraphQLDepth), graphql.MaxQueryLength(maxGraphQLLength))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing GraphQL schema: %v", err)
+	}
+
+	return &graphqlHandler{repo: h.Repo, schema: schema}, nil
+}
+
+// graphqlHandler runs GraphQL requests against the schema.
+type graphqlHandler struct {
+	repo   *connection.Repository
+	schema *graphql.Schema
+}
+
+// ServeHTTP godoc
+// @Summary Query the library with GraphQL
+// @Description Runs a GraphQL query or mutation over groups, songs, their details, stats, credits and lyrics.
+// @Description Lists are cursor connections: pass the endCursor of a page as after to get the next one.
+// @Description Mutations need the same permissions as the REST routes they mirror. The schema is in
+// @Description handlers/schema.graphql and can be introspected.
+// @Tags graphql
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param query body models.GraphQLRequest true "GraphQL request"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} JSON
+// @Router /api/graphql [post]
+// ServeHTTP handles a GraphQL request.
+func (g *graphqlHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var request models.GraphQLRequest
+
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		respondJSONError(w, http.StatusBadRequest, fmt.Sprintf("failed to decode GraphQL request: %v", err))
+		return
+	}
+
+	ctx := withLoaders(r.Context(), g.repo)
+	response := g.schema.Exec(ctx, request.Query, request.OperationName, request.Variables)
+
+	RespondJSON(w, http.StatusOK, response)
+}
+
+// loaders batch the lookups of the resolvers of one GraphQL request.
+type loaders struct {
+	repo   *connection.Repository
+	userID int
+
+	groups  *loader[models.Group]
+	details *loader[models.SongDetails]
+	credits *loader[[]models.SongCredit]
+	aliases *loader[[]string]
+
+	mu sync.Mutex
+	// groupIDs are the groups resolved so far, whose songs are fetched together.
+	groupIDs []int
+	// groupSongs load the pages of the songs of groups, by limit and offset.
+	groupSongs map[[2]int]*loader[[]models.Song]
+	// nodes is the number of songs and groups the connections may still return.
+	nodes int
+}
+
+type loadersKey struct{}
+
+// withLoaders returns a context with new loaders for a request.
+func withLoaders(ctx context.Context, repo *connection.Repository) context.Context {
+	return context.WithValue(ctx, loadersKey{}, &loaders{
+		repo:   repo,
+		userID: contextUserID(ctx),
+		groups: newLoader(repo.GroupsByIDs),
+		details: newLoader(func(ids []int) (map[int]models.SongDetails, error) {
+			songs, err := repo.DetailedSongsByIDs(ids)
+			if err != nil {
+				return nil, err
+			}
+			details := make(map[int]models.SongDetails, len(songs))
+			for _, song := range songs {
+				details[song.Song.ID] = song.SongDetails
+			}
+			return details, nil
+		}),
+		credits:    newLoader(repo.SongCredits),
+		aliases:    newLoader(repo.GroupAliasNames),
+		groupSongs: make(map[[2]int]*loader[[]models.Song]),
+		nodes:      maxGraphQLNodes,
+	})
+}
+
+func loadersFrom(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
+
+// wantSongs queues the groups, details and credits of songs, to be fetched together when the first of
+// them is resolved.
+func (l *loaders) wantSongs(songs []models.Song) {
+	groupIDs := make([]int, len(songs))
+	for i, song := range songs {
+		groupIDs[i] = song.GroupID
+		l.details.want(song.ID)
+		l.credits.want(song.ID)
+	}
+	l.wantGroups(groupIDs...)
+}
+
+// wantGroups queues groups, their aliases and their songs, to be fetched together when the first of
+// them is resolved.
+func (l *loaders) wantGroups(ids ...int) {
+	l.groups.want(ids...)
+	l.aliases.want(ids...)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.groupIDs = append(l.groupIDs, ids...)
+	for _, songs := range l.groupSongs {
+		songs.want(ids...)
+	}
+}
+
+// songsOf returns the loader of a page of the songs of groups. Pages are fetched for all the wanted
+// groups with one query.
+func (l *loaders) songsOf(limit, offset int) *loader[[]models.Song] {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	page := [2]int{limit, offset}
+	songs, ok := l.groupSongs[page]
+	if !ok {
+		songs = newLoader(func(ids []int) (map[int][]models.Song, error) {
+			return l.repo.GroupSongLists(ids, l.userID, limit, offset)
+		})
+		songs.want(l.groupIDs...)
+		l.groupSongs[page] = songs
+	}
+	return songs
+}
+
+// charge takes the nodes a connection may return from the budget of the request.
+func (l *loaders) charge(nodes int) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if nodes > l.nodes {
+		return &graphqlError{status: http.StatusBadRequest, err: fmt.Errorf("query too complex: its connections may return more than %d songs and groups", maxGraphQLNodes)}
+	}
+	l.nodes -= nodes
+	return nil
+}
+
+// loader is a dataloader by ID: the IDs wanted by a page of results are fetched with a single call of
+// fetch on the first load of any of them, and the values are kept for the rest of the request.
+type loader[V any] struct {
+	fetch func(ids []int) (map[int]V, error)
+
+	mu      sync.Mutex
+	wanted  []int
+	fetched map[int]bool
+	values  map[int]V
+}
+
+func newLoader[V any](fetch func(ids []int) (map[int]V, error)) *loader[V] {
+	return &loader[V]{fetch: fetch, fetched: make(map[int]bool), values: make(map[int]V)}
+}
+
+// want queues ids to be fetched with the next load.
+func (l *loader[V]) want(ids ...int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.wanted = append(l.wanted, ids...)
+}
+
+// load returns the value of an ID, or false when there is none, fetching it along with the wanted IDs.
+func (l *loader[V]) load(id int) (V, bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.fetched[id] {
+		ids := []int{id}
+		queued := map[int]bool{id: true}
+		for _, wanted := range l.wanted {
+			if !l.fetched[wanted] && !queued[wanted] {
+				queued[wanted] = true
+				ids = append(ids, wanted)
+			}
+		}
+
+		values, err := l.fetch(ids)
+		if err != nil {
+			var zero V
+			return zero, false, err
+		}
+		l.wanted = nil
+		for _, id := range ids {
+			l.fetched[id] = true
+			if value, ok := values[id]; ok {
+				l.values[id] = value
+			}
+		}
+	}
+
+	value, ok := l.values[id]
+	return value, ok, nil
+}
+
+// graphqlError is an error of a resolver. Its code in the extensions of the response stands for the
+// status the REST API responds with.
+type graphqlError struct {
+	status int
+	err    error
+	// songID is the existing song of a conflict.
+	songID int
+}
+
+func (e *graphqlError) Error() string {
+	return e.err.Error()
+}
+
+// Extensions adds the code of the error to the GraphQL response.
+func (e *graphqlError) Extensions() map[string]interface{} {
+	codes := map[int]string{
+		http.StatusBadRequest:   "BAD_USER_INPUT",
+		http.StatusUnauthorized: "UNAUTHENTICATED",
+		http.StatusForbidden:    "FORBIDDEN",
+		http.StatusNotFound:     "NOT_FOUND",
+		http.StatusConflict:     "CONFLICT",
+	}
+
+	code, ok := codes[e.status]
+	if !ok {
+		code = "INTERNAL_SERVER_ERROR"
+	}
+
+	extensions := map[string]interface{}{"code": code}
+	if e.songID != 0 {
+		extensions["songId"] = fmt.Sprint(e.songID)
+	}
+	return extensions
+}
+
+// requirePermission checks that the user of a context has a permission, like RequirePermission.
+func requirePermission(ctx context.Context, permission auth.Permission) error {
+	status, err := checkPermission(ctx, permission)
+	if err != nil {
+		return &graphqlError{status: status, err: err}
+	}
+	return nil
+}
@@ -0,0 +1,597 @@
+package handlers
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/graph-gophers/graphql-go"
+	"github.com/noctusha/music/auth"
+	"github.com/noctusha/music/models"
+)
+
+// maxPageSize is the largest page of a GraphQL connection or a gRPC list.
+const maxPageSize = 100
+
+// graphqlResolver resolves the queries and mutations of the GraphQL schema.
+type graphqlResolver struct {
+	h *Handler
+}
+
+type pageArgs struct {
+	First int32
+	After *string
+}
+
+// page returns the limit and offset of a page of a connection.
+func (args pageArgs) page() (int, int, error) {
+	after := ""
+	if args.After != nil {
+		after = *args.After
+	}
+
+	limit, offset, err := parsePage(int(args.First), after)
+	if err != nil {
+		return 0, 0, &graphqlError{status: http.StatusBadRequest, err: err}
+	}
+	return limit, offset, nil
+}
+
+// parsePage reads the size of a page of 1 to maxPageSize items, and the cursor of the item it
+// follows, "" for the first page. It returns the limit and offset of the page.
+func parsePage(size int, after string) (int, int, error) {
+	if size < 1 || size > maxPageSize {
+		return 0, 0, fmt.Errorf("invalid page size: %d, expected 1 to %d", size, maxPageSize)
+	}
+	if after == "" {
+		return size, 0, nil
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(after)
+	value, found := strings.CutPrefix(string(decoded), "offset:")
+	position, atoiErr := strconv.Atoi(value)
+	if err != nil || !found || atoiErr != nil || position < 0 {
+		return 0, 0, fmt.Errorf("invalid cursor: %v", after)
+	}
+	return size, position + 1, nil
+}
+
+// cursor is the opaque cursor of the item at a position of a connection.
+func cursor(position int) string {
+	return base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("offset:%d", position)))
+}
+
+// parseID reads the ID of a song or a group.
+func parseID(id graphql.ID) (int, error) {
+	value, err := strconv.Atoi(string(id))
+	if err != nil || value <= 0 {
+		return 0, &graphqlError{status: http.StatusBadRequest, err: fmt.Errorf("invalid id: %v", id)}
+	}
+	return value, nil
+}
+
+func (q *graphqlResolver) Song(ctx context.Context, args struct{ ID graphql.ID }) (*songResolver, error) {
+	songID, err := parseID(args.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	song, err := q.h.Repo.GetSongByID(strconv.Itoa(songID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve song: %v", err)
+	}
+	if song == nil {
+		return nil, nil
+	}
+
+	return &songResolver{h: q.h, song: *song}, nil
+}
+
+type songFilterInput struct {
+	Group           *string
+	Name            *string
+	ReleaseDate     *string
+	Text            *string
+	MinRating       *float64
+	ExcludeExplicit *bool
+	Tags            *[]string
+	Moods           *[]string
+	Genre           *string
+	Sort            *string
+}
+
+func (q *graphqlResolver) Songs(ctx context.Context, args struct {
+	pageArgs
+	Filter *songFilterInput
+}) (*songConnectionResolver, error) {
+	filter := models.SongFilter{UserID: contextUserID(ctx)}
+
+	if input := args.Filter; input != nil {
+		if input.Group != nil {
+			filter.Group = *input.Group
+		}
+		if input.Name != nil {
+			filter.Name = *input.Name
+		}
+		if input.ReleaseDate != nil {
+			filter.ReleaseDate = *input.ReleaseDate
+		}
+		if input.Text != nil {
+			filter.Text = *input.Text
+		}
+		if input.Genre != nil {
+			filter.Genre = *input.Genre
+		}
+		if input.MinRating != nil {
+			if *input.MinRating < 1 || *input.MinRating > 5 {
+				return nil, &graphqlError{status: http.StatusBadRequest, err: fmt.Errorf("invalid minRating: %v, expected a number from 1 to 5", *input.MinRating)}
+			}
+			filter.MinRating = *input.MinRating
+		}
+		if input.ExcludeExplicit != nil {
+			filter.ExcludeExplicit = *input.ExcludeExplicit
+		}
+		if input.Tags != nil {
+			tags, err := normalizeLabels(*input.Tags)
+			if err != nil {
+				return nil, &graphqlError{status: http.StatusBadRequest, err: err}
+			}
+			filter.Tags = tags
+		}
+		if input.Moods != nil {
+			moods, err := normalizeLabels(*input.Moods)
+			if err != nil {
+				return nil, &graphqlError{status: http.StatusBadRequest, err: err}
+			}
+			filter.Moods = moods
+		}
+		if input.Sort != nil {
+			filter.Sort = strings.ToLower(*input.Sort)
+		}
+	}
+
+	return q.h.songConnection(ctx, filter, args.pageArgs)
+}
+
+func (q *graphqlResolver) Group(ctx context.Context, args struct{ ID graphql.ID }) (*groupResolver, error) {
+	groupID, err := parseID(args.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	group, err := q.h.groupByID(groupID)
+	if err != nil || group == nil {
+		return nil, err
+	}
+	loadersFrom(ctx).wantGroups(group.ID)
+
+	return &groupResolver{h: q.h, group: *group}, nil
+}
+
+func (q *graphqlResolver) Groups(ctx context.Context, args pageArgs) (*groupConnectionResolver, error) {
+	limit, offset, err := args.page()
+	if err != nil {
+		return nil, err
+	}
+
+	err = loadersFrom(ctx).charge(limit)
+	if err != nil {
+		return nil, err
+	}
+
+	groups, err := q.h.Repo.GroupList(limit+1, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select groups from database: %v", err)
+	}
+
+	connection := &groupConnectionResolver{pageInfo: pageInfoResolver{hasPrevious: offset > 0, hasNext: len(groups) > limit}}
+	if len(groups) > limit {
+		groups = groups[:limit]
+	}
+
+	for i, group := range groups {
+		loadersFrom(ctx).wantGroups(group.ID)
+		connection.edges = append(connection.edges, &groupEdgeResolver{cursor: cursor(offset + i), node: &groupResolver{h: q.h, group: group}})
+	}
+	connection.pageInfo.setCursors(offset, len(groups))
+
+	return connection, nil
+}
+
+func (q *graphqlResolver) CreateSong(ctx context.Context, args struct{ Group, Name string }) (*songResolver, error) {
+	group, name := strings.TrimSpace(args.Group), strings.TrimSpace(args.Name)
+	if group == "" || name == "" {
+		return nil, &graphqlError{status: http.StatusBadRequest, err: fmt.Errorf("no group or song name")}
+	}
+
+	err := requirePermission(ctx, auth.PermSongsCreate)
+	if err != nil {
+		return nil, err
+	}
+
+	song, status, err := q.h.createSong(ctx, group, name)
+	if status == http.StatusConflict {
+		return nil, &graphqlError{status: status, err: err, songID: song.ID}
+	}
+	if err != nil {
+		return nil, &graphqlError{status: status, err: err}
+	}
+
+	return &songResolver{h: q.h, song: *song}, nil
+}
+
+type songInput struct {
+	Name        *string
+	GroupID     *graphql.ID
+	ReleaseDate *string
+	Text        *string
+	Link        *string
+	Album       *string
+}
+
+func (q *graphqlResolver) UpdateSong(ctx context.Context, args struct {
+	ID    graphql.ID
+	Input songInput
+}) (*songResolver, error) {
+	songID, err := parseID(args.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	payload := models.SongPayload{Name: args.Input.Name, ReleaseDate: args.Input.ReleaseDate, Text: args.Input.Text, Link: args.Input.Link, Album: args.Input.Album}
+	if args.Input.GroupID != nil {
+		groupID, err := parseID(*args.Input.GroupID)
+		if err != nil {
+			return nil, err
+		}
+		payload.GroupID = &groupID
+	}
+	err = validateSongPayload(&payload)
+	if err != nil {
+		return nil, &graphqlError{status: http.StatusBadRequest, err: err}
+	}
+
+	err = requirePermission(ctx, auth.PermSongsEdit)
+	if err != nil {
+		return nil, err
+	}
+
+	song, _, status, err := q.h.editSong(ctx, songID, func(song *models.Song, songDetails *models.SongDetails) {
+		applySongPayload(song, songDetails, payload)
+	})
+	if err != nil {
+		return nil, &graphqlError{status: status, err: err}
+	}
+
+	return &songResolver{h: q.h, song: *song}, nil
+}
+
+func (q *graphqlResolver) DeleteSong(ctx context.Context, args struct{ ID graphql.ID }) (graphql.ID, error) {
+	songID, err := parseID(args.ID)
+	if err != nil {
+		return "", err
+	}
+
+	err = requirePermission(ctx, auth.PermSongsDelete)
+	if err != nil {
+		return "", err
+	}
+
+	status, err := q.h.deleteSong(songID)
+	if err != nil {
+		return "", &graphqlError{status: status, err: err}
+	}
+
+	return args.ID, nil
+}
+
+// songConnection resolves a page of the songs of a filter.
+func (h *Handler) songConnection(ctx context.Context, filter models.SongFilter, args pageArgs) (*songConnectionResolver, error) {
+	limit, offset, err := args.page()
+	if err != nil {
+		return nil, err
+	}
+
+	err = loadersFrom(ctx).charge(limit)
+	if err != nil {
+		return nil, err
+	}
+
+	filter.Limit, filter.Offset = limit+1, offset
+	songs, err := h.Repo.SongList(filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select songs from database: %v", err)
+	}
+
+	return h.newSongConnection(ctx, songs, limit, offset), nil
+}
+
+// newSongConnection resolves a page of songs fetched with one more song than its limit, which tells
+// whether there is a next page.
+func (h *Handler) newSongConnection(ctx context.Context, songs []models.Song, limit, offset int) *songConnectionResolver {
+	connection := &songConnectionResolver{pageInfo: pageInfoResolver{hasPrevious: offset > 0, hasNext: len(songs) > limit}}
+	if len(songs) > limit {
+		songs = songs[:limit]
+	}
+
+	loadersFrom(ctx).wantSongs(songs)
+	for i, song := range songs {
+		connection.edges = append(connection.edges, &songEdgeResolver{cursor: cursor(offset + i), node: &songResolver{h: h, song: song}})
+	}
+	connection.pageInfo.setCursors(offset, len(songs))
+
+	return connection
+}
+
+type songConnectionResolver struct {
+	edges    []*songEdgeResolver
+	pageInfo pageInfoResolver
+}
+
+func (c *songConnectionResolver) Edges() []*songEdgeResolver { return c.edges }
+
+func (c *songConnectionResolver) PageInfo() *pageInfoResolver { return &c.pageInfo }
+
+type songEdgeResolver struct {
+	cursor string
+	node   *songResolver
+}
+
+func (e *songEdgeResolver) Cursor() string { return e.cursor }
+
+func (e *songEdgeResolver) Node() *songResolver { return e.node }
+
+type groupConnectionResolver struct {
+	edges    []*groupEdgeResolver
+	pageInfo pageInfoResolver
+}
+
+func (c *groupConnectionResolver) Edges() []*groupEdgeResolver { return c.edges }
+
+func (c *groupConnectionResolver) PageInfo() *pageInfoResolver { return &c.pageInfo }
+
+type groupEdgeResolver struct {
+	cursor string
+	node   *groupResolver
+}
+
+func (e *groupEdgeResolver) Cursor() string { return e.cursor }
+
+func (e *groupEdgeResolver) Node() *groupResolver { return e.node }
+
+type pageInfoResolver struct {
+	hasNext, hasPrevious bool
+	start, end           *string
+}
+
+// setCursors sets the cursors of the first and the last of the items of a page.
+func (p *pageInfoResolver) setCursors(offset, items int) {
+	if items == 0 {
+		return
+	}
+	start, end := cursor(offset), cursor(offset+items-1)
+	p.start, p.end = &start, &end
+}
+
+func (p *pageInfoResolver) HasNextPage() bool { return p.hasNext }
+
+func (p *pageInfoResolver) HasPreviousPage() bool { return p.hasPrevious }
+
+func (p *pageInfoResolver) StartCursor() *string { return p.start }
+
+func (p *pageInfoResolver) EndCursor() *string { return p.end }
+
+type groupResolver struct {
+	h     *Handler
+	group models.Group
+}
+
+func (g *groupResolver) ID() graphql.ID { return graphql.ID(strconv.Itoa(g.group.ID)) }
+
+func (g *groupResolver) Name() string { return g.group.Name }
+
+func (g *groupResolver) Aliases(ctx context.Context) ([]string, error) {
+	aliases, _, err := loadersFrom(ctx).aliases.load(g.group.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve aliases: %v", err)
+	}
+	return append([]string{}, aliases...), nil
+}
+
+// Songs resolves a page of the songs of the group by name. The pages of all the groups of the request
+// are fetched together.
+func (g *groupResolver) Songs(ctx context.Context, args pageArgs) (*songConnectionResolver, error) {
+	limit, offset, err := args.page()
+	if err != nil {
+		return nil, err
+	}
+
+	l := loadersFrom(ctx)
+	err = l.charge(limit)
+	if err != nil {
+		return nil, err
+	}
+
+	songs, _, err := l.songsOf(limit+1, offset).load(g.group.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select songs from database: %v", err)
+	}
+
+	return g.h.newSongConnection(ctx, songs, limit, offset), nil
+}
+
+type songResolver struct {
+	h    *Handler
+	song models.Song
+}
+
+func (s *songResolver) ID() graphql.ID { return graphql.ID(strconv.Itoa(s.song.ID)) }
+
+func (s *songResolver) Name() string { return s.song.Name }
+
+func (s *songResolver) Explicit() bool { return s.song.Explicit }
+
+func (s *songResolver) Group(ctx context.Context) (*groupResolver, error) {
+	group, ok, err := loadersFrom(ctx).groups.load(s.song.GroupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve group: %v", err)
+	}
+	if !ok {
+		return nil, &graphqlError{status: http.StatusNotFound, err: fmt.Errorf("no such group with group_id: %v", s.song.GroupID)}
+	}
+	return &groupResolver{h: s.h, group: group}, nil
+}
+
+// details loads the details of the song; a song without details has them all unknown.
+func (s *songResolver) details(ctx context.Context) (models.SongDetails, error) {
+	details, _, err := loadersFrom(ctx).details.load(s.song.ID)
+	if err != nil {
+		return details, fmt.Errorf("failed to retrieve song details: %v", err)
+	}
+	return details, nil
+}
+
+func (s *songResolver) Details(ctx context.Context) (*songDetailsResolver, error) {
+	details, err := s.details(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &songDetailsResolver{details: details}, nil
+}
+
+func (s *songResolver) Stats(ctx context.Context) (*songStatsResolver, error) {
+	stats := s.song.Stats
+	if stats == nil {
+		var err error
+		stats, err = s.h.Repo.SongStats(s.song.ID, contextUserID(ctx))
+		if err != nil {
+			return nil, fmt.Errorf("failed to retrieve song stats: %v", err)
+		}
+		if stats == nil {
+			return nil, &graphqlError{status: http.StatusNotFound, err: fmt.Errorf("no such song with song_id: %v", s.song.ID)}
+		}
+	}
+	return &songStatsResolver{stats: *stats}, nil
+}
+
+func (s *songResolver) Credits(ctx context.Context) ([]*creditResolver, error) {
+	credits, _, err := loadersFrom(ctx).credits.load(s.song.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve credits: %v", err)
+	}
+
+	resolvers := make([]*creditResolver, len(credits))
+	for i, credit := range credits {
+		resolvers[i] = &creditResolver{credit: credit}
+	}
+	return resolvers, nil
+}
+
+func (s *songResolver) Lyrics(ctx context.Context, args struct{ Censored bool }) (*lyricsResolver, error) {
+	details, err := s.details(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !knownDetail(details.Text) {
+		return nil, nil
+	}
+
+	text := details.Text
+	if args.Censored {
+		if s.h.Repo.Explicit == nil {
+			return nil, errNoWordLists
+		}
+		text = s.h.Repo.Explicit.Censor(text)
+	}
+	return &lyricsResolver{text: text}, nil
+}
+
+type songDetailsResolver struct {
+	details models.SongDetails
+}
+
+// known returns a detail, or nil when it is unknown.
+func known(value string) *string {
+	if !knownDetail(value) {
+		return nil
+	}
+	return &value
+}
+
+func (d *songDetailsResolver) ReleaseDate() *string {
+	if !knownDate(d.details.ReleaseDate) {
+		return nil
+	}
+	// Dates are scanned as RFC 3339 timestamps.
+	date, _, _ := strings.Cut(d.details.ReleaseDate, "T")
+	return &date
+}
+
+func (d *songDetailsResolver) Album() *string { return known(d.details.Album) }
+
+func (d *songDetailsResolver) Link() *string { return known(d.details.Link) }
+
+type songStatsResolver struct {
+	stats models.SongStats
+}
+
+func (s *songStatsResolver) AverageRating() float64 { return s.stats.AverageRating }
+
+func (s *songStatsResolver) Ratings() int32 { return int32(s.stats.Ratings) }
+
+func (s *songStatsResolver) Plays() int32 { return int32(s.stats.Plays) }
+
+func (s *songStatsResolver) Favorites() int32 { return int32(s.stats.Favorites) }
+
+func (s *songStatsResolver) Favorite() bool { return s.stats.Favorite }
+
+func (s *songStatsResolver) Rating() *int32 {
+	if s.stats.Rating == 0 {
+		return nil
+	}
+	rating := int32(s.stats.Rating)
+	return &rating
+}
+
+type creditResolver struct {
+	credit models.SongCredit
+}
+
+func (c *creditResolver) Name() string { return c.credit.Name }
+
+func (c *creditResolver) Role() string { return c.credit.Role }
+
+type lyricsResolver struct {
+	text string
+}
+
+func (l *lyricsResolver) Text() string { return l.text }
+
+// Sections splits the lyrics into verses, like GetText.
+func (l *lyricsResolver) Sections() []*lyricsSectionResolver {
+	verses := strings.Split(l.text, "\n\n")
+
+	sections := make([]*lyricsSectionResolver, len(verses))
+	for i, verse := range verses {
+		sections[i] = &lyricsSectionResolver{index: i, text: verse}
+	}
+	return sections
+}
+
+func (l *lyricsResolver) FirstVerse() *lyricsSectionResolver {
+	return l.Sections()[0]
+}
+
+type lyricsSectionResolver struct {
+	index int
+	text  string
+}
+
+func (s *lyricsSectionResolver) Index() int32 { return int32(s.index) }
+
+func (s *lyricsSectionResolver) Text() string { return s.text }
+
+func (s *lyricsSectionResolver) Lines() []string { return strings.Split(s.text, "\n") }
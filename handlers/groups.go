@@ -0,0 +1,387 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/noctusha/music/auth"
+	"github.com/noctusha/music/connection"
+	"github.com/noctusha/music/models"
+	"github.com/noctusha/music/playlist"
+)
+
+// CreateGroup godoc
+// @Summary Add a group
+// @Description Adds a group without songs; the editor who adds it becomes its first maintainer. The name may not
+// @Description be used by another group or alias, regardless of case.
+// @Tags groups
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param group body models.GroupPayload true "Group"
+// @Success 201 {object} models.Group
+// @Failure 400 {object} JSON
+// @Failure 401 {object} JSON
+// @Failure 403 {object} JSON
+// @Failure 409 {object} JSON
+// @Failure 500 {object} JSON
+// @Router /api/groups [post]
+// CreateGroup handles the request to add a group.
+func (h *Handler) CreateGroup(w http.ResponseWriter, r *http.Request) {
+	var payload models.GroupPayload
+
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		respondJSONError(w, http.StatusBadRequest, fmt.Sprintf("failed to decode group: %v", err))
+		return
+	}
+
+	group, status, err := h.createGroup(r.Context(), payload.Name)
+	if err != nil {
+		respondJSONError(w, status, err.Error())
+		return
+	}
+
+	RespondJSON(w, http.StatusCreated, group)
+}
+
+// RenameGroup godoc
+// @Summary Rename a group
+// @Description Renames a group. The name may not be the name or an alias of another group, regardless of case.
+// @Description Editors can only rename the groups they maintain.
+// @Tags groups
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param group_id path int true "Group ID"
+// @Param group body models.GroupPayload true "Group"
+// @Success 200 {object} models.Group
+// @Failure 400 {object} JSON
+// @Failure 401 {object} JSON
+// @Failure 403 {object} JSON
+// @Failure 404 {object} JSON
+// @Failure 409 {object} JSON
+// @Failure 500 {object} JSON
+// @Router /api/groups/{group_id} [patch]
+// RenameGroup handles the request to rename a group.
+func (h *Handler) RenameGroup(w http.ResponseWriter, r *http.Request) {
+	group, ok := h.pathGroup(w, r)
+	if !ok {
+		return
+	}
+
+	var payload models.GroupPayload
+
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		respondJSONError(w, http.StatusBadRequest, fmt.Sprintf("failed to decode group: %v", err))
+		return
+	}
+
+	renamed, status, err := h.renameGroup(r.Context(), group.ID, payload.Name)
+	if err != nil {
+		respondJSONError(w, status, err.Error())
+		return
+	}
+
+	RespondJSON(w, http.StatusOK, renamed)
+}
+
+// ListGroupAliases godoc
+// @Summary Get group aliases
+// @Description Returns the other names of a group. New songs and imports naming an alias are added to the group.
+// @Tags groups
+// @Produce json
+// @Param group_id path int true "Group ID"
+// @Success 200 {array} models.GroupAlias
+// @Failure 400 {object} JSON
+// @Failure 404 {object} JSON
+// @Failure 500 {object} JSON
+// @Router /api/groups/{group_id}/aliases [get]
+// ListGroupAliases handles the request to list the aliases of a group.
+func (h *Handler) ListGroupAliases(w http.ResponseWriter, r *http.Request) {
+	group, ok := h.pathGroup(w, r)
+	if !ok {
+		return
+	}
+
+	h.respondGroupAliases(w, group.ID)
+}
+
+// AddGroupAlias godoc
+// @Summary Add a group alias
+// @Description Adds another name of a group. The name may not be used by another group or alias, regardless of case.
+// @Description Editors can only add aliases to the groups they maintain.
+// @Tags groups
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param group_id path int true "Group ID"
+// @Param alias body models.AliasPayload true "Alias"
+// @Success 200 {array} models.GroupAlias
+// @Failure 400 {object} JSON
+// @Failure 401 {object} JSON
+// @Failure 403 {object} JSON
+// @Failure 404 {object} JSON
+// @Failure 409 {object} JSON
+// @Failure 500 {object} JSON
+// @Router /api/groups/{group_id}/aliases [post]
+// AddGroupAlias handles the request to add an alias to a group.
+func (h *Handler) AddGroupAlias(w http.ResponseWriter, r *http.Request) {
+	group, ok := h.pathGroup(w, r)
+	if !ok {
+		return
+	}
+
+	var payload models.AliasPayload
+
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		respondJSONError(w, http.StatusBadRequest, fmt.Sprintf("failed to decode alias: %v", err))
+		return
+	}
+
+	payload.Name = strings.TrimSpace(payload.Name)
+	if payload.Name == "" {
+		respondJSONError(w, http.StatusBadRequest, "no alias name")
+		return
+	}
+	if utf8.RuneCountInString(payload.Name) > 255 {
+		respondJSONError(w, http.StatusBadRequest, "alias is longer than 255 characters")
+		return
+	}
+
+	if !h.requireGroup(w, r, group.ID, auth.PermSongsEdit) {
+		return
+	}
+
+	_, err = h.Repo.AddGroupAlias(group.ID, payload.Name)
+	if err != nil {
+		respondGroupError(w, err)
+		return
+	}
+	h.syncAutocomplete(group.ID)
+
+	h.respondGroupAliases(w, group.ID)
+}
+
+// DeleteGroupAlias godoc
+// @Summary Delete a group alias
+// @Description Deletes another name of a group. Editors can only delete the aliases of the groups they maintain.
+// @Tags groups
+// @Produce json
+// @Security BearerAuth
+// @Param group_id path int true "Group ID"
+// @Param alias_id path int true "Alias ID"
+// @Success 200 {array} models.GroupAlias
+// @Failure 400 {object} JSON
+// @Failure 401 {object} JSON
+// @Failure 403 {object} JSON
+// @Failure 404 {object} JSON
+// @Failure 500 {object} JSON
+// @Router /api/groups/{group_id}/aliases/{alias_id} [delete]
+// DeleteGroupAlias handles the request to delete an alias of a group.
+func (h *Handler) DeleteGroupAlias(w http.ResponseWriter, r *http.Request) {
+	group, ok := h.pathGroup(w, r)
+	if !ok {
+		return
+	}
+
+	aliasID, err := pathID(r, "alias_id")
+	if err != nil {
+		respondJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if !h.requireGroup(w, r, group.ID, auth.PermSongsEdit) {
+		return
+	}
+
+	err = h.Repo.DeleteGroupAlias(group.ID, aliasID)
+	if err != nil {
+		respondGroupError(w, err)
+		return
+	}
+	h.syncAutocomplete(group.ID)
+
+	h.respondGroupAliases(w, group.ID)
+}
+
+// ListDuplicateGroups godoc
+// @Summary Find duplicate groups
+// @Description Returns the pairs of groups whose names are similar after normalization: case, punctuation,
+// @Description bracketed remarks, guest artists and a leading "the" are ignored. The group with more songs comes first.
+// @Tags groups
+// @Produce json
+// @Security BearerAuth
+// @Param threshold query number false "Minimal similarity of the names, from 0.5 to 1, 0.85 by default"
+// @Success 200 {array} models.GroupDuplicate
+// @Failure 400 {object} JSON
+// @Failure 401 {object} JSON
+// @Failure 403 {object} JSON
+// @Failure 500 {object} JSON
+// @Router /api/groups/duplicates [get]
+// ListDuplicateGroups handles the request to find groups that are likely the same.
+func (h *Handler) ListDuplicateGroups(w http.ResponseWriter, r *http.Request) {
+	threshold := 0.85
+	if value := r.URL.Query().Get("threshold"); value != "" {
+		var err error
+		threshold, err = strconv.ParseFloat(value, 64)
+		if err != nil || threshold < 0.5 || threshold > 1 {
+			respondJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid threshold: %v, expected 0.5 to 1", value))
+			return
+		}
+	}
+
+	groups, err := h.Repo.GroupSummaries()
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to select groups from database: %v", err))
+		return
+	}
+
+	RespondJSON(w, http.StatusOK, groupDuplicates(groups, threshold))
+}
+
+// MergeGroups godoc
+// @Summary Merge groups
+// @Description Moves the songs of the given groups into this group and deletes them. Their names become
+// @Description aliases of this group, and their ids redirect to it with 308 Permanent Redirect. Their maintainers,
+// @Description genres, tags and aliases move along.
+// @Tags groups
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param group_id path int true "ID of the group to merge into"
+// @Param groups body models.MergeGroupsPayload true "Groups to merge"
+// @Success 200 {object} models.GroupMerge
+// @Failure 400 {object} JSON
+// @Failure 401 {object} JSON
+// @Failure 403 {object} JSON
+// @Failure 404 {object} JSON
+// @Failure 500 {object} JSON
+// @Router /api/groups/{group_id}/merge [post]
+// MergeGroups handles the request to merge groups into another one.
+func (h *Handler) MergeGroups(w http.ResponseWriter, r *http.Request) {
+	group, ok := h.pathGroup(w, r)
+	if !ok {
+		return
+	}
+
+	var payload models.MergeGroupsPayload
+
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		respondJSONError(w, http.StatusBadRequest, fmt.Sprintf("failed to decode groups: %v", err))
+		return
+	}
+
+	merged := []int{}
+	for _, id := range payload.GroupIDs {
+		if !slices.Contains(merged, id) {
+			merged = append(merged, id)
+		}
+	}
+	if len(merged) == 0 {
+		respondJSONError(w, http.StatusBadRequest, "no groups to merge")
+		return
+	}
+
+	moved, err := h.Repo.MergeGroups(group.ID, merged)
+	if err != nil {
+		respondGroupError(w, err)
+		return
+	}
+	h.syncAutocomplete(append([]int{group.ID}, merged...)...)
+	h.syncSearch()
+
+	aliases, err := h.Repo.GroupAliases(group.ID)
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to select aliases from database: %v", err))
+		return
+	}
+
+	RespondJSON(w, http.StatusOK, models.GroupMerge{Group: *group, Merged: merged, SongsMoved: moved, Aliases: aliases})
+}
+
+func (h *Handler) respondGroupAliases(w http.ResponseWriter, groupID int) {
+	aliases, err := h.Repo.GroupAliases(groupID)
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to select aliases from database: %v", err))
+		return
+	}
+
+	RespondJSON(w, http.StatusOK, aliases)
+}
+
+// groupDuplicates pairs the groups whose normalized names are at least threshold similar, the most similar first.
+func groupDuplicates(groups []models.GroupSummary, threshold float64) []models.GroupDuplicate {
+	type normalized struct {
+		group  models.GroupSummary
+		name   string
+		length int
+	}
+
+	names := make([]normalized, 0, len(groups))
+	for _, group := range groups {
+		name := playlist.Normalize(group.Name)
+		if name != "" {
+			names = append(names, normalized{group: group, name: name, length: utf8.RuneCountInString(name)})
+		}
+	}
+	sort.SliceStable(names, func(i, j int) bool { return names[i].length < names[j].length })
+
+	duplicates := []models.GroupDuplicate{}
+	for i, a := range names {
+		for _, b := range names[i+1:] {
+			// Names differing in length by more than the threshold allows cannot be similar enough.
+			if float64(a.length) < threshold*float64(b.length) {
+				break
+			}
+
+			score := playlist.Similarity(a.name, b.name)
+			if score < threshold {
+				continue
+			}
+
+			keep, duplicate := a.group, b.group
+			if duplicate.Songs > keep.Songs || duplicate.Songs == keep.Songs && duplicate.ID < keep.ID {
+				keep, duplicate = duplicate, keep
+			}
+			duplicates = append(duplicates, models.GroupDuplicate{Group: keep, Duplicate: duplicate, Score: math.Round(score*1000) / 1000})
+		}
+	}
+
+	sort.Slice(duplicates, func(i, j int) bool {
+		if duplicates[i].Score != duplicates[j].Score {
+			return duplicates[i].Score > duplicates[j].Score
+		}
+		if duplicates[i].Group.ID != duplicates[j].Group.ID {
+			return duplicates[i].Group.ID < duplicates[j].Group.ID
+		}
+		return duplicates[i].Duplicate.ID < duplicates[j].Duplicate.ID
+	})
+
+	return duplicates
+}
+
+// respondGroupError maps the group errors of the repository to status codes.
+func respondGroupError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, connection.ErrGroupNotFound), errors.Is(err, connection.ErrAliasNotFound):
+		respondJSONError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, connection.ErrMergeIntoSelf):
+		respondJSONError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, connection.ErrAliasExists):
+		respondJSONError(w, http.StatusConflict, err.Error())
+	default:
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to update groups: %v", err))
+	}
+}
@@ -0,0 +1,454 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/noctusha/music/auth"
+	"github.com/noctusha/music/models"
+	"github.com/noctusha/music/musicpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// Limits of the results streamed by SearchLyrics.
+const (
+	defaultStreamedResults = 100
+	maxStreamedResults     = 1000
+)
+
+// defaultPageSize is the size of a page of a gRPC list when the request does not set it.
+const defaultPageSize = 25
+
+// GRPC returns the gRPC server of the catalogue, with the health and reflection services.
+func (h *Handler) GRPC() *grpc.Server {
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(h.unaryInterceptor),
+		grpc.ChainStreamInterceptor(h.streamInterceptor),
+	)
+
+	musicpb.RegisterMusicServer(server, &grpcServer{h: h})
+
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus(musicpb.Music_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(server, healthServer)
+
+	reflection.Register(server)
+
+	return server
+}
+
+// unaryInterceptor authenticates a call like Authenticate and turns a panic of its handler into an
+// internal error, as net/http does for the HTTP handlers.
+func (h *Handler) unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			log.Printf("panic in %s: %v", info.FullMethod, p)
+			err = status.Error(codes.Internal, "internal error")
+		}
+	}()
+
+	ctx, err = h.authenticateCall(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// streamInterceptor is unaryInterceptor for streaming calls.
+func (h *Handler) streamInterceptor(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			log.Printf("panic in %s: %v", info.FullMethod, p)
+			err = status.Error(codes.Internal, "internal error")
+		}
+	}()
+
+	ctx, err := h.authenticateCall(stream.Context())
+	if err != nil {
+		return err
+	}
+	return handler(srv, &authenticatedStream{ServerStream: stream, ctx: ctx})
+}
+
+// authenticatedStream is a server stream with the principal of its call in its context.
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
+
+// authenticateCall identifies the user of a call from the credentials of its metadata: a bearer token
+// or API key in "authorization", or an API key in "x-api-key". Calls without credentials are anonymous.
+func (h *Handler) authenticateCall(ctx context.Context) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	value := func(key string) string {
+		values := md.Get(key)
+		if len(values) == 0 {
+			return ""
+		}
+		return values[0]
+	}
+
+	credential, err := parseCredential(value(apiKeyHeader), value("authorization"))
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	if credential == "" {
+		return ctx, nil
+	}
+
+	principal, code, err := h.principal(credential)
+	if err != nil {
+		return nil, grpcError(code, err)
+	}
+	return auth.WithPrincipal(ctx, principal), nil
+}
+
+// grpcError is the gRPC status of an error for the HTTP status the REST API responds with.
+func grpcError(statusCode int, err error) error {
+	codesByStatus := map[int]codes.Code{
+		http.StatusBadRequest:   codes.InvalidArgument,
+		http.StatusUnauthorized: codes.Unauthenticated,
+		http.StatusForbidden:    codes.PermissionDenied,
+		http.StatusNotFound:     codes.NotFound,
+		http.StatusConflict:     codes.AlreadyExists,
+	}
+
+	code, ok := codesByStatus[statusCode]
+	if !ok {
+		code = codes.Internal
+	}
+	return status.Error(code, err.Error())
+}
+
+// grpcServer implements the Music gRPC service over the handlers.
+type grpcServer struct {
+	musicpb.UnimplementedMusicServer
+	h *Handler
+}
+
+// listPage reads the page of a list request.
+func listPage(pageSize int32, pageToken string) (int, int, error) {
+	size := int(pageSize)
+	if size == 0 {
+		size = defaultPageSize
+	}
+
+	limit, offset, err := parsePage(size, pageToken)
+	if err != nil {
+		return 0, 0, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return limit, offset, nil
+}
+
+func (s *grpcServer) ListSongs(ctx context.Context, req *musicpb.ListSongsRequest) (*musicpb.ListSongsResponse, error) {
+	limit, offset, err := listPage(req.PageSize, req.PageToken)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := models.SongFilter{
+		Group:           req.Group,
+		GroupID:         int(req.GroupId),
+		Name:            req.Name,
+		ReleaseDate:     req.ReleaseDate,
+		Text:            req.Text,
+		MinRating:       req.MinRating,
+		ExcludeExplicit: req.ExcludeExplicit,
+		Genre:           req.Genre,
+		Limit:           limit + 1,
+		Offset:          offset,
+		UserID:          contextUserID(ctx),
+	}
+
+	if filter.MinRating != 0 && (filter.MinRating < 1 || filter.MinRating > 5) {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid min_rating: %v, expected a number from 1 to 5", filter.MinRating)
+	}
+	if len(req.Tags) > 0 {
+		filter.Tags, err = normalizeLabels(req.Tags)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+	if len(req.Moods) > 0 {
+		filter.Moods, err = normalizeLabels(req.Moods)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+
+	switch req.Sort {
+	case musicpb.SongSort_SONG_SORT_UNSPECIFIED, musicpb.SongSort_SONG_SORT_NAME:
+		filter.Sort = models.SortName
+	case musicpb.SongSort_SONG_SORT_POPULARITY:
+		filter.Sort = models.SortPopularity
+	case musicpb.SongSort_SONG_SORT_RATING:
+		filter.Sort = models.SortRating
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "unknown sort: %v", req.Sort)
+	}
+
+	songs, err := s.h.Repo.DetailedSongList(filter)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to select songs from database: %v", err)
+	}
+
+	response := &musicpb.ListSongsResponse{}
+	if len(songs) > limit {
+		songs = songs[:limit]
+		response.NextPageToken = cursor(offset + limit - 1)
+	}
+	for _, song := range songs {
+		response.Songs = append(response.Songs, songMessage(song))
+	}
+
+	return response, nil
+}
+
+func (s *grpcServer) GetSong(ctx context.Context, req *musicpb.GetSongRequest) (*musicpb.Song, error) {
+	return s.song(int(req.Id))
+}
+
+func (s *grpcServer) CreateSong(ctx context.Context, req *musicpb.CreateSongRequest) (*musicpb.Song, error) {
+	group, name := strings.TrimSpace(req.Group), strings.TrimSpace(req.Name)
+	if group == "" || name == "" {
+		return nil, status.Error(codes.InvalidArgument, "no group or song name")
+	}
+
+	code, err := checkPermission(ctx, auth.PermSongsCreate)
+	if err != nil {
+		return nil, grpcError(code, err)
+	}
+
+	song, code, err := s.h.createSong(ctx, group, name)
+	if code == http.StatusConflict {
+		return nil, status.Errorf(codes.AlreadyExists, "%v: song_id %d", err, song.ID)
+	}
+	if err != nil {
+		return nil, grpcError(code, err)
+	}
+
+	return s.song(song.ID)
+}
+
+func (s *grpcServer) UpdateSong(ctx context.Context, req *musicpb.UpdateSongRequest) (*musicpb.Song, error) {
+	payload := models.SongPayload{Name: req.Name, ReleaseDate: req.ReleaseDate, Text: req.Text, Link: req.Link, Album: req.Album}
+	if req.GroupId != nil {
+		groupID := int(*req.GroupId)
+		payload.GroupID = &groupID
+	}
+	err := validateSongPayload(&payload)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	code, err := checkPermission(ctx, auth.PermSongsEdit)
+	if err != nil {
+		return nil, grpcError(code, err)
+	}
+
+	song, _, code, err := s.h.editSong(ctx, int(req.Id), func(song *models.Song, songDetails *models.SongDetails) {
+		applySongPayload(song, songDetails, payload)
+	})
+	if err != nil {
+		return nil, grpcError(code, err)
+	}
+
+	return s.song(song.ID)
+}
+
+func (s *grpcServer) DeleteSong(ctx context.Context, req *musicpb.DeleteSongRequest) (*emptypb.Empty, error) {
+	code, err := checkPermission(ctx, auth.PermSongsDelete)
+	if err != nil {
+		return nil, grpcError(code, err)
+	}
+
+	code, err = s.h.deleteSong(int(req.Id))
+	if err != nil {
+		return nil, grpcError(code, err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// song retrieves a song with its group and details.
+func (s *grpcServer) song(songID int) (*musicpb.Song, error) {
+	songs, err := s.h.Repo.DetailedSongsByIDs([]int{songID})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to retrieve song: %v", err)
+	}
+	if len(songs) == 0 {
+		return nil, status.Errorf(codes.NotFound, "no such song with song_id: %v", songID)
+	}
+
+	return songMessage(songs[0]), nil
+}
+
+// songMessage converts a song with its group and details; unknown details are empty.
+func songMessage(song models.DetailedSong) *musicpb.Song {
+	details := &musicpb.SongDetails{}
+	if knownDate(song.SongDetails.ReleaseDate) {
+		details.ReleaseDate, _, _ = strings.Cut(song.SongDetails.ReleaseDate, "T")
+	}
+	if knownDetail(song.SongDetails.Text) {
+		details.Text = song.SongDetails.Text
+	}
+	if knownDetail(song.SongDetails.Link) {
+		details.Link = song.SongDetails.Link
+	}
+	if knownDetail(song.SongDetails.Album) {
+		details.Album = song.SongDetails.Album
+	}
+
+	return &musicpb.Song{
+		Id:       int64(song.Song.ID),
+		Name:     song.Song.Name,
+		GroupId:  int64(song.Song.GroupID),
+		Group:    song.Group,
+		Explicit: song.Song.Explicit,
+		Details:  details,
+	}
+}
+
+func (s *grpcServer) ListGroups(ctx context.Context, req *musicpb.ListGroupsRequest) (*musicpb.ListGroupsResponse, error) {
+	limit, offset, err := listPage(req.PageSize, req.PageToken)
+	if err != nil {
+		return nil, err
+	}
+
+	groups, err := s.h.Repo.GroupList(limit+1, offset)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to select groups from database: %v", err)
+	}
+
+	response := &musicpb.ListGroupsResponse{}
+	if len(groups) > limit {
+		groups = groups[:limit]
+		response.NextPageToken = cursor(offset + limit - 1)
+	}
+
+	ids := make([]int, len(groups))
+	for i, group := range groups {
+		ids[i] = group.ID
+	}
+	aliases, err := s.h.Repo.GroupAliasNames(ids)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to retrieve aliases: %v", err)
+	}
+
+	for _, group := range groups {
+		response.Groups = append(response.Groups, &musicpb.Group{Id: int64(group.ID), Name: group.Name, Aliases: aliases[group.ID]})
+	}
+
+	return response, nil
+}
+
+func (s *grpcServer) GetGroup(ctx context.Context, req *musicpb.GetGroupRequest) (*musicpb.Group, error) {
+	group, err := s.h.groupByID(int(req.Id))
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if group == nil {
+		return nil, status.Errorf(codes.NotFound, "no such group with group_id: %v", req.Id)
+	}
+
+	return s.group(*group)
+}
+
+func (s *grpcServer) CreateGroup(ctx context.Context, req *musicpb.CreateGroupRequest) (*musicpb.Group, error) {
+	group, code, err := s.h.createGroup(ctx, req.Name)
+	if err != nil {
+		return nil, grpcError(code, err)
+	}
+
+	return s.group(*group)
+}
+
+func (s *grpcServer) UpdateGroup(ctx context.Context, req *musicpb.UpdateGroupRequest) (*musicpb.Group, error) {
+	group, code, err := s.h.renameGroup(ctx, int(req.Id), req.Name)
+	if err != nil {
+		return nil, grpcError(code, err)
+	}
+
+	return s.group(*group)
+}
+
+func (s *grpcServer) DeleteGroup(ctx context.Context, req *musicpb.DeleteGroupRequest) (*emptypb.Empty, error) {
+	code, err := checkPermission(ctx, auth.PermGroupsDelete)
+	if err != nil {
+		return nil, grpcError(code, err)
+	}
+
+	group, err := s.h.Repo.GetGroupByID(int(req.Id))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to retrieve group: %v", err)
+	}
+	if group == nil {
+		return nil, status.Errorf(codes.NotFound, "no such group with group_id: %v", req.Id)
+	}
+
+	err = s.h.deleteGroup(group.ID)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// group converts a group with its aliases.
+func (s *grpcServer) group(group models.Group) (*musicpb.Group, error) {
+	aliases, err := s.h.Repo.GroupAliasNames([]int{group.ID})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to retrieve aliases: %v", err)
+	}
+
+	return &musicpb.Group{Id: int64(group.ID), Name: group.Name, Aliases: aliases[group.ID]}, nil
+}
+
+func (s *grpcServer) SearchLyrics(req *musicpb.SearchLyricsRequest, stream grpc.ServerStreamingServer[musicpb.SearchLyricsResult]) error {
+	query := strings.TrimSpace(req.Query)
+	if query == "" {
+		return status.Error(codes.InvalidArgument, "missing search query")
+	}
+
+	limit := int(req.Limit)
+	if limit == 0 {
+		limit = defaultStreamedResults
+	}
+	if limit < 1 || limit > maxStreamedResults {
+		return status.Errorf(codes.InvalidArgument, "invalid limit: %d, expected 1 to %d", limit, maxStreamedResults)
+	}
+
+	results, err := s.h.Fulltext.Search(query, limit, 0)
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to search songs: %v", err)
+	}
+
+	for _, result := range results.Results {
+		err = stream.Send(&musicpb.SearchLyricsResult{
+			SongId:  int64(result.SongID),
+			GroupId: int64(result.GroupID),
+			Group:   result.Group,
+			Song:    result.Song,
+			Score:   result.Score,
+			Fields:  result.Fields,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
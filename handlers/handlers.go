@@ -1,22 +1,34 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/gorilla/mux"
+	"github.com/noctusha/music/auth"
 	"github.com/noctusha/music/connection"
+	"github.com/noctusha/music/fulltext"
 	"github.com/noctusha/music/models"
+	"github.com/noctusha/music/musicinfo"
+	"github.com/noctusha/music/recommend"
+	"github.com/noctusha/music/suggest"
 	"log"
 	"net/http"
-	"net/url"
-	"os"
+	"slices"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 )
 
-// Handler struct contains the repository for database operations.
+// Handler struct contains the repository for database operations, the token signer,
+// the index of similar songs, the index of search suggestions and the full-text index.
 type Handler struct {
-	Repo *connection.Repository
+	Repo     *connection.Repository
+	Auth     *auth.Signer
+	Similar  *recommend.Index
+	Suggest  *suggest.Index
+	Fulltext *fulltext.Index
 }
 
 // JSON struct is used for standard JSON responses.
@@ -54,95 +66,186 @@ func respondJSONError(w http.ResponseWriter, statusCode int, message string) {
 	RespondJSON(w, statusCode, JSON{Err: message})
 }
 
-// NewHandler creates a new Handler with the given repository.
-func NewHandler(repo *connection.Repository) *Handler {
+// NewHandler creates a new Handler with the given repository and token signer. The full-text
+// index is saved to indexPath.
+func NewHandler(repo *connection.Repository, signer *auth.Signer, indexPath string) *Handler {
 	return &Handler{
-		Repo: repo,
+		Repo:     repo,
+		Auth:     signer,
+		Similar:  recommend.NewIndex(repo),
+		Suggest:  suggest.NewIndex(repo),
+		Fulltext: fulltext.NewIndex(repo, indexPath),
 	}
 }
 
 // ListSongs godoc
 // @Summary Get list of songs
-// @Description Returns a list of songs with filtering and pagination
+// @Description Returns a list of songs with filtering, sorting and pagination. Each song has its average
+// @Description rating, plays and favorites, and for authenticated users their own favorite and rating.
+// @Description Include and fields embed related resources and select fields like GET /api/songs/{song_id}.
 // @Tags songs
 // @Accept json
 // @Produce json
-// @Param group query string false "Group name"
-// @Param name query string false "Song name"
+// @Param group query string false "Group name or alias, also matched regardless of accents and Cyrillic or Latin spelling"
+// @Param name query string false "Song name, also matched regardless of accents and Cyrillic or Latin spelling"
 // @Param releaseDate query string false "Release date"
 // @Param text query string false "Song text"
 // @Param link query string false "Song link"
+// @Param favoritesOnly query bool false "Only the favorites of the current user"
+// @Param minRating query number false "Minimal average rating, from 1 to 5"
+// @Param excludeExplicit query bool false "Leave out explicit songs"
+// @Param tags query string false "Comma-separated tags of the song or its group"
+// @Param moods query string false "Comma-separated moods of the song or its group"
+// @Param tagMatch query string false "Whether songs need all the tags and moods or any of them, all by default" Enums(all, any)
+// @Param genre query string false "Genre, including its subgenres and the genres inherited from the group"
+// @Param sort query string false "Sort order, name by default" Enums(name, popularity, rating)
 // @Param limit query int false "Limit"
 // @Param offset query int false "Offset"
-// @Success 200 {object} JSON
+// @Param include query string false "Comma-separated resources to embed in each song: group, details, credits"
+// @Param fields query string false "Comma-separated fields of each song to return, such as name,group.name; the id is always returned"
+// @Success 200 {object} models.SongList
 // @Failure 400 {object} JSON
 // @Failure 500 {object} JSON
 // @Router /api/songs [get]
+// @Router /api/v2/songs [get]
 // ListSongs handles the request to list songs with optional filters and pagination.
 func (h *Handler) ListSongs(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseSongFilter(r, "include", "fields")
+	if err != nil {
+		respondJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	include, fields, err := parseSongResource(r, nil)
+	if err != nil {
+		respondJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	songs, err := h.Repo.SongList(filter)
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to select song from database: %v", err))
+		return
+	}
+
+	resources, err := h.songResources(songs, include)
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondFields(w, http.StatusOK, models.SongList{Songs: resources}, fields, "song")
+}
+
+// parseSongFilter reads the ListSongs filters, sort order and pagination parameters from the query.
+// Parameters listed in extra are left for the caller to handle.
+func parseSongFilter(r *http.Request, extra ...string) (models.SongFilter, error) {
 	var (
-		limit       int
-		offset      int
-		group       string
-		name        string
-		releaseDate string
-		text        string
-		link        string
-		err         error
+		filter = models.SongFilter{UserID: currentUserID(r)}
+		err    error
 	)
 
 	for parameter, vals := range r.URL.Query() {
 		switch parameter {
 		case "limit":
-			limit, err = strconv.Atoi(vals[0])
+			filter.Limit, err = strconv.Atoi(vals[0])
 			if err != nil {
-				respondJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid limit format: %v", err))
-				return
+				return filter, fmt.Errorf("invalid limit format: %v", err)
 			}
 		case "offset":
-			offset, err = strconv.Atoi(vals[0])
+			filter.Offset, err = strconv.Atoi(vals[0])
 			if err != nil {
-				respondJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid offset format: %v", err))
-				return
+				return filter, fmt.Errorf("invalid offset format: %v", err)
 			}
 		case "group":
-			group = vals[0]
+			filter.Group = vals[0]
 		case "name":
-			name = vals[0]
+			filter.Name = vals[0]
 		case "releaseDate":
-			releaseDate = vals[0]
+			filter.ReleaseDate = vals[0]
 		case "text":
-			text = vals[0]
+			filter.Text = vals[0]
 		case "link":
-			link = vals[0]
+			filter.Link = vals[0]
+		case "favoritesOnly":
+			filter.FavoritesOnly, err = strconv.ParseBool(vals[0])
+			if err != nil {
+				return filter, fmt.Errorf("invalid favoritesOnly format: %v", err)
+			}
+			if filter.FavoritesOnly && filter.UserID == 0 {
+				return filter, fmt.Errorf("favoritesOnly requires authentication")
+			}
+		case "minRating":
+			filter.MinRating, err = strconv.ParseFloat(vals[0], 64)
+			if err != nil || filter.MinRating < 1 || filter.MinRating > 5 {
+				return filter, fmt.Errorf("invalid minRating: %v, expected a number from 1 to 5", vals[0])
+			}
+		case "excludeExplicit":
+			filter.ExcludeExplicit, err = strconv.ParseBool(vals[0])
+			if err != nil {
+				return filter, fmt.Errorf("invalid excludeExplicit format: %v", err)
+			}
+		case "tags", "moods":
+			var labels []string
+			for _, value := range vals {
+				labels = append(labels, strings.Split(value, ",")...)
+			}
+			labels, err = normalizeLabels(labels)
+			if err != nil {
+				return filter, err
+			}
+			if parameter == "tags" {
+				filter.Tags = labels
+			} else {
+				filter.Moods = labels
+			}
+		case "tagMatch":
+			switch vals[0] {
+			case models.TagMatchAll, models.TagMatchAny:
+				filter.TagMatch = vals[0]
+			default:
+				return filter, fmt.Errorf("unknown tagMatch: %v, expected all or any", vals[0])
+			}
+		case "genre":
+			filter.Genre = vals[0]
+		case "sort":
+			switch vals[0] {
+			case models.SortName, models.SortPopularity, models.SortRating:
+				filter.Sort = vals[0]
+			default:
+				return filter, fmt.Errorf("unknown sort order: %v", vals[0])
+			}
 		default:
-			respondJSONError(w, http.StatusBadRequest, fmt.Sprintf("unrecognized query parameter: %v", parameter))
-			return
+			if !slices.Contains(extra, parameter) {
+				return filter, fmt.Errorf("unrecognized query parameter: %v", parameter)
+			}
 		}
 	}
-	songs, err := h.Repo.SongList(group, name, releaseDate, text, link, limit, offset)
-	if err != nil {
-		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to select song from database: %v", err))
-		return
-	}
 
-	RespondJSON(w, http.StatusOK, JSON{Songs: &songs})
+	return filter, nil
 }
 
+// errNoWordLists is returned for censored lyrics when EXPLICIT_WORDS_DIR is not set.
+var errNoWordLists = errors.New("explicit words are not loaded, so lyrics cannot be censored")
+
 // GetText godoc
 // @Summary Get song text
-// @Description Returns the text of a song with pagination over verses
+// @Description Returns the text of a song with pagination over verses. Censored masks the explicit words
+// @Description but their first letter, such as "f***"; it fails with 503 when no explicit word lists are loaded.
 // @Tags songs
 // @Accept json
 // @Produce json
 // @Param song_id path string true "Song ID"
 // @Param page query int false "Page number"
 // @Param limit query int false "Number of verses per page"
+// @Param censored query bool false "Mask explicit words"
 // @Success 200 {object} JSON
 // @Failure 400 {object} JSON
 // @Failure 404 {object} JSON
 // @Failure 500 {object} JSON
+// @Failure 503 {object} JSON
 // @Router /api/songs/{song_id}/text [get]
+// @Router /api/v2/songs/{song_id}/text [get]
 // GetText handles the request to retrieve the text of a song with pagination.
 func (h *Handler) GetText(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -159,6 +262,21 @@ func (h *Handler) GetText(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if value := r.URL.Query().Get("censored"); value != "" {
+		censored, err := strconv.ParseBool(value)
+		if err != nil {
+			respondJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid censored format: %v", err))
+			return
+		}
+		if censored {
+			if h.Repo.Explicit == nil {
+				respondJSONError(w, http.StatusServiceUnavailable, errNoWordLists.Error())
+				return
+			}
+			text = h.Repo.Explicit.Censor(text)
+		}
+	}
+
 	verses := strings.Split(text, "\n\n")
 
 	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
@@ -193,69 +311,179 @@ func (h *Handler) GetText(w http.ResponseWriter, r *http.Request) {
 // @Tags songs
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param song_id path string true "Song ID"
 // @Success 200 {object} JSON
 // @Failure 400 {object} JSON
+// @Failure 401 {object} JSON
+// @Failure 403 {object} JSON
 // @Failure 500 {object} JSON
+// @Deprecated
 // @Router /api/songs/{song_id}/delete [delete]
 // DeleteSong handles the request to delete a song.
 func (h *Handler) DeleteSong(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	songID := vars["song_id"]
 
-	err := h.Repo.SongDelete(songID)
+	song, err := h.Repo.GetSongByID(songID)
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to retrieve song: %v", err))
+		return
+	}
+
+	err = h.Repo.SongDelete(songID)
 	if err != nil {
 		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to delete song: %v", err))
 		return
 	}
+	if song != nil {
+		h.syncAutocomplete(song.GroupID)
+		h.syncSearch(song.ID)
+	}
 
 	RespondJSON(w, http.StatusOK, JSON{})
 }
 
 // EditSong godoc
 // @Summary Edit song data
-// @Description Edits song data by ID
+// @Description Edits song data by ID. Editors can only edit the songs of the groups they maintain.
 // @Tags songs
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param song_id path string true "Song ID"
 // @Param song body models.EditSongPayload true "Song data"
 // @Success 200 {object} JSON
 // @Failure 400 {object} JSON
+// @Failure 401 {object} JSON
+// @Failure 403 {object} JSON
+// @Failure 404 {object} JSON
+// @Failure 409 {object} JSON
 // @Failure 500 {object} JSON
+// @Deprecated
 // @Router /api/songs/{song_id}/edit [patch]
 // EditSong handles the request to edit a song's data.
 func (h *Handler) EditSong(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	songID := vars["song_id"]
+	var payload models.EditSongPayload
 
-	song, err := h.Repo.GetSongByID(songID)
+	err := json.NewDecoder(r.Body).Decode(&payload)
 	if err != nil {
-		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to retrieve song: %v", err))
+		respondJSONError(w, http.StatusBadRequest, fmt.Sprintf("failed to decode updatedSong body: %v", err))
 		return
 	}
 
-	var payload models.EditSongPayload
+	song, songDetails, ok := h.updateSong(w, r, func(song *models.Song, songDetails *models.SongDetails) {
+		applySongEdit(song, songDetails, payload)
+	})
+	if !ok {
+		return
+	}
 
-	err = json.NewDecoder(r.Body).Decode(&payload)
+	RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"song":         song,
+		"song_details": songDetails,
+	})
+}
+
+// updateSong saves the changes that edit makes to the song of the path and its details, like
+// editSong. It responds with an error and returns false when the song was not saved.
+func (h *Handler) updateSong(w http.ResponseWriter, r *http.Request, edit func(*models.Song, *models.SongDetails)) (*models.Song, *models.SongDetails, bool) {
+	songID, err := pathID(r, "song_id")
 	if err != nil {
-		respondJSONError(w, http.StatusBadRequest, fmt.Sprintf("failed to decode updatedSong body: %v", err))
-		return
+		respondJSONError(w, http.StatusBadRequest, err.Error())
+		return nil, nil, false
 	}
 
-	if payload.Song.Name != "" {
-		song.Name = payload.Song.Name
+	song, songDetails, status, err := h.editSong(r.Context(), songID, edit)
+	switch {
+	case status == http.StatusUnauthorized:
+		respondUnauthorized(w, err.Error())
+		return nil, nil, false
+	case err != nil:
+		respondJSONError(w, status, err.Error())
+		return nil, nil, false
 	}
-	if payload.Song.GroupID != 0 {
-		song.GroupID = payload.Song.GroupID
+
+	return song, songDetails, true
+}
+
+// editSong saves the changes that edit makes to a song and its details, once the user is allowed to
+// edit the songs of its group and of the group it moves to. It returns the song with its stats, or
+// the status to respond with when it was not saved.
+func (h *Handler) editSong(ctx context.Context, songID int, edit func(*models.Song, *models.SongDetails)) (*models.Song, *models.SongDetails, int, error) {
+	id := strconv.Itoa(songID)
+	song, err := h.Repo.GetSongByID(id)
+	if err != nil {
+		return nil, nil, http.StatusInternalServerError, fmt.Errorf("failed to retrieve song: %v", err)
+	}
+	if song == nil {
+		return nil, nil, http.StatusNotFound, fmt.Errorf("no such song with song_id: %v", songID)
+	}
+	status, err := h.checkGroup(ctx, song.GroupID, auth.PermSongsEdit)
+	if err != nil {
+		return nil, nil, status, err
 	}
 
-	songDetails, err := h.Repo.GetSongDetailsByID(songID)
+	songDetails, err := h.Repo.GetSongDetailsByID(id)
 	if err != nil {
-		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to retrieve song: %v", err))
-		return
+		return nil, nil, http.StatusInternalServerError, fmt.Errorf("failed to retrieve song: %v", err)
+	}
+	if songDetails == nil {
+		songDetails = &models.SongDetails{SongID: song.ID}
+	}
+
+	groupID, text := song.GroupID, songDetails.Text
+	edit(song, songDetails)
+
+	if song.GroupID != groupID {
+		group, err := h.Repo.GetGroupByID(song.GroupID)
+		if err != nil {
+			return nil, nil, http.StatusInternalServerError, fmt.Errorf("failed to retrieve group: %v", err)
+		}
+		if group == nil {
+			return nil, nil, http.StatusBadRequest, fmt.Errorf("no such group with group_id: %d", song.GroupID)
+		}
+		status, err := h.checkGroup(ctx, song.GroupID, auth.PermSongsEdit)
+		if err != nil {
+			return nil, nil, status, err
+		}
+	}
+
+	err = h.Repo.UpdateSong(song, songDetails)
+	if errors.Is(err, connection.ErrSongExists) {
+		return nil, nil, http.StatusConflict, err
+	}
+	if err != nil {
+		return nil, nil, http.StatusInternalServerError, fmt.Errorf("failed to update song: %v", err)
+	}
+
+	_, err = h.Repo.RejectStaleSuggestions(song.ID)
+	if err != nil {
+		log.Printf("error rejecting suggestions of song %d: %v", song.ID, err)
+	}
+
+	if songDetails.Text != text {
+		h.syncSimilar()
+	}
+	h.syncAutocomplete(groupID, song.GroupID)
+	h.syncSearch(song.ID)
+
+	song.Stats, err = h.Repo.SongStats(song.ID, contextUserID(ctx))
+	if err != nil {
+		return nil, nil, http.StatusInternalServerError, fmt.Errorf("failed to retrieve song stats: %v", err)
 	}
 
+	return song, songDetails, 0, nil
+}
+
+// applySongEdit copies the non-empty fields of an edit to a song and its details.
+func applySongEdit(song *models.Song, songDetails *models.SongDetails, payload models.EditSongPayload) {
+	if payload.Song.Name != "" {
+		song.Name = payload.Song.Name
+	}
+	if payload.Song.GroupID != 0 {
+		song.GroupID = payload.Song.GroupID
+	}
 	if payload.SongDetails.ReleaseDate != "" {
 		songDetails.ReleaseDate = payload.SongDetails.ReleaseDate
 	}
@@ -265,29 +493,29 @@ func (h *Handler) EditSong(w http.ResponseWriter, r *http.Request) {
 	if payload.SongDetails.Link != "" {
 		songDetails.Link = payload.SongDetails.Link
 	}
-
-	err = h.Repo.UpdateSong(song, songDetails)
-	if err != nil {
-		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to update song: %v", err))
-		return
+	if payload.SongDetails.Album != "" {
+		songDetails.Album = payload.SongDetails.Album
 	}
-
-	RespondJSON(w, http.StatusOK, map[string]interface{}{
-		"song":         song,
-		"song_details": songDetails,
-	})
 }
 
 // NewSong godoc
 // @Summary Add a new song
-// @Description Adds a new song and saves it to the database
+// @Description Adds a new song and saves it to the database. Editors can only add songs to the groups
+// @Description they maintain or to a new group, which they then maintain. A song whose name matches a song of
+// @Description the group regardless of case, spaces, punctuation, accents and Cyrillic or Latin spelling is not
+// @Description added: the response is 409 Conflict with the ID of the existing song.
 // @Tags songs
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param song body models.NewSongPayload true "New song"
 // @Success 201 {object} models.Song
 // @Failure 400 {object} JSON
+// @Failure 401 {object} JSON
+// @Failure 403 {object} JSON
+// @Failure 409 {object} models.SongConflict
 // @Failure 500 {object} JSON
+// @Deprecated
 // @Router /api/songs/new [post]
 // NewSong handles the request to add a new song.
 func (h *Handler) NewSong(w http.ResponseWriter, r *http.Request) {
@@ -308,53 +536,132 @@ func (h *Handler) NewSong(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	apiBaseURL := os.Getenv("EXTERNAL_API_URL")
-	if apiBaseURL == "" {
-		respondJSONError(w, http.StatusInternalServerError, "External API URL is not configured")
+	song, status, err := h.createSong(r.Context(), payload.Group, payload.Song)
+	if status == http.StatusConflict {
+		RespondJSON(w, status, models.SongConflict{Error: err.Error(), SongID: song.ID})
 		return
 	}
-
-	apiURL := fmt.Sprintf("%s/info?group=%s&song=%s", apiBaseURL, url.QueryEscape(payload.Group), url.QueryEscape(payload.Song))
-	resp, err := http.Get(apiURL)
 	if err != nil {
-		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Error making request to external API: %v", err))
+		respondJSONError(w, status, err.Error())
 		return
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("External API returned status %d", resp.StatusCode))
-		return
+	RespondJSON(w, http.StatusCreated, song)
+}
+
+// createSong adds a song with details from the external API, creating its group when needed.
+// It returns the status to respond with when the song cannot be created; when the group already
+// has the song, it returns the existing song with http.StatusConflict and connection.ErrSongExists.
+func (h *Handler) createSong(ctx context.Context, group, name string) (*models.Song, int, error) {
+	groupID, err := h.Repo.GetGroupID(group)
+	if err != nil {
+		return nil, http.StatusInternalServerError, fmt.Errorf("failed to retrieve groupID: %v", err)
+	}
+	if groupID != 0 {
+		status, err := h.checkGroup(ctx, groupID, auth.PermSongsCreate)
+		if err != nil {
+			return nil, status, err
+		}
+	} else if principal := auth.PrincipalFromContext(ctx); !principal.Can(auth.PermSongsCreate) {
+		return nil, http.StatusForbidden, fmt.Errorf("missing permission: %s", auth.PermSongsCreate)
+	}
+
+	if groupID != 0 {
+		existing, err := h.Repo.FindSongInGroup(groupID, name)
+		if err != nil {
+			return nil, http.StatusInternalServerError, fmt.Errorf("failed to retrieve song: %v", err)
+		}
+		if existing != nil {
+			return existing, http.StatusConflict, connection.ErrSongExists
+		}
 	}
 
-	var details models.SongDetails
-	err = json.NewDecoder(resp.Body).Decode(&details)
+	client, err := musicinfo.NewClient()
 	if err != nil {
-		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Error parsing external API response: %v", err))
-		return
+		return nil, http.StatusInternalServerError, err
 	}
 
-	groupID, err := h.Repo.GetGroupID(payload.Group)
+	details, err := client.SongDetails(group, name)
 	if err != nil {
-		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to retrieve groupID: %v", err))
-		return
+		return nil, http.StatusInternalServerError, err
 	}
 
 	if groupID == 0 {
-		groupID, err = h.Repo.NewGroup(payload.Group)
+		groupID, err = h.addGroup(ctx, group)
 		if err != nil {
-			respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to retrieve groupID: %v", err))
-			return
+			return nil, http.StatusInternalServerError, err
 		}
 	}
 
-	song := models.Song{Name: payload.Song, GroupID: groupID}
+	song := models.Song{Name: name, GroupID: groupID}
 
-	err = h.Repo.CreateSongWithDetails(song, details)
+	song.ID, err = h.Repo.CreateSongWithDetails(song, details)
+	if errors.Is(err, connection.ErrSongExists) {
+		// The song was added while its details were fetched.
+		existing, findErr := h.Repo.FindSongInGroup(groupID, name)
+		if findErr == nil && existing != nil {
+			return existing, http.StatusConflict, err
+		}
+	}
 	if err != nil {
-		respondJSONError(w, http.StatusBadRequest, fmt.Sprintf("failed to create song: %v", err))
-		return
+		return nil, http.StatusBadRequest, fmt.Errorf("failed to create song: %v", err)
 	}
+	h.syncAutocomplete(groupID)
+	h.syncSearch(song.ID)
 
-	RespondJSON(w, http.StatusCreated, song)
+	return &song, 0, nil
+}
+
+// validGroupName trims the name of a group and checks its length.
+func validGroupName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > 255 {
+		return "", fmt.Errorf("group name must be 1 to 255 characters")
+	}
+	return name, nil
+}
+
+// createGroup adds a group once the user may add songs, returning the status to respond with when
+// it was not added. A name already used by a group or an alias is a conflict.
+func (h *Handler) createGroup(ctx context.Context, name string) (*models.Group, int, error) {
+	name, err := validGroupName(name)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	status, err := checkPermission(ctx, auth.PermSongsCreate)
+	if err != nil {
+		return nil, status, err
+	}
+
+	groupID, err := h.Repo.GetGroupID(name)
+	if err != nil {
+		return nil, http.StatusInternalServerError, fmt.Errorf("failed to retrieve groupID: %v", err)
+	}
+	if groupID != 0 {
+		return nil, http.StatusConflict, fmt.Errorf("%w: group_id %d", connection.ErrAliasExists, groupID)
+	}
+
+	groupID, err = h.addGroup(ctx, name)
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	h.syncAutocomplete(groupID)
+
+	return &models.Group{ID: groupID, Name: name}, 0, nil
+}
+
+// addGroup creates a group. The editor who adds a group becomes its first maintainer.
+func (h *Handler) addGroup(ctx context.Context, name string) (int, error) {
+	groupID, err := h.Repo.NewGroup(name)
+	if err != nil {
+		return 0, fmt.Errorf("failed to retrieve groupID: %v", err)
+	}
+
+	err = h.Repo.AddGroupMaintainer(groupID, contextUserID(ctx))
+	if err != nil {
+		return 0, fmt.Errorf("failed to add maintainer: %v", err)
+	}
+
+	return groupID, nil
 }
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/noctusha/music/importer"
+	"github.com/noctusha/music/musicinfo"
+)
+
+// Duplicate policies of a bulk import.
+const (
+	policySkip   = "skip"
+	policyUpsert = "upsert"
+)
+
+// ImportSongs godoc
+// @Summary Bulk import songs
+// @Description Imports songs from CSV (with a group,song,release_date,text,link,album header), a JSON array or NDJSON.
+// @Description Rows are stored in batches inside transactions and the response reports the outcome of every row.
+// @Description A bundle from /api/export is restored with its original ids in a single transaction.
+// @Tags songs
+// @Accept text/csv
+// @Accept json
+// @Accept application/x-ndjson
+// @Accept application/zip
+// @Produce json
+// @Security BearerAuth
+// @Param format query string false "Input format, taken from Content-Type when omitted" Enums(csv, json, ndjson, bundle)
+// @Param dryRun query bool false "Validate and report without saving"
+// @Param policy query string false "What to do with existing songs" Enums(skip, upsert)
+// @Param enrich query bool false "Fill in missing details from the external API"
+// @Param batchSize query int false "Rows per transaction"
+// @Success 200 {object} models.ImportReport
+// @Failure 400 {object} JSON
+// @Failure 401 {object} JSON
+// @Failure 403 {object} JSON
+// @Failure 500 {object} JSON
+// @Router /api/songs/import [post]
+// ImportSongs handles the request to import songs in bulk.
+func (h *Handler) ImportSongs(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	options := importer.Options{Format: query.Get("format")}
+	if options.Format == "" {
+		options.Format = importer.FormatFromContentType(r.Header.Get("Content-Type"))
+	}
+	if options.Format == "" {
+		respondJSONError(w, http.StatusBadRequest, "unknown import format: set the format parameter or Content-Type")
+		return
+	}
+
+	var err error
+	if query.Get("dryRun") != "" {
+		options.DryRun, err = strconv.ParseBool(query.Get("dryRun"))
+		if err != nil {
+			respondJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid dryRun format: %v", err))
+			return
+		}
+	}
+
+	switch query.Get("policy") {
+	case "", policySkip:
+	case policyUpsert:
+		options.Upsert = true
+	default:
+		respondJSONError(w, http.StatusBadRequest, fmt.Sprintf("unknown policy: %v", query.Get("policy")))
+		return
+	}
+
+	if query.Get("batchSize") != "" {
+		options.BatchSize, err = strconv.Atoi(query.Get("batchSize"))
+		if err != nil {
+			respondJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid batchSize format: %v", err))
+			return
+		}
+	}
+
+	if options.Format == importer.FormatBundle {
+		h.restoreBundle(w, r.Body, options.DryRun)
+		return
+	}
+
+	imp := importer.Importer{Repo: h.Repo, Options: options}
+
+	enrich := false
+	if query.Get("enrich") != "" {
+		enrich, err = strconv.ParseBool(query.Get("enrich"))
+		if err != nil {
+			respondJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid enrich format: %v", err))
+			return
+		}
+	}
+	if enrich {
+		imp.Client, err = musicinfo.NewClient()
+		if err != nil {
+			respondJSONError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	report, err := imp.Import(r.Body)
+	if err != nil {
+		respondJSONError(w, importStatus(err), fmt.Sprintf("failed to import songs after %d rows: %v", len(report.Rows), err))
+		return
+	}
+	if !options.DryRun {
+		h.syncAutocomplete()
+		h.syncSearch()
+	}
+
+	RespondJSON(w, http.StatusOK, report)
+}
+
+// importStatus is the status of a failed import: 400 for malformed input, 500 otherwise.
+func importStatus(err error) int {
+	var inputErr *importer.InputError
+	if errors.As(err, &inputErr) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/noctusha/music/models"
+	"github.com/noctusha/music/playlist"
+)
+
+// ImportPlaylist godoc
+// @Summary Import a playlist
+// @Description Parses an M3U, extended M3U or XSPF playlist and resolves its entries against the library
+// @Description by group and title, falling back to the most similar song. Unmatched entries are reported.
+// @Description With save the matched songs are stored as a new private playlist of the authenticated user.
+// @Tags playlists
+// @Accept audio/x-mpegurl
+// @Accept application/xspf+xml
+// @Produce json
+// @Security BearerAuth
+// @Param format query string false "Playlist format, taken from Content-Type when omitted" Enums(m3u, m3u8, xspf)
+// @Param save query bool false "Save the matched songs as a playlist"
+// @Param name query string false "Name of the saved playlist, taken from the file when omitted"
+// @Success 200 {object} models.PlaylistImportReport
+// @Failure 400 {object} JSON
+// @Failure 401 {object} JSON
+// @Failure 500 {object} JSON
+// @Router /api/playlists/import [post]
+// ImportPlaylist handles the request to resolve a playlist against the library.
+func (h *Handler) ImportPlaylist(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	save := false
+	var err error
+	if query.Get("save") != "" {
+		save, err = strconv.ParseBool(query.Get("save"))
+		if err != nil {
+			respondJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid save format: %v", err))
+			return
+		}
+	}
+
+	var userID int
+	if save {
+		var ok bool
+		userID, ok = h.requireUser(w, r)
+		if !ok {
+			return
+		}
+	}
+
+	format := query.Get("format")
+	if format == "" {
+		format = playlist.FormatFromContentType(r.Header.Get("Content-Type"))
+	}
+	if format == "" {
+		respondJSONError(w, http.StatusBadRequest, "unknown playlist format: set the format parameter or Content-Type")
+		return
+	}
+
+	list, err := playlist.Parse(r.Body, format)
+	if err != nil {
+		respondJSONError(w, http.StatusBadRequest, fmt.Sprintf("failed to parse playlist: %v", err))
+		return
+	}
+
+	names, err := h.Repo.SongNames()
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to select songs from database: %v", err))
+		return
+	}
+
+	aliases, err := h.Repo.GroupAliases(0)
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to select aliases from database: %v", err))
+		return
+	}
+
+	resolver := playlist.NewResolver(names)
+	resolver.AddAliases(aliases)
+	report := resolver.Resolve(list)
+	if save {
+		err = h.savePlaylist(report, userID, query.Get("name"))
+		if err != nil {
+			respondJSONError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	RespondJSON(w, http.StatusOK, report)
+}
+
+// savePlaylist stores the matched entries of an imported playlist as a new private playlist.
+func (h *Handler) savePlaylist(report *models.PlaylistImportReport, userID int, name string) error {
+	if name == "" {
+		name = report.Title
+	}
+	if name == "" {
+		name = "Imported playlist"
+	}
+
+	var songIDs []int
+	for _, entry := range report.Entries {
+		if entry.SongID != 0 {
+			songIDs = append(songIDs, entry.SongID)
+		}
+	}
+
+	created, err := h.Repo.CreatePlaylistWithEntries(models.Playlist{OwnerID: userID, Name: name, Visibility: models.PlaylistPrivate}, userID, songIDs)
+	if err != nil {
+		return fmt.Errorf("failed to save playlist: %v", err)
+	}
+
+	report.PlaylistID = created.ID
+	return nil
+}
+
+// ExportPlaylist godoc
+// @Summary Export songs as a playlist
+// @Description Writes songs as an extended M3U (UTF-8) or XSPF playlist.
+// @Description Songs are selected either by ids or by the ListSongs filters.
+// @Tags playlists
+// @Produce audio/x-mpegurl
+// @Produce application/xspf+xml
+// @Param format query string false "Playlist format" Enums(m3u, m3u8, xspf)
+// @Param title query string false "Playlist title"
+// @Param ids query string false "Comma separated song ids, in playlist order"
+// @Param group query string false "Group name or alias, also matched regardless of accents and Cyrillic or Latin spelling"
+// @Param name query string false "Song name, also matched regardless of accents and Cyrillic or Latin spelling"
+// @Param releaseDate query string false "Release date"
+// @Param text query string false "Song text"
+// @Param link query string false "Song link"
+// @Param favoritesOnly query bool false "Only the favorites of the current user"
+// @Param minRating query number false "Minimal average rating, from 1 to 5"
+// @Param excludeExplicit query bool false "Leave out explicit songs"
+// @Param tags query string false "Comma-separated tags of the song or its group"
+// @Param moods query string false "Comma-separated moods of the song or its group"
+// @Param tagMatch query string false "Whether songs need all the tags and moods or any of them, all by default" Enums(all, any)
+// @Param genre query string false "Genre, including its subgenres and the genres inherited from the group"
+// @Param sort query string false "Sort order, name by default" Enums(name, popularity, rating)
+// @Param limit query int false "Limit"
+// @Param offset query int false "Offset"
+// @Success 200 {file} file
+// @Failure 400 {object} JSON
+// @Failure 500 {object} JSON
+// @Router /api/playlists/export [get]
+// ExportPlaylist handles the request to export songs as a playlist file.
+func (h *Handler) ExportPlaylist(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	format, ok := playlistFormat(w, query.Get("format"))
+	if !ok {
+		return
+	}
+
+	filter, err := parseSongFilter(r, "format", "title", "ids")
+	if err != nil {
+		respondJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var songs []models.DetailedSong
+	if query.Get("ids") != "" {
+		ids, err := ParseIDs(query.Get("ids"))
+		if err != nil {
+			respondJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		songs, err = h.Repo.DetailedSongsByIDs(ids)
+		if err != nil {
+			respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to select songs from database: %v", err))
+			return
+		}
+	} else {
+		songs, err = h.Repo.DetailedSongList(filter)
+		if err != nil {
+			respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to select songs from database: %v", err))
+			return
+		}
+	}
+
+	writePlaylist(w, format, query.Get("title"), songs)
+}
+
+// playlistFormat validates the format of a playlist export, defaulting to M3U8.
+func playlistFormat(w http.ResponseWriter, format string) (string, bool) {
+	switch format {
+	case "":
+		return playlist.FormatM3U8, true
+	case playlist.FormatM3U, playlist.FormatM3U8, playlist.FormatXSPF:
+		return format, true
+	default:
+		respondJSONError(w, http.StatusBadRequest, fmt.Sprintf("unsupported format: %v", format))
+		return "", false
+	}
+}
+
+// writePlaylist responds with songs as a playlist file.
+func writePlaylist(w http.ResponseWriter, format, title string, songs []models.DetailedSong) {
+	var buf bytes.Buffer
+	err := playlist.Write(&buf, format, title, songs)
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to write playlist: %v", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", playlist.ContentType(format))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"playlist.%s\"", format))
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(buf.Bytes())
+	if err != nil {
+		log.Printf("error writing playlist: %v", err)
+	}
+}
@@ -0,0 +1,783 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/noctusha/music/auth"
+	"github.com/noctusha/music/connection"
+	"github.com/noctusha/music/models"
+)
+
+// ListPlaylists godoc
+// @Summary Get playlists
+// @Description Returns the playlists of the current user and the public and collaborative playlists of others
+// @Tags playlists
+// @Produce json
+// @Security BearerAuth
+// @Param limit query int false "Limit"
+// @Param offset query int false "Offset"
+// @Success 200 {array} models.Playlist
+// @Failure 400 {object} JSON
+// @Failure 500 {object} JSON
+// @Router /api/playlists [get]
+// ListPlaylists handles the request to list the playlists visible to the current user.
+func (h *Handler) ListPlaylists(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r)
+
+	limit, offset, err := parsePagination(r.URL.Query())
+	if err != nil {
+		respondJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	playlists, err := h.Repo.ListPlaylists(userID, limit, offset)
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to select playlists from database: %v", err))
+		return
+	}
+
+	for i := range playlists {
+		hideShareToken(&playlists[i], userID)
+	}
+	RespondJSON(w, http.StatusOK, playlists)
+}
+
+// CreatePlaylist godoc
+// @Summary Create a playlist
+// @Description Creates an empty playlist owned by the current user
+// @Tags playlists
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param playlist body models.PlaylistPayload true "New playlist"
+// @Success 201 {object} models.Playlist
+// @Failure 400 {object} JSON
+// @Failure 401 {object} JSON
+// @Failure 500 {object} JSON
+// @Router /api/playlists [post]
+// CreatePlaylist handles the request to create a playlist.
+func (h *Handler) CreatePlaylist(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	var payload models.PlaylistPayload
+
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		respondJSONError(w, http.StatusBadRequest, fmt.Sprintf("failed to decode playlist: %v", err))
+		return
+	}
+	if payload.Name == "" {
+		respondJSONError(w, http.StatusBadRequest, "no playlist name")
+		return
+	}
+	if payload.Visibility == "" {
+		payload.Visibility = models.PlaylistPrivate
+	}
+	if !validVisibility(payload.Visibility) {
+		respondJSONError(w, http.StatusBadRequest, fmt.Sprintf("unknown visibility: %v", payload.Visibility))
+		return
+	}
+
+	playlist, err := h.Repo.CreatePlaylist(models.Playlist{
+		OwnerID:     userID,
+		Name:        payload.Name,
+		Description: payload.Description,
+		Visibility:  payload.Visibility,
+	})
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to create playlist: %v", err))
+		return
+	}
+
+	RespondJSON(w, http.StatusCreated, playlist)
+}
+
+// GetPlaylist godoc
+// @Summary Get a playlist
+// @Description Returns a playlist with its entries in order. Private playlists are only visible to their owner.
+// @Tags playlists
+// @Produce json
+// @Security BearerAuth
+// @Param playlist_id path int true "Playlist ID"
+// @Success 200 {object} models.Playlist
+// @Failure 400 {object} JSON
+// @Failure 404 {object} JSON
+// @Failure 500 {object} JSON
+// @Router /api/playlists/{playlist_id} [get]
+// GetPlaylist handles the request to get a playlist with its entries.
+func (h *Handler) GetPlaylist(w http.ResponseWriter, r *http.Request) {
+	playlist, userID, ok := h.readablePlaylist(w, r)
+	if !ok {
+		return
+	}
+
+	h.respondPlaylist(w, playlist, userID)
+}
+
+// GetSharedPlaylist godoc
+// @Summary Get a shared playlist
+// @Description Returns a playlist with its entries by its share token, whatever its visibility
+// @Tags playlists
+// @Produce json
+// @Param token path string true "Share token"
+// @Success 200 {object} models.Playlist
+// @Failure 404 {object} JSON
+// @Failure 500 {object} JSON
+// @Router /api/shared/{token} [get]
+// GetSharedPlaylist handles the request to get a playlist by its share token.
+func (h *Handler) GetSharedPlaylist(w http.ResponseWriter, r *http.Request) {
+	token := mux.Vars(r)["token"]
+
+	playlist, err := h.Repo.GetPlaylistByShareToken(token)
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to retrieve playlist: %v", err))
+		return
+	}
+	if playlist == nil {
+		respondJSONError(w, http.StatusNotFound, "no such shared playlist")
+		return
+	}
+
+	h.respondPlaylist(w, playlist, 0)
+}
+
+// EditPlaylist godoc
+// @Summary Edit a playlist
+// @Description Changes the name, description or visibility of a playlist. Only the owner can edit it.
+// @Tags playlists
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param playlist_id path int true "Playlist ID"
+// @Param playlist body models.PlaylistPayload true "Playlist data"
+// @Success 200 {object} models.Playlist
+// @Failure 400 {object} JSON
+// @Failure 401 {object} JSON
+// @Failure 403 {object} JSON
+// @Failure 404 {object} JSON
+// @Failure 500 {object} JSON
+// @Router /api/playlists/{playlist_id} [patch]
+// EditPlaylist handles the request to edit a playlist.
+func (h *Handler) EditPlaylist(w http.ResponseWriter, r *http.Request) {
+	playlist, ok := h.ownPlaylist(w, r)
+	if !ok {
+		return
+	}
+
+	var payload models.PlaylistPayload
+
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		respondJSONError(w, http.StatusBadRequest, fmt.Sprintf("failed to decode playlist: %v", err))
+		return
+	}
+
+	if payload.Name != "" {
+		playlist.Name = payload.Name
+	}
+	if payload.Description != "" {
+		playlist.Description = payload.Description
+	}
+	if payload.Visibility != "" {
+		if !validVisibility(payload.Visibility) {
+			respondJSONError(w, http.StatusBadRequest, fmt.Sprintf("unknown visibility: %v", payload.Visibility))
+			return
+		}
+		playlist.Visibility = payload.Visibility
+	}
+
+	err = h.Repo.UpdatePlaylist(playlist)
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to update playlist: %v", err))
+		return
+	}
+
+	RespondJSON(w, http.StatusOK, playlist)
+}
+
+// DeletePlaylist godoc
+// @Summary Delete a playlist
+// @Description Deletes a playlist with its entries. Only the owner can delete it.
+// @Tags playlists
+// @Security BearerAuth
+// @Param playlist_id path int true "Playlist ID"
+// @Success 200 {object} JSON
+// @Failure 401 {object} JSON
+// @Failure 403 {object} JSON
+// @Failure 404 {object} JSON
+// @Failure 500 {object} JSON
+// @Router /api/playlists/{playlist_id} [delete]
+// DeletePlaylist handles the request to delete a playlist.
+func (h *Handler) DeletePlaylist(w http.ResponseWriter, r *http.Request) {
+	playlist, ok := h.ownPlaylist(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.Repo.DeletePlaylist(playlist.ID)
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to delete playlist: %v", err))
+		return
+	}
+
+	RespondJSON(w, http.StatusOK, nil)
+}
+
+// AddPlaylistEntry godoc
+// @Summary Add a song to a playlist
+// @Description Inserts a song at a position of a playlist, 1 being the first; without a position the song is appended.
+// @Description The owner and, for collaborative playlists, every user can add songs.
+// @Tags playlists
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param playlist_id path int true "Playlist ID"
+// @Param entry body models.PlaylistEntryPayload true "Song and position"
+// @Success 200 {object} models.Playlist
+// @Failure 400 {object} JSON
+// @Failure 401 {object} JSON
+// @Failure 403 {object} JSON
+// @Failure 404 {object} JSON
+// @Failure 500 {object} JSON
+// @Router /api/playlists/{playlist_id}/entries [post]
+// AddPlaylistEntry handles the request to add a song to a playlist.
+func (h *Handler) AddPlaylistEntry(w http.ResponseWriter, r *http.Request) {
+	playlist, userID, ok := h.editablePlaylist(w, r)
+	if !ok {
+		return
+	}
+
+	var payload models.PlaylistEntryPayload
+
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		respondJSONError(w, http.StatusBadRequest, fmt.Sprintf("failed to decode playlist entry: %v", err))
+		return
+	}
+
+	song, err := h.Repo.GetSongByID(strconv.Itoa(payload.SongID))
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to retrieve song: %v", err))
+		return
+	}
+	if song == nil {
+		respondJSONError(w, http.StatusNotFound, fmt.Sprintf("no such song with song_id: %v", payload.SongID))
+		return
+	}
+
+	err = h.Repo.InsertPlaylistEntries(playlist.ID, userID, []int{song.ID}, payload.Position)
+	if err != nil {
+		respondPlaylistError(w, err)
+		return
+	}
+
+	h.respondPlaylist(w, playlist, userID)
+}
+
+// MovePlaylistEntry godoc
+// @Summary Move a playlist entry
+// @Description Moves an entry to another position of its playlist, 1 being the first
+// @Tags playlists
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param playlist_id path int true "Playlist ID"
+// @Param entry_id path int true "Entry ID"
+// @Param entry body models.PlaylistEntryPayload true "New position"
+// @Success 200 {object} models.Playlist
+// @Failure 400 {object} JSON
+// @Failure 401 {object} JSON
+// @Failure 403 {object} JSON
+// @Failure 404 {object} JSON
+// @Failure 500 {object} JSON
+// @Router /api/playlists/{playlist_id}/entries/{entry_id} [patch]
+// MovePlaylistEntry handles the request to move a playlist entry.
+func (h *Handler) MovePlaylistEntry(w http.ResponseWriter, r *http.Request) {
+	playlist, userID, ok := h.editablePlaylist(w, r)
+	if !ok {
+		return
+	}
+
+	entryID, err := pathID(r, "entry_id")
+	if err != nil {
+		respondJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var payload models.PlaylistEntryPayload
+
+	err = json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		respondJSONError(w, http.StatusBadRequest, fmt.Sprintf("failed to decode playlist entry: %v", err))
+		return
+	}
+
+	err = h.Repo.MovePlaylistEntry(playlist.ID, entryID, payload.Position)
+	if err != nil {
+		respondPlaylistError(w, err)
+		return
+	}
+
+	h.respondPlaylist(w, playlist, userID)
+}
+
+// RemovePlaylistEntry godoc
+// @Summary Remove a playlist entry
+// @Description Removes an entry from its playlist; the following entries move up
+// @Tags playlists
+// @Produce json
+// @Security BearerAuth
+// @Param playlist_id path int true "Playlist ID"
+// @Param entry_id path int true "Entry ID"
+// @Success 200 {object} models.Playlist
+// @Failure 400 {object} JSON
+// @Failure 401 {object} JSON
+// @Failure 403 {object} JSON
+// @Failure 404 {object} JSON
+// @Failure 500 {object} JSON
+// @Router /api/playlists/{playlist_id}/entries/{entry_id} [delete]
+// RemovePlaylistEntry handles the request to remove a playlist entry.
+func (h *Handler) RemovePlaylistEntry(w http.ResponseWriter, r *http.Request) {
+	playlist, userID, ok := h.editablePlaylist(w, r)
+	if !ok {
+		return
+	}
+
+	entryID, err := pathID(r, "entry_id")
+	if err != nil {
+		respondJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = h.Repo.RemovePlaylistEntry(playlist.ID, entryID)
+	if err != nil {
+		respondPlaylistError(w, err)
+		return
+	}
+
+	h.respondPlaylist(w, playlist, userID)
+}
+
+// SharePlaylist godoc
+// @Summary Share a playlist
+// @Description Creates a new share token for a playlist, replacing the previous one. Anyone with the token can read the playlist.
+// @Tags playlists
+// @Produce json
+// @Security BearerAuth
+// @Param playlist_id path int true "Playlist ID"
+// @Success 200 {object} models.Playlist
+// @Failure 401 {object} JSON
+// @Failure 403 {object} JSON
+// @Failure 404 {object} JSON
+// @Failure 500 {object} JSON
+// @Router /api/playlists/{playlist_id}/share [post]
+// SharePlaylist handles the request to create a share token for a playlist.
+func (h *Handler) SharePlaylist(w http.ResponseWriter, r *http.Request) {
+	playlist, ok := h.ownPlaylist(w, r)
+	if !ok {
+		return
+	}
+
+	token, err := newShareToken()
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	err = h.Repo.SetPlaylistShareToken(playlist.ID, token)
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to share playlist: %v", err))
+		return
+	}
+
+	playlist.ShareToken = token
+	RespondJSON(w, http.StatusOK, playlist)
+}
+
+// UnsharePlaylist godoc
+// @Summary Stop sharing a playlist
+// @Description Revokes the share token of a playlist
+// @Tags playlists
+// @Produce json
+// @Security BearerAuth
+// @Param playlist_id path int true "Playlist ID"
+// @Success 200 {object} models.Playlist
+// @Failure 401 {object} JSON
+// @Failure 403 {object} JSON
+// @Failure 404 {object} JSON
+// @Failure 500 {object} JSON
+// @Router /api/playlists/{playlist_id}/share [delete]
+// UnsharePlaylist handles the request to revoke the share token of a playlist.
+func (h *Handler) UnsharePlaylist(w http.ResponseWriter, r *http.Request) {
+	playlist, ok := h.ownPlaylist(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.Repo.SetPlaylistShareToken(playlist.ID, "")
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to unshare playlist: %v", err))
+		return
+	}
+
+	playlist.ShareToken = ""
+	RespondJSON(w, http.StatusOK, playlist)
+}
+
+// DuplicatePlaylist godoc
+// @Summary Duplicate a playlist
+// @Description Copies a readable playlist with its entries to a new private playlist of the current user
+// @Tags playlists
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param playlist_id path int true "Playlist ID"
+// @Param playlist body models.DuplicatePlaylistPayload false "Name of the copy"
+// @Success 201 {object} models.Playlist
+// @Failure 400 {object} JSON
+// @Failure 401 {object} JSON
+// @Failure 404 {object} JSON
+// @Failure 500 {object} JSON
+// @Router /api/playlists/{playlist_id}/duplicate [post]
+// DuplicatePlaylist handles the request to copy a playlist.
+func (h *Handler) DuplicatePlaylist(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.requireUser(w, r)
+	if !ok {
+		return
+	}
+	playlist, _, ok := h.readablePlaylist(w, r)
+	if !ok {
+		return
+	}
+
+	var payload models.DuplicatePlaylistPayload
+	if r.ContentLength != 0 {
+		err := json.NewDecoder(r.Body).Decode(&payload)
+		if err != nil {
+			respondJSONError(w, http.StatusBadRequest, fmt.Sprintf("failed to decode playlist: %v", err))
+			return
+		}
+	}
+	if payload.Name == "" {
+		payload.Name = playlist.Name + " (copy)"
+	}
+
+	id, err := h.Repo.DuplicatePlaylist(playlist.ID, userID, payload.Name)
+	if err != nil {
+		respondPlaylistError(w, err)
+		return
+	}
+
+	copied, err := h.Repo.GetPlaylist(id)
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to retrieve playlist: %v", err))
+		return
+	}
+
+	RespondJSON(w, http.StatusCreated, copied)
+}
+
+// MergePlaylist godoc
+// @Summary Merge playlists
+// @Description Appends the entries of a readable source playlist to this playlist, optionally skipping songs it already contains
+// @Tags playlists
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param playlist_id path int true "Target playlist ID"
+// @Param merge body models.MergePlaylistPayload true "Source playlist"
+// @Success 200 {object} models.MergePlaylistResult
+// @Failure 400 {object} JSON
+// @Failure 401 {object} JSON
+// @Failure 403 {object} JSON
+// @Failure 404 {object} JSON
+// @Failure 500 {object} JSON
+// @Router /api/playlists/{playlist_id}/merge [post]
+// MergePlaylist handles the request to merge a playlist into another.
+func (h *Handler) MergePlaylist(w http.ResponseWriter, r *http.Request) {
+	target, userID, ok := h.editablePlaylist(w, r)
+	if !ok {
+		return
+	}
+
+	var payload models.MergePlaylistPayload
+
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		respondJSONError(w, http.StatusBadRequest, fmt.Sprintf("failed to decode merge: %v", err))
+		return
+	}
+	if payload.SourceID == target.ID {
+		respondJSONError(w, http.StatusBadRequest, "cannot merge a playlist into itself")
+		return
+	}
+
+	source, err := h.Repo.GetPlaylist(payload.SourceID)
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to retrieve playlist: %v", err))
+		return
+	}
+	if source == nil || !source.ReadableBy(userID) {
+		respondJSONError(w, http.StatusNotFound, fmt.Sprintf("no such playlist with playlist_id: %v", payload.SourceID))
+		return
+	}
+
+	added, skipped, err := h.Repo.MergePlaylists(target.ID, source.ID, userID, payload.SkipDuplicates)
+	if err != nil {
+		respondPlaylistError(w, err)
+		return
+	}
+
+	merged, err := h.playlistWithEntries(target.ID, userID)
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	RespondJSON(w, http.StatusOK, models.MergePlaylistResult{Added: added, Skipped: skipped, Playlist: *merged})
+}
+
+// ExportUserPlaylist godoc
+// @Summary Export a playlist
+// @Description Writes a playlist as an extended M3U (UTF-8) or XSPF file
+// @Tags playlists
+// @Produce audio/x-mpegurl
+// @Produce application/xspf+xml
+// @Security BearerAuth
+// @Param playlist_id path int true "Playlist ID"
+// @Param format query string false "Playlist format" Enums(m3u, m3u8, xspf)
+// @Success 200 {file} file
+// @Failure 400 {object} JSON
+// @Failure 404 {object} JSON
+// @Failure 500 {object} JSON
+// @Router /api/playlists/{playlist_id}/export [get]
+// ExportUserPlaylist handles the request to export a playlist as a playlist file.
+func (h *Handler) ExportUserPlaylist(w http.ResponseWriter, r *http.Request) {
+	playlist, _, ok := h.readablePlaylist(w, r)
+	if !ok {
+		return
+	}
+
+	format, ok := playlistFormat(w, r.URL.Query().Get("format"))
+	if !ok {
+		return
+	}
+
+	songs, err := h.Repo.PlaylistSongs(playlist.ID)
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to select songs from database: %v", err))
+		return
+	}
+
+	writePlaylist(w, format, playlist.Name, songs)
+}
+
+// currentUserID returns the ID of the authenticated user, or 0 for anonymous requests.
+func currentUserID(r *http.Request) int {
+	return contextUserID(r.Context())
+}
+
+// contextUserID returns the ID of the user authenticated in a context, or 0 when it is anonymous.
+func contextUserID(ctx context.Context) int {
+	principal := auth.PrincipalFromContext(ctx)
+	if principal == nil {
+		return 0
+	}
+	return principal.UserID
+}
+
+// requireUser returns the ID of an existing authenticated user, responding with an error when there is none.
+func (h *Handler) requireUser(w http.ResponseWriter, r *http.Request) (int, bool) {
+	userID := currentUserID(r)
+	if userID == 0 {
+		respondUnauthorized(w, "authentication required")
+		return 0, false
+	}
+
+	user, err := h.Repo.GetUserByID(userID)
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to retrieve user: %v", err))
+		return 0, false
+	}
+	if user == nil {
+		respondUnauthorized(w, fmt.Sprintf("no such user with user_id: %v", userID))
+		return 0, false
+	}
+
+	return userID, true
+}
+
+// readablePlaylist loads the playlist of the request if the acting user may read it.
+// Private playlists of other users are reported as missing.
+func (h *Handler) readablePlaylist(w http.ResponseWriter, r *http.Request) (*models.Playlist, int, bool) {
+	userID := currentUserID(r)
+
+	playlistID, err := pathID(r, "playlist_id")
+	if err != nil {
+		respondJSONError(w, http.StatusBadRequest, err.Error())
+		return nil, 0, false
+	}
+
+	playlist, err := h.Repo.GetPlaylist(playlistID)
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to retrieve playlist: %v", err))
+		return nil, 0, false
+	}
+	if playlist == nil || !playlist.ReadableBy(userID) {
+		respondJSONError(w, http.StatusNotFound, fmt.Sprintf("no such playlist with playlist_id: %v", playlistID))
+		return nil, 0, false
+	}
+
+	return playlist, userID, true
+}
+
+// editablePlaylist loads the playlist of the request if the acting user may change its entries.
+func (h *Handler) editablePlaylist(w http.ResponseWriter, r *http.Request) (*models.Playlist, int, bool) {
+	userID, ok := h.requireUser(w, r)
+	if !ok {
+		return nil, 0, false
+	}
+
+	playlist, _, ok := h.readablePlaylist(w, r)
+	if !ok {
+		return nil, 0, false
+	}
+	if playlist.OwnerID != userID && playlist.Visibility != models.PlaylistCollaborative {
+		respondJSONError(w, http.StatusForbidden, "only the owner can change a playlist that is not collaborative")
+		return nil, 0, false
+	}
+
+	return playlist, userID, true
+}
+
+// ownPlaylist loads the playlist of the request if the acting user owns it.
+func (h *Handler) ownPlaylist(w http.ResponseWriter, r *http.Request) (*models.Playlist, bool) {
+	userID, ok := h.requireUser(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	playlist, _, ok := h.readablePlaylist(w, r)
+	if !ok {
+		return nil, false
+	}
+	if playlist.OwnerID != userID {
+		respondJSONError(w, http.StatusForbidden, "only the owner can manage a playlist")
+		return nil, false
+	}
+
+	return playlist, true
+}
+
+// playlistWithEntries reloads a playlist together with its entries.
+func (h *Handler) playlistWithEntries(playlistID, userID int) (*models.Playlist, error) {
+	playlist, err := h.Repo.GetPlaylist(playlistID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve playlist: %v", err)
+	}
+	if playlist == nil {
+		return nil, connection.ErrPlaylistNotFound
+	}
+
+	playlist.Entries, err = h.Repo.PlaylistEntries(playlistID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve playlist entries: %v", err)
+	}
+
+	hideShareToken(playlist, userID)
+	return playlist, nil
+}
+
+// respondPlaylist responds with the current state of a playlist and its entries.
+func (h *Handler) respondPlaylist(w http.ResponseWriter, playlist *models.Playlist, userID int) {
+	current, err := h.playlistWithEntries(playlist.ID, userID)
+	if err != nil {
+		respondPlaylistError(w, err)
+		return
+	}
+
+	RespondJSON(w, http.StatusOK, current)
+}
+
+// respondPlaylistError maps the playlist errors of the repository to status codes.
+func respondPlaylistError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, connection.ErrPlaylistNotFound), errors.Is(err, connection.ErrPlaylistEntryNotFound):
+		respondJSONError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, connection.ErrInvalidPosition):
+		respondJSONError(w, http.StatusBadRequest, err.Error())
+	default:
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to update playlist: %v", err))
+	}
+}
+
+// hideShareToken clears the share token of a playlist the user does not own.
+func hideShareToken(playlist *models.Playlist, userID int) {
+	if playlist.OwnerID != userID {
+		playlist.ShareToken = ""
+	}
+}
+
+func validVisibility(visibility string) bool {
+	switch visibility {
+	case models.PlaylistPrivate, models.PlaylistPublic, models.PlaylistCollaborative:
+		return true
+	default:
+		return false
+	}
+}
+
+// newShareToken returns a random URL-safe token.
+func newShareToken() (string, error) {
+	b := make([]byte, 24)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate share token: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// parsePagination parses the optional limit and offset query parameters, which must not be negative.
+func parsePagination(query url.Values) (limit, offset int, err error) {
+	if query.Get("limit") != "" {
+		limit, err = strconv.Atoi(query.Get("limit"))
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid limit format: %v", err)
+		}
+		if limit < 0 {
+			return 0, 0, fmt.Errorf("limit must not be negative: %d", limit)
+		}
+	}
+	if query.Get("offset") != "" {
+		offset, err = strconv.Atoi(query.Get("offset"))
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid offset format: %v", err)
+		}
+		if offset < 0 {
+			return 0, 0, fmt.Errorf("offset must not be negative: %d", offset)
+		}
+	}
+	return limit, offset, nil
+}
+
+// pathID parses a numeric path variable.
+func pathID(r *http.Request, name string) (int, error) {
+	value := mux.Vars(r)[name]
+	id, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %v", name, value)
+	}
+	return id, nil
+}
@@ -0,0 +1,375 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/noctusha/music/auth"
+	"github.com/noctusha/music/connection"
+	"github.com/noctusha/music/models"
+)
+
+// SetUserRole godoc
+// @Summary Change the role of a user
+// @Description Sets the role of a user to reader, editor or admin. The new role applies at once
+// @Description to the tokens and API keys already issued.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param user_id path int true "User ID"
+// @Param role body models.RolePayload true "New role"
+// @Success 200 {object} models.User
+// @Failure 400 {object} JSON
+// @Failure 401 {object} JSON
+// @Failure 403 {object} JSON
+// @Failure 404 {object} JSON
+// @Failure 500 {object} JSON
+// @Router /api/users/{user_id}/role [put]
+// SetUserRole handles the request to change the role of a user.
+func (h *Handler) SetUserRole(w http.ResponseWriter, r *http.Request) {
+	userID, err := pathID(r, "user_id")
+	if err != nil {
+		respondJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var payload models.RolePayload
+
+	err = json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		respondJSONError(w, http.StatusBadRequest, fmt.Sprintf("failed to decode role: %v", err))
+		return
+	}
+	if !auth.ValidRole(payload.Role) {
+		respondJSONError(w, http.StatusBadRequest, fmt.Sprintf("unknown role: %q, expected one of %v", payload.Role, auth.Roles))
+		return
+	}
+
+	updated, err := h.Repo.SetUserRole(userID, payload.Role)
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to update user: %v", err))
+		return
+	}
+	if !updated {
+		respondJSONError(w, http.StatusNotFound, fmt.Sprintf("no such user with user_id: %v", userID))
+		return
+	}
+
+	user, err := h.Repo.GetUserByID(userID)
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to retrieve user: %v", err))
+		return
+	}
+
+	RespondJSON(w, http.StatusOK, user)
+}
+
+// DeleteGroup godoc
+// @Summary Delete a group
+// @Description Deletes a group together with all of its songs
+// @Tags groups
+// @Produce json
+// @Security BearerAuth
+// @Param group_id path int true "Group ID"
+// @Success 200 {object} JSON
+// @Failure 400 {object} JSON
+// @Failure 401 {object} JSON
+// @Failure 403 {object} JSON
+// @Failure 404 {object} JSON
+// @Failure 500 {object} JSON
+// @Router /api/groups/{group_id} [delete]
+// DeleteGroup handles the request to delete a group.
+func (h *Handler) DeleteGroup(w http.ResponseWriter, r *http.Request) {
+	group, ok := h.pathGroup(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.deleteGroup(group.ID)
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	RespondJSON(w, http.StatusOK, JSON{})
+}
+
+// renameGroup renames a group once the user may edit its songs, returning the status to respond with
+// when it was not renamed. The name may not be the name or an alias of another group.
+func (h *Handler) renameGroup(ctx context.Context, groupID int, name string) (*models.Group, int, error) {
+	name, err := validGroupName(name)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	group, err := h.Repo.GetGroupByID(groupID)
+	if err != nil {
+		return nil, http.StatusInternalServerError, fmt.Errorf("failed to retrieve group: %v", err)
+	}
+	if group == nil {
+		return nil, http.StatusNotFound, fmt.Errorf("no such group with group_id: %v", groupID)
+	}
+
+	status, err := h.checkGroup(ctx, group.ID, auth.PermSongsEdit)
+	if err != nil {
+		return nil, status, err
+	}
+
+	err = h.Repo.RenameGroup(group.ID, name)
+	switch {
+	case errors.Is(err, connection.ErrGroupNotFound):
+		return nil, http.StatusNotFound, err
+	case errors.Is(err, connection.ErrAliasExists):
+		return nil, http.StatusConflict, err
+	case err != nil:
+		return nil, http.StatusInternalServerError, fmt.Errorf("failed to update group: %v", err)
+	}
+	h.syncAutocomplete(group.ID)
+	h.syncSearch()
+
+	group.Name = name
+	return group, 0, nil
+}
+
+// deleteGroup deletes a group with its songs.
+func (h *Handler) deleteGroup(groupID int) error {
+	err := h.Repo.DeleteGroup(groupID)
+	if err != nil {
+		return fmt.Errorf("failed to delete group: %v", err)
+	}
+	h.syncAutocomplete(groupID)
+	h.syncSearch()
+
+	return nil
+}
+
+// ListGroupMaintainers godoc
+// @Summary Get group maintainers
+// @Description Returns the users allowed to create and edit the songs of a group
+// @Tags groups
+// @Produce json
+// @Security BearerAuth
+// @Param group_id path int true "Group ID"
+// @Success 200 {array} models.User
+// @Failure 400 {object} JSON
+// @Failure 401 {object} JSON
+// @Failure 403 {object} JSON
+// @Failure 404 {object} JSON
+// @Failure 500 {object} JSON
+// @Router /api/groups/{group_id}/maintainers [get]
+// ListGroupMaintainers handles the request to list the maintainers of a group.
+func (h *Handler) ListGroupMaintainers(w http.ResponseWriter, r *http.Request) {
+	group, ok := h.pathGroup(w, r)
+	if !ok {
+		return
+	}
+
+	users, err := h.Repo.GroupMaintainers(group.ID)
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to select maintainers from database: %v", err))
+		return
+	}
+
+	RespondJSON(w, http.StatusOK, users)
+}
+
+// AddGroupMaintainer godoc
+// @Summary Add a group maintainer
+// @Description Allows an editor to create and edit the songs of a group
+// @Tags groups
+// @Produce json
+// @Security BearerAuth
+// @Param group_id path int true "Group ID"
+// @Param user_id path int true "User ID"
+// @Success 200 {array} models.User
+// @Failure 400 {object} JSON
+// @Failure 401 {object} JSON
+// @Failure 403 {object} JSON
+// @Failure 404 {object} JSON
+// @Failure 500 {object} JSON
+// @Router /api/groups/{group_id}/maintainers/{user_id} [put]
+// AddGroupMaintainer handles the request to add a maintainer to a group.
+func (h *Handler) AddGroupMaintainer(w http.ResponseWriter, r *http.Request) {
+	group, ok := h.pathGroup(w, r)
+	if !ok {
+		return
+	}
+
+	userID, err := pathID(r, "user_id")
+	if err != nil {
+		respondJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	user, err := h.Repo.GetUserByID(userID)
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to retrieve user: %v", err))
+		return
+	}
+	if user == nil {
+		respondJSONError(w, http.StatusNotFound, fmt.Sprintf("no such user with user_id: %v", userID))
+		return
+	}
+
+	err = h.Repo.AddGroupMaintainer(group.ID, userID)
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to add maintainer: %v", err))
+		return
+	}
+
+	h.ListGroupMaintainers(w, r)
+}
+
+// RemoveGroupMaintainer godoc
+// @Summary Remove a group maintainer
+// @Description Revokes the right of a user to create and edit the songs of a group
+// @Tags groups
+// @Produce json
+// @Security BearerAuth
+// @Param group_id path int true "Group ID"
+// @Param user_id path int true "User ID"
+// @Success 200 {array} models.User
+// @Failure 400 {object} JSON
+// @Failure 401 {object} JSON
+// @Failure 403 {object} JSON
+// @Failure 404 {object} JSON
+// @Failure 500 {object} JSON
+// @Router /api/groups/{group_id}/maintainers/{user_id} [delete]
+// RemoveGroupMaintainer handles the request to remove a maintainer from a group.
+func (h *Handler) RemoveGroupMaintainer(w http.ResponseWriter, r *http.Request) {
+	group, ok := h.pathGroup(w, r)
+	if !ok {
+		return
+	}
+
+	userID, err := pathID(r, "user_id")
+	if err != nil {
+		respondJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = h.Repo.RemoveGroupMaintainer(group.ID, userID)
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to remove maintainer: %v", err))
+		return
+	}
+
+	h.ListGroupMaintainers(w, r)
+}
+
+// pathGroup loads the group in the group_id path variable, responding with 404 when it does not exist
+// and redirecting to the group a merged group was merged into.
+func (h *Handler) pathGroup(w http.ResponseWriter, r *http.Request) (*models.Group, bool) {
+	groupID, err := pathID(r, "group_id")
+	if err != nil {
+		respondJSONError(w, http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+
+	group, err := h.Repo.GetGroupByID(groupID)
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to retrieve group: %v", err))
+		return nil, false
+	}
+	if group == nil {
+		redirectID, err := h.Repo.GroupRedirect(groupID)
+		if err != nil {
+			respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to retrieve group: %v", err))
+			return nil, false
+		}
+		if redirectID == 0 {
+			respondJSONError(w, http.StatusNotFound, fmt.Sprintf("no such group with group_id: %v", groupID))
+			return nil, false
+		}
+
+		// The group was merged into another one: send the client there, keeping the method.
+		location := *r.URL
+		location.Path = strings.Replace(r.URL.Path, fmt.Sprintf("/groups/%d", groupID), fmt.Sprintf("/groups/%d", redirectID), 1)
+		http.Redirect(w, r, location.RequestURI(), http.StatusPermanentRedirect)
+		return nil, false
+	}
+
+	return group, true
+}
+
+// groupByID retrieves a group by ID, or nil when there is none. A merged group resolves to the group
+// it was merged into.
+func (h *Handler) groupByID(groupID int) (*models.Group, error) {
+	group, err := h.Repo.GetGroupByID(groupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve group: %v", err)
+	}
+	if group != nil {
+		return group, nil
+	}
+
+	redirectID, err := h.Repo.GroupRedirect(groupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve group: %v", err)
+	}
+	if redirectID == 0 {
+		return nil, nil
+	}
+
+	group, err = h.Repo.GetGroupByID(redirectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve group: %v", err)
+	}
+	return group, nil
+}
+
+// requireGroup checks that the user of a request may use a permission on the songs of a
+// group: admins may on every group, editors only on the groups they maintain.
+func (h *Handler) requireGroup(w http.ResponseWriter, r *http.Request, groupID int, permission auth.Permission) bool {
+	status, err := h.checkGroup(r.Context(), groupID, permission)
+	if err != nil {
+		if status == http.StatusUnauthorized {
+			respondUnauthorized(w, err.Error())
+		} else {
+			respondJSONError(w, status, err.Error())
+		}
+		return false
+	}
+	return true
+}
+
+// checkPermission is RequirePermission without the response: it returns the status to respond with
+// when the user of a context lacks a permission.
+func checkPermission(ctx context.Context, permission auth.Permission) (int, error) {
+	principal := auth.PrincipalFromContext(ctx)
+	if principal == nil {
+		return http.StatusUnauthorized, fmt.Errorf("authentication required")
+	}
+	if !principal.Can(permission) {
+		return http.StatusForbidden, fmt.Errorf("missing permission: %s", permission)
+	}
+	return 0, nil
+}
+
+// checkGroup is requireGroup without the response: it returns the status to respond with when the check fails.
+func (h *Handler) checkGroup(ctx context.Context, groupID int, permission auth.Permission) (int, error) {
+	status, err := checkPermission(ctx, permission)
+	if err != nil {
+		return status, err
+	}
+
+	principal := auth.PrincipalFromContext(ctx)
+	if principal.Can(auth.PermGroupsAny) {
+		return 0, nil
+	}
+
+	maintainer, err := h.Repo.IsGroupMaintainer(groupID, principal.UserID)
+	if err != nil {
+		return http.StatusInternalServerError, fmt.Errorf("failed to retrieve group maintainers: %v", err)
+	}
+	if !maintainer {
+		return http.StatusForbidden, fmt.Errorf("missing permission: %s on group %d, the user does not maintain it", permission, groupID)
+	}
+
+	return 0, nil
+}
@@ -0,0 +1,278 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/noctusha/music/auth"
+	"github.com/noctusha/music/models"
+	"github.com/noctusha/music/playlist"
+)
+
+// scrobbleClockSkew is how far in the future a scrobble may be, to allow for the clocks of players.
+const scrobbleClockSkew = 5 * time.Minute
+
+// Scrobble godoc
+// @Summary Scrobble plays
+// @Description Records a batch of up to 50 plays of the current user. Songs are given by ID or by group and
+// @Description title; titles are matched against the songs of their group like playlist entries, or by name
+// @Description regardless of case and punctuation when no group is given. With create unmatched
+// @Description songs are added through the same flow as /api/songs/new, which needs the songs:create permission.
+// @Description Plays are identified by song and time to the second, so resubmitted scrobbles are reported as duplicates.
+// @Tags scrobbles
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param create query bool false "Create unmatched songs"
+// @Param scrobbles body models.ScrobblePayload true "Plays"
+// @Success 200 {object} models.ScrobbleReport
+// @Failure 400 {object} JSON
+// @Failure 401 {object} JSON
+// @Failure 403 {object} JSON
+// @Failure 500 {object} JSON
+// @Router /api/scrobbles [post]
+// Scrobble handles the request to record a batch of plays.
+func (h *Handler) Scrobble(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	create := false
+	var err error
+	if r.URL.Query().Get("create") != "" {
+		create, err = strconv.ParseBool(r.URL.Query().Get("create"))
+		if err != nil {
+			respondJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid create format: %v", err))
+			return
+		}
+	}
+	if create && !auth.PrincipalFromContext(r.Context()).Can(auth.PermSongsCreate) {
+		respondForbidden(w, fmt.Sprintf("missing permission: %s", auth.PermSongsCreate))
+		return
+	}
+
+	var payload models.ScrobblePayload
+
+	err = json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		respondJSONError(w, http.StatusBadRequest, fmt.Sprintf("failed to decode scrobbles: %v", err))
+		return
+	}
+	if len(payload.Scrobbles) == 0 {
+		respondJSONError(w, http.StatusBadRequest, "no scrobbles")
+		return
+	}
+	if len(payload.Scrobbles) > models.MaxScrobbles {
+		respondJSONError(w, http.StatusBadRequest, fmt.Sprintf("too many scrobbles: %d, at most %d are accepted at once", len(payload.Scrobbles), models.MaxScrobbles))
+		return
+	}
+
+	// The songs given by ID are selected at once, and the titles are looked up per scrobble.
+	var ids []int
+	for _, scrobble := range payload.Scrobbles {
+		if scrobble.SongID != 0 {
+			ids = append(ids, scrobble.SongID)
+		}
+	}
+	byID := make(map[int]models.SongName, len(ids))
+	if len(ids) > 0 {
+		names, err := h.Repo.SongNamesByIDs(ids)
+		if err != nil {
+			respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to select songs from database: %v", err))
+			return
+		}
+		for _, name := range names {
+			byID[name.ID] = name
+		}
+	}
+
+	// Resolvers over the songs of the groups scrobbled in this batch, by group id.
+	resolvers := make(map[int]*playlist.Resolver)
+
+	// Songs created for this batch, so a song scrobbled twice is only created once.
+	created := make(map[string]models.SongName)
+
+	report := models.ScrobbleReport{Results: make([]models.ScrobbleResult, len(payload.Scrobbles))}
+	var plays []models.Play
+	var playResults []int
+
+	now := time.Now()
+	for i, scrobble := range payload.Scrobbles {
+		result := &report.Results[i]
+		result.Index = i
+
+		switch {
+		case scrobble.PlayedAt.IsZero():
+			result.Status, result.Error = models.ScrobbleInvalid, "no played_at"
+		case scrobble.PlayedAt.After(now.Add(scrobbleClockSkew)):
+			result.Status, result.Error = models.ScrobbleInvalid, "played_at is in the future"
+		case scrobble.Duration < 0:
+			result.Status, result.Error = models.ScrobbleInvalid, "negative duration"
+		case scrobble.SongID != 0:
+			name, ok := byID[scrobble.SongID]
+			if !ok {
+				result.Status, result.Error = models.ScrobbleUnmatched, fmt.Sprintf("no such song with song_id: %v", scrobble.SongID)
+				break
+			}
+			result.Status, result.SongID, result.Group, result.Song = models.ScrobbleAccepted, name.ID, name.Group, name.Name
+		case scrobble.Title == "":
+			result.Status, result.Error = models.ScrobbleInvalid, "no song_id or title"
+		default:
+			match, err := h.matchScrobble(scrobble.Group, scrobble.Title, resolvers)
+			if err != nil {
+				respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to select songs from database: %v", err))
+				return
+			}
+			if match.SongID != 0 {
+				result.Status, result.SongID, result.Group, result.Song, result.Score = models.ScrobbleAccepted, match.SongID, match.MatchedGroup, match.MatchedSong, match.Score
+				break
+			}
+
+			key := strings.ToLower(scrobble.Group) + "\x00" + strings.ToLower(scrobble.Title)
+			if name, ok := created[key]; ok {
+				result.Status, result.SongID, result.Group, result.Song = models.ScrobbleCreated, name.ID, name.Group, name.Name
+				break
+			}
+			if !create || scrobble.Group == "" {
+				result.Status = models.ScrobbleUnmatched
+				break
+			}
+
+			song, status, err := h.createSong(r.Context(), scrobble.Group, scrobble.Title)
+			if status == http.StatusConflict {
+				// The resolver missed a song whose name differs only in punctuation.
+				result.Status, result.SongID, result.Group, result.Song = models.ScrobbleAccepted, song.ID, scrobble.Group, song.Name
+				break
+			}
+			if err != nil {
+				result.Status, result.Error = models.ScrobbleUnmatched, err.Error()
+				break
+			}
+			created[key] = models.SongName{ID: song.ID, GroupID: song.GroupID, Group: scrobble.Group, Name: song.Name}
+			delete(resolvers, song.GroupID)
+			result.Status, result.SongID, result.Group, result.Song = models.ScrobbleCreated, song.ID, scrobble.Group, song.Name
+		}
+
+		if result.SongID != 0 {
+			plays = append(plays, models.Play{SongID: result.SongID, PlayedAt: scrobble.PlayedAt.Truncate(time.Second), Duration: scrobble.Duration})
+			playResults = append(playResults, i)
+		}
+	}
+
+	inserted, err := h.Repo.RecordPlays(userID, plays)
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to record plays: %v", err))
+		return
+	}
+	for j, ok := range inserted {
+		if !ok {
+			report.Results[playResults[j]].Status = models.ScrobbleDuplicate
+		}
+	}
+
+	for _, result := range report.Results {
+		switch result.Status {
+		case models.ScrobbleAccepted:
+			report.Accepted++
+		case models.ScrobbleCreated:
+			report.Accepted++
+			report.Created++
+		case models.ScrobbleDuplicate:
+			report.Duplicates++
+		case models.ScrobbleUnmatched:
+			report.Unmatched++
+		case models.ScrobbleInvalid:
+			report.Invalid++
+		}
+	}
+
+	RespondJSON(w, http.StatusOK, report)
+}
+
+// matchScrobble matches the group and title of a scrobble. The title is matched against the songs of the
+// group like a playlist entry, or by name among all songs when there is no group. resolvers keeps the
+// resolvers of the groups already matched, by group id.
+func (h *Handler) matchScrobble(group, title string, resolvers map[int]*playlist.Resolver) (models.PlaylistEntryResult, error) {
+	if group == "" {
+		// Bracketed remarks and guest artists are ignored as they are by the resolver.
+		names, err := h.Repo.SongNamesByName(title, playlist.Normalize(title))
+		if err != nil || len(names) == 0 {
+			return models.PlaylistEntryResult{}, err
+		}
+		return models.PlaylistEntryResult{SongID: names[0].ID, MatchedGroup: names[0].Group, MatchedSong: names[0].Name, Score: 1}, nil
+	}
+
+	groupID, err := h.Repo.GetGroupID(group)
+	if err != nil || groupID == 0 {
+		return models.PlaylistEntryResult{}, err
+	}
+
+	resolver, ok := resolvers[groupID]
+	if !ok {
+		names, err := h.Repo.GroupSongNames(groupID)
+		if err != nil {
+			return models.PlaylistEntryResult{}, err
+		}
+		resolver = playlist.NewResolver(names)
+		resolvers[groupID] = resolver
+	}
+
+	// The group is known, so only the titles are compared.
+	return resolver.Match("", title), nil
+}
+
+// ListHistory godoc
+// @Summary Get listening history
+// @Description Returns the plays of the current user, the latest first
+// @Tags scrobbles
+// @Produce json
+// @Security BearerAuth
+// @Param from query string false "Start of the period, RFC 3339"
+// @Param to query string false "End of the period, RFC 3339, exclusive"
+// @Param limit query int false "Limit"
+// @Param offset query int false "Offset"
+// @Success 200 {array} models.Play
+// @Failure 400 {object} JSON
+// @Failure 401 {object} JSON
+// @Failure 500 {object} JSON
+// @Router /api/history [get]
+// ListHistory handles the request to list the plays of the current user.
+func (h *Handler) ListHistory(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+
+	limit, offset, err := parsePagination(query)
+	if err != nil {
+		respondJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var period [2]time.Time
+	for i, parameter := range []string{"from", "to"} {
+		if query.Get(parameter) == "" {
+			continue
+		}
+		period[i], err = time.Parse(time.RFC3339, query.Get(parameter))
+		if err != nil {
+			respondJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid %s format: %v", parameter, err))
+			return
+		}
+	}
+
+	plays, err := h.Repo.ListPlays(userID, period[0], period[1], limit, offset)
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to select plays from database: %v", err))
+		return
+	}
+
+	RespondJSON(w, http.StatusOK, plays)
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// maxSearchLimit is the largest page of full-text search results.
+const maxSearchLimit = 100
+
+// Search godoc
+// @Summary Search songs
+// @Description Searches group names, song names and lyrics for the words of q, in English and Russian
+// @Description regardless of their forms: "running" finds "run" and "песни" finds "песня". Songs containing
+// @Description more of the rarer words come first, a match in the song name counting most and one in the
+// @Description lyrics least. Words in double quotes must appear together as a phrase in one of these fields.
+// @Tags search
+// @Produce json
+// @Param q query string true "Words to search, phrases in double quotes"
+// @Param limit query int false "Number of results, from 1 to 100, 20 by default"
+// @Param offset query int false "Number of results to skip"
+// @Success 200 {object} models.SearchResults
+// @Failure 400 {object} JSON
+// @Failure 500 {object} JSON
+// @Router /api/search [get]
+// Search handles the request to search songs by their names and lyrics.
+func (h *Handler) Search(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	q := strings.TrimSpace(query.Get("q"))
+	if q == "" {
+		respondJSONError(w, http.StatusBadRequest, "missing search query")
+		return
+	}
+
+	limit := 20
+	if value := query.Get("limit"); value != "" {
+		var err error
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxSearchLimit {
+			respondJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid limit: %v, expected 1 to %d", value, maxSearchLimit))
+			return
+		}
+	}
+
+	offset := 0
+	if value := query.Get("offset"); value != "" {
+		var err error
+		offset, err = strconv.Atoi(value)
+		if err != nil || offset < 0 {
+			respondJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid offset: %v", value))
+			return
+		}
+	}
+
+	results, err := h.Fulltext.Search(q, limit, offset)
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to search songs: %v", err))
+		return
+	}
+
+	RespondJSON(w, http.StatusOK, results)
+}
+
+// syncSearch updates the full-text index in the background after songs were created, changed or
+// deleted. Without songs it syncs the whole index, for changes of groups or many songs.
+func (h *Handler) syncSearch(songIDs ...int) {
+	go func() {
+		if len(songIDs) == 0 {
+			err := h.Fulltext.Sync()
+			if err != nil {
+				log.Printf("error syncing search index: %v", err)
+			}
+			return
+		}
+
+		err := h.Fulltext.Update(songIDs...)
+		if err != nil {
+			log.Printf("error updating songs %v in search index: %v", songIDs, err)
+		}
+	}()
+}
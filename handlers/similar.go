@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/noctusha/music/models"
+)
+
+// GetSimilarSongs godoc
+// @Summary Get similar songs
+// @Description Returns the songs most similar to a song. The score combines the TF-IDF cosine similarity
+// @Description of the lyrics (80%) with a shared group (10%) and a release within 5 years (10%).
+// @Tags songs
+// @Produce json
+// @Param song_id path int true "Song ID"
+// @Param limit query int false "Number of songs, 10 by default"
+// @Success 200 {array} models.SimilarSong
+// @Failure 400 {object} JSON
+// @Failure 404 {object} JSON
+// @Failure 500 {object} JSON
+// @Router /api/songs/{song_id}/similar [get]
+// GetSimilarSongs handles the request to get the songs similar to a song.
+func (h *Handler) GetSimilarSongs(w http.ResponseWriter, r *http.Request) {
+	songID, err := pathID(r, "song_id")
+	if err != nil {
+		respondJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	limit := 10
+	if value := r.URL.Query().Get("limit"); value != "" {
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > 100 {
+			respondJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid limit: %v, expected 1 to 100", value))
+			return
+		}
+	}
+
+	song, err := h.Repo.GetSongByID(strconv.Itoa(songID))
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to retrieve song: %v", err))
+		return
+	}
+	if song == nil {
+		respondJSONError(w, http.StatusNotFound, fmt.Sprintf("no such song with song_id: %v", songID))
+		return
+	}
+
+	similar, err := h.Similar.Similar(songID, limit)
+	if err == nil && similar == nil {
+		// The song was added after the last sync.
+		err = h.Similar.Sync()
+		if err == nil {
+			similar, err = h.Similar.Similar(songID, limit)
+		}
+	}
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to find similar songs: %v", err))
+		return
+	}
+
+	ids := make([]int, len(similar))
+	for i, s := range similar {
+		ids[i] = s.SongID
+	}
+	songs, err := h.Repo.DetailedSongsByIDs(ids)
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to select songs from database: %v", err))
+		return
+	}
+
+	names := make(map[int]int, len(songs))
+	for i, s := range songs {
+		names[s.Song.ID] = i
+	}
+	for i := range similar {
+		if j, ok := names[similar[i].SongID]; ok {
+			similar[i].Group = songs[j].Group
+			similar[i].Song = songs[j].Song.Name
+		}
+	}
+	if similar == nil {
+		similar = []models.SimilarSong{}
+	}
+
+	RespondJSON(w, http.StatusOK, similar)
+}
+
+// syncSimilar recounts the terms of changed lyrics in the background.
+func (h *Handler) syncSimilar() {
+	go func() {
+		err := h.Similar.Sync()
+		if err != nil {
+			log.Printf("error syncing similar songs: %v", err)
+		}
+	}()
+}
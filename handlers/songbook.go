@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/noctusha/music/models"
+	"github.com/noctusha/music/songbook"
+)
+
+// Songbook godoc
+// @Summary Export a songbook
+// @Description Renders a songbook with table of contents, group index and one song per page.
+// @Description Songs are selected by ids, by a playlist in its order or by the ListSongs filters. Private
+// @Description playlists can only be exported by their owner.
+// @Tags songbook
+// @Produce html
+// @Produce application/pdf
+// @Param format query string false "Output format" Enums(html, pdf)
+// @Param title query string false "Songbook title"
+// @Param ids query string false "Comma separated song ids, in songbook order"
+// @Param playlist_id query int false "Playlist ID; the title defaults to the playlist name"
+// @Param group query string false "Group name or alias, also matched regardless of accents and Cyrillic or Latin spelling"
+// @Param name query string false "Song name, also matched regardless of accents and Cyrillic or Latin spelling"
+// @Param releaseDate query string false "Release date"
+// @Param text query string false "Song text"
+// @Param link query string false "Song link"
+// @Param favoritesOnly query bool false "Only the favorites of the current user"
+// @Param minRating query number false "Minimal average rating, from 1 to 5"
+// @Param excludeExplicit query bool false "Leave out explicit songs"
+// @Param tags query string false "Comma-separated tags of the song or its group"
+// @Param moods query string false "Comma-separated moods of the song or its group"
+// @Param tagMatch query string false "Whether songs need all the tags and moods or any of them, all by default" Enums(all, any)
+// @Param genre query string false "Genre, including its subgenres and the genres inherited from the group"
+// @Param sort query string false "Sort order, name by default" Enums(name, popularity, rating)
+// @Param limit query int false "Limit"
+// @Param offset query int false "Offset"
+// @Success 200 {file} file
+// @Failure 400 {object} JSON
+// @Failure 404 {object} JSON
+// @Failure 500 {object} JSON
+// @Router /api/songbook [get]
+// Songbook handles the request to export songs as an HTML or PDF songbook.
+func (h *Handler) Songbook(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	format := query.Get("format")
+	if format == "" {
+		format = songbook.FormatHTML
+	}
+	if format != songbook.FormatHTML && format != songbook.FormatPDF {
+		respondJSONError(w, http.StatusBadRequest, fmt.Sprintf("unsupported format: %v", format))
+		return
+	}
+
+	filter, err := parseSongFilter(r, "format", "title", "ids", "playlist_id")
+	if err != nil {
+		respondJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	title := query.Get("title")
+
+	var songs []models.DetailedSong
+	switch {
+	case query.Get("playlist_id") != "":
+		playlistID, err := strconv.Atoi(query.Get("playlist_id"))
+		if err != nil {
+			respondJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid playlist_id format: %v", err))
+			return
+		}
+		playlist, err := h.Repo.GetPlaylist(playlistID)
+		if err != nil {
+			respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to retrieve playlist: %v", err))
+			return
+		}
+		if playlist == nil || !playlist.ReadableBy(currentUserID(r)) {
+			respondJSONError(w, http.StatusNotFound, fmt.Sprintf("no such playlist with playlist_id: %v", playlistID))
+			return
+		}
+		if title == "" {
+			title = playlist.Name
+		}
+		songs, err = h.Repo.PlaylistSongs(playlist.ID)
+		if err != nil {
+			respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to select songs from database: %v", err))
+			return
+		}
+	case query.Get("ids") != "":
+		ids, err := ParseIDs(query.Get("ids"))
+		if err != nil {
+			respondJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		songs, err = h.Repo.DetailedSongsByIDs(ids)
+		if err != nil {
+			respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to select songs from database: %v", err))
+			return
+		}
+	default:
+		if filter.Limit == 0 {
+			filter.Limit = songbook.MaxSongs
+		}
+		songs, err = h.Repo.DetailedSongList(filter)
+		if err != nil {
+			respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to select songs from database: %v", err))
+			return
+		}
+	}
+
+	if len(songs) == 0 {
+		respondJSONError(w, http.StatusNotFound, "no songs found for the songbook")
+		return
+	}
+
+	book := songbook.New(title, songs)
+	book.FontPath = os.Getenv("SONGBOOK_FONT")
+
+	var buf bytes.Buffer
+	err = book.Write(&buf, format)
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to render songbook: %v", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", songbook.ContentType(format))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"songbook.%s\"", format))
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(buf.Bytes())
+	if err != nil {
+		log.Printf("error writing songbook: %v", err)
+	}
+}
+
+// ParseIDs parses a comma separated list of song ids.
+func ParseIDs(value string) ([]int, error) {
+	var ids []int
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid id %q: %v", part, err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
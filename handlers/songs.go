@@ -0,0 +1,357 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/noctusha/music/auth"
+	"github.com/noctusha/music/models"
+)
+
+// Resources of a song that include can embed.
+const (
+	includeGroup   = "group"
+	includeDetails = "details"
+	includeCredits = "credits"
+)
+
+// maxCredits is the largest number of credits of a song.
+const maxCredits = 100
+
+// songFields are the paths that fields can select in a song, such as "name" or "group.name".
+var songFields = jsonFields(reflect.TypeOf(models.SongResource{}), "", map[string]bool{})
+
+// GetSong godoc
+// @Summary Get a song
+// @Description Returns a song with its stats and the resources given in include: its group, details and
+// @Description credits, the group and details by default. Fields selects the fields of the response by their
+// @Description paths, such as "name,group.name,details.release_date"; the id is always returned, and a field
+// @Description of a resource includes it.
+// @Tags songs
+// @Produce json
+// @Param song_id path int true "Song ID"
+// @Param include query string false "Comma-separated resources to embed: group, details, credits"
+// @Param fields query string false "Comma-separated fields to return"
+// @Success 200 {object} models.SongResource
+// @Failure 400 {object} JSON
+// @Failure 404 {object} JSON
+// @Failure 500 {object} JSON
+// @Router /api/songs/{song_id} [get]
+// @Router /api/v2/songs/{song_id} [get]
+// GetSong handles the request to get a song with its related resources.
+func (h *Handler) GetSong(w http.ResponseWriter, r *http.Request) {
+	include, fields, err := parseSongResource(r, []string{includeGroup, includeDetails})
+	if err != nil {
+		respondJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	song, ok := h.pathSong(w, r)
+	if !ok {
+		return
+	}
+
+	song.Stats, err = h.Repo.SongStats(song.ID, currentUserID(r))
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to retrieve song stats: %v", err))
+		return
+	}
+
+	resources, err := h.songResources([]models.Song{*song}, include)
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondFields(w, http.StatusOK, resources[0], fields, "")
+}
+
+// SetSongCredits godoc
+// @Summary Change the credits of a song
+// @Description Replaces the credits of a song with the given ones, in their order. Editors can only change
+// @Description the songs of the groups they maintain.
+// @Tags songs
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param song_id path int true "Song ID"
+// @Param credits body models.SongCreditsPayload true "Credits"
+// @Success 200 {array} models.SongCredit
+// @Failure 400 {object} JSON
+// @Failure 401 {object} JSON
+// @Failure 403 {object} JSON
+// @Failure 404 {object} JSON
+// @Failure 500 {object} JSON
+// @Router /api/songs/{song_id}/credits [put]
+// SetSongCredits handles the request to change the credits of a song.
+func (h *Handler) SetSongCredits(w http.ResponseWriter, r *http.Request) {
+	song, ok := h.pathSong(w, r)
+	if !ok {
+		return
+	}
+
+	var payload models.SongCreditsPayload
+
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		respondJSONError(w, http.StatusBadRequest, fmt.Sprintf("failed to decode credits: %v", err))
+		return
+	}
+
+	if len(payload.Credits) > maxCredits {
+		respondJSONError(w, http.StatusBadRequest, fmt.Sprintf("too many credits: %d, at most %d", len(payload.Credits), maxCredits))
+		return
+	}
+	for i, credit := range payload.Credits {
+		credit.Name = strings.TrimSpace(credit.Name)
+		if credit.Name == "" || utf8.RuneCountInString(credit.Name) > 255 {
+			respondJSONError(w, http.StatusBadRequest, "credit name must be 1 to 255 characters")
+			return
+		}
+		switch credit.Role {
+		case models.CreditPerformer, models.CreditFeaturing, models.CreditWriter, models.CreditComposer, models.CreditLyricist, models.CreditProducer:
+		default:
+			respondJSONError(w, http.StatusBadRequest, fmt.Sprintf("unknown credit role: %v", credit.Role))
+			return
+		}
+		payload.Credits[i] = credit
+	}
+
+	if !h.requireGroup(w, r, song.GroupID, auth.PermSongsEdit) {
+		return
+	}
+
+	err = h.Repo.SetSongCredits(song.ID, payload.Credits)
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to update credits: %v", err))
+		return
+	}
+
+	credits, err := h.Repo.SongCredits([]int{song.ID})
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to retrieve credits: %v", err))
+		return
+	}
+
+	RespondJSON(w, http.StatusOK, append([]models.SongCredit{}, credits[song.ID]...))
+}
+
+// parseSongResource reads the include and fields parameters of a song resource. Without include, the
+// resources in defaults are included. A field of a resource includes it.
+func parseSongResource(r *http.Request, defaults []string) (map[string]bool, []string, error) {
+	query := r.URL.Query()
+
+	include := make(map[string]bool)
+	names := defaults
+	if query.Has("include") {
+		names = splitList(query.Get("include"))
+	}
+	for _, name := range names {
+		switch name {
+		case includeGroup, includeDetails, includeCredits:
+			include[name] = true
+		default:
+			return nil, nil, fmt.Errorf("unknown include: %v, expected group, details or credits", name)
+		}
+	}
+
+	fields := splitList(query.Get("fields"))
+	for _, field := range fields {
+		if !songFields[field] {
+			return nil, nil, fmt.Errorf("unknown field: %v", field)
+		}
+		resource, _, _ := strings.Cut(field, ".")
+		switch resource {
+		case includeGroup, includeDetails, includeCredits:
+			include[resource] = true
+		}
+	}
+
+	return include, fields, nil
+}
+
+// splitList splits a comma-separated parameter, dropping empty items.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// songResources embeds the included resources in songs, with one query per resource for all of them.
+func (h *Handler) songResources(songs []models.Song, include map[string]bool) ([]models.SongResource, error) {
+	resources := make([]models.SongResource, len(songs))
+	ids := make([]int, len(songs))
+	for i, song := range songs {
+		resources[i].Song = song
+		ids[i] = song.ID
+	}
+	if len(songs) == 0 {
+		return resources, nil
+	}
+
+	if include[includeGroup] || include[includeDetails] {
+		detailed, err := h.Repo.DetailedSongsByIDs(ids)
+		if err != nil {
+			return nil, fmt.Errorf("failed to retrieve song details: %v", err)
+		}
+
+		byID := make(map[int]models.DetailedSong, len(detailed))
+		for _, song := range detailed {
+			byID[song.Song.ID] = song
+		}
+
+		for i := range resources {
+			song, ok := byID[resources[i].ID]
+			if !ok {
+				continue
+			}
+			if include[includeGroup] {
+				resources[i].Group = &models.Group{ID: song.Song.GroupID, Name: song.Group}
+			}
+			if include[includeDetails] {
+				details := song.SongDetails
+				resources[i].Details = &details
+			}
+		}
+	}
+
+	if include[includeCredits] {
+		credits, err := h.Repo.SongCredits(ids)
+		if err != nil {
+			return nil, fmt.Errorf("failed to retrieve credits: %v", err)
+		}
+
+		for i := range resources {
+			songCredits := append([]models.SongCredit{}, credits[resources[i].ID]...)
+			resources[i].Credits = &songCredits
+		}
+	}
+
+	return resources, nil
+}
+
+// respondFields responds with the payload reduced to the given fields, or whole without fields.
+// With a key, the fields are selected in each item of the list under that key instead.
+func respondFields(w http.ResponseWriter, statusCode int, payload interface{}, fields []string, key string) {
+	if len(fields) == 0 {
+		RespondJSON(w, statusCode, payload)
+		return
+	}
+
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to encode response: %v", err))
+		return
+	}
+
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+	err = decoder.Decode(&value)
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to decode response: %v", err))
+		return
+	}
+
+	// The id identifies the song, so it is always kept.
+	tree := fieldTree{"id": nil}
+	for _, field := range fields {
+		tree.add(strings.Split(field, "."))
+	}
+
+	if object, ok := value.(map[string]interface{}); ok && key != "" {
+		object[key] = tree.selectIn(object[key])
+	} else {
+		value = tree.selectIn(value)
+	}
+
+	RespondJSON(w, statusCode, value)
+}
+
+// fieldTree holds selected field paths by their names; a nil subtree selects the whole field.
+type fieldTree map[string]fieldTree
+
+func (t fieldTree) add(path []string) {
+	subtree, ok := t[path[0]]
+	if len(path) == 1 {
+		t[path[0]] = nil
+		return
+	}
+	if ok && subtree == nil {
+		// The whole field is already selected.
+		return
+	}
+	if subtree == nil {
+		subtree = fieldTree{}
+		t[path[0]] = subtree
+	}
+	subtree.add(path[1:])
+}
+
+// selectIn keeps the selected fields of a decoded JSON object, or of each object of a list.
+func (t fieldTree) selectIn(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for name, field := range value {
+			subtree, ok := t[name]
+			switch {
+			case !ok:
+				delete(value, name)
+			case subtree != nil:
+				value[name] = subtree.selectIn(field)
+			}
+		}
+		return value
+	case []interface{}:
+		for i, item := range value {
+			value[i] = t.selectIn(item)
+		}
+		return value
+	}
+	return value
+}
+
+// jsonFields adds to paths the dotted paths of the JSON fields of a type, through pointers, slices
+// and embedded structs.
+func jsonFields(t reflect.Type, prefix string, paths map[string]bool) map[string]bool {
+	for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return paths
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" || !field.IsExported() {
+			continue
+		}
+		if field.Anonymous && name == "" {
+			jsonFields(field.Type, prefix, paths)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		path := prefix + name
+		paths[path] = true
+		if !slices.Contains([]reflect.Kind{reflect.Pointer, reflect.Slice, reflect.Struct}, field.Type.Kind()) {
+			continue
+		}
+		jsonFields(field.Type, path+".", paths)
+	}
+	return paths
+}
@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/noctusha/music/models"
+)
+
+// GetStats godoc
+// @Summary Get catalogue statistics
+// @Description Returns the number of songs and groups, lyrics coverage, songs without links or albums,
+// @Description and the songs per group, release year and decade
+// @Tags stats
+// @Produce json
+// @Param groups query int false "Number of groups with the most songs, 20 by default"
+// @Success 200 {object} models.CatalogueStats
+// @Failure 400 {object} JSON
+// @Failure 500 {object} JSON
+// @Router /api/stats [get]
+// GetStats handles the request to get the statistics of the catalogue.
+func (h *Handler) GetStats(w http.ResponseWriter, r *http.Request) {
+	groups := 20
+	if value := r.URL.Query().Get("groups"); value != "" {
+		var err error
+		groups, err = strconv.Atoi(value)
+		if err != nil || groups < 0 {
+			respondJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid groups: %v", value))
+			return
+		}
+	}
+
+	stats, err := h.Repo.CatalogueStats(groups)
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to compute statistics: %v", err))
+		return
+	}
+
+	RespondJSON(w, http.StatusOK, stats)
+}
+
+// TopPlayed godoc
+// @Summary Get the most played songs
+// @Description Ranks the songs by plays in the day, ISO week or month containing date (UTC).
+// @Description Charts are refreshed periodically, so the latest plays may be missing.
+// @Tags stats
+// @Produce json
+// @Param period query string false "Period, week by default" Enums(day, week, month)
+// @Param date query string false "A date in the period, YYYY-MM-DD, today by default"
+// @Param limit query int false "Number of songs, 10 by default"
+// @Success 200 {object} models.Chart
+// @Failure 400 {object} JSON
+// @Failure 500 {object} JSON
+// @Router /api/charts/top-played [get]
+// TopPlayed handles the request to get the chart of the most played songs.
+func (h *Handler) TopPlayed(w http.ResponseWriter, r *http.Request) {
+	chart, limit, err := parseChart(r.URL.Query(), "top-played")
+	if err != nil {
+		respondJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	chart.Entries, err = h.Repo.TopPlayed(chart.From, chart.To, limit)
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to compute chart: %v", err))
+		return
+	}
+
+	RespondJSON(w, http.StatusOK, chart)
+}
+
+// TopRated godoc
+// @Summary Get the best rated songs
+// @Description Ranks the songs by the average of the ratings given in the day, ISO week or month containing date (UTC).
+// @Description Charts are refreshed periodically, so the latest ratings may be missing.
+// @Tags stats
+// @Produce json
+// @Param period query string false "Period, week by default" Enums(day, week, month)
+// @Param date query string false "A date in the period, YYYY-MM-DD, today by default"
+// @Param minRatings query int false "Minimal number of ratings in the period, 1 by default"
+// @Param limit query int false "Number of songs, 10 by default"
+// @Success 200 {object} models.Chart
+// @Failure 400 {object} JSON
+// @Failure 500 {object} JSON
+// @Router /api/charts/top-rated [get]
+// TopRated handles the request to get the chart of the best rated songs.
+func (h *Handler) TopRated(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	chart, limit, err := parseChart(query, "top-rated", "minRatings")
+	if err != nil {
+		respondJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	minRatings := 1
+	if value := query.Get("minRatings"); value != "" {
+		minRatings, err = strconv.Atoi(value)
+		if err != nil {
+			respondJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid minRatings format: %v", err))
+			return
+		}
+	}
+
+	chart.Entries, err = h.Repo.TopRated(chart.From, chart.To, minRatings, limit)
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to compute chart: %v", err))
+		return
+	}
+
+	RespondJSON(w, http.StatusOK, chart)
+}
+
+// parseChart reads the period, date and limit of a chart. Parameters listed in extra are left for the caller.
+func parseChart(query url.Values, name string, extra ...string) (*models.Chart, int, error) {
+	chart := &models.Chart{Chart: name, Period: models.PeriodWeek}
+	limit := 10
+
+	date := time.Now().UTC()
+	for parameter, vals := range query {
+		var err error
+		switch parameter {
+		case "period":
+			chart.Period = vals[0]
+		case "date":
+			date, err = time.Parse(time.DateOnly, vals[0])
+			if err != nil {
+				return nil, 0, fmt.Errorf("invalid date format: %v", err)
+			}
+		case "limit":
+			limit, err = strconv.Atoi(vals[0])
+			if err != nil || limit < 1 || limit > 100 {
+				return nil, 0, fmt.Errorf("invalid limit: %v, expected 1 to 100", vals[0])
+			}
+		default:
+			if !slices.Contains(extra, parameter) {
+				return nil, 0, fmt.Errorf("unrecognized query parameter: %v", parameter)
+			}
+		}
+	}
+
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+	switch chart.Period {
+	case models.PeriodDay:
+		chart.From, chart.To = day, day.AddDate(0, 0, 1)
+	case models.PeriodWeek:
+		// ISO weeks start on Monday.
+		chart.From = day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+		chart.To = chart.From.AddDate(0, 0, 7)
+	case models.PeriodMonth:
+		chart.From = time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, time.UTC)
+		chart.To = chart.From.AddDate(0, 1, 0)
+	default:
+		return nil, 0, fmt.Errorf("unknown period: %v", chart.Period)
+	}
+
+	return chart, limit, nil
+}
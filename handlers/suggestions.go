@@ -0,0 +1,459 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/noctusha/music/auth"
+	"github.com/noctusha/music/connection"
+	"github.com/noctusha/music/diff"
+	"github.com/noctusha/music/importer"
+	"github.com/noctusha/music/models"
+)
+
+// SuggestEdit godoc
+// @Summary Suggest an edit of a song
+// @Description Stores an edit of a song, with the same fields as /api/songs/{song_id}/edit, as a pending
+// @Description suggestion for moderators to review. Empty fields and fields equal to the current values are
+// @Description left unchanged. The suggestion is rejected automatically if the song changes before review.
+// @Tags suggestions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param song_id path int true "Song ID"
+// @Param suggestion body models.SuggestionPayload true "Suggested edit"
+// @Success 201 {object} models.Suggestion
+// @Failure 400 {object} JSON
+// @Failure 401 {object} JSON
+// @Failure 404 {object} JSON
+// @Failure 500 {object} JSON
+// @Router /api/songs/{song_id}/suggestions [post]
+// SuggestEdit handles the request to suggest an edit of a song.
+func (h *Handler) SuggestEdit(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	songID, err := pathID(r, "song_id")
+	if err != nil {
+		respondJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var payload models.SuggestionPayload
+
+	err = json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		respondJSONError(w, http.StatusBadRequest, fmt.Sprintf("failed to decode suggestion: %v", err))
+		return
+	}
+
+	song, err := h.Repo.GetSongByID(strconv.Itoa(songID))
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to retrieve song: %v", err))
+		return
+	}
+	if song == nil {
+		respondJSONError(w, http.StatusNotFound, fmt.Sprintf("no such song with song_id: %v", songID))
+		return
+	}
+
+	songDetails, err := h.Repo.GetSongDetailsByID(strconv.Itoa(songID))
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to retrieve song: %v", err))
+		return
+	}
+	if songDetails == nil {
+		respondJSONError(w, http.StatusNotFound, fmt.Sprintf("no details for song with song_id: %v", songID))
+		return
+	}
+
+	status, err := h.trimSuggestion(&payload, song, songDetails)
+	if err != nil {
+		respondJSONError(w, status, err.Error())
+		return
+	}
+
+	id, err := h.Repo.CreateSuggestion(songID, userID, payload)
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to create suggestion: %v", err))
+		return
+	}
+	if id == 0 {
+		respondJSONError(w, http.StatusNotFound, fmt.Sprintf("no such song with song_id: %v", songID))
+		return
+	}
+
+	h.respondSuggestion(w, http.StatusCreated, id)
+}
+
+// trimSuggestion validates a suggested edit and clears the fields that would not change the song.
+// It returns the status to respond with when the suggestion is invalid.
+func (h *Handler) trimSuggestion(payload *models.SuggestionPayload, song *models.Song, songDetails *models.SongDetails) (int, error) {
+	date, err := importer.NormalizeDate(payload.SongDetails.ReleaseDate)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+	current, err := importer.NormalizeDate(songDetails.ReleaseDate)
+	if err != nil {
+		current = songDetails.ReleaseDate
+	}
+	payload.SongDetails.ReleaseDate = date
+
+	if payload.Song.GroupID != 0 && payload.Song.GroupID != song.GroupID {
+		group, err := h.Repo.GetGroupByID(payload.Song.GroupID)
+		if err != nil {
+			return http.StatusInternalServerError, fmt.Errorf("failed to retrieve group: %v", err)
+		}
+		if group == nil {
+			return http.StatusBadRequest, fmt.Errorf("no such group with group_id: %v", payload.Song.GroupID)
+		}
+	}
+
+	keep := func(value *string, current string) {
+		if *value == current {
+			*value = ""
+		}
+	}
+	keep(&payload.Song.Name, song.Name)
+	keep(&payload.SongDetails.ReleaseDate, current)
+	keep(&payload.SongDetails.Text, songDetails.Text)
+	keep(&payload.SongDetails.Link, songDetails.Link)
+	keep(&payload.SongDetails.Album, songDetails.Album)
+	if payload.Song.GroupID == song.GroupID {
+		payload.Song.GroupID = 0
+	}
+
+	proposed := models.EditSongPayload{Song: payload.Song, SongDetails: payload.SongDetails}
+	proposed.Song.ID = 0
+	proposed.SongDetails.ID = 0
+	proposed.SongDetails.SongID = 0
+	if proposed == (models.EditSongPayload{}) {
+		return http.StatusBadRequest, fmt.Errorf("the suggestion does not change the song")
+	}
+
+	return 0, nil
+}
+
+// ListSuggestions godoc
+// @Summary Get the moderation queue
+// @Description Returns suggested edits, the oldest first. Editors see the suggestions for the groups they
+// @Description maintain, admins all of them. With mine any user gets their own suggestions instead.
+// @Description Pending suggestions whose song changed since they were made are rejected first.
+// @Tags suggestions
+// @Produce json
+// @Security BearerAuth
+// @Param status query string false "Status, pending by default" Enums(pending, approved, rejected, all)
+// @Param song_id query int false "Song ID"
+// @Param mine query bool false "Only the suggestions of the current user"
+// @Param limit query int false "Limit"
+// @Param offset query int false "Offset"
+// @Success 200 {array} models.Suggestion
+// @Failure 400 {object} JSON
+// @Failure 401 {object} JSON
+// @Failure 403 {object} JSON
+// @Failure 500 {object} JSON
+// @Router /api/suggestions [get]
+// ListSuggestions handles the request to list suggested edits.
+func (h *Handler) ListSuggestions(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	principal := auth.PrincipalFromContext(r.Context())
+
+	var filter models.SuggestionFilter
+	var err error
+
+	filter.Limit, filter.Offset, err = parsePagination(query)
+	if err != nil {
+		respondJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	switch status := query.Get("status"); status {
+	case "":
+		filter.Status = models.SuggestionPending
+	case "all":
+	case models.SuggestionPending, models.SuggestionApproved, models.SuggestionRejected:
+		filter.Status = status
+	default:
+		respondJSONError(w, http.StatusBadRequest, fmt.Sprintf("unknown status: %v", status))
+		return
+	}
+
+	if query.Get("song_id") != "" {
+		filter.SongID, err = strconv.Atoi(query.Get("song_id"))
+		if err != nil {
+			respondJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid song_id format: %v", err))
+			return
+		}
+	}
+
+	mine := false
+	if query.Get("mine") != "" {
+		mine, err = strconv.ParseBool(query.Get("mine"))
+		if err != nil {
+			respondJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid mine format: %v", err))
+			return
+		}
+	}
+
+	switch {
+	case mine:
+		filter.UserID = principal.UserID
+	case !principal.Can(auth.PermSongsEdit):
+		respondForbidden(w, fmt.Sprintf("missing permission: %s", auth.PermSongsEdit))
+		return
+	case !principal.Can(auth.PermGroupsAny):
+		filter.MaintainerID = principal.UserID
+	}
+
+	suggestions, err := h.Repo.ListSuggestions(filter)
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to select suggestions from database: %v", err))
+		return
+	}
+
+	for i := range suggestions {
+		suggestions[i].Changes = suggestionChanges(&suggestions[i])
+	}
+
+	RespondJSON(w, http.StatusOK, suggestions)
+}
+
+// GetSuggestion godoc
+// @Summary Get a suggested edit
+// @Description Returns a suggested edit with its diff against the song. Available to its author and its moderators.
+// @Tags suggestions
+// @Produce json
+// @Security BearerAuth
+// @Param suggestion_id path int true "Suggestion ID"
+// @Success 200 {object} models.Suggestion
+// @Failure 400 {object} JSON
+// @Failure 401 {object} JSON
+// @Failure 403 {object} JSON
+// @Failure 404 {object} JSON
+// @Failure 500 {object} JSON
+// @Router /api/suggestions/{suggestion_id} [get]
+// GetSuggestion handles the request to get a suggested edit.
+func (h *Handler) GetSuggestion(w http.ResponseWriter, r *http.Request) {
+	suggestion, ok := h.pathSuggestion(w, r)
+	if !ok {
+		return
+	}
+
+	if suggestion.UserID != currentUserID(r) && !h.requireGroup(w, r, suggestion.Base.Song.GroupID, auth.PermSongsEdit) {
+		return
+	}
+
+	h.respondSuggestion(w, http.StatusOK, suggestion.ID)
+}
+
+// ApproveSuggestion godoc
+// @Summary Approve a suggested edit
+// @Description Applies a pending suggested edit to the song. If the song changed since the edit was
+// @Description suggested, the suggestion is rejected instead and 409 is returned.
+// @Tags suggestions
+// @Produce json
+// @Security BearerAuth
+// @Param suggestion_id path int true "Suggestion ID"
+// @Success 200 {object} models.Suggestion
+// @Failure 400 {object} JSON
+// @Failure 401 {object} JSON
+// @Failure 403 {object} JSON
+// @Failure 404 {object} JSON
+// @Failure 409 {object} JSON
+// @Failure 500 {object} JSON
+// @Router /api/suggestions/{suggestion_id}/approve [post]
+// ApproveSuggestion handles the request to approve a suggested edit.
+func (h *Handler) ApproveSuggestion(w http.ResponseWriter, r *http.Request) {
+	suggestion, ok := h.pathSuggestion(w, r)
+	if !ok {
+		return
+	}
+
+	if !h.requireGroup(w, r, suggestion.Base.Song.GroupID, auth.PermSongsEdit) {
+		return
+	}
+	if suggestion.Proposed.Song.GroupID != 0 && !h.requireGroup(w, r, suggestion.Proposed.Song.GroupID, auth.PermSongsEdit) {
+		return
+	}
+	if suggestion.Status != models.SuggestionPending {
+		respondJSONError(w, http.StatusConflict, fmt.Sprintf("suggestion is already %s", suggestion.Status))
+		return
+	}
+
+	approved, err := h.Repo.ApproveSuggestion(suggestion.ID, currentUserID(r), func(song *models.Song, songDetails *models.SongDetails) {
+		applySongEdit(song, songDetails, suggestion.Proposed)
+	})
+	if errors.Is(err, connection.ErrSongExists) {
+		respondJSONError(w, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to approve suggestion: %v", err))
+		return
+	}
+	if !approved {
+		_, err = h.Repo.RejectStaleSuggestions(suggestion.SongID)
+		if err != nil {
+			respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to reject stale suggestions: %v", err))
+			return
+		}
+		respondJSONError(w, http.StatusConflict, fmt.Sprintf("suggestion was rejected: %s", connection.StaleSuggestionReason))
+		return
+	}
+
+	if suggestion.Proposed.SongDetails.Text != "" {
+		h.syncSimilar()
+	}
+	h.syncAutocomplete(suggestion.Base.Song.GroupID, suggestion.Proposed.Song.GroupID)
+	h.syncSearch(suggestion.SongID)
+
+	// The other pending suggestions for the song were made against its previous state.
+	_, err = h.Repo.RejectStaleSuggestions(suggestion.SongID)
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to reject stale suggestions: %v", err))
+		return
+	}
+
+	h.respondSuggestion(w, http.StatusOK, suggestion.ID)
+}
+
+// RejectSuggestion godoc
+// @Summary Reject a suggested edit
+// @Description Rejects a pending suggested edit with a reason shown to its author
+// @Tags suggestions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param suggestion_id path int true "Suggestion ID"
+// @Param reason body models.RejectSuggestionPayload true "Reason"
+// @Success 200 {object} models.Suggestion
+// @Failure 400 {object} JSON
+// @Failure 401 {object} JSON
+// @Failure 403 {object} JSON
+// @Failure 404 {object} JSON
+// @Failure 409 {object} JSON
+// @Failure 500 {object} JSON
+// @Router /api/suggestions/{suggestion_id}/reject [post]
+// RejectSuggestion handles the request to reject a suggested edit.
+func (h *Handler) RejectSuggestion(w http.ResponseWriter, r *http.Request) {
+	suggestion, ok := h.pathSuggestion(w, r)
+	if !ok {
+		return
+	}
+
+	var payload models.RejectSuggestionPayload
+
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		respondJSONError(w, http.StatusBadRequest, fmt.Sprintf("failed to decode rejection: %v", err))
+		return
+	}
+	payload.Reason = strings.TrimSpace(payload.Reason)
+	if payload.Reason == "" {
+		respondJSONError(w, http.StatusBadRequest, "no rejection reason")
+		return
+	}
+
+	if !h.requireGroup(w, r, suggestion.Base.Song.GroupID, auth.PermSongsEdit) {
+		return
+	}
+
+	rejected, err := h.Repo.RejectSuggestion(suggestion.ID, currentUserID(r), payload.Reason)
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to reject suggestion: %v", err))
+		return
+	}
+	if !rejected {
+		respondJSONError(w, http.StatusConflict, fmt.Sprintf("suggestion is already %s", suggestion.Status))
+		return
+	}
+
+	h.respondSuggestion(w, http.StatusOK, suggestion.ID)
+}
+
+// pathSuggestion loads the suggestion in the suggestion_id path variable, rejecting it first if its song changed.
+func (h *Handler) pathSuggestion(w http.ResponseWriter, r *http.Request) (*models.Suggestion, bool) {
+	suggestionID, err := pathID(r, "suggestion_id")
+	if err != nil {
+		respondJSONError(w, http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+
+	suggestion, err := h.Repo.GetSuggestion(suggestionID)
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to retrieve suggestion: %v", err))
+		return nil, false
+	}
+	if suggestion == nil {
+		respondJSONError(w, http.StatusNotFound, fmt.Sprintf("no such suggestion with suggestion_id: %v", suggestionID))
+		return nil, false
+	}
+	if suggestion.Status != models.SuggestionPending {
+		return suggestion, true
+	}
+
+	rejected, err := h.Repo.RejectStaleSuggestions(suggestion.SongID)
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to reject stale suggestions: %v", err))
+		return nil, false
+	}
+	if rejected > 0 {
+		suggestion, err = h.Repo.GetSuggestion(suggestionID)
+		if err != nil {
+			respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to retrieve suggestion: %v", err))
+			return nil, false
+		}
+	}
+
+	return suggestion, true
+}
+
+// respondSuggestion responds with a suggestion and its changes.
+func (h *Handler) respondSuggestion(w http.ResponseWriter, status, id int) {
+	suggestion, err := h.Repo.GetSuggestion(id)
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to retrieve suggestion: %v", err))
+		return
+	}
+	if suggestion == nil {
+		respondJSONError(w, http.StatusNotFound, fmt.Sprintf("no such suggestion with suggestion_id: %v", id))
+		return
+	}
+
+	suggestion.Changes = suggestionChanges(suggestion)
+	RespondJSON(w, status, suggestion)
+}
+
+// suggestionChanges lists the fields a suggestion changes, from the song as it was when the edit was suggested.
+func suggestionChanges(suggestion *models.Suggestion) []models.FieldChange {
+	base, proposed := suggestion.Base, suggestion.Proposed
+
+	changes := []models.FieldChange{}
+	add := func(field, old, new string) {
+		if new == "" || new == old {
+			return
+		}
+		change := models.FieldChange{Field: field, Old: old, New: new}
+		if field == "text" {
+			change.Lines = diff.Lines(old, new)
+		}
+		changes = append(changes, change)
+	}
+
+	add("name", base.Song.Name, proposed.Song.Name)
+	if proposed.Song.GroupID != 0 {
+		add("group_id", strconv.Itoa(base.Song.GroupID), strconv.Itoa(proposed.Song.GroupID))
+	}
+	add("release_date", base.SongDetails.ReleaseDate, proposed.SongDetails.ReleaseDate)
+	add("text", base.SongDetails.Text, proposed.SongDetails.Text)
+	add("link", base.SongDetails.Link, proposed.SongDetails.Link)
+	add("album", base.SongDetails.Album, proposed.SongDetails.Album)
+
+	return changes
+}
@@ -0,0 +1,423 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/noctusha/music/auth"
+	"github.com/noctusha/music/connection"
+	"github.com/noctusha/music/models"
+)
+
+// maxTagLength is the longest tag or mood name, in characters.
+const maxTagLength = 64
+
+// ListGenres godoc
+// @Summary Get genres
+// @Description Returns the tree of genres: the top-level genres with their subgenres, by name
+// @Tags taxonomy
+// @Produce json
+// @Success 200 {array} models.Genre
+// @Failure 500 {object} JSON
+// @Router /api/genres [get]
+// ListGenres handles the request to list the genres.
+func (h *Handler) ListGenres(w http.ResponseWriter, r *http.Request) {
+	genres, err := h.Repo.Genres()
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to select genres from database: %v", err))
+		return
+	}
+
+	RespondJSON(w, http.StatusOK, genreTree(genres, nil))
+}
+
+// CreateGenre godoc
+// @Summary Create a genre
+// @Description Creates a genre, as a subgenre when parent_id is given. Names are unique regardless of case.
+// @Tags taxonomy
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param genre body models.GenrePayload true "Genre"
+// @Success 201 {object} models.Genre
+// @Failure 400 {object} JSON
+// @Failure 401 {object} JSON
+// @Failure 403 {object} JSON
+// @Failure 409 {object} JSON
+// @Failure 500 {object} JSON
+// @Router /api/genres [post]
+// CreateGenre handles the request to create a genre.
+func (h *Handler) CreateGenre(w http.ResponseWriter, r *http.Request) {
+	payload, ok := decodeGenre(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := h.Repo.CreateGenre(payload)
+	if err != nil {
+		respondGenreError(w, err)
+		return
+	}
+
+	RespondJSON(w, http.StatusCreated, models.Genre{ID: id, Name: payload.Name, ParentID: payload.ParentID})
+}
+
+// UpdateGenre godoc
+// @Summary Change a genre
+// @Description Renames a genre and moves it under another parent, or to the top level without parent_id.
+// @Description A genre cannot be moved under itself or its subgenres.
+// @Tags taxonomy
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param genre_id path int true "Genre ID"
+// @Param genre body models.GenrePayload true "Genre"
+// @Success 200 {object} models.Genre
+// @Failure 400 {object} JSON
+// @Failure 401 {object} JSON
+// @Failure 403 {object} JSON
+// @Failure 404 {object} JSON
+// @Failure 409 {object} JSON
+// @Failure 500 {object} JSON
+// @Router /api/genres/{genre_id} [put]
+// UpdateGenre handles the request to change a genre.
+func (h *Handler) UpdateGenre(w http.ResponseWriter, r *http.Request) {
+	genreID, err := pathID(r, "genre_id")
+	if err != nil {
+		respondJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	payload, ok := decodeGenre(w, r)
+	if !ok {
+		return
+	}
+
+	err = h.Repo.UpdateGenre(genreID, payload)
+	if err != nil {
+		respondGenreError(w, err)
+		return
+	}
+
+	RespondJSON(w, http.StatusOK, models.Genre{ID: genreID, Name: payload.Name, ParentID: payload.ParentID})
+}
+
+// DeleteGenre godoc
+// @Summary Delete a genre
+// @Description Deletes a genre without subgenres and removes it from its songs and groups
+// @Tags taxonomy
+// @Produce json
+// @Security BearerAuth
+// @Param genre_id path int true "Genre ID"
+// @Success 200 {object} JSON
+// @Failure 400 {object} JSON
+// @Failure 401 {object} JSON
+// @Failure 403 {object} JSON
+// @Failure 404 {object} JSON
+// @Failure 409 {object} JSON
+// @Failure 500 {object} JSON
+// @Router /api/genres/{genre_id} [delete]
+// DeleteGenre handles the request to delete a genre.
+func (h *Handler) DeleteGenre(w http.ResponseWriter, r *http.Request) {
+	genreID, err := pathID(r, "genre_id")
+	if err != nil {
+		respondJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = h.Repo.DeleteGenre(genreID)
+	if err != nil {
+		respondGenreError(w, err)
+		return
+	}
+
+	RespondJSON(w, http.StatusOK, JSON{})
+}
+
+// ListTags godoc
+// @Summary Autocomplete tags and moods
+// @Description Returns the tags and moods in use whose name or one of its words starts with q. Names
+// @Description starting with q come first, then the most used.
+// @Tags taxonomy
+// @Produce json
+// @Param q query string false "Start of the name"
+// @Param kind query string false "Kind, both by default" Enums(tag, mood)
+// @Param limit query int false "Limit"
+// @Param offset query int false "Offset"
+// @Success 200 {array} models.Tag
+// @Failure 400 {object} JSON
+// @Failure 500 {object} JSON
+// @Router /api/tags [get]
+// ListTags handles the request to autocomplete tags and moods.
+func (h *Handler) ListTags(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	limit, offset, err := parsePagination(query)
+	if err != nil {
+		respondJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	kind := query.Get("kind")
+	if kind != "" && kind != models.TagKindTag && kind != models.TagKindMood {
+		respondJSONError(w, http.StatusBadRequest, fmt.Sprintf("unknown kind: %v", kind))
+		return
+	}
+
+	tags, err := h.Repo.Tags(kind, normalizeLabel(query.Get("q")), limit, offset)
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to select tags from database: %v", err))
+		return
+	}
+
+	RespondJSON(w, http.StatusOK, tags)
+}
+
+// GetSongTaxonomy godoc
+// @Summary Get the genres, tags and moods of a song
+// @Description Returns the genres, tags and moods of a song. A song without genres has those of its group,
+// @Description and it also carries the tags and moods of its group, listed as inherited.
+// @Tags taxonomy
+// @Produce json
+// @Param song_id path int true "Song ID"
+// @Success 200 {object} models.Taxonomy
+// @Failure 400 {object} JSON
+// @Failure 404 {object} JSON
+// @Failure 500 {object} JSON
+// @Router /api/songs/{song_id}/taxonomy [get]
+// GetSongTaxonomy handles the request to get the genres, tags and moods of a song.
+func (h *Handler) GetSongTaxonomy(w http.ResponseWriter, r *http.Request) {
+	song, ok := h.pathSong(w, r)
+	if !ok {
+		return
+	}
+
+	h.respondSongTaxonomy(w, song.ID)
+}
+
+// SetSongTaxonomy godoc
+// @Summary Change the genres, tags and moods of a song
+// @Description Replaces the lists given in the payload; omitted lists are left unchanged. Tags and moods
+// @Description are lower-cased and created when first used. Editors can only change the songs of the groups they maintain.
+// @Tags taxonomy
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param song_id path int true "Song ID"
+// @Param taxonomy body models.TaxonomyPayload true "Genres, tags and moods"
+// @Success 200 {object} models.Taxonomy
+// @Failure 400 {object} JSON
+// @Failure 401 {object} JSON
+// @Failure 403 {object} JSON
+// @Failure 404 {object} JSON
+// @Failure 500 {object} JSON
+// @Router /api/songs/{song_id}/taxonomy [put]
+// SetSongTaxonomy handles the request to change the genres, tags and moods of a song.
+func (h *Handler) SetSongTaxonomy(w http.ResponseWriter, r *http.Request) {
+	song, ok := h.pathSong(w, r)
+	if !ok {
+		return
+	}
+
+	payload, ok := decodeTaxonomy(w, r)
+	if !ok {
+		return
+	}
+
+	if !h.requireGroup(w, r, song.GroupID, auth.PermSongsEdit) {
+		return
+	}
+
+	err := h.Repo.SetSongTaxonomy(song.ID, payload)
+	if err != nil {
+		respondGenreError(w, err)
+		return
+	}
+
+	h.respondSongTaxonomy(w, song.ID)
+}
+
+// GetGroupTaxonomy godoc
+// @Summary Get the genres, tags and moods of a group
+// @Description Returns the genres, tags and moods of a group, which its songs inherit
+// @Tags taxonomy
+// @Produce json
+// @Param group_id path int true "Group ID"
+// @Success 200 {object} models.Taxonomy
+// @Failure 400 {object} JSON
+// @Failure 404 {object} JSON
+// @Failure 500 {object} JSON
+// @Router /api/groups/{group_id}/taxonomy [get]
+// GetGroupTaxonomy handles the request to get the genres, tags and moods of a group.
+func (h *Handler) GetGroupTaxonomy(w http.ResponseWriter, r *http.Request) {
+	group, ok := h.pathGroup(w, r)
+	if !ok {
+		return
+	}
+
+	h.respondGroupTaxonomy(w, group.ID)
+}
+
+// SetGroupTaxonomy godoc
+// @Summary Change the genres, tags and moods of a group
+// @Description Replaces the lists given in the payload; omitted lists are left unchanged. Songs without
+// @Description genres of their own inherit the genres of their group. Editors can only change the groups they maintain.
+// @Tags taxonomy
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param group_id path int true "Group ID"
+// @Param taxonomy body models.TaxonomyPayload true "Genres, tags and moods"
+// @Success 200 {object} models.Taxonomy
+// @Failure 400 {object} JSON
+// @Failure 401 {object} JSON
+// @Failure 403 {object} JSON
+// @Failure 404 {object} JSON
+// @Failure 500 {object} JSON
+// @Router /api/groups/{group_id}/taxonomy [put]
+// SetGroupTaxonomy handles the request to change the genres, tags and moods of a group.
+func (h *Handler) SetGroupTaxonomy(w http.ResponseWriter, r *http.Request) {
+	group, ok := h.pathGroup(w, r)
+	if !ok {
+		return
+	}
+
+	payload, ok := decodeTaxonomy(w, r)
+	if !ok {
+		return
+	}
+
+	if !h.requireGroup(w, r, group.ID, auth.PermSongsEdit) {
+		return
+	}
+
+	err := h.Repo.SetGroupTaxonomy(group.ID, payload)
+	if err != nil {
+		respondGenreError(w, err)
+		return
+	}
+
+	h.respondGroupTaxonomy(w, group.ID)
+}
+
+func (h *Handler) respondSongTaxonomy(w http.ResponseWriter, songID int) {
+	taxonomy, err := h.Repo.SongTaxonomy(songID)
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to retrieve taxonomy: %v", err))
+		return
+	}
+
+	RespondJSON(w, http.StatusOK, taxonomy)
+}
+
+func (h *Handler) respondGroupTaxonomy(w http.ResponseWriter, groupID int) {
+	taxonomy, err := h.Repo.GroupTaxonomy(groupID)
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to retrieve taxonomy: %v", err))
+		return
+	}
+
+	RespondJSON(w, http.StatusOK, taxonomy)
+}
+
+// decodeGenre reads and checks the genre of a request body.
+func decodeGenre(w http.ResponseWriter, r *http.Request) (models.GenrePayload, bool) {
+	var payload models.GenrePayload
+
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		respondJSONError(w, http.StatusBadRequest, fmt.Sprintf("failed to decode genre: %v", err))
+		return payload, false
+	}
+
+	payload.Name = strings.Join(strings.Fields(payload.Name), " ")
+	if payload.Name == "" {
+		respondJSONError(w, http.StatusBadRequest, "no genre name")
+		return payload, false
+	}
+	if utf8.RuneCountInString(payload.Name) > 255 {
+		respondJSONError(w, http.StatusBadRequest, "genre name is longer than 255 characters")
+		return payload, false
+	}
+
+	return payload, true
+}
+
+// decodeTaxonomy reads the taxonomy of a request body, normalizing the tags and moods.
+func decodeTaxonomy(w http.ResponseWriter, r *http.Request) (models.TaxonomyPayload, bool) {
+	var payload models.TaxonomyPayload
+
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		respondJSONError(w, http.StatusBadRequest, fmt.Sprintf("failed to decode taxonomy: %v", err))
+		return payload, false
+	}
+
+	for _, labels := range []*[]string{payload.Tags, payload.Moods} {
+		if labels == nil {
+			continue
+		}
+		*labels, err = normalizeLabels(*labels)
+		if err != nil {
+			respondJSONError(w, http.StatusBadRequest, err.Error())
+			return payload, false
+		}
+	}
+
+	return payload, true
+}
+
+// normalizeLabels normalizes tag or mood names and removes the empty and repeated ones.
+func normalizeLabels(names []string) ([]string, error) {
+	labels := []string{}
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		label := normalizeLabel(name)
+		if label == "" || seen[label] {
+			continue
+		}
+		if utf8.RuneCountInString(label) > maxTagLength {
+			return nil, fmt.Errorf("tag %q is longer than %d characters", label, maxTagLength)
+		}
+		seen[label] = true
+		labels = append(labels, label)
+	}
+	return labels, nil
+}
+
+// normalizeLabel lower-cases a tag or mood name and collapses its spaces.
+func normalizeLabel(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+// genreTree nests the genres under their parents, keeping their order.
+func genreTree(genres []models.Genre, parentID *int) []models.Genre {
+	tree := []models.Genre{}
+	for _, genre := range genres {
+		if (parentID == nil) != (genre.ParentID == nil) || parentID != nil && *parentID != *genre.ParentID {
+			continue
+		}
+		id := genre.ID
+		genre.Children = genreTree(genres, &id)
+		tree = append(tree, genre)
+	}
+	return tree
+}
+
+// respondGenreError maps the genre errors of the repository to status codes.
+func respondGenreError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, connection.ErrGenreNotFound):
+		respondJSONError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, connection.ErrGenreCycle):
+		respondJSONError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, connection.ErrGenreExists), errors.Is(err, connection.ErrGenreHasSubgenres):
+		respondJSONError(w, http.StatusConflict, err.Error())
+	default:
+		respondJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to update genres: %v", err))
+	}
+}
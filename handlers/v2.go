@@ -0,0 +1,304 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/noctusha/music/models"
+)
+
+// The v1 song routes are deprecated since /api/v2 was released and will be removed at their sunset.
+var (
+	v1Deprecation = time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC)
+	v1Sunset      = time.Date(2027, time.April, 18, 0, 0, 0, 0, time.UTC)
+)
+
+// CreateSongV2 godoc
+// @Summary Add a new song
+// @Description Adds a new song with details from the music info service, like POST /api/songs/new. The response
+// @Description is 201 Created with the song, its group and details, and its URL in the Location header. A song
+// @Description the group already has is not added: the response is 409 Conflict with the Location of that song.
+// @Tags v2
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param song body models.NewSongPayload true "New song"
+// @Success 201 {object} models.SongResource
+// @Header 201 {string} Location "URL of the song"
+// @Failure 400 {object} JSON
+// @Failure 401 {object} JSON
+// @Failure 403 {object} JSON
+// @Failure 409 {object} models.SongConflict
+// @Failure 500 {object} JSON
+// @Router /api/v2/songs [post]
+// CreateSongV2 handles the request to add a new song.
+func (h *Handler) CreateSongV2(w http.ResponseWriter, r *http.Request) {
+	var payload models.NewSongPayload
+
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		respondJSONError(w, http.StatusBadRequest, fmt.Sprintf("failed to decode song: %v", err))
+		return
+	}
+
+	payload.Group, payload.Song = strings.TrimSpace(payload.Group), strings.TrimSpace(payload.Song)
+	if payload.Group == "" {
+		respondJSONError(w, http.StatusBadRequest, "no group name")
+		return
+	}
+	if payload.Song == "" {
+		respondJSONError(w, http.StatusBadRequest, "no song name")
+		return
+	}
+
+	song, status, err := h.createSong(r.Context(), payload.Group, payload.Song)
+	if status == http.StatusConflict {
+		w.Header().Set("Location", songLocation(song.ID))
+		RespondJSON(w, status, models.SongConflict{Error: err.Error(), SongID: song.ID})
+		return
+	}
+	if err != nil {
+		respondJSONError(w, status, err.Error())
+		return
+	}
+
+	w.Header().Set("Location", songLocation(song.ID))
+	h.respondSongResource(w, r, http.StatusCreated, *song)
+}
+
+// ReplaceSong godoc
+// @Summary Replace a song
+// @Description Replaces a song and its details. The name and group_id are required; omitted details become
+// @Description unknown. Editors can only change the songs of the groups they maintain, and move them to those groups.
+// @Tags v2
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param song_id path int true "Song ID"
+// @Param song body models.SongPayload true "Song"
+// @Success 200 {object} models.SongResource
+// @Failure 400 {object} JSON
+// @Failure 401 {object} JSON
+// @Failure 403 {object} JSON
+// @Failure 404 {object} JSON
+// @Failure 409 {object} JSON
+// @Failure 500 {object} JSON
+// @Router /api/v2/songs/{song_id} [put]
+// ReplaceSong handles the request to replace a song and its details.
+func (h *Handler) ReplaceSong(w http.ResponseWriter, r *http.Request) {
+	payload, ok := decodeSongPayload(w, r)
+	if !ok {
+		return
+	}
+	if payload.Name == nil || payload.GroupID == nil {
+		respondJSONError(w, http.StatusBadRequest, "name and group_id are required")
+		return
+	}
+
+	details := models.SongDetails{ReleaseDate: models.DefaultReleaseDate, Text: models.UnknownValue, Link: models.UnknownValue, Album: models.UnknownValue}
+	if payload.ReleaseDate == nil {
+		payload.ReleaseDate = &details.ReleaseDate
+	}
+	if payload.Text == nil {
+		payload.Text = &details.Text
+	}
+	if payload.Link == nil {
+		payload.Link = &details.Link
+	}
+	if payload.Album == nil {
+		payload.Album = &details.Album
+	}
+
+	h.editSongV2(w, r, payload)
+}
+
+// PatchSong godoc
+// @Summary Change a song
+// @Description Changes the fields of a song and its details given in the payload; omitted fields are left
+// @Description unchanged. Editors can only change the songs of the groups they maintain, and move them to those groups.
+// @Tags v2
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param song_id path int true "Song ID"
+// @Param song body models.SongPayload true "Changed fields"
+// @Success 200 {object} models.SongResource
+// @Failure 400 {object} JSON
+// @Failure 401 {object} JSON
+// @Failure 403 {object} JSON
+// @Failure 404 {object} JSON
+// @Failure 409 {object} JSON
+// @Failure 500 {object} JSON
+// @Router /api/v2/songs/{song_id} [patch]
+// PatchSong handles the request to change some fields of a song.
+func (h *Handler) PatchSong(w http.ResponseWriter, r *http.Request) {
+	payload, ok := decodeSongPayload(w, r)
+	if !ok {
+		return
+	}
+
+	h.editSongV2(w, r, payload)
+}
+
+// DeleteSongV2 godoc
+// @Summary Delete a song
+// @Description Deletes a song by ID. The response is 204 No Content.
+// @Tags v2
+// @Security BearerAuth
+// @Param song_id path int true "Song ID"
+// @Success 204
+// @Failure 400 {object} JSON
+// @Failure 401 {object} JSON
+// @Failure 403 {object} JSON
+// @Failure 404 {object} JSON
+// @Failure 500 {object} JSON
+// @Router /api/v2/songs/{song_id} [delete]
+// DeleteSongV2 handles the request to delete a song.
+func (h *Handler) DeleteSongV2(w http.ResponseWriter, r *http.Request) {
+	songID, err := pathID(r, "song_id")
+	if err != nil {
+		respondJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	status, err := h.deleteSong(songID)
+	if err != nil {
+		respondJSONError(w, status, err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// deleteSong deletes a song, returning the status to respond with when it was not deleted.
+func (h *Handler) deleteSong(songID int) (int, error) {
+	id := strconv.Itoa(songID)
+	song, err := h.Repo.GetSongByID(id)
+	if err != nil {
+		return http.StatusInternalServerError, fmt.Errorf("failed to retrieve song: %v", err)
+	}
+	if song == nil {
+		return http.StatusNotFound, fmt.Errorf("no such song with song_id: %v", songID)
+	}
+
+	err = h.Repo.SongDelete(id)
+	if err != nil {
+		return http.StatusInternalServerError, fmt.Errorf("failed to delete song: %v", err)
+	}
+	h.syncAutocomplete(song.GroupID)
+	h.syncSearch(songID)
+
+	return 0, nil
+}
+
+// Deprecated is a middleware that marks a v1 route as deprecated, pointing to its successor in /api/v2.
+// The successor may contain the variables of the route, such as {song_id}.
+func (h *Handler) Deprecated(successor string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		link := successor
+		for name, value := range mux.Vars(r) {
+			link = strings.ReplaceAll(link, "{"+name+"}", value)
+		}
+
+		w.Header().Set("Deprecation", fmt.Sprintf("@%d", v1Deprecation.Unix()))
+		w.Header().Set("Sunset", v1Sunset.Format(http.TimeFormat))
+		w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, link))
+		next.ServeHTTP(w, r)
+	})
+}
+
+// decodeSongPayload reads and validates the song of a v2 request. It responds with an error
+// and returns false when the payload is invalid.
+func decodeSongPayload(w http.ResponseWriter, r *http.Request) (models.SongPayload, bool) {
+	var payload models.SongPayload
+
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		respondJSONError(w, http.StatusBadRequest, fmt.Sprintf("failed to decode song: %v", err))
+		return payload, false
+	}
+
+	err = validateSongPayload(&payload)
+	if err != nil {
+		respondJSONError(w, http.StatusBadRequest, err.Error())
+		return payload, false
+	}
+
+	return payload, true
+}
+
+// validateSongPayload checks the fields a payload has, trimming the name.
+func validateSongPayload(payload *models.SongPayload) error {
+	if payload.Name != nil {
+		name := strings.TrimSpace(*payload.Name)
+		if name == "" {
+			return fmt.Errorf("empty song name")
+		}
+		payload.Name = &name
+	}
+	if payload.GroupID != nil && *payload.GroupID <= 0 {
+		return fmt.Errorf("invalid group_id: %d", *payload.GroupID)
+	}
+	if payload.ReleaseDate != nil {
+		_, err := time.Parse(time.DateOnly, *payload.ReleaseDate)
+		if err != nil {
+			return fmt.Errorf("invalid release_date: %v, expected YYYY-MM-DD", *payload.ReleaseDate)
+		}
+	}
+	return nil
+}
+
+// editSongV2 applies the fields of a payload to the song of the path and responds with the song.
+func (h *Handler) editSongV2(w http.ResponseWriter, r *http.Request, payload models.SongPayload) {
+	song, _, ok := h.updateSong(w, r, func(song *models.Song, songDetails *models.SongDetails) {
+		applySongPayload(song, songDetails, payload)
+	})
+	if !ok {
+		return
+	}
+
+	h.respondSongResource(w, r, http.StatusOK, *song)
+}
+
+// applySongPayload copies the fields a payload has to a song and its details.
+func applySongPayload(song *models.Song, songDetails *models.SongDetails, payload models.SongPayload) {
+	if payload.Name != nil {
+		song.Name = *payload.Name
+	}
+	if payload.GroupID != nil {
+		song.GroupID = *payload.GroupID
+	}
+	if payload.ReleaseDate != nil {
+		songDetails.ReleaseDate = *payload.ReleaseDate
+	}
+	if payload.Text != nil {
+		songDetails.Text = *payload.Text
+	}
+	if payload.Link != nil {
+		songDetails.Link = *payload.Link
+	}
+	if payload.Album != nil {
+		songDetails.Album = *payload.Album
+	}
+}
+
+// respondSongResource responds with a song, its group and details.
+func (h *Handler) respondSongResource(w http.ResponseWriter, r *http.Request, statusCode int, song models.Song) {
+	resources, err := h.songResources([]models.Song{song}, map[string]bool{includeGroup: true, includeDetails: true})
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	RespondJSON(w, statusCode, resources[0])
+}
+
+// songLocation is the URL of a song in /api/v2.
+func songLocation(songID int) string {
+	return fmt.Sprintf("/api/v2/songs/%d", songID)
+}
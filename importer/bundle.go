@@ -0,0 +1,144 @@
+package importer
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/noctusha/music/connection"
+	"github.com/noctusha/music/exporter"
+	"github.com/noctusha/music/models"
+)
+
+// FormatBundle is the zipped JSON bundle written by the catalogue export.
+const FormatBundle = exporter.FormatBundle
+
+// RestoreBundle imports a bundle written by exporter.Export, keeping the ids of groups,
+// songs and details so that an export and restore round-trip without changes. The whole
+// bundle is restored in one transaction; with dryRun it is rolled back at the end.
+// Report rows are numbered in bundle order, groups first.
+func RestoreBundle(repo *connection.Repository, r io.ReaderAt, size int64, dryRun bool) (*models.ImportReport, error) {
+	report := &models.ImportReport{DryRun: dryRun, Rows: []models.ImportRowResult{}}
+
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return report, &InputError{Err: fmt.Errorf("error opening bundle: %v", err)}
+	}
+
+	var manifest exporter.Manifest
+	err = readBundleFile(archive, exporter.BundleManifest, func(decoder *json.Decoder) error {
+		return decode(decoder, &manifest)
+	})
+	if err != nil {
+		return report, err
+	}
+	if manifest.Version != exporter.BundleVersion {
+		return report, &InputError{Err: fmt.Errorf("unsupported bundle version: %d", manifest.Version)}
+	}
+
+	restore, err := repo.BeginRestore()
+	if err != nil {
+		return report, err
+	}
+
+	rows := 0
+	err = readBundleFile(archive, exporter.BundleGroups, func(decoder *json.Decoder) error {
+		return decodeArray(decoder, func() error {
+			var group models.Group
+			err := decode(decoder, &group)
+			if err != nil {
+				return err
+			}
+
+			status, err := restore.Group(group)
+			if err != nil {
+				return err
+			}
+
+			rows++
+			recordResult(report, models.ImportRowResult{Row: rows, Group: group.Name, Status: status})
+			return nil
+		})
+	})
+	if err != nil {
+		restore.Rollback()
+		return report, err
+	}
+
+	err = readBundleFile(archive, exporter.BundleSongs, func(decoder *json.Decoder) error {
+		return decodeArray(decoder, func() error {
+			var song models.ExportSong
+			err := decode(decoder, &song)
+			if err != nil {
+				return err
+			}
+
+			status, err := restore.Song(song)
+			if err != nil {
+				return err
+			}
+
+			rows++
+			recordResult(report, models.ImportRowResult{Row: rows, Group: song.Group, Song: song.Song, Status: status, SongID: song.SongID})
+			return nil
+		})
+	})
+	if err != nil {
+		restore.Rollback()
+		return report, err
+	}
+
+	if dryRun {
+		return report, restore.Rollback()
+	}
+	return report, restore.Commit()
+}
+
+// readBundleFile opens a file of the bundle and passes a decoder for it to read.
+func readBundleFile(archive *zip.Reader, name string, read func(*json.Decoder) error) error {
+	file, err := archive.Open(name)
+	if err != nil {
+		return &InputError{Err: fmt.Errorf("error opening %s: %v", name, err)}
+	}
+	defer file.Close()
+
+	err = read(json.NewDecoder(file))
+	if err != nil {
+		return fmt.Errorf("error reading %s: %w", name, err)
+	}
+	return nil
+}
+
+// decodeArray calls element for every element of the JSON array at the decoder position.
+func decodeArray(decoder *json.Decoder, element func() error) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return &InputError{Err: err}
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return &InputError{Err: fmt.Errorf("expected a JSON array")}
+	}
+
+	for decoder.More() {
+		err = element()
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = decoder.Token()
+	if err != nil {
+		return &InputError{Err: err}
+	}
+	return nil
+}
+
+// decode decodes the next JSON value of a bundle file, reporting a malformed value as an InputError.
+func decode(decoder *json.Decoder, v interface{}) error {
+	err := decoder.Decode(v)
+	if err != nil {
+		return &InputError{Err: err}
+	}
+	return nil
+}
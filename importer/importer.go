@@ -0,0 +1,197 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/noctusha/music/connection"
+	"github.com/noctusha/music/models"
+	"github.com/noctusha/music/musicinfo"
+)
+
+// DefaultBatchSize is the number of rows stored per transaction.
+const DefaultBatchSize = 100
+
+// Options controls a bulk import.
+type Options struct {
+	Format    string
+	BatchSize int
+	// DryRun processes every row and reports the outcome without saving anything.
+	// Batches are rolled back independently, so duplicates spanning batches are
+	// reported as created.
+	DryRun bool
+	// Upsert updates existing songs instead of skipping them.
+	Upsert bool
+}
+
+// Importer loads songs in bulk into the repository.
+type Importer struct {
+	Repo *connection.Repository
+	// Client fills in missing release dates, texts and links when set.
+	Client  *musicinfo.Client
+	Options Options
+}
+
+// pendingRow is a parsed row waiting for its batch to be stored.
+type pendingRow struct {
+	number int
+	row    models.ImportRow
+}
+
+// Import reads all rows from r and stores them in batches, returning a per-row report.
+// Rows that cannot be parsed or validated are reported as failed; an error is only
+// returned when reading or storing cannot continue, together with the report of the
+// rows processed so far.
+func (im *Importer) Import(r io.Reader) (*models.ImportReport, error) {
+	report := &models.ImportReport{DryRun: im.Options.DryRun, Rows: []models.ImportRowResult{}}
+
+	reader, err := NewReader(r, im.Options.Format)
+	if err != nil {
+		return report, err
+	}
+
+	batchSize := im.Options.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+
+	batch := make([]pendingRow, 0, batchSize)
+
+	for number := 1; ; number++ {
+		row, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		var rowErr *RowError
+		if errors.As(err, &rowErr) {
+			recordResult(report, models.ImportRowResult{Row: number, Status: models.ImportFailed, Error: rowErr.Error()})
+			continue
+		}
+		if err != nil {
+			return report, err
+		}
+
+		row, err = im.prepare(row)
+		if err != nil {
+			recordResult(report, models.ImportRowResult{Row: number, Group: row.Group, Song: row.Song, Status: models.ImportFailed, Error: err.Error()})
+			continue
+		}
+
+		batch = append(batch, pendingRow{number: number, row: row})
+		if len(batch) == batchSize {
+			err = im.flush(report, batch)
+			if err != nil {
+				return report, err
+			}
+			batch = batch[:0]
+		}
+	}
+
+	err = im.flush(report, batch)
+	if err != nil {
+		return report, err
+	}
+
+	return report, nil
+}
+
+// prepare validates and normalizes a row and enriches it through the external API.
+func (im *Importer) prepare(row models.ImportRow) (models.ImportRow, error) {
+	row.Group = strings.TrimSpace(row.Group)
+	row.Song = strings.TrimSpace(row.Song)
+
+	if row.Group == "" {
+		return row, fmt.Errorf("no group name")
+	}
+	if row.Song == "" {
+		return row, fmt.Errorf("no song name")
+	}
+
+	if im.Client != nil && (row.ReleaseDate == "" || row.Text == "" || row.Link == "") {
+		details, err := im.Client.SongDetails(row.Group, row.Song)
+		if err != nil {
+			return row, err
+		}
+		if row.ReleaseDate == "" {
+			row.ReleaseDate = details.ReleaseDate
+		}
+		if row.Text == "" {
+			row.Text = details.Text
+		}
+		if row.Link == "" {
+			row.Link = details.Link
+		}
+		if row.Album == "" {
+			row.Album = details.Album
+		}
+	}
+
+	date, err := NormalizeDate(row.ReleaseDate)
+	if err != nil {
+		return row, err
+	}
+	row.ReleaseDate = date
+
+	return row, nil
+}
+
+// flush stores a batch and records the results of its rows.
+func (im *Importer) flush(report *models.ImportReport, batch []pendingRow) error {
+	if len(batch) == 0 {
+		return nil
+	}
+
+	rows := make([]models.ImportRow, len(batch))
+	for i, pending := range batch {
+		rows[i] = pending.row
+	}
+
+	results, err := im.Repo.ImportSongs(rows, im.Options.Upsert, im.Options.DryRun)
+	if err != nil {
+		return fmt.Errorf("error importing rows %d-%d: %v", batch[0].number, batch[len(batch)-1].number, err)
+	}
+
+	for i, result := range results {
+		result.Row = batch[i].number
+		recordResult(report, result)
+	}
+	return nil
+}
+
+// recordResult adds a row result to the report and updates its counters.
+func recordResult(report *models.ImportReport, result models.ImportRowResult) {
+	switch result.Status {
+	case models.ImportCreated:
+		report.Created++
+	case models.ImportUpdated:
+		report.Updated++
+	case models.ImportSkipped:
+		report.Skipped++
+	case models.ImportFailed:
+		report.Failed++
+	}
+	report.Rows = append(report.Rows, result)
+}
+
+// dateLayouts are the accepted release date formats.
+var dateLayouts = []string{time.DateOnly, "02.01.2006", time.RFC3339}
+
+// NormalizeDate converts a release date to YYYY-MM-DD. Empty dates stay empty.
+func NormalizeDate(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", nil
+	}
+
+	for _, layout := range dateLayouts {
+		date, err := time.Parse(layout, value)
+		if err == nil {
+			return date.Format(time.DateOnly), nil
+		}
+	}
+	return "", fmt.Errorf("invalid release date: %v", value)
+}
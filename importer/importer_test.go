@@ -0,0 +1,152 @@
+package importer
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/noctusha/music/models"
+)
+
+func TestPrepare(t *testing.T) {
+	tests := []struct {
+		name string
+		row  models.ImportRow
+		want models.ImportRow
+		err  string
+	}{
+		{
+			name: "trimmed names",
+			row:  models.ImportRow{Group: "  Muse ", Song: "\tUprising\n", ReleaseDate: "07.09.2009"},
+			want: models.ImportRow{Group: "Muse", Song: "Uprising", ReleaseDate: "2009-09-07"},
+		},
+		{
+			name: "timestamp",
+			row:  models.ImportRow{Group: "Muse", Song: "Uprising", ReleaseDate: "2009-09-07T10:00:00+03:00"},
+			want: models.ImportRow{Group: "Muse", Song: "Uprising", ReleaseDate: "2009-09-07"},
+		},
+		{
+			name: "no release date",
+			row:  models.ImportRow{Group: "Muse", Song: "Uprising", Text: "text"},
+			want: models.ImportRow{Group: "Muse", Song: "Uprising", Text: "text"},
+		},
+		{name: "no group", row: models.ImportRow{Group: "  ", Song: "Uprising"}, err: "no group name"},
+		{name: "no song", row: models.ImportRow{Group: "Muse"}, err: "no song name"},
+		{name: "invalid date", row: models.ImportRow{Group: "Muse", Song: "Uprising", ReleaseDate: "2009/09/07"}, err: "invalid release date: 2009/09/07"},
+		{name: "impossible date", row: models.ImportRow{Group: "Muse", Song: "Uprising", ReleaseDate: "2009-02-30"}, err: "invalid release date: 2009-02-30"},
+	}
+
+	im := &Importer{}
+	for _, test := range tests {
+		row, err := im.prepare(test.row)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("%s: prepare error = %v, want %q", test.name, err, test.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: prepare: %v", test.name, err)
+			continue
+		}
+		if row != test.want {
+			t.Errorf("%s: prepare = %+v, want %+v", test.name, row, test.want)
+		}
+	}
+}
+
+// Imports of rows that all fail never reach the repository, so they run without one.
+func TestImportFailedRows(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		data   string
+		rows   []models.ImportRowResult
+	}{
+		{
+			name:   "CSV",
+			format: FormatCSV,
+			// Row numbers count records, so the row after multi-line lyrics is row 3,
+			// while the parse error names the line of the input.
+			data: "group,song,text,release_date\n" +
+				",Uprising,,\n" +
+				"Muse,,\"first line\nsecond line\",\n" +
+				"Muse,Resist\"ance,,\n" +
+				"Muse,Uprising,,someday\n",
+			rows: []models.ImportRowResult{
+				{Row: 1, Song: "Uprising", Status: models.ImportFailed, Error: "no group name"},
+				{Row: 2, Group: "Muse", Status: models.ImportFailed, Error: "no song name"},
+				{Row: 3, Status: models.ImportFailed, Error: `parse error on line 5, column 12: bare " in non-quoted-field`},
+				{Row: 4, Group: "Muse", Song: "Uprising", Status: models.ImportFailed, Error: "invalid release date: someday"},
+			},
+		},
+		{
+			name:   "NDJSON",
+			format: FormatNDJSON,
+			data: "{\"group\": \"Muse\"}\n" +
+				"\n" +
+				"not json\n",
+			rows: []models.ImportRowResult{
+				{Row: 1, Group: "Muse", Status: models.ImportFailed, Error: "no song name"},
+				{Row: 2, Status: models.ImportFailed, Error: "invalid JSON on line 3: invalid character 'o' in literal null (expecting 'u')"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		im := &Importer{Options: Options{Format: test.format}}
+		report, err := im.Import(strings.NewReader(test.data))
+		if err != nil {
+			t.Errorf("%s: Import: %v", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(report.Rows, test.rows) {
+			t.Errorf("%s: rows = %+v, want %+v", test.name, report.Rows, test.rows)
+		}
+		if report.Failed != len(test.rows) || report.Created+report.Updated+report.Skipped != 0 {
+			t.Errorf("%s: report counts %+v, want %d failed", test.name, report, len(test.rows))
+		}
+	}
+}
+
+func TestImportInputError(t *testing.T) {
+	// The malformed second element stops the import before the pending batch is stored.
+	im := &Importer{Options: Options{Format: FormatJSON, DryRun: true}}
+	report, err := im.Import(strings.NewReader(`[{"group": "", "song": "Uprising"}, {"group": "Muse", "song": "Uprising"}, {`))
+
+	var inputErr *InputError
+	if !errors.As(err, &inputErr) {
+		t.Fatalf("Import error = %v, want an InputError", err)
+	}
+	if !report.DryRun || report.Failed != 1 || len(report.Rows) != 1 {
+		t.Errorf("report = %+v, want the failed first row only", report)
+	}
+
+	_, err = (&Importer{Options: Options{Format: "yaml"}}).Import(strings.NewReader("- song"))
+	if !errors.As(err, &inputErr) {
+		t.Errorf("Import of an unsupported format = %v, want an InputError", err)
+	}
+}
+
+func TestNormalizeDate(t *testing.T) {
+	for value, date := range map[string]string{
+		"2009-09-07":           "2009-09-07",
+		" 07.09.2009 ":         "2009-09-07",
+		"2009-09-07T23:30:00Z": "2009-09-07",
+		"":                     "",
+		"   ":                  "",
+	} {
+		got, err := NormalizeDate(value)
+		if err != nil || got != date {
+			t.Errorf("NormalizeDate(%q) = %q, %v, want %q", value, got, err, date)
+		}
+	}
+
+	for _, value := range []string{"2009", "09/07/2009", "2009-13-01", "31.02.2009"} {
+		_, err := NormalizeDate(value)
+		if err == nil {
+			t.Errorf("NormalizeDate(%q) returned no error", value)
+		}
+	}
+}
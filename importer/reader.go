@@ -0,0 +1,246 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/noctusha/music/models"
+)
+
+// Supported import formats.
+const (
+	FormatCSV    = "csv"
+	FormatJSON   = "json"
+	FormatNDJSON = "ndjson"
+)
+
+// RowError reports a row that could not be parsed. Reading can continue after it.
+type RowError struct {
+	Err error
+}
+
+func (e *RowError) Error() string {
+	return e.Err.Error()
+}
+
+// InputError reports an import that cannot be read because it is malformed or in an unsupported
+// format, as opposed to one that could not be stored. Reading cannot continue after it.
+type InputError struct {
+	Err error
+}
+
+func (e *InputError) Error() string {
+	return e.Err.Error()
+}
+
+// malformed marks the errors caused by malformed input as an InputError; I/O errors are returned as is.
+func malformed(err error) error {
+	var syntaxErr *json.SyntaxError
+	var parseErr *csv.ParseError
+	if errors.As(err, &syntaxErr) || errors.As(err, &parseErr) || errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, bufio.ErrTooLong) {
+		return &InputError{Err: err}
+	}
+	return err
+}
+
+// RowReader reads import rows one at a time. Next returns io.EOF after the last row.
+type RowReader interface {
+	Next() (models.ImportRow, error)
+}
+
+// NewReader creates a RowReader for the given format.
+func NewReader(r io.Reader, format string) (RowReader, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVReader(r)
+	case FormatJSON:
+		return newJSONReader(r)
+	case FormatNDJSON:
+		return newNDJSONReader(r), nil
+	case FormatBundle:
+		return nil, &InputError{Err: fmt.Errorf("bundles are imported with RestoreBundle")}
+	default:
+		return nil, &InputError{Err: fmt.Errorf("unsupported import format: %v", format)}
+	}
+}
+
+// FormatFromContentType maps a request Content-Type to an import format.
+func FormatFromContentType(contentType string) string {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	switch strings.TrimSpace(strings.ToLower(mediaType)) {
+	case "text/csv", "application/csv":
+		return FormatCSV
+	case "application/json":
+		return FormatJSON
+	case "application/x-ndjson", "application/ndjson", "application/jsonlines", "application/x-jsonlines":
+		return FormatNDJSON
+	case "application/zip":
+		return FormatBundle
+	default:
+		return ""
+	}
+}
+
+// FormatFromFileName guesses the import format from a file extension.
+func FormatFromFileName(name string) string {
+	switch {
+	case strings.HasSuffix(name, ".csv"):
+		return FormatCSV
+	case strings.HasSuffix(name, ".ndjson"), strings.HasSuffix(name, ".jsonl"):
+		return FormatNDJSON
+	case strings.HasSuffix(name, ".json"):
+		return FormatJSON
+	case strings.HasSuffix(name, ".zip"):
+		return FormatBundle
+	default:
+		return ""
+	}
+}
+
+// csvColumns maps accepted CSV headers to the row fields they fill.
+var csvColumns = map[string]func(*models.ImportRow, string){
+	"group":        func(row *models.ImportRow, v string) { row.Group = v },
+	"song":         func(row *models.ImportRow, v string) { row.Song = v },
+	"release_date": func(row *models.ImportRow, v string) { row.ReleaseDate = v },
+	"releasedate":  func(row *models.ImportRow, v string) { row.ReleaseDate = v },
+	"text":         func(row *models.ImportRow, v string) { row.Text = v },
+	"link":         func(row *models.ImportRow, v string) { row.Link = v },
+	"album":        func(row *models.ImportRow, v string) { row.Album = v },
+}
+
+// csvReader reads rows from CSV with a header line naming the columns.
+type csvReader struct {
+	reader  *csv.Reader
+	columns []func(*models.ImportRow, string)
+}
+
+func newCSVReader(r io.Reader) (*csvReader, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, malformed(fmt.Errorf("error reading CSV header: %w", err))
+	}
+
+	columns := make([]func(*models.ImportRow, string), len(header))
+	seen := make(map[string]bool)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		columns[i] = csvColumns[name]
+		seen[name] = true
+	}
+	if !seen["group"] || !seen["song"] {
+		return nil, &InputError{Err: fmt.Errorf("CSV header must contain group and song columns")}
+	}
+
+	return &csvReader{reader: reader, columns: columns}, nil
+}
+
+func (c *csvReader) Next() (models.ImportRow, error) {
+	var row models.ImportRow
+
+	record, err := c.reader.Read()
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return row, &RowError{Err: err}
+		}
+		return row, err
+	}
+
+	for i, value := range record {
+		if i < len(c.columns) && c.columns[i] != nil {
+			c.columns[i](&row, strings.TrimSpace(value))
+		}
+	}
+	return row, nil
+}
+
+// jsonReader reads rows from a JSON array without loading the whole array.
+type jsonReader struct {
+	decoder *json.Decoder
+}
+
+func newJSONReader(r io.Reader) (*jsonReader, error) {
+	decoder := json.NewDecoder(r)
+
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, malformed(fmt.Errorf("error reading JSON array: %w", err))
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return nil, &InputError{Err: fmt.Errorf("JSON import must be an array of songs")}
+	}
+
+	return &jsonReader{decoder: decoder}, nil
+}
+
+func (j *jsonReader) Next() (models.ImportRow, error) {
+	var row models.ImportRow
+
+	if !j.decoder.More() {
+		_, err := j.decoder.Token()
+		if err != nil {
+			return row, malformed(fmt.Errorf("error reading JSON array: %w", err))
+		}
+		return row, io.EOF
+	}
+
+	err := j.decoder.Decode(&row)
+	if err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return row, &RowError{Err: err}
+		}
+		return row, malformed(fmt.Errorf("error decoding JSON array: %w", err))
+	}
+	return row, nil
+}
+
+// ndjsonReader reads one JSON object per line.
+type ndjsonReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+// maxNDJSONLine is the longest accepted NDJSON line; lyrics can be long.
+const maxNDJSONLine = 4 << 20
+
+func newNDJSONReader(r io.Reader) *ndjsonReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxNDJSONLine)
+	return &ndjsonReader{scanner: scanner}
+}
+
+func (n *ndjsonReader) Next() (models.ImportRow, error) {
+	var row models.ImportRow
+
+	for n.scanner.Scan() {
+		n.line++
+		line := bytes.TrimSpace(n.scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		err := json.Unmarshal(line, &row)
+		if err != nil {
+			return row, &RowError{Err: fmt.Errorf("invalid JSON on line %d: %v", n.line, err)}
+		}
+		return row, nil
+	}
+
+	err := n.scanner.Err()
+	if err != nil {
+		return row, malformed(fmt.Errorf("error reading NDJSON after line %d: %w", n.line, err))
+	}
+	return row, io.EOF
+}
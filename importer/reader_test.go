@@ -0,0 +1,229 @@
+package importer
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/noctusha/music/models"
+)
+
+// readAll reads rows until the end of input or an error that stops reading, collecting
+// the messages of the row errors on the way.
+func readAll(reader RowReader) ([]models.ImportRow, []string, error) {
+	rows := []models.ImportRow{}
+	rowErrors := []string{}
+	for {
+		row, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return rows, rowErrors, nil
+		}
+
+		var rowErr *RowError
+		if errors.As(err, &rowErr) {
+			rowErrors = append(rowErrors, rowErr.Error())
+			continue
+		}
+		if err != nil {
+			return rows, rowErrors, err
+		}
+		rows = append(rows, row)
+	}
+}
+
+func TestReader(t *testing.T) {
+	tests := []struct {
+		name      string
+		format    string
+		data      string
+		rows      []models.ImportRow
+		rowErrors []string
+	}{
+		{
+			name:   "CSV header mapping",
+			format: FormatCSV,
+			// Headers are matched case-insensitively after a byte order mark, in any order;
+			// unknown columns are ignored and both spellings of the release date are accepted.
+			data: "\ufeffSong, GROUP ,ReleaseDate,Extra,album\n" +
+				"Uprising,Muse,2009-09-07,ignored,The Resistance\n" +
+				"  Yesterday  ,The Beatles\n",
+			rows: []models.ImportRow{
+				{Group: "Muse", Song: "Uprising", ReleaseDate: "2009-09-07", Album: "The Resistance"},
+				{Group: "The Beatles", Song: "Yesterday"},
+			},
+		},
+		{
+			name:   "CSV quoted multi-line lyrics",
+			format: FormatCSV,
+			data: "group,song,release_date,text,link\r\n" +
+				"Кино,Группа крови,1988-01-01,\"Тёплое место,\r\nно улицы ждут\n\"\"отпечатков\"\" наших ног\",https://example.com/kino\r\n" +
+				"Muse,Uprising,,,\r\n",
+			rows: []models.ImportRow{
+				{Group: "Кино", Song: "Группа крови", ReleaseDate: "1988-01-01", Text: "Тёплое место,\nно улицы ждут\n\"отпечатков\" наших ног", Link: "https://example.com/kino"},
+				{Group: "Muse", Song: "Uprising"},
+			},
+		},
+		{
+			name:   "CSV malformed row",
+			format: FormatCSV,
+			data: "group,song\n" +
+				"Muse,Upri\"sing\n" +
+				"Muse,Resistance\n",
+			rows:      []models.ImportRow{{Group: "Muse", Song: "Resistance"}},
+			rowErrors: []string{`parse error on line 2, column 10: bare " in non-quoted-field`},
+		},
+		{
+			name:   "JSON",
+			format: FormatJSON,
+			data: `[
+				{"group": "Muse", "song": "Uprising", "release_date": "2009-09-07", "text": "Paranoia is in bloom\nThe PR transmissions will resume"},
+				{"group": 7, "song": "Wrong type"},
+				{"group": "Кино", "song": "Группа крови", "album": "Группа крови", "unknown": true}
+			]`,
+			rows: []models.ImportRow{
+				{Group: "Muse", Song: "Uprising", ReleaseDate: "2009-09-07", Text: "Paranoia is in bloom\nThe PR transmissions will resume"},
+				{Group: "Кино", Song: "Группа крови", Album: "Группа крови"},
+			},
+			rowErrors: []string{"json: cannot unmarshal number into Go struct field ImportRow.group of type string"},
+		},
+		{
+			name:   "empty JSON array",
+			format: FormatJSON,
+			data:   " [ ] ",
+			rows:   []models.ImportRow{},
+		},
+		{
+			name:   "NDJSON with blank lines and a malformed line",
+			format: FormatNDJSON,
+			data: "{\"group\": \"Muse\", \"song\": \"Uprising\"}\n" +
+				"\n" +
+				"{\"group\": \"Muse\", \"song\": \n" +
+				"  {\"group\": \"Кино\", \"song\": \"Группа крови\", \"text\": \"line one\\nline two\"}  \r\n",
+			rows: []models.ImportRow{
+				{Group: "Muse", Song: "Uprising"},
+				{Group: "Кино", Song: "Группа крови", Text: "line one\nline two"},
+			},
+			rowErrors: []string{"invalid JSON on line 3: unexpected end of JSON input"},
+		},
+	}
+
+	for _, test := range tests {
+		reader, err := NewReader(strings.NewReader(test.data), test.format)
+		if err != nil {
+			t.Errorf("%s: NewReader: %v", test.name, err)
+			continue
+		}
+
+		rows, rowErrors, err := readAll(reader)
+		if err != nil {
+			t.Errorf("%s: reading stopped: %v", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(rows, test.rows) {
+			t.Errorf("%s: rows = %+v, want %+v", test.name, rows, test.rows)
+		}
+		if test.rowErrors == nil {
+			test.rowErrors = []string{}
+		}
+		if !reflect.DeepEqual(rowErrors, test.rowErrors) {
+			t.Errorf("%s: row errors = %q, want %q", test.name, rowErrors, test.rowErrors)
+		}
+	}
+}
+
+func TestReaderInputErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		data   string
+		// rows is the number of rows read before the error.
+		rows int
+		// message is a part of the error message, such as the line at fault.
+		message string
+	}{
+		{"unsupported format", "xml", "<songs/>", 0, "unsupported import format"},
+		{"bundle", FormatBundle, "PK", 0, "RestoreBundle"},
+		{"empty CSV", FormatCSV, "", 0, "CSV header"},
+		{"CSV without a song column", FormatCSV, "group,name\nMuse,Uprising\n", 0, "group and song columns"},
+		{"CSV header with an unterminated quote", FormatCSV, "group,\"song\n", 0, "line 1"},
+		{"JSON object", FormatJSON, `{"group": "Muse", "song": "Uprising"}`, 0, "array of songs"},
+		{"empty JSON", FormatJSON, "", 0, "EOF"},
+		{"malformed JSON element", FormatJSON, `[{"group": "Muse", "song": "Uprising"}, {"group": }]`, 1, "invalid character"},
+		{"truncated JSON array", FormatJSON, `[{"group": "Muse", "song": "Uprising"}`, 1, "unexpected end"},
+		{"unterminated JSON element", FormatJSON, `[{"group": "Muse"`, 0, "unexpected EOF"},
+		{
+			name:    "NDJSON line over the limit",
+			format:  FormatNDJSON,
+			data:    "{\"group\": \"Muse\", \"song\": \"Uprising\"}\n{\"text\": \"" + strings.Repeat("a", maxNDJSONLine) + "\"}\n",
+			rows:    1,
+			message: "after line 1",
+		},
+	}
+
+	for _, test := range tests {
+		reader, err := NewReader(strings.NewReader(test.data), test.format)
+		rows := []models.ImportRow{}
+		if err == nil {
+			rows, _, err = readAll(reader)
+		}
+
+		var inputErr *InputError
+		if !errors.As(err, &inputErr) {
+			t.Errorf("%s: error = %v, want an InputError", test.name, err)
+			continue
+		}
+		if !strings.Contains(err.Error(), test.message) {
+			t.Errorf("%s: error = %q, want it to mention %q", test.name, err, test.message)
+		}
+		if len(rows) != test.rows {
+			t.Errorf("%s: read %d rows before the error, want %d", test.name, len(rows), test.rows)
+		}
+	}
+}
+
+func TestMalformed(t *testing.T) {
+	// Errors of the underlying reader are not caused by the input and are returned as is.
+	readErr := errors.New("connection reset")
+	reader := newNDJSONReader(io.MultiReader(strings.NewReader("{\"group\": \"Muse\", \"song\": \"Uprising\"}\n"), iotest.ErrReader(readErr)))
+
+	_, err := reader.Next()
+	if err != nil {
+		t.Fatalf("Next: %v", err)
+	}
+	_, err = reader.Next()
+	var inputErr *InputError
+	if !errors.Is(err, readErr) || errors.As(err, &inputErr) {
+		t.Errorf("Next = %v, want the read error, not an InputError", err)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	for contentType, format := range map[string]string{
+		"text/csv; charset=utf-8": FormatCSV,
+		"Application/JSON":        FormatJSON,
+		"application/x-ndjson":    FormatNDJSON,
+		"application/jsonlines":   FormatNDJSON,
+		"application/zip":         FormatBundle,
+		"text/plain":              "",
+	} {
+		if got := FormatFromContentType(contentType); got != format {
+			t.Errorf("FormatFromContentType(%q) = %q, want %q", contentType, got, format)
+		}
+	}
+
+	for name, format := range map[string]string{
+		"songs.csv":    FormatCSV,
+		"songs.json":   FormatJSON,
+		"songs.ndjson": FormatNDJSON,
+		"songs.jsonl":  FormatNDJSON,
+		"catalog.zip":  FormatBundle,
+		"songs.txt":    "",
+	} {
+		if got := FormatFromFileName(name); got != format {
+			t.Errorf("FormatFromFileName(%q) = %q, want %q", name, got, format)
+		}
+	}
+}
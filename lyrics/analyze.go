@@ -0,0 +1,201 @@
+package lyrics
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/noctusha/music/models"
+)
+
+// Analyze computes the analytics of the lyrics of one or more songs, listing up to top
+// most frequent words. Empty texts are skipped; names and IDs are left to the caller.
+func Analyze(texts []string, top int) *models.LyricsAnalytics {
+	analytics := &models.LyricsAnalytics{TopWords: []models.WordCount{}, Languages: map[string]int{}}
+
+	counts := make(map[string]int)
+	var lineWords, lineChars int
+	var letterWords, letters int
+	var readability, readabilityWords float64
+
+	for _, text := range texts {
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		analytics.Songs++
+
+		var words []string
+		// Lines are compared by their words, so case and punctuation do not hide a repeat.
+		lines := make(map[string]int)
+		var keys []string
+		var songWords, songSyllables int
+
+		for _, line := range strings.Split(text, "\n") {
+			line = strings.TrimSpace(line)
+			if isAnnotation(line) {
+				continue
+			}
+			lw := Words(line)
+			if len(lw) == 0 {
+				continue
+			}
+
+			analytics.Lines++
+			lineWords += len(lw)
+			lineChars += utf8.RuneCountInString(line)
+
+			key := strings.Join(lw, " ")
+			lines[key]++
+			keys = append(keys, key)
+			words = append(words, lw...)
+		}
+
+		for _, key := range keys {
+			if lines[key] > 1 {
+				analytics.RepeatedLines++
+			}
+		}
+
+		for _, word := range words {
+			counts[word]++
+
+			n := countLetters(word)
+			if n > 0 {
+				letters += n
+				songWords++
+				songSyllables += Syllables(word)
+			}
+		}
+		letterWords += songWords
+		analytics.Words += len(words)
+
+		language := Language(words)
+		analytics.Languages[language]++
+
+		if coefficients, ok := flesch[language]; ok && songWords > 0 {
+			score := coefficients[0] - coefficients[1]*float64(songWords)/float64(len(keys)) -
+				coefficients[2]*float64(songSyllables)/float64(songWords)
+			readability += score * float64(songWords)
+			readabilityWords += float64(songWords)
+		}
+	}
+
+	analytics.UniqueWords = len(counts)
+	if analytics.Lines > 0 {
+		analytics.AverageLineWords = float64(lineWords) / float64(analytics.Lines)
+		analytics.AverageLineChars = float64(lineChars) / float64(analytics.Lines)
+		analytics.RepetitionRatio = float64(analytics.RepeatedLines) / float64(analytics.Lines)
+	}
+	if letterWords > 0 {
+		analytics.AverageWordLetters = float64(letters) / float64(letterWords)
+	}
+	if readabilityWords > 0 {
+		score := readability / readabilityWords
+		analytics.Readability = &score
+	}
+
+	analytics.Language = Undetermined
+	for language, songs := range analytics.Languages {
+		best := analytics.Languages[analytics.Language]
+		if songs > best || songs == best && language < analytics.Language {
+			analytics.Language = language
+		}
+	}
+
+	for word, count := range counts {
+		if IsTerm(word) {
+			analytics.TopWords = append(analytics.TopWords, models.WordCount{Word: word, Count: count})
+		}
+	}
+	sort.Slice(analytics.TopWords, func(i, j int) bool {
+		if analytics.TopWords[i].Count != analytics.TopWords[j].Count {
+			return analytics.TopWords[i].Count > analytics.TopWords[j].Count
+		}
+		return analytics.TopWords[i].Word < analytics.TopWords[j].Word
+	})
+	if len(analytics.TopWords) > top {
+		analytics.TopWords = analytics.TopWords[:top]
+	}
+
+	return analytics
+}
+
+// Language detects the language of words from Words: the language whose stop words occur
+// most, or without stop words the script of most letters. It returns Undetermined when
+// neither English nor Russian is found.
+func Language(words []string) string {
+	var english, russian int
+	for _, word := range words {
+		if stopWords[English][word] {
+			english++
+		}
+		if stopWords[Russian][word] {
+			russian++
+		}
+	}
+
+	if english == 0 && russian == 0 {
+		for _, word := range words {
+			for _, r := range word {
+				switch {
+				case unicode.Is(unicode.Latin, r):
+					english++
+				case unicode.Is(unicode.Cyrillic, r):
+					russian++
+				}
+			}
+		}
+	}
+
+	switch {
+	case english > russian:
+		return English
+	case russian > english:
+		return Russian
+	}
+	return Undetermined
+}
+
+// flesch are the coefficients of the Flesch reading ease of each language: the base score and the
+// weights of the words per sentence and the syllables per word.
+var flesch = map[string][3]float64{
+	English: {206.835, 1.015, 84.6},
+	Russian: {206.835, 1.3, 60.1},
+}
+
+// Syllables estimates the number of syllables of a word from Words. Every Russian vowel is a
+// syllable; in English a run of vowels is one and a final silent e is not counted.
+func Syllables(word string) int {
+	runes := []rune(word)
+	syllables := 0
+	inVowels := false
+	for _, r := range runes {
+		switch {
+		case isRussianVowel(r):
+			syllables++
+			inVowels = false
+		case isEnglishVowel(r):
+			if !inVowels {
+				syllables++
+			}
+			inVowels = true
+		default:
+			inVowels = false
+		}
+	}
+
+	n := len(runes)
+	if syllables > 1 && n > 2 && runes[n-1] == 'e' && !isEnglishVowel(runes[n-2]) && runes[n-2] != 'l' {
+		syllables--
+	}
+	if syllables == 0 && countLetters(word) > 0 {
+		return 1
+	}
+	return syllables
+}
+
+// isAnnotation reports whether a line only marks a part of the song, such as [Chorus].
+func isAnnotation(line string) bool {
+	return len(line) > 1 && line[0] == '[' && line[len(line)-1] == ']'
+}
@@ -0,0 +1,84 @@
+package lyrics
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSyllables(t *testing.T) {
+	for word, syllables := range map[string]int{
+		"sea":       1,
+		"yellow":    2,
+		"submarine": 3,
+		"live":      1,
+		"little":    2,
+		"the":       1,
+		"rhythm":    1,
+		"don't":     1,
+		"we're":     1,
+		"молоко":    3,
+		"группа":    2,
+		"крови":     2,
+		"в":         1,
+		"по-русски": 3,
+		"2009":      0,
+	} {
+		if got := Syllables(word); got != syllables {
+			t.Errorf("Syllables(%q) = %d, want %d", word, got, syllables)
+		}
+	}
+}
+
+func TestAnalyzeReadability(t *testing.T) {
+	tests := []struct {
+		name    string
+		texts   []string
+		letters float64
+		// readability is NaN when it is not computed.
+		readability float64
+	}{
+		{
+			name: "English",
+			// 7 words in 2 lines, 10 syllables and 27 letters; the annotation is skipped.
+			texts:       []string{"We all live in a\n[Chorus]\nyellow submarine"},
+			letters:     27.0 / 7,
+			readability: 206.835 - 1.015*7/2 - 84.6*10/7,
+		},
+		{
+			name: "Russian",
+			// 4 words in 2 lines, 8 syllables and 19 letters; the number is not a word.
+			texts:       []string{"Группа крови\nна рукаве 1988"},
+			letters:     19.0 / 4,
+			readability: 206.835 - 1.3*4/2 - 60.1*8/4,
+		},
+		{
+			name:        "group weighted by words",
+			texts:       []string{"We all live in a\nyellow submarine", "", "Группа крови\nна рукаве"},
+			letters:     (27.0 + 19) / 11,
+			readability: ((206.835-1.015*7/2-84.6*10/7)*7 + (206.835-1.3*4/2-60.1*8/4)*4) / 11,
+		},
+		{
+			name:        "undetermined language",
+			texts:       []string{"1 2 3\n4 5"},
+			readability: math.NaN(),
+		},
+	}
+
+	for _, test := range tests {
+		analytics := Analyze(test.texts, 10)
+		if math.Abs(analytics.AverageWordLetters-test.letters) > 1e-9 {
+			t.Errorf("%s: AverageWordLetters = %v, want %v", test.name, analytics.AverageWordLetters, test.letters)
+		}
+
+		switch {
+		case math.IsNaN(test.readability):
+			if analytics.Readability != nil {
+				t.Errorf("%s: Readability = %v, want nil", test.name, *analytics.Readability)
+			}
+		case analytics.Readability == nil:
+			t.Errorf("%s: Readability = nil, want %v", test.name, test.readability)
+		case math.Abs(*analytics.Readability-test.readability) > 1e-9:
+			t.Errorf("%s: Readability = %v, want %v", test.name, *analytics.Readability, test.readability)
+		}
+	}
+}
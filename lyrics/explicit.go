@@ -0,0 +1,188 @@
+package lyrics
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// WordList matches explicit words. An entry ending in * matches every word starting with
+// it, which covers the inflected forms of Russian words. A nil WordList matches nothing.
+type WordList struct {
+	words    map[string]bool
+	prefixes []string
+	// Languages lists the languages the entries were loaded for.
+	Languages []string
+}
+
+// NewWordList creates a WordList from entries of a single word each.
+func NewWordList(entries []string) (*WordList, error) {
+	list := &WordList{words: make(map[string]bool)}
+	for _, entry := range entries {
+		err := list.add(entry)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return list, nil
+}
+
+// LoadWordLists reads the explicit words of every language from the <language>.txt files of a directory,
+// such as en.txt and ru.txt. Each line holds one entry; blank lines and lines starting with # are skipped.
+func LoadWordLists(dir string) (*WordList, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.txt"))
+	if err != nil {
+		return nil, fmt.Errorf("error listing word lists: %v", err)
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no word lists in %s", dir)
+	}
+
+	list := &WordList{words: make(map[string]bool)}
+	for _, path := range paths {
+		err = list.load(path)
+		if err != nil {
+			return nil, err
+		}
+		list.Languages = append(list.Languages, strings.TrimSuffix(filepath.Base(path), ".txt"))
+	}
+
+	return list, nil
+}
+
+func (l *WordList) load(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("error opening word list: %v", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		entry := strings.TrimSpace(scanner.Text())
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+		err = l.add(entry)
+		if err != nil {
+			return fmt.Errorf("%s:%d: %v", path, line, err)
+		}
+	}
+
+	err = scanner.Err()
+	if err != nil {
+		return fmt.Errorf("error reading word list %s: %v", path, err)
+	}
+	return nil
+}
+
+func (l *WordList) add(entry string) error {
+	prefix := strings.HasSuffix(entry, "*")
+	words := Words(strings.TrimSuffix(entry, "*"))
+	if len(words) != 1 {
+		return fmt.Errorf("invalid entry %q, expected a single word", entry)
+	}
+
+	if prefix {
+		l.prefixes = append(l.prefixes, words[0])
+	} else {
+		l.words[words[0]] = true
+	}
+	return nil
+}
+
+// Match reports whether a word from Words is explicit. Words joined by hyphens or
+// apostrophes match when any of their parts does.
+func (l *WordList) Match(word string) bool {
+	if l == nil {
+		return false
+	}
+	if l.match(word) {
+		return true
+	}
+	if !strings.ContainsAny(word, "-'") {
+		return false
+	}
+	for _, part := range strings.FieldsFunc(word, isJoiner) {
+		if l.match(part) {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *WordList) match(word string) bool {
+	if l.words[word] {
+		return true
+	}
+	for _, prefix := range l.prefixes {
+		if strings.HasPrefix(word, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Explicit reports whether a text contains an explicit word.
+func (l *WordList) Explicit(text string) bool {
+	if l == nil {
+		return false
+	}
+	for _, word := range Words(text) {
+		if l.Match(word) {
+			return true
+		}
+	}
+	return false
+}
+
+// Detect reports whether a text contains an explicit word like Explicit, but returns nil for a nil
+// WordList, which cannot tell. Stored flags are kept where Detect returns nil.
+func (l *WordList) Detect(text string) *bool {
+	if l == nil {
+		return nil
+	}
+	explicit := l.Explicit(text)
+	return &explicit
+}
+
+// Censor masks every letter of the explicit words of a text but the first with an asterisk.
+// Of words joined by hyphens or apostrophes only the explicit parts are masked.
+func (l *WordList) Censor(text string) string {
+	if l == nil {
+		return text
+	}
+
+	runes := []rune(text)
+	tokenize(runes, func(start, end int, word string) {
+		if l.match(word) {
+			mask(runes[start:end])
+			return
+		}
+
+		// The runes of word are the folded runes of the text, one for one.
+		folded := []rune(word)
+		part := 0
+		for i := 0; i <= len(folded); i++ {
+			if i < len(folded) && !isJoiner(folded[i]) {
+				continue
+			}
+			if i > part && l.match(string(folded[part:i])) {
+				mask(runes[start+part : start+i])
+			}
+			part = i + 1
+		}
+	})
+	return string(runes)
+}
+
+// mask replaces the letters of a word but the first with asterisks, keeping hyphens and apostrophes.
+func mask(word []rune) {
+	for i := 1; i < len(word); i++ {
+		if !isJoiner(word[i]) {
+			word[i] = '*'
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package lyrics
+
+import "unicode"
+
+// Stem reduces a word from Words to its stem with the Snowball stemmer of its language:
+// English for Latin words and Russian for Cyrillic ones. Other words are left as they are.
+func Stem(word string) string {
+	latin, cyrillic := 0, 0
+	for _, r := range word {
+		switch {
+		case r >= 'a' && r <= 'z':
+			latin++
+		case unicode.Is(unicode.Cyrillic, r):
+			cyrillic++
+		case r == '\'' || r == '-':
+		default:
+			return word
+		}
+	}
+
+	switch {
+	case latin > 0 && cyrillic == 0:
+		return stemEnglish(word)
+	case cyrillic > 0 && latin == 0:
+		return stemRussian(word)
+	}
+	return word
+}
+
+// endsWith returns the longest of suffixes that word ends with, or "".
+func endsWith(word []rune, suffixes []string) string {
+	longest := ""
+	for _, suffix := range suffixes {
+		if len(suffix) > len(longest) && hasSuffix(word, suffix) {
+			longest = suffix
+		}
+	}
+	return longest
+}
+
+func hasSuffix(word []rune, suffix string) bool {
+	s := []rune(suffix)
+	if len(s) > len(word) {
+		return false
+	}
+	for i, r := range s {
+		if word[len(word)-len(s)+i] != r {
+			return false
+		}
+	}
+	return true
+}
+
+// runeLen is the number of runes of a suffix.
+func runeLen(suffix string) int {
+	return len([]rune(suffix))
+}
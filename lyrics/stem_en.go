@@ -0,0 +1,204 @@
+package lyrics
+
+import "strings"
+
+// enExceptions are the words the English Snowball stemmer maps directly.
+var enExceptions = map[string]string{
+	"skis": "ski", "skies": "sky", "dying": "die", "lying": "lie", "tying": "tie",
+	"idly": "idl", "gently": "gentl", "ugly": "ugli", "early": "earli", "only": "onli", "singly": "singl",
+	"sky": "sky", "news": "news", "howe": "howe", "atlas": "atlas", "cosmos": "cosmos", "bias": "bias", "andes": "andes",
+}
+
+// enExceptions1a are the words left as they are after step 1a.
+var enExceptions1a = map[string]bool{
+	"inning": true, "outing": true, "canning": true, "herring": true, "earring": true,
+	"proceed": true, "exceed": true, "succeed": true,
+}
+
+// Replacements of steps 2 and 3, applied in R1.
+var (
+	enStep2 = map[string]string{
+		"tional": "tion", "enci": "ence", "anci": "ance", "abli": "able", "entli": "ent",
+		"izer": "ize", "ization": "ize", "ational": "ate", "ation": "ate", "ator": "ate",
+		"alism": "al", "aliti": "al", "alli": "al", "fulness": "ful", "ousli": "ous", "ousness": "ous",
+		"iveness": "ive", "iviti": "ive", "biliti": "ble", "bli": "ble", "ogi": "og", "fulli": "ful",
+		"lessli": "less", "li": "",
+	}
+	enStep3 = map[string]string{
+		"tional": "tion", "ational": "ate", "alize": "al", "icate": "ic", "iciti": "ic", "ical": "ic",
+		"ful": "", "ness": "", "ative": "",
+	}
+	enStep4 = []string{"al", "ance", "ence", "er", "ic", "able", "ible", "ant", "ement", "ment", "ent",
+		"ism", "ate", "iti", "ous", "ive", "ize", "ion"}
+)
+
+func isEnglishVowel(r rune) bool {
+	switch r {
+	case 'a', 'e', 'i', 'o', 'u', 'y':
+		return true
+	}
+	return false
+}
+
+// stemEnglish implements the English (Porter2) Snowball stemmer on a lower-case word.
+func stemEnglish(word string) string {
+	if stem, ok := enExceptions[word]; ok {
+		return stem
+	}
+	if len(word) <= 2 {
+		return word
+	}
+
+	w := []rune(strings.TrimPrefix(word, "'"))
+	// A y starting the word or following a vowel is a consonant, marked as Y.
+	for i, r := range w {
+		if r == 'y' && (i == 0 || isEnglishVowel(w[i-1])) {
+			w[i] = 'Y'
+		}
+	}
+
+	r1 := afterVowelConsonant(w, 0, isEnglishVowel)
+	for _, prefix := range []string{"gener", "commun", "arsen"} {
+		if strings.HasPrefix(string(w), prefix) {
+			r1 = len(prefix)
+		}
+	}
+	r2 := afterVowelConsonant(w, r1, isEnglishVowel)
+	if r1 > 0 && r2 < r1 {
+		r2 = r1
+	}
+
+	// Step 0.
+	if suffix := endsWith(w, []string{"'s'", "'s", "'"}); suffix != "" {
+		w = w[:len(w)-runeLen(suffix)]
+	}
+
+	// Step 1a.
+	switch suffix := endsWith(w, []string{"sses", "ied", "ies", "us", "ss", "s"}); suffix {
+	case "sses":
+		w = w[:len(w)-2]
+	case "ied", "ies":
+		if len(w) > 4 {
+			w = append(w[:len(w)-3], 'i')
+		} else {
+			w = append(w[:len(w)-3], 'i', 'e')
+		}
+	case "s":
+		if containsVowel(w[:len(w)-2]) {
+			w = w[:len(w)-1]
+		}
+	}
+	if enExceptions1a[string(w)] {
+		return string(w)
+	}
+
+	// Step 1b.
+	switch suffix := endsWith(w, []string{"eed", "eedly", "ed", "edly", "ing", "ingly"}); suffix {
+	case "eed", "eedly":
+		if len(w)-runeLen(suffix) >= r1 {
+			w = append(w[:len(w)-runeLen(suffix)], 'e', 'e')
+		}
+	case "ed", "edly", "ing", "ingly":
+		stem := w[:len(w)-runeLen(suffix)]
+		if containsVowel(stem) {
+			w = stem
+			switch {
+			case hasSuffix(w, "at") || hasSuffix(w, "bl") || hasSuffix(w, "iz"):
+				w = append(w, 'e')
+			case endsWith(w, []string{"bb", "dd", "ff", "gg", "mm", "nn", "pp", "rr", "tt"}) != "":
+				w = w[:len(w)-1]
+			case isShortWord(w, r1):
+				w = append(w, 'e')
+			}
+		}
+	}
+
+	// Step 1c.
+	if n := len(w); n > 2 && (w[n-1] == 'y' || w[n-1] == 'Y') && !isEnglishVowel(w[n-2]) {
+		w[n-1] = 'i'
+	}
+
+	// Step 2.
+	if suffix := endsWithKey(w, enStep2); suffix != "" && len(w)-runeLen(suffix) >= r1 {
+		stem := w[:len(w)-runeLen(suffix)]
+		switch suffix {
+		case "ogi":
+			if hasSuffix(stem, "l") {
+				w = append(stem, []rune(enStep2[suffix])...)
+			}
+		case "li":
+			if len(stem) > 0 && strings.ContainsRune("cdeghkmnrt", stem[len(stem)-1]) {
+				w = stem
+			}
+		default:
+			w = append(stem, []rune(enStep2[suffix])...)
+		}
+	}
+
+	// Step 3.
+	if suffix := endsWithKey(w, enStep3); suffix != "" && len(w)-runeLen(suffix) >= r1 {
+		if suffix != "ative" || len(w)-runeLen(suffix) >= r2 {
+			w = append(w[:len(w)-runeLen(suffix)], []rune(enStep3[suffix])...)
+		}
+	}
+
+	// Step 4.
+	if suffix := endsWith(w, enStep4); suffix != "" && len(w)-runeLen(suffix) >= r2 {
+		stem := w[:len(w)-runeLen(suffix)]
+		if suffix != "ion" || hasSuffix(stem, "s") || hasSuffix(stem, "t") {
+			w = stem
+		}
+	}
+
+	// Step 5.
+	switch n := len(w); {
+	case n > 0 && w[n-1] == 'e':
+		if n-1 >= r2 || (n-1 >= r1 && !endsInShortSyllable(w[:n-1])) {
+			w = w[:n-1]
+		}
+	case n > 1 && w[n-1] == 'l' && w[n-2] == 'l' && n-1 >= r2:
+		w = w[:n-1]
+	}
+
+	return strings.ReplaceAll(string(w), "Y", "y")
+}
+
+// endsWithKey returns the longest key of replacements that word ends with, or "".
+func endsWithKey(word []rune, replacements map[string]string) string {
+	longest := ""
+	for suffix := range replacements {
+		if len(suffix) > len(longest) && hasSuffix(word, suffix) {
+			longest = suffix
+		}
+	}
+	return longest
+}
+
+func containsVowel(word []rune) bool {
+	for _, r := range word {
+		if isEnglishVowel(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// endsInShortSyllable reports whether a word ends in a vowel followed by a non-vowel other than w, x
+// and Y and preceded by a non-vowel, or is a vowel followed by a non-vowel.
+func endsInShortSyllable(word []rune) bool {
+	n := len(word)
+	switch {
+	case n == 2:
+		return isEnglishVowel(word[0]) && !isEnglishVowel(word[1])
+	case n > 2:
+		last := word[n-1]
+		return !isEnglishVowel(word[n-3]) && isEnglishVowel(word[n-2]) && !isEnglishVowel(last) &&
+			last != 'w' && last != 'x' && last != 'Y'
+	}
+	return false
+}
+
+// isShortWord reports whether a word ends in a short syllable and has an empty R1.
+func isShortWord(word []rune, r1 int) bool {
+	return r1 >= len(word) && endsInShortSyllable(word)
+}
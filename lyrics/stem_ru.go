@@ -0,0 +1,130 @@
+package lyrics
+
+// The endings of the Russian Snowball stemmer. The endings of the first groups only
+// count after а or я, which stay.
+var (
+	ruPerfectiveGerund1 = []string{"в", "вши", "вшись"}
+	ruPerfectiveGerund2 = []string{"ив", "ивши", "ившись", "ыв", "ывши", "ывшись"}
+	ruAdjective         = []string{"ее", "ие", "ые", "ое", "ими", "ыми", "ей", "ий", "ый", "ой", "ем", "им", "ым", "ом",
+		"его", "ого", "ему", "ому", "их", "ых", "ую", "юю", "ая", "яя", "ою", "ею"}
+	ruParticiple1 = []string{"ем", "нн", "вш", "ющ", "щ"}
+	ruParticiple2 = []string{"ивш", "ывш", "ующ"}
+	ruReflexive   = []string{"ся", "сь"}
+	ruVerb1       = []string{"ла", "на", "ете", "йте", "ли", "й", "л", "ем", "н", "ло", "но", "ет", "ют", "ны", "ть", "ешь", "нно"}
+	ruVerb2       = []string{"ила", "ыла", "ена", "ейте", "уйте", "ите", "или", "ыли", "ей", "уй", "ил", "ыл", "им", "ым", "ен",
+		"ило", "ыло", "ено", "ят", "ует", "уют", "ит", "ыт", "ены", "ить", "ыть", "ишь", "ую", "ю"}
+	ruNoun = []string{"а", "ев", "ов", "ие", "ье", "е", "иями", "ями", "ами", "еи", "ии", "и", "ией", "ей", "ой", "ий", "й",
+		"иям", "ям", "ием", "ем", "ам", "ом", "о", "у", "ах", "иях", "ях", "ы", "ь", "ию", "ью", "ю", "ия", "ья", "я"}
+	ruSuperlative  = []string{"ейш", "ейше"}
+	ruDerivational = []string{"ост", "ость"}
+)
+
+func isRussianVowel(r rune) bool {
+	switch r {
+	case 'а', 'е', 'и', 'о', 'у', 'ы', 'э', 'ю', 'я':
+		return true
+	}
+	return false
+}
+
+// stemRussian implements the Russian Snowball stemmer on a lower-case word with ё folded into е.
+func stemRussian(word string) string {
+	runes := []rune(word)
+
+	// RV is the region after the first vowel, R2 the region after the second vowel-consonant
+	// pair. Only the part of the word in RV is changed.
+	rv := len(runes)
+	for i, r := range runes {
+		if isRussianVowel(r) {
+			rv = i + 1
+			break
+		}
+	}
+	r1 := afterVowelConsonant(runes, 0, isRussianVowel)
+	r2 := afterVowelConsonant(runes, r1, isRussianVowel)
+
+	prefix, stem := runes[:rv], runes[rv:]
+
+	// Step 1.
+	if removed, ok := removeGrouped(stem, ruPerfectiveGerund1, ruPerfectiveGerund2); ok {
+		stem = removed
+	} else {
+		if suffix := endsWith(stem, ruReflexive); suffix != "" {
+			stem = stem[:len(stem)-runeLen(suffix)]
+		}
+		if removed, ok := removeAdjectival(stem); ok {
+			stem = removed
+		} else if removed, ok := removeGrouped(stem, ruVerb1, ruVerb2); ok {
+			stem = removed
+		} else if suffix := endsWith(stem, ruNoun); suffix != "" {
+			stem = stem[:len(stem)-runeLen(suffix)]
+		}
+	}
+
+	// Step 2.
+	if hasSuffix(stem, "и") {
+		stem = stem[:len(stem)-1]
+	}
+
+	// Step 3.
+	if suffix := endsWith(stem, ruDerivational); suffix != "" && len(prefix)+len(stem)-runeLen(suffix) >= r2 {
+		stem = stem[:len(stem)-runeLen(suffix)]
+	}
+
+	// Step 4.
+	switch {
+	case hasSuffix(stem, "нн"):
+		stem = stem[:len(stem)-1]
+	case endsWith(stem, ruSuperlative) != "":
+		stem = stem[:len(stem)-runeLen(endsWith(stem, ruSuperlative))]
+		if hasSuffix(stem, "нн") {
+			stem = stem[:len(stem)-1]
+		}
+	case hasSuffix(stem, "ь"):
+		stem = stem[:len(stem)-1]
+	}
+
+	return string(prefix) + string(stem)
+}
+
+// removeGrouped removes the longest ending of the two groups. An ending of the first group
+// is only removed after а or я.
+func removeGrouped(stem []rune, group1, group2 []string) ([]rune, bool) {
+	suffix1, suffix2 := endsWith(stem, group1), endsWith(stem, group2)
+
+	switch {
+	case suffix1 == "" && suffix2 == "":
+		return stem, false
+	case runeLen(suffix1) > runeLen(suffix2):
+		before := len(stem) - runeLen(suffix1) - 1
+		if before < 0 || (stem[before] != 'а' && stem[before] != 'я') {
+			return stem, false
+		}
+		return stem[:len(stem)-runeLen(suffix1)], true
+	default:
+		return stem[:len(stem)-runeLen(suffix2)], true
+	}
+}
+
+// removeAdjectival removes an adjective ending with the participle ending before it, if any.
+func removeAdjectival(stem []rune) ([]rune, bool) {
+	suffix := endsWith(stem, ruAdjective)
+	if suffix == "" {
+		return stem, false
+	}
+	stem = stem[:len(stem)-runeLen(suffix)]
+
+	removed, _ := removeGrouped(stem, ruParticiple1, ruParticiple2)
+	return removed, true
+}
+
+// afterVowelConsonant returns the start of the region after the first non-vowel following a vowel,
+// searching from start, or len(word) when there is none.
+func afterVowelConsonant(word []rune, start int, isVowel func(rune) bool) int {
+	for i := start + 1; i < len(word); i++ {
+		if !isVowel(word[i]) && isVowel(word[i-1]) {
+			return i + 1
+		}
+	}
+	return len(word)
+}
@@ -0,0 +1,90 @@
+package lyrics
+
+import "testing"
+
+// stemTests are words with their stems from the English and Russian Snowball stemmers.
+var stemTests = []struct {
+	word string
+	stem string
+}{
+	// English
+	{"consign", "consign"}, {"consigned", "consign"}, {"consigning", "consign"},
+	{"consignment", "consign"}, {"consist", "consist"}, {"consisted", "consist"},
+	{"consistency", "consist"}, {"consistent", "consist"}, {"consistently", "consist"},
+	{"consisting", "consist"}, {"consists", "consist"}, {"generously", "generous"},
+	{"generous", "generous"}, {"generate", "generat"}, {"generates", "generat"},
+	{"generated", "generat"}, {"generating", "generat"}, {"general", "general"},
+	{"generalization", "general"}, {"generalizations", "general"}, {"caresses", "caress"},
+	{"ponies", "poni"}, {"ties", "tie"}, {"caress", "caress"}, {"cats", "cat"}, {"feed", "feed"},
+	{"agreed", "agre"}, {"plastered", "plaster"}, {"bled", "bled"}, {"motoring", "motor"},
+	{"sing", "sing"}, {"conflated", "conflat"}, {"troubled", "troubl"}, {"sized", "size"},
+	{"hopping", "hop"}, {"tanned", "tan"}, {"falling", "fall"}, {"hissing", "hiss"}, {"fizzed", "fizz"},
+	{"failing", "fail"}, {"filing", "file"}, {"happy", "happi"}, {"sky", "sky"},
+	{"relational", "relat"}, {"conditional", "condit"}, {"rational", "ration"}, {"valenci", "valenc"},
+	{"hesitanci", "hesit"}, {"digitizer", "digit"}, {"conformabli", "conform"}, {"radicalli", "radic"},
+	{"differentli", "differ"}, {"vileli", "vile"}, {"analogousli", "analog"},
+	{"vietnamization", "vietnam"}, {"predication", "predic"}, {"operator", "oper"},
+	{"feudalism", "feudal"}, {"decisiveness", "decis"}, {"hopefulness", "hope"},
+	{"callousness", "callous"}, {"formaliti", "formal"}, {"sensitiviti", "sensit"},
+	{"sensibiliti", "sensibl"}, {"triplicate", "triplic"}, {"formative", "format"},
+	{"formalize", "formal"}, {"electriciti", "electr"}, {"electrical", "electr"}, {"hopeful", "hope"},
+	{"goodness", "good"}, {"revival", "reviv"}, {"allowance", "allow"}, {"inference", "infer"},
+	{"airliner", "airlin"}, {"gyroscopic", "gyroscop"}, {"adjustable", "adjust"},
+	{"defensible", "defens"}, {"irritant", "irrit"}, {"replacement", "replac"},
+	{"adjustment", "adjust"}, {"dependent", "depend"}, {"adoption", "adopt"},
+	{"homologou", "homologou"}, {"communism", "communism"}, {"activate", "activ"},
+	{"angulariti", "angular"}, {"homologous", "homolog"}, {"effective", "effect"},
+	{"bowdlerize", "bowdler"}, {"probate", "probat"}, {"rate", "rate"}, {"cease", "ceas"},
+	{"controll", "control"}, {"roll", "roll"}, {"dying", "die"}, {"lying", "lie"}, {"skies", "sky"},
+	{"news", "news"}, {"innings", "inning"}, {"inning", "inning"}, {"outings", "outing"},
+	{"canning", "canning"}, {"proceed", "proceed"}, {"exceed", "exceed"}, {"succeed", "succeed"},
+	{"gently", "gentl"}, {"early", "earli"}, {"only", "onli"}, {"dreaming", "dream"},
+	{"dreams", "dream"}, {"dreamed", "dream"}, {"loved", "love"}, {"lovely", "love"},
+	{"loving", "love"}, {"lover", "lover"}, {"lovers", "lover"}, {"singing", "sing"},
+	{"singer", "singer"}, {"crying", "cri"}, {"cried", "cri"}, {"cries", "cri"}, {"tonight", "tonight"},
+	{"yesterday", "yesterday"}, {"heartbreaker", "heartbreak"}, {"beautiful", "beauti"},
+	{"beautifully", "beauti"}, {"happiness", "happi"}, {"running", "run"}, {"runner", "runner"},
+	{"nights", "night"}, {"dancing", "danc"}, {"danced", "danc"}, {"wonderful", "wonder"},
+	{"ours", "our"}, {"yellow", "yellow"}, {"submarine", "submarin"}, {"remember", "rememb"},
+	{"remembering", "rememb"}, {"believe", "believ"}, {"believer", "believ"}, {"beliefs", "belief"},
+	{"forever", "forev"}, {"everything", "everyth"}, {"together", "togeth"}, {"arsenal", "arsenal"},
+	{"communication", "communic"}, {"organization", "organ"}, {"organizations", "organ"},
+	// Russian
+	{"в", "в"}, {"вавиловка", "вавиловк"}, {"вагнера", "вагнер"}, {"вагон", "вагон"},
+	{"вагона", "вагон"}, {"вагоне", "вагон"}, {"вагонов", "вагон"}, {"вагоном", "вагон"},
+	{"вагоны", "вагон"}, {"важная", "важн"}, {"важнее", "важн"}, {"важнейшие", "важн"},
+	{"важнейшими", "важн"}, {"важничаешь", "важнича"}, {"важно", "важн"}, {"важного", "важн"},
+	{"важной", "важн"}, {"важном", "важн"}, {"важному", "важн"}, {"важную", "важн"}, {"важный", "важн"},
+	{"важных", "важн"}, {"вазах", "ваз"}, {"вазы", "ваз"}, {"вакса", "вакс"}, {"вакханка", "вакханк"},
+	{"вал", "вал"}, {"валандался", "валанда"}, {"валентина", "валентин"}, {"валерьян", "валерья"},
+	{"валерьяна", "валерья"}, {"валерьяном", "валерьян"}, {"валетами", "валет"}, {"вали", "вал"},
+	{"валил", "вал"}, {"валился", "вал"}, {"валится", "вал"}, {"валов", "вал"},
+	{"вальдшнепа", "вальдшнеп"}, {"вальс", "вальс"}, {"вальса", "вальс"}, {"вальсе", "вальс"},
+	{"вальсишку", "вальсишк"}, {"вальтер", "вальтер"}, {"валяется", "валя"}, {"валялась", "валя"},
+	{"валялись", "валя"}, {"валялось", "валя"}, {"валялся", "валя"}, {"валять", "валя"},
+	{"валяются", "валя"}, {"вам", "вам"}, {"вами", "вам"}, {"любовь", "любов"}, {"любви", "любв"},
+	{"любовью", "любов"}, {"любить", "люб"}, {"люблю", "любл"}, {"любил", "люб"}, {"любила", "люб"},
+	{"любимый", "любим"}, {"любимая", "любим"}, {"любимого", "любим"}, {"песня", "песн"},
+	{"песни", "песн"}, {"песен", "пес"}, {"песнями", "песн"}, {"песнях", "песн"}, {"ночь", "ноч"},
+	{"ночи", "ноч"}, {"ночью", "ноч"}, {"ночами", "ноч"}, {"сердце", "сердц"}, {"сердца", "сердц"},
+	{"сердцем", "сердц"}, {"сердечный", "сердечн"}, {"звезда", "звезд"}, {"звезды", "звезд"},
+	{"звёзды", "звёзды"}, {"звездой", "звезд"}, {"город", "город"}, {"города", "город"},
+	{"городами", "город"}, {"городской", "городск"}, {"танцевать", "танцева"}, {"танцую", "танц"},
+	{"танцевали", "танцева"}, {"танцующий", "танц"}, {"бегущий", "бегущ"}, {"прочитанный", "прочита"},
+	{"прочитавши", "прочита"}, {"улыбаясь", "улыб"}, {"улыбнулась", "улыбнул"},
+	{"улыбнувшись", "улыбнувш"}, {"красивейший", "красив"}, {"красивее", "красив"},
+	{"красивость", "красив"}, {"красота", "красот"}, {"мечтания", "мечтан"},
+	{"мечтательность", "мечтательн"}, {"счастье", "счаст"}, {"счастливый", "счастлив"},
+	{"счастливейшие", "счастлив"}, {"дорогой", "дорог"}, {"дороги", "дорог"}, {"дорогами", "дорог"},
+	{"говорить", "говор"}, {"говорит", "говор"}, {"говорили", "говор"}, {"говорившие", "говор"},
+	{"сказал", "сказа"}, {"сказала", "сказа"}, {"сказавшись", "сказа"},
+}
+
+func TestStem(t *testing.T) {
+	for _, test := range stemTests {
+		got := Stem(test.word)
+		if got != test.stem {
+			t.Errorf("Stem(%q) = %q, want %q", test.word, got, test.stem)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package lyrics
+
+import "strings"
+
+// Languages of the stop word lists, with Undetermined for texts in neither of them.
+const (
+	English      = "en"
+	Russian      = "ru"
+	Undetermined = "und"
+)
+
+// stopWords are the most frequent function words of each language, in the form produced by Words.
+var stopWords = map[string]map[string]bool{
+	English: set(`
+a about above after again against ain all am an and any are aren't as at be because been before being
+below between both but by can can't cannot could couldn't did didn't do does doesn't doing don't down
+during each few for from further gonna gotta had hadn't has hasn't have haven't having he he'd he'll he's
+her here here's hers herself him himself his how how's i i'd i'll i'm i've if in into is isn't it it's its
+itself just let's me more most mustn't my myself no nor not now of off oh on once only or other ought our
+ours ourselves out over own same shan't she she'd she'll she's should shouldn't so some such than that
+that's the their theirs them themselves then there there's these they they'd they'll they're they've this
+those through to too under until up us very wanna was wasn't we we'd we'll we're we've were weren't what
+what's when when's where where's which while who who's whom why why's will with won't would wouldn't yeah
+you you'd you'll you're you've your yours yourself yourselves`),
+	Russian: set(`
+а без более больше будет будто бы был была были было быть в вам вас вдруг ведь во вот впрочем все всегда
+всего всех всю вы где да даже два для до другой его ее ей ему если есть еще ж же за зачем здесь и из или
+им иногда их к как какая какой когда конечно кто куда ли лучше между меня мне много может можно мой моя мы
+на над надо наконец нас не него нее ней нельзя нет ни нибудь никогда ним них ничего но ну о об один он она
+они опять от перед по под после потом потому почти при про раз разве с сам свою себе себя сейчас со совсем
+так такой там тебе тебя тем теперь то тогда того тоже только том тот три тут ты у уж уже хорошо хоть чего
+чем через что чтоб чтобы чуть эти этого этой этом этот эту я ах ох эх ой`),
+}
+
+func set(words string) map[string]bool {
+	fields := strings.Fields(words)
+	m := make(map[string]bool, len(fields))
+	for _, word := range fields {
+		m[word] = true
+	}
+	return m
+}
+
+// IsStopWord reports whether a word is an English or Russian stop word.
+func IsStopWord(word string) bool {
+	return stopWords[English][word] || stopWords[Russian][word]
+}
@@ -0,0 +1,93 @@
+// Package lyrics splits song texts into words and filters the English and Russian stop words.
+package lyrics
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Words splits a text into lower-case words. Letters, digits and marks form words; an
+// apostrophe or hyphen joins two words ("don't", "по-русски"). Ё is folded into е.
+func Words(text string) []string {
+	var words []string
+	tokenize([]rune(text), func(_, _ int, word string) {
+		words = append(words, word)
+	})
+	return words
+}
+
+// tokenize calls yield with each word of Words and the range of runes it was read from.
+func tokenize(runes []rune, yield func(start, end int, word string)) {
+	var word strings.Builder
+	start := 0
+
+	for i, r := range runes {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || (unicode.Is(unicode.Mn, r) && word.Len() > 0):
+			if word.Len() == 0 {
+				start = i
+			}
+			word.WriteRune(fold(r))
+		case isJoiner(r) && word.Len() > 0 && i+1 < len(runes) && unicode.IsLetter(runes[i+1]):
+			if r != '-' {
+				r = '\''
+			}
+			word.WriteRune(r)
+		default:
+			if word.Len() > 0 {
+				yield(start, i, word.String())
+				word.Reset()
+			}
+		}
+	}
+	if word.Len() > 0 {
+		yield(start, len(runes), word.String())
+	}
+}
+
+// Terms counts the words of a text that carry meaning: stop words, numbers and single letters are left out.
+func Terms(text string) map[string]int {
+	terms := make(map[string]int)
+	for _, word := range Words(text) {
+		if IsTerm(word) {
+			terms[word]++
+		}
+	}
+	return terms
+}
+
+// IsTerm reports whether a word from Words carries meaning.
+func IsTerm(word string) bool {
+	if IsStopWord(word) {
+		return false
+	}
+	return countLetters(word) > 1
+}
+
+// countLetters counts the letters of a word.
+func countLetters(word string) int {
+	n := 0
+	for _, r := range word {
+		if unicode.IsLetter(r) {
+			n++
+		}
+	}
+	return n
+}
+
+func fold(r rune) rune {
+	r = unicode.ToLower(r)
+	if r == 'ё' {
+		return 'е'
+	}
+	return r
+}
+
+// isJoiner reports whether r joins the parts of a word: apostrophes and hyphens.
+func isJoiner(r rune) bool {
+	switch r {
+	case '\'', '’', 'ʼ', '-', '‐':
+		return true
+	}
+	return false
+}
@@ -7,17 +7,27 @@
 // @host localhost:8081
 // @BasePath /
 
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description Access token or API key as "Bearer <token>"
+
 package main
 
 import (
 	"fmt"
 	"github.com/gorilla/mux"
 	"github.com/joho/godotenv"
+	"github.com/noctusha/music/auth"
 	"github.com/noctusha/music/connection"
 	"github.com/noctusha/music/handlers"
+	"github.com/noctusha/music/lyrics"
 	"log"
+	"net"
 	"net/http"
 	"os"
+	"path/filepath"
+	"time"
 
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
@@ -28,6 +38,7 @@ import (
 )
 
 // main is the entry point of the application.
+// Without arguments it starts the HTTP server; otherwise the first argument selects a command.
 func main() {
 	err := godotenv.Load()
 	if err != nil {
@@ -40,6 +51,17 @@ func main() {
 	}
 	defer repo.Close()
 
+	// Without word lists the detected flags are kept as they are and lyrics cannot be censored.
+	explicitDir := os.Getenv("EXPLICIT_WORDS_DIR")
+	if explicitDir == "" {
+		log.Printf("EXPLICIT_WORDS_DIR is not set, explicit songs are not detected")
+	} else {
+		repo.Explicit, err = lyrics.LoadWordLists(explicitDir)
+		if err != nil {
+			log.Fatalf("error loading explicit words: %v", err)
+		}
+	}
+
 	migrationDir := os.Getenv("MIGRATION_DIR")
 	if migrationDir == "" {
 		migrationDir = "file://migrations"
@@ -58,18 +80,189 @@ func main() {
 		log.Fatalf("Error applying migrations: %v", err)
 	}
 
-	handler := handlers.NewHandler(repo)
+	command := "serve"
+	if len(os.Args) > 1 {
+		command = os.Args[1]
+	}
+
+	switch command {
+	case "serve":
+		serve(repo)
+	case "songbook":
+		err = runSongbook(repo, os.Args[2:])
+	case "import":
+		err = runImport(repo, os.Args[2:])
+	case "export":
+		err = runExport(repo, os.Args[2:])
+	case "scan":
+		err = runScan(repo, os.Args[2:])
+	case "role":
+		err = runRole(repo, os.Args[2:])
+	default:
+		err = fmt.Errorf("unknown command: %v", command)
+	}
+	if err != nil {
+		log.Fatalf("%v: %v", command, err)
+	}
+}
+
+// serve registers the routes and starts the HTTP server.
+func serve(repo *connection.Repository) {
+	signer, err := auth.NewSignerFromEnv()
+	if err != nil {
+		log.Fatalf("error initializing token signer: %v", err)
+	}
+
+	indexPath, err := fulltextIndexPath()
+	if err != nil {
+		log.Fatalf("error locating search index: %v", err)
+	}
+	handler := handlers.NewHandler(repo, signer, indexPath)
+
+	// The search index is reloaded from its last snapshot, and its first sync only reindexes the songs changed since.
+	err = handler.Fulltext.Load()
+	if err != nil {
+		log.Printf("error loading search index: %v", err)
+	}
+
+	// The explicit words may have changed since the songs were written. Without word lists the
+	// detected flags are kept as they are.
+	if repo.Explicit != nil {
+		go func() {
+			changed, err := repo.DetectExplicit()
+			if err != nil {
+				log.Printf("error detecting explicit songs: %v", err)
+			} else if changed > 0 {
+				log.Printf("explicit flag changed for %d songs", changed)
+			}
+		}()
+	}
+
+	go every(refreshInterval("CHARTS_REFRESH_INTERVAL"), repo.RefreshCharts)
+	go every(refreshInterval("SIMILAR_REFRESH_INTERVAL"), handler.Similar.Sync)
+	go every(refreshInterval("SUGGEST_REFRESH_INTERVAL"), handler.Suggest.Sync)
+	go every(refreshInterval("FULLTEXT_REFRESH_INTERVAL"), handler.Fulltext.Sync)
+
+	graphqlHandler, err := handler.GraphQL()
+	if err != nil {
+		log.Fatalf("error initializing GraphQL: %v", err)
+	}
 
 	router := mux.NewRouter()
+	router.Use(handler.Authenticate)
 
 	// Swagger UI handler
 	router.PathPrefix("/swagger/").Handler(httpSwagger.WrapHandler)
 
-	router.Methods(http.MethodGet).Path("/api/songs").HandlerFunc(handler.ListSongs)
-	router.Methods(http.MethodGet).Path("/api/songs/{song_id}/text").HandlerFunc(handler.GetText)
-	router.Methods(http.MethodDelete).Path("/api/songs/{song_id}/delete").HandlerFunc(handler.DeleteSong)
-	router.Methods(http.MethodPatch).Path("/api/songs/{song_id}/edit").HandlerFunc(handler.EditSong)
-	router.Methods(http.MethodPost).Path("/api/songs/new").HandlerFunc(handler.NewSong)
+	// The resource-oriented routes of /api/v2 replace the v1 song routes, which are deprecated.
+	v2 := router.PathPrefix("/api/v2").Subrouter()
+	v2.Methods(http.MethodGet).Path("/songs").HandlerFunc(handler.ListSongs)
+	v2.Methods(http.MethodPost).Path("/songs").Handler(handler.RequirePermission(auth.PermSongsCreate, handler.CreateSongV2))
+	v2.Methods(http.MethodGet).Path("/songs/{song_id:[0-9]+}").HandlerFunc(handler.GetSong)
+	v2.Methods(http.MethodPut).Path("/songs/{song_id:[0-9]+}").Handler(handler.RequirePermission(auth.PermSongsEdit, handler.ReplaceSong))
+	v2.Methods(http.MethodPatch).Path("/songs/{song_id:[0-9]+}").Handler(handler.RequirePermission(auth.PermSongsEdit, handler.PatchSong))
+	v2.Methods(http.MethodDelete).Path("/songs/{song_id:[0-9]+}").Handler(handler.RequirePermission(auth.PermSongsDelete, handler.DeleteSongV2))
+	v2.Methods(http.MethodGet).Path("/songs/{song_id:[0-9]+}/text").HandlerFunc(handler.GetText)
+
+	router.Methods(http.MethodGet).Path("/api/songs").Handler(handler.Deprecated("/api/v2/songs", http.HandlerFunc(handler.ListSongs)))
+	router.Methods(http.MethodGet).Path("/api/songs/{song_id:[0-9]+}").HandlerFunc(handler.GetSong)
+	router.Methods(http.MethodGet).Path("/api/songs/{song_id}/text").Handler(handler.Deprecated("/api/v2/songs/{song_id}/text", http.HandlerFunc(handler.GetText)))
+	router.Methods(http.MethodDelete).Path("/api/songs/{song_id}/delete").Handler(handler.Deprecated("/api/v2/songs/{song_id}", handler.RequirePermission(auth.PermSongsDelete, handler.DeleteSong)))
+	router.Methods(http.MethodPatch).Path("/api/songs/{song_id}/edit").Handler(handler.Deprecated("/api/v2/songs/{song_id}", handler.RequirePermission(auth.PermSongsEdit, handler.EditSong)))
+	router.Methods(http.MethodPost).Path("/api/songs/new").Handler(handler.Deprecated("/api/v2/songs", handler.RequirePermission(auth.PermSongsCreate, handler.NewSong)))
+	router.Methods(http.MethodPost).Path("/api/songs/import").Handler(handler.RequirePermission(auth.PermSongsImport, handler.ImportSongs))
+	router.Methods(http.MethodGet).Path("/api/songs/duplicates").Handler(handler.RequirePermission(auth.PermSongsMerge, handler.ListDuplicateSongs))
+	router.Methods(http.MethodPost).Path("/api/songs/{song_id:[0-9]+}/merge").Handler(handler.RequirePermission(auth.PermSongsMerge, handler.MergeSongs))
+	router.Methods(http.MethodGet).Path("/api/songs/{song_id:[0-9]+}/stats").HandlerFunc(handler.GetSongStats)
+	router.Methods(http.MethodGet).Path("/api/songs/{song_id:[0-9]+}/similar").HandlerFunc(handler.GetSimilarSongs)
+	router.Methods(http.MethodGet).Path("/api/songs/{song_id:[0-9]+}/analytics").HandlerFunc(handler.GetSongAnalytics)
+	router.Methods(http.MethodPut).Path("/api/songs/{song_id:[0-9]+}/explicit").Handler(handler.RequirePermission(auth.PermSongsEdit, handler.SetSongExplicit))
+	router.Methods(http.MethodGet).Path("/api/songs/{song_id:[0-9]+}/taxonomy").HandlerFunc(handler.GetSongTaxonomy)
+	router.Methods(http.MethodPut).Path("/api/songs/{song_id:[0-9]+}/taxonomy").Handler(handler.RequirePermission(auth.PermSongsEdit, handler.SetSongTaxonomy))
+	router.Methods(http.MethodPut).Path("/api/songs/{song_id:[0-9]+}/credits").Handler(handler.RequirePermission(auth.PermSongsEdit, handler.SetSongCredits))
+	router.Methods(http.MethodPut).Path("/api/songs/{song_id:[0-9]+}/favorite").Handler(handler.RequireUser(handler.AddFavorite))
+	router.Methods(http.MethodDelete).Path("/api/songs/{song_id:[0-9]+}/favorite").Handler(handler.RequireUser(handler.RemoveFavorite))
+	router.Methods(http.MethodPut).Path("/api/songs/{song_id:[0-9]+}/rating").Handler(handler.RequireUser(handler.RateSong))
+	router.Methods(http.MethodDelete).Path("/api/songs/{song_id:[0-9]+}/rating").Handler(handler.RequireUser(handler.RemoveRating))
+	router.Methods(http.MethodPost).Path("/api/songs/{song_id:[0-9]+}/plays").Handler(handler.RequireUser(handler.RecordPlay))
+	router.Methods(http.MethodPost).Path("/api/scrobbles").Handler(handler.RequireUser(handler.Scrobble))
+	router.Methods(http.MethodGet).Path("/api/history").Handler(handler.RequireUser(handler.ListHistory))
+	router.Methods(http.MethodPost).Path("/api/songs/{song_id:[0-9]+}/suggestions").Handler(handler.RequireUser(handler.SuggestEdit))
+	router.Methods(http.MethodGet).Path("/api/suggestions").Handler(handler.RequireUser(handler.ListSuggestions))
+	router.Methods(http.MethodGet).Path("/api/suggestions/{suggestion_id:[0-9]+}").Handler(handler.RequireUser(handler.GetSuggestion))
+	router.Methods(http.MethodPost).Path("/api/suggestions/{suggestion_id:[0-9]+}/approve").Handler(handler.RequirePermission(auth.PermSongsEdit, handler.ApproveSuggestion))
+	router.Methods(http.MethodPost).Path("/api/suggestions/{suggestion_id:[0-9]+}/reject").Handler(handler.RequirePermission(auth.PermSongsEdit, handler.RejectSuggestion))
+	router.Methods(http.MethodGet).Path("/api/suggest").HandlerFunc(handler.Autocomplete)
+	router.Methods(http.MethodGet).Path("/api/search").HandlerFunc(handler.Search)
+	router.Methods(http.MethodPost).Path("/api/graphql").Handler(graphqlHandler)
+	router.Methods(http.MethodGet).Path("/api/stats").HandlerFunc(handler.GetStats)
+	router.Methods(http.MethodGet).Path("/api/charts/top-played").HandlerFunc(handler.TopPlayed)
+	router.Methods(http.MethodGet).Path("/api/charts/top-rated").HandlerFunc(handler.TopRated)
+	router.Methods(http.MethodGet).Path("/api/songbook").HandlerFunc(handler.Songbook)
+	router.Methods(http.MethodGet).Path("/api/export").HandlerFunc(handler.ExportCatalogue)
+	router.Methods(http.MethodPost).Path("/api/playlists/import").HandlerFunc(handler.ImportPlaylist)
+	router.Methods(http.MethodGet).Path("/api/playlists/export").HandlerFunc(handler.ExportPlaylist)
+	router.Methods(http.MethodPost).Path("/api/auth/register").HandlerFunc(handler.Register)
+	router.Methods(http.MethodPost).Path("/api/auth/login").HandlerFunc(handler.Login)
+	router.Methods(http.MethodPost).Path("/api/auth/refresh").HandlerFunc(handler.Refresh)
+	router.Methods(http.MethodGet).Path("/api/auth/me").Handler(handler.RequireUser(handler.Me))
+	router.Methods(http.MethodGet).Path("/api/auth/keys").Handler(handler.RequireUser(handler.ListAPIKeys))
+	router.Methods(http.MethodPost).Path("/api/auth/keys").Handler(handler.RequireUser(handler.CreateAPIKey))
+	router.Methods(http.MethodDelete).Path("/api/auth/keys/{key_id:[0-9]+}").Handler(handler.RequireUser(handler.DeleteAPIKey))
+	router.Methods(http.MethodGet).Path("/.well-known/jwks.json").HandlerFunc(handler.JWKS)
+	router.Methods(http.MethodPut).Path("/api/users/{user_id:[0-9]+}/role").Handler(handler.RequirePermission(auth.PermUsersManage, handler.SetUserRole))
+	router.Methods(http.MethodGet).Path("/api/groups/{group_id:[0-9]+}/analytics").HandlerFunc(handler.GetGroupAnalytics)
+	router.Methods(http.MethodGet).Path("/api/groups/{group_id:[0-9]+}/taxonomy").HandlerFunc(handler.GetGroupTaxonomy)
+	router.Methods(http.MethodPut).Path("/api/groups/{group_id:[0-9]+}/taxonomy").Handler(handler.RequirePermission(auth.PermSongsEdit, handler.SetGroupTaxonomy))
+	router.Methods(http.MethodGet).Path("/api/groups/duplicates").Handler(handler.RequirePermission(auth.PermGroupsMerge, handler.ListDuplicateGroups))
+	router.Methods(http.MethodPost).Path("/api/groups/{group_id:[0-9]+}/merge").Handler(handler.RequirePermission(auth.PermGroupsMerge, handler.MergeGroups))
+	router.Methods(http.MethodGet).Path("/api/groups/{group_id:[0-9]+}/aliases").HandlerFunc(handler.ListGroupAliases)
+	router.Methods(http.MethodPost).Path("/api/groups/{group_id:[0-9]+}/aliases").Handler(handler.RequirePermission(auth.PermSongsEdit, handler.AddGroupAlias))
+	router.Methods(http.MethodDelete).Path("/api/groups/{group_id:[0-9]+}/aliases/{alias_id:[0-9]+}").Handler(handler.RequirePermission(auth.PermSongsEdit, handler.DeleteGroupAlias))
+	router.Methods(http.MethodPost).Path("/api/groups").Handler(handler.RequirePermission(auth.PermSongsCreate, handler.CreateGroup))
+	router.Methods(http.MethodPatch).Path("/api/groups/{group_id:[0-9]+}").Handler(handler.RequirePermission(auth.PermSongsEdit, handler.RenameGroup))
+	router.Methods(http.MethodDelete).Path("/api/groups/{group_id:[0-9]+}").Handler(handler.RequirePermission(auth.PermGroupsDelete, handler.DeleteGroup))
+	router.Methods(http.MethodGet).Path("/api/groups/{group_id:[0-9]+}/maintainers").Handler(handler.RequirePermission(auth.PermUsersManage, handler.ListGroupMaintainers))
+	router.Methods(http.MethodPut).Path("/api/groups/{group_id:[0-9]+}/maintainers/{user_id:[0-9]+}").Handler(handler.RequirePermission(auth.PermUsersManage, handler.AddGroupMaintainer))
+	router.Methods(http.MethodDelete).Path("/api/groups/{group_id:[0-9]+}/maintainers/{user_id:[0-9]+}").Handler(handler.RequirePermission(auth.PermUsersManage, handler.RemoveGroupMaintainer))
+	router.Methods(http.MethodGet).Path("/api/genres").HandlerFunc(handler.ListGenres)
+	router.Methods(http.MethodPost).Path("/api/genres").Handler(handler.RequirePermission(auth.PermGenresManage, handler.CreateGenre))
+	router.Methods(http.MethodPut).Path("/api/genres/{genre_id:[0-9]+}").Handler(handler.RequirePermission(auth.PermGenresManage, handler.UpdateGenre))
+	router.Methods(http.MethodDelete).Path("/api/genres/{genre_id:[0-9]+}").Handler(handler.RequirePermission(auth.PermGenresManage, handler.DeleteGenre))
+	router.Methods(http.MethodGet).Path("/api/tags").HandlerFunc(handler.ListTags)
+	router.Methods(http.MethodGet).Path("/api/playlists").HandlerFunc(handler.ListPlaylists)
+	router.Methods(http.MethodPost).Path("/api/playlists").Handler(handler.RequireUser(handler.CreatePlaylist))
+	router.Methods(http.MethodGet).Path("/api/playlists/{playlist_id:[0-9]+}").HandlerFunc(handler.GetPlaylist)
+	router.Methods(http.MethodPatch).Path("/api/playlists/{playlist_id:[0-9]+}").Handler(handler.RequireUser(handler.EditPlaylist))
+	router.Methods(http.MethodDelete).Path("/api/playlists/{playlist_id:[0-9]+}").Handler(handler.RequireUser(handler.DeletePlaylist))
+	router.Methods(http.MethodPost).Path("/api/playlists/{playlist_id:[0-9]+}/entries").Handler(handler.RequireUser(handler.AddPlaylistEntry))
+	router.Methods(http.MethodPatch).Path("/api/playlists/{playlist_id:[0-9]+}/entries/{entry_id:[0-9]+}").Handler(handler.RequireUser(handler.MovePlaylistEntry))
+	router.Methods(http.MethodDelete).Path("/api/playlists/{playlist_id:[0-9]+}/entries/{entry_id:[0-9]+}").Handler(handler.RequireUser(handler.RemovePlaylistEntry))
+	router.Methods(http.MethodPost).Path("/api/playlists/{playlist_id:[0-9]+}/share").Handler(handler.RequireUser(handler.SharePlaylist))
+	router.Methods(http.MethodDelete).Path("/api/playlists/{playlist_id:[0-9]+}/share").Handler(handler.RequireUser(handler.UnsharePlaylist))
+	router.Methods(http.MethodPost).Path("/api/playlists/{playlist_id:[0-9]+}/duplicate").Handler(handler.RequireUser(handler.DuplicatePlaylist))
+	router.Methods(http.MethodPost).Path("/api/playlists/{playlist_id:[0-9]+}/merge").Handler(handler.RequireUser(handler.MergePlaylist))
+	router.Methods(http.MethodGet).Path("/api/playlists/{playlist_id:[0-9]+}/export").HandlerFunc(handler.ExportUserPlaylist)
+	router.Methods(http.MethodGet).Path("/api/shared/{token}").HandlerFunc(handler.GetSharedPlaylist)
+
+	grpcAddress := os.Getenv("GRPC_ADDRESS")
+	if grpcAddress == "" {
+		grpcAddress = ":9090"
+	}
+
+	listener, err := net.Listen("tcp", grpcAddress)
+	if err != nil {
+		log.Fatalf("error listening for gRPC: %v", err)
+	}
+
+	go func() {
+		fmt.Printf("gRPC server is running on %v\n", grpcAddress)
+
+		err := handler.GRPC().Serve(listener)
+		if err != nil {
+			log.Fatalf("error starting gRPC server: %v", err)
+		}
+	}()
 
 	fmt.Printf("server is running on port %v\n", os.Getenv("SERVER_ADDRESS"))
 
@@ -77,5 +270,52 @@ func main() {
 	if err != nil {
 		log.Fatalf("error starting server: %v", err)
 	}
+}
+
+// refreshInterval reads a refresh interval from the environment, 10 minutes by default.
+func refreshInterval(name string) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return 10 * time.Minute
+	}
+
+	interval, err := time.ParseDuration(value)
+	if err != nil || interval <= 0 {
+		log.Fatalf("invalid %s: %v", name, value)
+	}
+	return interval
+}
+
+// every runs refresh at startup and then every interval, logging its errors.
+func every(interval time.Duration, refresh func() error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		err := refresh()
+		if err != nil {
+			log.Printf("%v", err)
+		}
+		<-ticker.C
+	}
+}
+
+// fulltextIndexPath returns FULLTEXT_INDEX_PATH, or fulltext.idx in the music directory of the user
+// cache directory, which is created if missing.
+func fulltextIndexPath() (string, error) {
+	path := os.Getenv("FULLTEXT_INDEX_PATH")
+	if path != "" {
+		return path, nil
+	}
 
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("FULLTEXT_INDEX_PATH is not set and there is no cache directory: %v", err)
+	}
+	dir := filepath.Join(cacheDir, "music")
+	err = os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return "", fmt.Errorf("error creating %s: %v", dir, err)
+	}
+	return filepath.Join(dir, "fulltext.idx"), nil
 }
@@ -0,0 +1 @@
+ALTER TABLE song_details ADD COLUMN IF NOT EXISTS album VARCHAR(255) NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS group_maintainers;
+ALTER TABLE users DROP COLUMN IF EXISTS role;
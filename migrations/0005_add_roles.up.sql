@@ -0,0 +1,11 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(16) NOT NULL DEFAULT 'reader'
+    CHECK (role IN ('reader', 'editor', 'admin'));
+
+-- Editors can only create and edit songs of the groups they maintain.
+CREATE TABLE IF NOT EXISTS group_maintainers (
+    group_id INTEGER NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    PRIMARY KEY (group_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_group_maintainers_user_id ON group_maintainers (user_id);
@@ -0,0 +1,30 @@
+-- Suggested edits keep a snapshot of the song they were made against: a pending
+-- suggestion is rejected once the song no longer matches the snapshot.
+-- Empty proposed values and a NULL group_id leave the field unchanged.
+CREATE TABLE IF NOT EXISTS song_suggestions (
+    id SERIAL PRIMARY KEY,
+    song_id INTEGER NOT NULL REFERENCES songs(id) ON DELETE CASCADE,
+    user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'rejected')),
+    comment TEXT NOT NULL DEFAULT '',
+    name VARCHAR(255) NOT NULL DEFAULT '',
+    group_id INTEGER REFERENCES groups(id) ON DELETE CASCADE,
+    release_date VARCHAR(10) NOT NULL DEFAULT '',
+    text TEXT NOT NULL DEFAULT '',
+    link TEXT NOT NULL DEFAULT '',
+    album VARCHAR(255) NOT NULL DEFAULT '',
+    base_name VARCHAR(255) NOT NULL,
+    base_group_id INTEGER NOT NULL,
+    base_release_date VARCHAR(10) NOT NULL,
+    base_text TEXT NOT NULL,
+    base_link TEXT NOT NULL,
+    base_album VARCHAR(255) NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    reviewed_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    reviewed_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_song_suggestions_status ON song_suggestions (status, created_at);
+CREATE INDEX IF NOT EXISTS idx_song_suggestions_song_id ON song_suggestions (song_id);
+CREATE INDEX IF NOT EXISTS idx_song_suggestions_user_id ON song_suggestions (user_id);
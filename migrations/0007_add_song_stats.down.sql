@@ -0,0 +1,4 @@
+DROP VIEW IF EXISTS song_stats;
+DROP TABLE IF EXISTS song_plays;
+DROP TABLE IF EXISTS song_ratings;
+DROP TABLE IF EXISTS song_favorites;
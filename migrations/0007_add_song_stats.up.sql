@@ -0,0 +1,49 @@
+CREATE TABLE IF NOT EXISTS song_favorites (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    song_id INTEGER NOT NULL REFERENCES songs(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, song_id)
+);
+
+CREATE TABLE IF NOT EXISTS song_ratings (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    song_id INTEGER NOT NULL REFERENCES songs(id) ON DELETE CASCADE,
+    rating SMALLINT NOT NULL CHECK (rating BETWEEN 1 AND 5),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, song_id)
+);
+
+CREATE TABLE IF NOT EXISTS song_plays (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    song_id INTEGER NOT NULL REFERENCES songs(id) ON DELETE CASCADE,
+    played_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_song_favorites_song_id ON song_favorites (song_id);
+CREATE INDEX IF NOT EXISTS idx_song_ratings_song_id ON song_ratings (song_id);
+CREATE INDEX IF NOT EXISTS idx_song_plays_song_id ON song_plays (song_id);
+CREATE INDEX IF NOT EXISTS idx_song_plays_user_id ON song_plays (user_id, played_at);
+
+-- Aggregates shown on song responses and used to sort by popularity.
+CREATE OR REPLACE VIEW song_stats AS
+SELECT
+    songs.id AS song_id,
+    COALESCE(ratings.average, 0) AS average_rating,
+    COALESCE(ratings.count, 0) AS ratings,
+    COALESCE(plays.count, 0) AS plays,
+    COALESCE(favorites.count, 0) AS favorites
+FROM
+    songs
+LEFT JOIN
+    (SELECT song_id, avg(rating)::float8 AS average, count(*) AS count FROM song_ratings GROUP BY song_id) AS ratings
+ON
+    ratings.song_id = songs.id
+LEFT JOIN
+    (SELECT song_id, count(*) AS count FROM song_plays GROUP BY song_id) AS plays
+ON
+    plays.song_id = songs.id
+LEFT JOIN
+    (SELECT song_id, count(*) AS count FROM song_favorites GROUP BY song_id) AS favorites
+ON
+    favorites.song_id = songs.id;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_song_plays_unique;
+ALTER TABLE song_plays DROP COLUMN IF EXISTS duration;
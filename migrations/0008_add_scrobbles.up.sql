@@ -0,0 +1,7 @@
+ALTER TABLE song_plays ADD COLUMN IF NOT EXISTS duration INTEGER CHECK (duration >= 0);
+
+-- A play is identified by its user, song and time, so resubmitted scrobbles are ignored.
+DELETE FROM song_plays AS a USING song_plays AS b
+WHERE a.id > b.id AND a.user_id = b.user_id AND a.song_id = b.song_id AND a.played_at = b.played_at;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_song_plays_unique ON song_plays (user_id, song_id, played_at);
@@ -0,0 +1 @@
+DROP MATERIALIZED VIEW IF EXISTS song_daily_stats;
@@ -0,0 +1,24 @@
+-- Plays and ratings per song and day (UTC), the source of the charts. The view is
+-- refreshed on a schedule by the server, so charts lag behind by up to the refresh interval.
+CREATE MATERIALIZED VIEW IF NOT EXISTS song_daily_stats AS
+SELECT
+    song_id,
+    day,
+    sum(plays)::integer AS plays,
+    sum(ratings)::integer AS ratings,
+    sum(rating_sum)::integer AS rating_sum
+FROM (
+    SELECT song_id, (played_at AT TIME ZONE 'UTC')::date AS day, count(*) AS plays, 0 AS ratings, 0 AS rating_sum
+    FROM song_plays
+    GROUP BY 1, 2
+    UNION ALL
+    SELECT song_id, (updated_at AT TIME ZONE 'UTC')::date AS day, 0, count(*), sum(rating)
+    FROM song_ratings
+    GROUP BY 1, 2
+) AS daily
+GROUP BY
+    song_id, day;
+
+-- The unique index allows REFRESH MATERIALIZED VIEW CONCURRENTLY.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_song_daily_stats_song_day ON song_daily_stats (song_id, day);
+CREATE INDEX IF NOT EXISTS idx_song_daily_stats_day ON song_daily_stats (day);
@@ -0,0 +1,9 @@
+-- Term counts of the lyrics, the stored part of the TF-IDF vectors used for similar songs.
+-- text_hash is the md5 of the text the terms were counted from, so only changed lyrics are recounted.
+CREATE TABLE IF NOT EXISTS song_terms (
+    song_id INTEGER PRIMARY KEY REFERENCES songs(id) ON DELETE CASCADE,
+    text_hash CHAR(32) NOT NULL,
+    terms TEXT[] NOT NULL,
+    counts INTEGER[] NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
@@ -0,0 +1,3 @@
+ALTER TABLE songs DROP COLUMN IF EXISTS explicit;
+ALTER TABLE songs DROP COLUMN IF EXISTS explicit_override;
+ALTER TABLE songs DROP COLUMN IF EXISTS explicit_detected;
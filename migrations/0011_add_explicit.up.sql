@@ -0,0 +1,5 @@
+-- explicit_detected is computed from the lyrics on write; editors may override it.
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS explicit_detected BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS explicit_override BOOLEAN;
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS explicit BOOLEAN
+    GENERATED ALWAYS AS (COALESCE(explicit_override, explicit_detected)) STORED;
@@ -0,0 +1,9 @@
+DROP VIEW IF EXISTS song_effective_tags;
+DROP VIEW IF EXISTS song_effective_genres;
+DROP VIEW IF EXISTS genre_ancestors;
+DROP TABLE IF EXISTS group_tags;
+DROP TABLE IF EXISTS song_tags;
+DROP TABLE IF EXISTS group_genres;
+DROP TABLE IF EXISTS song_genres;
+DROP TABLE IF EXISTS tags;
+DROP TABLE IF EXISTS genres;
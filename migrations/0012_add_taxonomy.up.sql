@@ -0,0 +1,69 @@
+-- Genres form a tree: a subgenre belongs to its parent, rock > alternative rock.
+CREATE TABLE IF NOT EXISTS genres (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    parent_id INTEGER REFERENCES genres(id) ON DELETE RESTRICT
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_genres_name ON genres (lower(name));
+CREATE INDEX IF NOT EXISTS idx_genres_parent_id ON genres (parent_id);
+
+-- Tags and moods are free-form labels, created when first used.
+CREATE TABLE IF NOT EXISTS tags (
+    id SERIAL PRIMARY KEY,
+    kind VARCHAR(8) NOT NULL CHECK (kind IN ('tag', 'mood')),
+    name VARCHAR(64) NOT NULL,
+    UNIQUE (kind, name)
+);
+
+CREATE TABLE IF NOT EXISTS song_genres (
+    song_id INTEGER NOT NULL REFERENCES songs(id) ON DELETE CASCADE,
+    genre_id INTEGER NOT NULL REFERENCES genres(id) ON DELETE CASCADE,
+    PRIMARY KEY (song_id, genre_id)
+);
+
+CREATE TABLE IF NOT EXISTS group_genres (
+    group_id INTEGER NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    genre_id INTEGER NOT NULL REFERENCES genres(id) ON DELETE CASCADE,
+    PRIMARY KEY (group_id, genre_id)
+);
+
+CREATE TABLE IF NOT EXISTS song_tags (
+    song_id INTEGER NOT NULL REFERENCES songs(id) ON DELETE CASCADE,
+    tag_id INTEGER NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (song_id, tag_id)
+);
+
+CREATE TABLE IF NOT EXISTS group_tags (
+    group_id INTEGER NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    tag_id INTEGER NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (group_id, tag_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_song_genres_genre_id ON song_genres (genre_id);
+CREATE INDEX IF NOT EXISTS idx_group_genres_genre_id ON group_genres (genre_id);
+CREATE INDEX IF NOT EXISTS idx_song_tags_tag_id ON song_tags (tag_id);
+CREATE INDEX IF NOT EXISTS idx_group_tags_tag_id ON group_tags (tag_id);
+
+-- Every genre with each of its ancestors and itself. UNION stops on a cycle.
+CREATE OR REPLACE VIEW genre_ancestors AS
+WITH RECURSIVE tree (ancestor_id, genre_id) AS (
+    SELECT id, id FROM genres
+    UNION
+    SELECT tree.ancestor_id, genres.id FROM tree JOIN genres ON genres.parent_id = tree.genre_id
+)
+SELECT ancestor_id, genre_id FROM tree;
+
+-- The genres of a song, or of its group when the song has none.
+CREATE OR REPLACE VIEW song_effective_genres AS
+SELECT song_id, genre_id, FALSE AS inherited FROM song_genres
+UNION ALL
+SELECT songs.id, group_genres.genre_id, TRUE
+FROM songs JOIN group_genres ON group_genres.group_id = songs.group_id
+WHERE NOT EXISTS (SELECT 1 FROM song_genres WHERE song_genres.song_id = songs.id);
+
+-- The tags and moods of a song together with those of its group.
+CREATE OR REPLACE VIEW song_effective_tags AS
+SELECT song_id, tag_id FROM song_tags
+UNION
+SELECT songs.id, group_tags.tag_id FROM songs JOIN group_tags ON group_tags.group_id = songs.group_id;
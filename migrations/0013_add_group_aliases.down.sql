@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS group_redirects;
+DROP TABLE IF EXISTS group_aliases;
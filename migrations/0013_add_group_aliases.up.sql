@@ -0,0 +1,19 @@
+-- Other names of a group, resolved like its name. Merged groups leave their name here.
+CREATE TABLE IF NOT EXISTS group_aliases (
+    id SERIAL PRIMARY KEY,
+    group_id INTEGER NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_group_aliases_name ON group_aliases (lower(name));
+CREATE INDEX IF NOT EXISTS idx_group_aliases_group_id ON group_aliases (group_id);
+
+-- The ids of merged groups, so that they still resolve to the group they were merged into.
+CREATE TABLE IF NOT EXISTS group_redirects (
+    from_id INTEGER PRIMARY KEY,
+    group_id INTEGER NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    merged_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_group_redirects_group_id ON group_redirects (group_id);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_songs_name_key;
+ALTER TABLE songs DROP COLUMN IF EXISTS pending_duplicate;
+ALTER TABLE songs DROP COLUMN IF EXISTS name_key;
+DROP FUNCTION IF EXISTS song_name_key(TEXT);
@@ -0,0 +1,18 @@
+-- song_name_key normalizes a song name for duplicate detection: case, spaces and punctuation are ignored.
+CREATE OR REPLACE FUNCTION song_name_key(name TEXT) RETURNS TEXT
+LANGUAGE sql IMMUTABLE STRICT PARALLEL SAFE
+AS $$ SELECT translate(lower(regexp_replace(name, '[^[:alnum:]]+', '', 'g')), 'ё', 'е') $$;
+
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS name_key TEXT GENERATED ALWAYS AS (song_name_key(name)) STORED;
+
+-- Songs that duplicate another one of their group before the constraint existed, or after
+-- a merge of groups, are exempt from it until they are merged.
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS pending_duplicate BOOLEAN NOT NULL DEFAULT FALSE;
+
+UPDATE songs SET pending_duplicate = TRUE
+WHERE EXISTS (
+    SELECT 1 FROM songs AS first
+    WHERE first.group_id = songs.group_id AND first.name_key = songs.name_key AND first.id < songs.id
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_songs_name_key ON songs (group_id, name_key) WHERE NOT pending_duplicate;
@@ -0,0 +1,23 @@
+DROP INDEX IF EXISTS idx_songs_name_key;
+ALTER TABLE songs DROP COLUMN IF EXISTS name_key;
+
+CREATE OR REPLACE FUNCTION song_name_key(name TEXT) RETURNS TEXT
+LANGUAGE sql IMMUTABLE STRICT PARALLEL SAFE
+AS $$ SELECT translate(lower(regexp_replace(name, '[^[:alnum:]]+', '', 'g')), 'ё', 'е') $$;
+
+ALTER TABLE songs ADD COLUMN name_key TEXT GENERATED ALWAYS AS (song_name_key(name)) STORED;
+
+UPDATE songs SET pending_duplicate = TRUE
+WHERE NOT pending_duplicate AND EXISTS (
+    SELECT 1 FROM songs AS first
+    WHERE first.group_id = songs.group_id AND first.name_key = songs.name_key AND first.id < songs.id
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_songs_name_key ON songs (group_id, name_key) WHERE NOT pending_duplicate;
+
+DROP INDEX IF EXISTS idx_group_aliases_search_key;
+DROP INDEX IF EXISTS idx_groups_search_key;
+ALTER TABLE songs DROP COLUMN IF EXISTS search_key;
+ALTER TABLE group_aliases DROP COLUMN IF EXISTS search_key;
+ALTER TABLE groups DROP COLUMN IF EXISTS search_key;
+DROP FUNCTION IF EXISTS search_key(TEXT);
@@ -0,0 +1,49 @@
+-- search_key folds a name for search: NFC normalization, lower case, Cyrillic transliterated to Latin
+-- (BGN/PCGN style, so "Кино" and "Kino" share a key), diacritics and apostrophes removed, and any other
+-- punctuation and whitespace collapsed into single spaces.
+CREATE OR REPLACE FUNCTION search_key(name TEXT) RETURNS TEXT
+LANGUAGE sql IMMUTABLE STRICT PARALLEL SAFE
+AS $$
+SELECT btrim(regexp_replace(
+    regexp_replace(
+        normalize(
+            replace(replace(replace(replace(replace(replace(replace(replace(replace(
+                translate(
+                    lower(normalize(name, NFC)),
+                    'абвгдеёзийклмнопрстуфыэіїєґўъь''’ʼ`',
+                    'abvgdeeziyklmnoprstufyeiiegu'
+                ),
+            'ж', 'zh'), 'х', 'kh'), 'ц', 'ts'), 'ч', 'ch'), 'ш', 'sh'), 'щ', 'shch'), 'ю', 'yu'), 'я', 'ya'), 'ß', 'ss'),
+            NFD
+        ),
+        '[\u0300-\u036f]', '', 'g'
+    ),
+    '[^[:alnum:]]+', ' ', 'g'
+))
+$$;
+
+ALTER TABLE groups ADD COLUMN IF NOT EXISTS search_key TEXT GENERATED ALWAYS AS (search_key(name)) STORED;
+ALTER TABLE group_aliases ADD COLUMN IF NOT EXISTS search_key TEXT GENERATED ALWAYS AS (search_key(name)) STORED;
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS search_key TEXT GENERATED ALWAYS AS (search_key(name)) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_groups_search_key ON groups (search_key);
+CREATE INDEX IF NOT EXISTS idx_group_aliases_search_key ON group_aliases (search_key);
+
+-- Song names are unique by their search key too. The stored name keys are recomputed with the new
+-- function, and the songs that now duplicate an older one become pending duplicates.
+DROP INDEX IF EXISTS idx_songs_name_key;
+ALTER TABLE songs DROP COLUMN IF EXISTS name_key;
+
+CREATE OR REPLACE FUNCTION song_name_key(name TEXT) RETURNS TEXT
+LANGUAGE sql IMMUTABLE STRICT PARALLEL SAFE
+AS $$ SELECT replace(search_key(name), ' ', '') $$;
+
+ALTER TABLE songs ADD COLUMN name_key TEXT GENERATED ALWAYS AS (song_name_key(name)) STORED;
+
+UPDATE songs SET pending_duplicate = TRUE
+WHERE NOT pending_duplicate AND EXISTS (
+    SELECT 1 FROM songs AS first
+    WHERE first.group_id = songs.group_id AND first.name_key = songs.name_key AND first.id < songs.id
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_songs_name_key ON songs (group_id, name_key) WHERE NOT pending_duplicate;
@@ -0,0 +1,10 @@
+-- The people credited on a song with their roles, in the order they are listed.
+CREATE TABLE IF NOT EXISTS song_credits (
+    id SERIAL PRIMARY KEY,
+    song_id INTEGER NOT NULL REFERENCES songs(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    role VARCHAR(32) NOT NULL,
+    position INTEGER NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_song_credits_song_name_role ON song_credits (song_id, lower(name), role);
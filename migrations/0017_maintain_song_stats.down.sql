@@ -0,0 +1,32 @@
+DROP TRIGGER IF EXISTS song_stats_favorite ON song_favorites;
+DROP TRIGGER IF EXISTS song_stats_play ON song_plays;
+DROP TRIGGER IF EXISTS song_stats_rating ON song_ratings;
+DROP TRIGGER IF EXISTS song_stats_song ON songs;
+DROP FUNCTION IF EXISTS song_stats_count_favorite();
+DROP FUNCTION IF EXISTS song_stats_count_play();
+DROP FUNCTION IF EXISTS song_stats_count_rating();
+DROP FUNCTION IF EXISTS song_stats_add_song();
+DROP TABLE IF EXISTS song_stats;
+
+-- Aggregates shown on song responses and used to sort by popularity.
+CREATE OR REPLACE VIEW song_stats AS
+SELECT
+    songs.id AS song_id,
+    COALESCE(ratings.average, 0) AS average_rating,
+    COALESCE(ratings.count, 0) AS ratings,
+    COALESCE(plays.count, 0) AS plays,
+    COALESCE(favorites.count, 0) AS favorites
+FROM
+    songs
+LEFT JOIN
+    (SELECT song_id, avg(rating)::float8 AS average, count(*) AS count FROM song_ratings GROUP BY song_id) AS ratings
+ON
+    ratings.song_id = songs.id
+LEFT JOIN
+    (SELECT song_id, count(*) AS count FROM song_plays GROUP BY song_id) AS plays
+ON
+    plays.song_id = songs.id
+LEFT JOIN
+    (SELECT song_id, count(*) AS count FROM song_favorites GROUP BY song_id) AS favorites
+ON
+    favorites.song_id = songs.id;
@@ -0,0 +1,106 @@
+-- The song stats are kept in a table that triggers on the ratings, plays and favorites update,
+-- so that songs are sorted by them without aggregating those tables on every query.
+DROP VIEW IF EXISTS song_stats;
+
+CREATE TABLE IF NOT EXISTS song_stats (
+    song_id INTEGER PRIMARY KEY REFERENCES songs(id) ON DELETE CASCADE,
+    ratings BIGINT NOT NULL DEFAULT 0,
+    rating_sum BIGINT NOT NULL DEFAULT 0,
+    plays BIGINT NOT NULL DEFAULT 0,
+    favorites BIGINT NOT NULL DEFAULT 0,
+    average_rating FLOAT8 GENERATED ALWAYS AS (CASE WHEN ratings > 0 THEN rating_sum::float8 / ratings ELSE 0 END) STORED
+);
+
+CREATE INDEX IF NOT EXISTS idx_song_stats_popularity ON song_stats (plays DESC, favorites DESC, average_rating DESC);
+CREATE INDEX IF NOT EXISTS idx_song_stats_rating ON song_stats (average_rating DESC, ratings DESC);
+
+CREATE OR REPLACE FUNCTION song_stats_add_song() RETURNS TRIGGER
+LANGUAGE plpgsql
+AS $$
+BEGIN
+    INSERT INTO song_stats (song_id) VALUES (NEW.id) ON CONFLICT DO NOTHING;
+    RETURN NULL;
+END
+$$;
+
+-- A row moved to another song, as when songs are merged, counts for the new one only.
+CREATE OR REPLACE FUNCTION song_stats_count_rating() RETURNS TRIGGER
+LANGUAGE plpgsql
+AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        UPDATE song_stats SET ratings = ratings - 1, rating_sum = rating_sum - OLD.rating WHERE song_id = OLD.song_id;
+    END IF;
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        UPDATE song_stats SET ratings = ratings + 1, rating_sum = rating_sum + NEW.rating WHERE song_id = NEW.song_id;
+    END IF;
+    RETURN NULL;
+END
+$$;
+
+CREATE OR REPLACE FUNCTION song_stats_count_play() RETURNS TRIGGER
+LANGUAGE plpgsql
+AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        UPDATE song_stats SET plays = plays - 1 WHERE song_id = OLD.song_id;
+    END IF;
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        UPDATE song_stats SET plays = plays + 1 WHERE song_id = NEW.song_id;
+    END IF;
+    RETURN NULL;
+END
+$$;
+
+CREATE OR REPLACE FUNCTION song_stats_count_favorite() RETURNS TRIGGER
+LANGUAGE plpgsql
+AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        UPDATE song_stats SET favorites = favorites - 1 WHERE song_id = OLD.song_id;
+    END IF;
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        UPDATE song_stats SET favorites = favorites + 1 WHERE song_id = NEW.song_id;
+    END IF;
+    RETURN NULL;
+END
+$$;
+
+DROP TRIGGER IF EXISTS song_stats_song ON songs;
+CREATE TRIGGER song_stats_song AFTER INSERT ON songs
+FOR EACH ROW EXECUTE FUNCTION song_stats_add_song();
+
+DROP TRIGGER IF EXISTS song_stats_rating ON song_ratings;
+CREATE TRIGGER song_stats_rating AFTER INSERT OR DELETE OR UPDATE OF song_id, rating ON song_ratings
+FOR EACH ROW EXECUTE FUNCTION song_stats_count_rating();
+
+DROP TRIGGER IF EXISTS song_stats_play ON song_plays;
+CREATE TRIGGER song_stats_play AFTER INSERT OR DELETE OR UPDATE OF song_id ON song_plays
+FOR EACH ROW EXECUTE FUNCTION song_stats_count_play();
+
+DROP TRIGGER IF EXISTS song_stats_favorite ON song_favorites;
+CREATE TRIGGER song_stats_favorite AFTER INSERT OR DELETE OR UPDATE OF song_id ON song_favorites
+FOR EACH ROW EXECUTE FUNCTION song_stats_count_favorite();
+
+INSERT INTO song_stats (song_id, ratings, rating_sum, plays, favorites)
+SELECT
+    songs.id,
+    COALESCE(ratings.count, 0),
+    COALESCE(ratings.sum, 0),
+    COALESCE(plays.count, 0),
+    COALESCE(favorites.count, 0)
+FROM
+    songs
+LEFT JOIN
+    (SELECT song_id, count(*) AS count, sum(rating) AS sum FROM song_ratings GROUP BY song_id) AS ratings
+ON
+    ratings.song_id = songs.id
+LEFT JOIN
+    (SELECT song_id, count(*) AS count FROM song_plays GROUP BY song_id) AS plays
+ON
+    plays.song_id = songs.id
+LEFT JOIN
+    (SELECT song_id, count(*) AS count FROM song_favorites GROUP BY song_id) AS favorites
+ON
+    favorites.song_id = songs.id
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,2 @@
+-- Scrobbles without a group are matched by name key alone; songs are already indexed by group.
+CREATE INDEX IF NOT EXISTS idx_songs_name_key_any ON songs (name_key);
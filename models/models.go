@@ -1,8 +1,10 @@
 package models
 
+import "time"
+
 // Group represents a musical group or artist.
 type Group struct {
-	ID   string `json:"id"`
+	ID   int    `json:"id"`
 	Name string `json:"name"`
 }
 
@@ -11,6 +13,20 @@ type Song struct {
 	ID      int    `json:"id"`
 	Name    string `json:"name"`
 	GroupID int    `json:"group_id"`
+	// Explicit is detected from the lyrics unless an editor has overridden it.
+	Explicit bool       `json:"explicit"`
+	Stats    *SongStats `json:"stats,omitempty"`
+}
+
+// SongStats holds the ratings, plays and favorites of a song. Favorite and Rating
+// are those of the current user and are only set for authenticated requests.
+type SongStats struct {
+	AverageRating float64 `json:"average_rating"`
+	Ratings       int     `json:"ratings"`
+	Plays         int     `json:"plays"`
+	Favorites     int     `json:"favorites"`
+	Favorite      bool    `json:"favorite,omitempty"`
+	Rating        int     `json:"rating,omitempty"`
 }
 
 // SongDetails contains additional details about a song.
@@ -20,8 +36,15 @@ type SongDetails struct {
 	ReleaseDate string `json:"release_date"`
 	Text        string `json:"text"`
 	Link        string `json:"link"`
+	Album       string `json:"album"`
 }
 
+// Defaults stored in song_details for the details that are not known.
+const (
+	DefaultReleaseDate = "1970-01-01"
+	UnknownValue       = "no information"
+)
+
 // NewSongPayload represents the payload for adding a new song.
 type NewSongPayload struct {
 	Group string `json:"group" example:"Muse"`
@@ -33,3 +56,753 @@ type EditSongPayload struct {
 	Song        Song        `json:"song"`
 	SongDetails SongDetails `json:"song_details"`
 }
+
+// Sort orders of ListSongs.
+const (
+	SortName       = "name"
+	SortPopularity = "popularity"
+	SortRating     = "rating"
+)
+
+// SongFilter holds the ListSongs filters, sort order and pagination parameters.
+type SongFilter struct {
+	// GroupID selects the songs of a group by its ID, Group by its name or alias.
+	GroupID     int
+	Group       string
+	Name        string
+	ReleaseDate string
+	Text        string
+	Link        string
+	// UserID is the user whose favorites FavoritesOnly selects and whose rating is shown.
+	UserID          int
+	FavoritesOnly   bool
+	MinRating       float64
+	ExcludeExplicit bool
+	// Tags and Moods select the songs labelled, directly or through their group, with all
+	// of them, or with any of them when TagMatch is TagMatchAny.
+	Tags     []string
+	Moods    []string
+	TagMatch string
+	// Genre selects the songs of a genre or its subgenres, by name.
+	Genre  string
+	Sort   string
+	Limit  int
+	Offset int
+}
+
+// Filtered reports whether the filter selects a subset of the songs rather than all of them.
+func (f SongFilter) Filtered() bool {
+	return f.GroupID != 0 || f.Group != "" || f.Name != "" || f.ReleaseDate != "" || f.Text != "" || f.Link != "" ||
+		f.FavoritesOnly || f.MinRating != 0 || f.ExcludeExplicit || len(f.Tags) > 0 || len(f.Moods) > 0 ||
+		f.Genre != "" || f.Limit != 0 || f.Offset != 0
+}
+
+// How the tags of a SongFilter are matched.
+const (
+	TagMatchAll = "all"
+	TagMatchAny = "any"
+)
+
+// DetailedSong represents a song together with its group name and details.
+type DetailedSong struct {
+	Song        Song        `json:"song"`
+	Group       string      `json:"group"`
+	SongDetails SongDetails `json:"song_details"`
+}
+
+// ImportRow represents a single song of a bulk import.
+type ImportRow struct {
+	Group       string `json:"group"`
+	Song        string `json:"song"`
+	ReleaseDate string `json:"release_date,omitempty"`
+	Text        string `json:"text,omitempty"`
+	Link        string `json:"link,omitempty"`
+	Album       string `json:"album,omitempty"`
+}
+
+// Statuses of an imported row.
+const (
+	ImportCreated = "created"
+	ImportUpdated = "updated"
+	ImportSkipped = "skipped"
+	ImportFailed  = "failed"
+)
+
+// ImportRowResult describes what happened to a single row of a bulk import.
+type ImportRowResult struct {
+	Row    int    `json:"row"`
+	Group  string `json:"group,omitempty"`
+	Song   string `json:"song,omitempty"`
+	Status string `json:"status" example:"created"`
+	SongID int    `json:"song_id,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// ImportReport summarizes a bulk import.
+type ImportReport struct {
+	DryRun  bool              `json:"dry_run"`
+	Created int               `json:"created"`
+	Updated int               `json:"updated"`
+	Skipped int               `json:"skipped"`
+	Failed  int               `json:"failed"`
+	Rows    []ImportRowResult `json:"rows"`
+}
+
+// ExportSong is a song with its group and details as written by the catalogue export.
+// Its fields are a superset of ImportRow, so exports can be imported again.
+type ExportSong struct {
+	SongID      int    `json:"song_id"`
+	GroupID     int    `json:"group_id"`
+	DetailsID   int    `json:"details_id"`
+	Group       string `json:"group"`
+	Song        string `json:"song"`
+	ReleaseDate string `json:"release_date"`
+	Text        string `json:"text"`
+	Link        string `json:"link"`
+	Album       string `json:"album"`
+}
+
+// SongName identifies a song by its id, group and name.
+type SongName struct {
+	ID      int    `json:"id"`
+	GroupID int    `json:"group_id"`
+	Group   string `json:"group"`
+	Name    string `json:"name"`
+}
+
+// Statuses of a playlist entry resolved against the library.
+const (
+	PlaylistMatched   = "matched"
+	PlaylistFuzzy     = "fuzzy"
+	PlaylistUnmatched = "unmatched"
+)
+
+// PlaylistEntryResult describes how a single playlist entry was resolved.
+type PlaylistEntryResult struct {
+	Position     int     `json:"position"`
+	Location     string  `json:"location,omitempty"`
+	Group        string  `json:"group,omitempty"`
+	Song         string  `json:"song,omitempty"`
+	Status       string  `json:"status" example:"matched"`
+	SongID       int     `json:"song_id,omitempty"`
+	MatchedGroup string  `json:"matched_group,omitempty"`
+	MatchedSong  string  `json:"matched_song,omitempty"`
+	Score        float64 `json:"score,omitempty"`
+}
+
+// PlaylistImportReport summarizes a playlist resolved against the library.
+type PlaylistImportReport struct {
+	Title     string `json:"title,omitempty"`
+	Matched   int    `json:"matched"`
+	Fuzzy     int    `json:"fuzzy"`
+	Unmatched int    `json:"unmatched"`
+	// PlaylistID is the playlist the matched songs were saved to.
+	PlaylistID int                   `json:"playlist_id,omitempty"`
+	Entries    []PlaylistEntryResult `json:"entries"`
+}
+
+// User represents a user of the library.
+type User struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	Role      string    `json:"role" example:"reader"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// RolePayload represents the payload for changing the role of a user.
+type RolePayload struct {
+	Role string `json:"role" example:"editor"`
+}
+
+// CredentialsPayload represents the payload for registering and logging in.
+type CredentialsPayload struct {
+	Name     string `json:"name" example:"alice"`
+	Password string `json:"password" example:"correct horse battery staple"`
+}
+
+// RefreshPayload represents the payload for refreshing tokens.
+type RefreshPayload struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// APIKey represents a long-lived key a user authenticates scripts with.
+// The key itself is only returned when it is created.
+type APIKey struct {
+	ID         int        `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix" example:"mk_AbCdEf"`
+	Key        string     `json:"key,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// NewAPIKeyPayload represents the payload for creating an API key.
+type NewAPIKeyPayload struct {
+	Name string `json:"name" example:"backup script"`
+}
+
+// Visibilities of a playlist. Public playlists can be read by everyone,
+// collaborative ones can also be edited by everyone.
+const (
+	PlaylistPrivate       = "private"
+	PlaylistPublic        = "public"
+	PlaylistCollaborative = "collaborative"
+)
+
+// Playlist represents an ordered list of songs owned by a user.
+type Playlist struct {
+	ID          int             `json:"id"`
+	OwnerID     int             `json:"owner_id"`
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Visibility  string          `json:"visibility" example:"private"`
+	ShareToken  string          `json:"share_token,omitempty"`
+	Size        int             `json:"size"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+	Entries     []PlaylistEntry `json:"entries,omitempty"`
+}
+
+// ReadableBy reports whether a user may read the playlist: its owner always can, other users and
+// anonymous ones (a zero userID) only when it is not private.
+func (p *Playlist) ReadableBy(userID int) bool {
+	return p.OwnerID == userID || p.Visibility != PlaylistPrivate
+}
+
+// PlaylistEntry is a song at a position of a playlist. The same song may appear more than once.
+type PlaylistEntry struct {
+	ID       int       `json:"id"`
+	Position int       `json:"position"`
+	SongID   int       `json:"song_id"`
+	Group    string    `json:"group"`
+	Song     string    `json:"song"`
+	AddedBy  int       `json:"added_by,omitempty"`
+	AddedAt  time.Time `json:"added_at"`
+}
+
+// PlaylistPayload represents the payload for creating or editing a playlist.
+type PlaylistPayload struct {
+	Name        string `json:"name" example:"Road trip"`
+	Description string `json:"description"`
+	Visibility  string `json:"visibility" example:"private"`
+}
+
+// PlaylistEntryPayload represents the payload for adding or moving a playlist entry.
+// Positions start at 1; a zero position appends the song.
+type PlaylistEntryPayload struct {
+	SongID   int `json:"song_id,omitempty"`
+	Position int `json:"position,omitempty"`
+}
+
+// DuplicatePlaylistPayload represents the payload for duplicating a playlist.
+type DuplicatePlaylistPayload struct {
+	Name string `json:"name"`
+}
+
+// MergePlaylistPayload represents the payload for merging a playlist into another.
+type MergePlaylistPayload struct {
+	SourceID       int  `json:"source_id"`
+	SkipDuplicates bool `json:"skip_duplicates"`
+}
+
+// MergePlaylistResult reports the outcome of a merge.
+type MergePlaylistResult struct {
+	Added    int      `json:"added"`
+	Skipped  int      `json:"skipped"`
+	Playlist Playlist `json:"playlist"`
+}
+
+// Statuses of a suggested edit.
+const (
+	SuggestionPending  = "pending"
+	SuggestionApproved = "approved"
+	SuggestionRejected = "rejected"
+)
+
+// SuggestionPayload represents the payload for suggesting an edit of a song.
+// Empty fields are left unchanged, as in EditSongPayload.
+type SuggestionPayload struct {
+	Song        Song        `json:"song"`
+	SongDetails SongDetails `json:"song_details"`
+	Comment     string      `json:"comment"`
+}
+
+// RejectSuggestionPayload represents the payload for rejecting a suggested edit.
+type RejectSuggestionPayload struct {
+	Reason string `json:"reason"`
+}
+
+// DiffLine is a line of a text diff. Op is "=" for a kept line, "-" for a removed and "+" for an added one.
+type DiffLine struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+// FieldChange is a field changed by a suggested edit. Texts also have a line diff.
+type FieldChange struct {
+	Field string     `json:"field"`
+	Old   string     `json:"old"`
+	New   string     `json:"new"`
+	Lines []DiffLine `json:"lines,omitempty"`
+}
+
+// Suggestion is an edit of a song suggested by a user and reviewed by a moderator.
+// Base holds the song as it was when the edit was suggested.
+type Suggestion struct {
+	ID         int             `json:"id"`
+	SongID     int             `json:"song_id"`
+	Group      string          `json:"group"`
+	Song       string          `json:"song"`
+	UserID     int             `json:"user_id,omitempty"`
+	Author     string          `json:"author,omitempty"`
+	Status     string          `json:"status"`
+	Comment    string          `json:"comment"`
+	Proposed   EditSongPayload `json:"proposed"`
+	Base       EditSongPayload `json:"-"`
+	Changes    []FieldChange   `json:"changes"`
+	Reason     string          `json:"reason,omitempty"`
+	ReviewedBy int             `json:"reviewed_by,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+	ReviewedAt *time.Time      `json:"reviewed_at,omitempty"`
+}
+
+// SuggestionFilter holds the filters and pagination parameters of the moderation queue.
+// Zero values do not filter.
+type SuggestionFilter struct {
+	Status string
+	SongID int
+	UserID int
+	// MaintainerID limits the queue to the groups a user maintains.
+	MaintainerID int
+	Limit        int
+	Offset       int
+}
+
+// RatingPayload represents the payload for rating a song.
+type RatingPayload struct {
+	Rating int `json:"rating" example:"5"`
+}
+
+// ExplicitPayload represents the payload for overriding the explicit flag of a song.
+// A null explicit removes the override, so the flag is detected from the lyrics again.
+type ExplicitPayload struct {
+	Explicit *bool `json:"explicit" example:"true"`
+}
+
+// SongExplicit is the explicit flag of a song with how it was decided.
+type SongExplicit struct {
+	SongID   int   `json:"song_id"`
+	Explicit bool  `json:"explicit"`
+	Detected bool  `json:"detected"`
+	Override *bool `json:"override"`
+}
+
+// PlayPayload represents the payload for recording a play of a song. PlayedAt defaults to now.
+type PlayPayload struct {
+	PlayedAt *time.Time `json:"played_at,omitempty"`
+}
+
+// MaxScrobbles is the largest batch of scrobbles accepted at once.
+const MaxScrobbles = 50
+
+// Statuses of a scrobble.
+const (
+	ScrobbleAccepted  = "accepted"
+	ScrobbleCreated   = "created"
+	ScrobbleDuplicate = "duplicate"
+	ScrobbleUnmatched = "unmatched"
+	ScrobbleInvalid   = "invalid"
+)
+
+// Scrobble reports that a song was listened to. The song is given by its ID or by group and title.
+type Scrobble struct {
+	SongID   int       `json:"song_id,omitempty"`
+	Group    string    `json:"group,omitempty" example:"Muse"`
+	Title    string    `json:"title,omitempty" example:"Supermassive Black Hole"`
+	PlayedAt time.Time `json:"played_at"`
+	// Duration is the number of seconds played.
+	Duration int `json:"duration,omitempty" example:"212"`
+}
+
+// ScrobblePayload represents a batch of scrobbles.
+type ScrobblePayload struct {
+	Scrobbles []Scrobble `json:"scrobbles"`
+}
+
+// ScrobbleResult is the outcome of a scrobble in a batch.
+type ScrobbleResult struct {
+	Index  int     `json:"index"`
+	Status string  `json:"status" example:"accepted"`
+	SongID int     `json:"song_id,omitempty"`
+	Group  string  `json:"group,omitempty"`
+	Song   string  `json:"song,omitempty"`
+	Score  float64 `json:"score,omitempty"`
+	Error  string  `json:"error,omitempty"`
+}
+
+// ScrobbleReport summarizes a batch of scrobbles.
+type ScrobbleReport struct {
+	Accepted   int              `json:"accepted"`
+	Created    int              `json:"created"`
+	Duplicates int              `json:"duplicates"`
+	Unmatched  int              `json:"unmatched"`
+	Invalid    int              `json:"invalid"`
+	Results    []ScrobbleResult `json:"results"`
+}
+
+// Play is a play of a song by a user.
+type Play struct {
+	ID       int64     `json:"id"`
+	SongID   int       `json:"song_id"`
+	Group    string    `json:"group"`
+	Song     string    `json:"song"`
+	PlayedAt time.Time `json:"played_at"`
+	Duration int       `json:"duration,omitempty"`
+}
+
+// GroupCount is the number of songs of a group.
+type GroupCount struct {
+	GroupID int    `json:"group_id"`
+	Group   string `json:"group"`
+	Songs   int    `json:"songs"`
+}
+
+// PeriodCount is the number of songs released in a year or a decade.
+type PeriodCount struct {
+	Period int `json:"period" example:"1990"`
+	Songs  int `json:"songs"`
+}
+
+// CatalogueStats summarizes the catalogue. Songs with the default release date are
+// counted as undated and left out of the years and decades.
+type CatalogueStats struct {
+	Songs          int           `json:"songs"`
+	Groups         int           `json:"groups"`
+	WithLyrics     int           `json:"with_lyrics"`
+	LyricsCoverage float64       `json:"lyrics_coverage"`
+	MissingLinks   int           `json:"missing_links"`
+	MissingAlbums  int           `json:"missing_albums"`
+	Undated        int           `json:"undated"`
+	PerGroup       []GroupCount  `json:"per_group"`
+	PerYear        []PeriodCount `json:"per_year"`
+	PerDecade      []PeriodCount `json:"per_decade"`
+}
+
+// Chart periods.
+const (
+	PeriodDay   = "day"
+	PeriodWeek  = "week"
+	PeriodMonth = "month"
+)
+
+// ChartEntry is a song in a chart.
+type ChartEntry struct {
+	Rank          int     `json:"rank"`
+	SongID        int     `json:"song_id"`
+	Group         string  `json:"group"`
+	Song          string  `json:"song"`
+	Plays         int     `json:"plays"`
+	Ratings       int     `json:"ratings"`
+	AverageRating float64 `json:"average_rating"`
+}
+
+// Chart is a ranking of songs over a period. To is exclusive.
+type Chart struct {
+	Chart   string       `json:"chart" example:"top-played"`
+	Period  string       `json:"period" example:"week"`
+	From    time.Time    `json:"from"`
+	To      time.Time    `json:"to"`
+	Entries []ChartEntry `json:"entries"`
+}
+
+// SongText is the text of a song with its md5 hash.
+type SongText struct {
+	SongID int
+	Text   string
+	Hash   string
+}
+
+// SongTerms are the counted terms of the lyrics of a song, with the group and release year used to rank similar songs.
+type SongTerms struct {
+	SongID  int
+	GroupID int
+	// Year is 0 for undated songs.
+	Year   int
+	Terms  []string
+	Counts []int64
+}
+
+// SimilarSong is a song recommended as similar to another one. Score combines the
+// similarity of the lyrics with a shared group and a close release year.
+type SimilarSong struct {
+	SongID      int     `json:"song_id"`
+	Group       string  `json:"group"`
+	Song        string  `json:"song"`
+	Score       float64 `json:"score"`
+	LyricsScore float64 `json:"lyrics_score"`
+	SameGroup   bool    `json:"same_group"`
+	SameEra     bool    `json:"same_era"`
+}
+
+// SongLyrics is the text of a song with its names. Text is empty for songs without lyrics.
+type SongLyrics struct {
+	SongID  int
+	GroupID int
+	Group   string
+	Song    string
+	Text    string
+	// Hash changes whenever the names or the text do.
+	Hash string
+}
+
+// WordCount is a word with the number of its occurrences.
+type WordCount struct {
+	Word  string `json:"word"`
+	Count int    `json:"count"`
+}
+
+// LyricsAnalytics describes the lyrics of a song or of all songs of a group.
+type LyricsAnalytics struct {
+	SongID  int    `json:"song_id,omitempty"`
+	Song    string `json:"song,omitempty"`
+	GroupID int    `json:"group_id"`
+	Group   string `json:"group"`
+	// Songs is the number of songs with lyrics.
+	Songs       int `json:"songs"`
+	Words       int `json:"words"`
+	UniqueWords int `json:"unique_words"`
+	// TopWords are the most frequent words, without stop words, numbers and single letters.
+	TopWords []WordCount `json:"top_words"`
+	// Lines counts the lines with words; blank lines and annotations such as [Chorus] are skipped.
+	Lines            int     `json:"lines"`
+	AverageLineWords float64 `json:"average_line_words"`
+	AverageLineChars float64 `json:"average_line_chars"`
+	// AverageWordLetters is the average number of letters in a word; numbers are not counted as words.
+	AverageWordLetters float64 `json:"average_word_letters"`
+	// Readability is the Flesch reading ease with lines taken as sentences, using Oborneva's coefficients
+	// for Russian; higher scores read easier. For a group the scores of its songs are weighted by their
+	// words. It is null when no song is in a determined language.
+	Readability *float64 `json:"readability"`
+	// RepeatedLines counts the lines that occur more than once in their song, as a chorus does.
+	RepeatedLines   int     `json:"repeated_lines"`
+	RepetitionRatio float64 `json:"repetition_ratio"`
+	// Language is the language of the lyrics, or of most songs of a group: en, ru or und when undetermined.
+	Language string `json:"language"`
+	// Languages counts the songs in each language.
+	Languages map[string]int `json:"languages"`
+}
+
+// Kinds of tags.
+const (
+	TagKindTag  = "tag"
+	TagKindMood = "mood"
+)
+
+// Genre is a genre with its subgenres. ParentID is nil for top-level genres.
+type Genre struct {
+	ID       int     `json:"id"`
+	Name     string  `json:"name"`
+	ParentID *int    `json:"parent_id"`
+	Children []Genre `json:"children,omitempty"`
+}
+
+// GenrePayload represents the payload for creating or changing a genre.
+type GenrePayload struct {
+	Name     string `json:"name" example:"Alternative rock"`
+	ParentID *int   `json:"parent_id" example:"1"`
+}
+
+// Tag is a free-form tag or mood with the number of songs and groups labelled with it.
+type Tag struct {
+	ID     int    `json:"id"`
+	Kind   string `json:"kind"`
+	Name   string `json:"name"`
+	Songs  int    `json:"songs"`
+	Groups int    `json:"groups"`
+}
+
+// Taxonomy holds the genres, tags and moods of a song or a group. The genres of a song
+// without its own are those of its group, with GenresInherited set. A song also carries
+// the tags and moods of its group, listed apart as inherited.
+type Taxonomy struct {
+	Genres          []Genre  `json:"genres"`
+	GenresInherited bool     `json:"genres_inherited"`
+	Tags            []string `json:"tags"`
+	Moods           []string `json:"moods"`
+	InheritedTags   []string `json:"inherited_tags,omitempty"`
+	InheritedMoods  []string `json:"inherited_moods,omitempty"`
+}
+
+// TaxonomyPayload represents the payload for changing the genres, tags and moods of a song or a group.
+// Each given list replaces the current one; omitted lists are left unchanged.
+type TaxonomyPayload struct {
+	GenreIDs *[]int    `json:"genre_ids" example:"1,2"`
+	Tags     *[]string `json:"tags" example:"live,cover"`
+	Moods    *[]string `json:"moods" example:"calm"`
+}
+
+// GroupAlias is another name of a group, resolved like its name.
+type GroupAlias struct {
+	ID      int    `json:"id"`
+	GroupID int    `json:"group_id"`
+	Group   string `json:"group"`
+	Name    string `json:"name"`
+}
+
+// GroupPayload represents the payload for adding or renaming a group.
+type GroupPayload struct {
+	Name string `json:"name" example:"The Beatles"`
+}
+
+// AliasPayload represents the payload for adding an alias to a group.
+type AliasPayload struct {
+	Name string `json:"name" example:"Beatles"`
+}
+
+// GroupSummary is a group with the number of its songs.
+type GroupSummary struct {
+	ID    int    `json:"id"`
+	Name  string `json:"name"`
+	Songs int    `json:"songs"`
+}
+
+// GroupDuplicate is a pair of groups whose names are so similar that they likely are the same group.
+// Group has at least as many songs as Duplicate, so it is the one to merge into.
+type GroupDuplicate struct {
+	Group     GroupSummary `json:"group"`
+	Duplicate GroupSummary `json:"duplicate"`
+	Score     float64      `json:"score"`
+}
+
+// MergeGroupsPayload represents the payload for merging groups into another one.
+type MergeGroupsPayload struct {
+	GroupIDs []int `json:"group_ids" example:"12"`
+}
+
+// GroupMerge reports a merge of groups.
+type GroupMerge struct {
+	Group      Group        `json:"group"`
+	Merged     []int        `json:"merged"`
+	SongsMoved int          `json:"songs_moved"`
+	Aliases    []GroupAlias `json:"aliases"`
+}
+
+// SongConflict is the response to adding a song that its group already has.
+type SongConflict struct {
+	Error  string `json:"error"`
+	SongID int    `json:"song_id"`
+}
+
+// SongDuplicate is a pair of songs of a group that likely are the same song. NameScore is the
+// similarity of their normalized names and LyricsScore that of their lyrics, unknown when either
+// has no lyrics. Song is the older one, so it is the one to merge into.
+type SongDuplicate struct {
+	Song        SongName `json:"song"`
+	Duplicate   SongName `json:"duplicate"`
+	Score       float64  `json:"score"`
+	NameScore   float64  `json:"name_score"`
+	LyricsScore *float64 `json:"lyrics_score"`
+}
+
+// MergeSongsPayload represents the payload for merging songs into another one.
+type MergeSongsPayload struct {
+	SongIDs []int `json:"song_ids" example:"42"`
+}
+
+// SongMerge reports a merge of songs.
+type SongMerge struct {
+	Song        Song        `json:"song"`
+	SongDetails SongDetails `json:"song_details"`
+	Merged      []int       `json:"merged"`
+}
+
+// Kinds of search suggestions.
+const (
+	SuggestGroup = "group"
+	SuggestSong  = "song"
+	SuggestAlbum = "album"
+)
+
+// SearchSuggestion is a group, song or album suggested for the beginning of a search. ID is the ID
+// of the group or song; GroupID and Group are the group of a song or album. Popularity is the number
+// of plays and favorites of the songs.
+type SearchSuggestion struct {
+	Kind       string `json:"kind" example:"song"`
+	ID         int    `json:"id,omitempty"`
+	Name       string `json:"name"`
+	GroupID    int    `json:"group_id,omitempty"`
+	Group      string `json:"group,omitempty"`
+	Popularity int    `json:"popularity"`
+	// Keys are the search keys the suggestion is found by.
+	Keys []string `json:"-"`
+}
+
+// SearchResult is a song found by a full-text search. Fields lists where the search matched:
+// group, song or text.
+type SearchResult struct {
+	SongID  int      `json:"song_id"`
+	GroupID int      `json:"group_id"`
+	Group   string   `json:"group"`
+	Song    string   `json:"song"`
+	Score   float64  `json:"score"`
+	Fields  []string `json:"fields"`
+}
+
+// SearchResults is a page of full-text search results, the best first.
+type SearchResults struct {
+	Total   int            `json:"total"`
+	Results []SearchResult `json:"results"`
+}
+
+// Roles of a song credit.
+const (
+	CreditPerformer = "performer"
+	CreditFeaturing = "featuring"
+	CreditWriter    = "writer"
+	CreditComposer  = "composer"
+	CreditLyricist  = "lyricist"
+	CreditProducer  = "producer"
+)
+
+// SongCredit is a person credited on a song.
+type SongCredit struct {
+	Name string `json:"name" example:"Matt Bellamy"`
+	Role string `json:"role" example:"writer" enums:"performer,featuring,writer,composer,lyricist,producer"`
+}
+
+// SongCreditsPayload represents the payload for replacing the credits of a song.
+type SongCreditsPayload struct {
+	Credits []SongCredit `json:"credits"`
+}
+
+// SongResource is a song with the related resources requested by include: its group, details
+// and credits. Without them it is marshalled like a Song.
+type SongResource struct {
+	Song
+	Group   *Group        `json:"group,omitempty"`
+	Details *SongDetails  `json:"details,omitempty"`
+	Credits *[]SongCredit `json:"credits,omitempty"`
+}
+
+// SongList is a page of songs with their included resources.
+type SongList struct {
+	Songs []SongResource `json:"song"`
+}
+
+// SongPayload represents a song and its details in the payloads of /api/v2. PUT replaces the song with it,
+// setting omitted details to unknown; PATCH only changes the fields it has.
+type SongPayload struct {
+	Name        *string `json:"name" example:"Supermassive Black Hole"`
+	GroupID     *int    `json:"group_id" example:"1"`
+	ReleaseDate *string `json:"release_date" example:"2006-06-19"`
+	Text        *string `json:"text"`
+	Link        *string `json:"link" example:"https://www.youtube.com/watch?v=Xsp3_a-PMTw"`
+	Album       *string `json:"album" example:"Black Holes and Revelations"`
+}
+
+// GraphQLRequest represents a GraphQL query or mutation with its variables.
+type GraphQLRequest struct {
+	Query         string                 `json:"query" example:"{ songs(first: 10) { edges { node { name group { name } } } } }"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
@@ -0,0 +1,53 @@
+package musicinfo
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/noctusha/music/models"
+)
+
+// Client requests song details from the external music info API.
+type Client struct {
+	BaseURL    string
+	HTTPClient *http.Client
+}
+
+// NewClient creates a Client for the API configured in EXTERNAL_API_URL.
+func NewClient() (*Client, error) {
+	apiBaseURL := os.Getenv("EXTERNAL_API_URL")
+	if apiBaseURL == "" {
+		return nil, fmt.Errorf("External API URL is not configured")
+	}
+
+	return &Client{
+		BaseURL:    apiBaseURL,
+		HTTPClient: http.DefaultClient,
+	}, nil
+}
+
+// SongDetails retrieves the details of a song by its group and name.
+func (c *Client) SongDetails(group, song string) (models.SongDetails, error) {
+	var details models.SongDetails
+
+	apiURL := fmt.Sprintf("%s/info?group=%s&song=%s", c.BaseURL, url.QueryEscape(group), url.QueryEscape(song))
+	resp, err := c.HTTPClient.Get(apiURL)
+	if err != nil {
+		return details, fmt.Errorf("Error making request to external API: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return details, fmt.Errorf("External API returned status %d", resp.StatusCode)
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(&details)
+	if err != nil {
+		return details, fmt.Errorf("Error parsing external API response: %v", err)
+	}
+
+	return details, nil
+}
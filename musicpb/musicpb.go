@@ -0,0 +1,5 @@
+// Package musicpb holds the protobuf messages and the gRPC service of the song library, generated
+// from music.proto.
+package musicpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative music.proto
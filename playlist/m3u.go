@@ -0,0 +1,174 @@
+package playlist
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+
+	"github.com/noctusha/music/models"
+)
+
+// audioExtensions are stripped from file names when a track name is derived from its location.
+var audioExtensions = []string{".mp3", ".flac", ".ogg", ".oga", ".opus", ".m4a", ".aac", ".wav", ".wma", ".ape"}
+
+// trackNumber matches the track number some file names start with, e.g. "01. " or "07_".
+var trackNumber = regexp.MustCompile(`^\d{1,3}(\s*[.\-_]\s*|\s+)`)
+
+// parseM3U reads a plain or extended M3U playlist. Files that are not valid UTF-8
+// are decoded as Windows-1251 unless utf8Only is set, as M3U8 files always are UTF-8.
+func parseM3U(data []byte, utf8Only bool) *Playlist {
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+	if !utf8.Valid(data) && !utf8Only {
+		decoded, err := charmap.Windows1251.NewDecoder().Bytes(data)
+		if err == nil {
+			data = decoded
+		}
+	}
+
+	playlist := &Playlist{Entries: []Entry{}}
+	var display string
+	next := Entry{Duration: -1}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), maxPlaylistSize)
+	for scanner.Scan() {
+		line := strings.TrimSpace(strings.ToValidUTF8(scanner.Text(), "\ufffd"))
+
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "#EXTINF:"):
+			next.Duration, display = parseExtInf(strings.TrimPrefix(line, "#EXTINF:"))
+		case strings.HasPrefix(line, "#PLAYLIST:"):
+			playlist.Title = strings.TrimSpace(strings.TrimPrefix(line, "#PLAYLIST:"))
+		case strings.HasPrefix(line, "#EXTALB:"):
+			next.Album = strings.TrimSpace(strings.TrimPrefix(line, "#EXTALB:"))
+		case strings.HasPrefix(line, "#EXTART:"):
+			next.Group = strings.TrimSpace(strings.TrimPrefix(line, "#EXTART:"))
+		case strings.HasPrefix(line, "#"):
+			// Other directives and comments carry nothing we store.
+		default:
+			next.Location = line
+			if display == "" {
+				display = nameFromLocation(line)
+			}
+			next.Group, next.Title = splitDisplay(next.Group, display)
+			playlist.Entries = append(playlist.Entries, next)
+
+			display = ""
+			next = Entry{Duration: -1}
+		}
+	}
+
+	return playlist
+}
+
+// parseExtInf parses the "duration attributes,display title" value of an #EXTINF directive.
+func parseExtInf(value string) (int, string) {
+	info, display := value, ""
+	quoted := false
+	for i, c := range value {
+		if c == '"' {
+			quoted = !quoted
+		}
+		if c == ',' && !quoted {
+			info, display = value[:i], value[i+1:]
+			break
+		}
+	}
+
+	duration := -1
+	fields := strings.Fields(info)
+	if len(fields) > 0 {
+		seconds, err := strconv.ParseFloat(fields[0], 64)
+		if err == nil && seconds >= 0 {
+			duration = int(seconds)
+		}
+	}
+
+	return duration, strings.TrimSpace(display)
+}
+
+// splitDisplay splits an "Artist - Title" display name. A group that is already
+// known is removed from the front of the display name.
+func splitDisplay(group, display string) (string, string) {
+	if group != "" {
+		title, found := strings.CutPrefix(display, group+" - ")
+		if found {
+			return group, strings.TrimSpace(title)
+		}
+		return group, display
+	}
+
+	parts := strings.Split(display, " - ")
+	if len(parts) == 1 {
+		return "", display
+	}
+	return strings.TrimSpace(parts[0]), strings.TrimSpace(strings.Join(parts[1:], " - "))
+}
+
+// nameFromLocation derives a display name from a file path or URL, dropping the
+// directory, the audio extension and a leading track number.
+func nameFromLocation(location string) string {
+	name := location
+	u, err := url.Parse(location)
+	if err == nil && u.Scheme != "" && len(u.Scheme) > 1 {
+		name = u.Path
+	} else {
+		name = strings.ReplaceAll(name, `\`, "/")
+	}
+	name = path.Base(name)
+
+	ext := strings.ToLower(path.Ext(name))
+	for _, audio := range audioExtensions {
+		if ext == audio {
+			name = name[:len(name)-len(ext)]
+			break
+		}
+	}
+
+	parts := strings.Split(name, " - ")
+	_, err = strconv.Atoi(strings.TrimSpace(parts[0]))
+	switch {
+	case len(parts) > 1 && err == nil:
+		name = strings.Join(parts[1:], " - ")
+	case len(parts) == 1:
+		name = trackNumber.ReplaceAllString(name, "")
+	}
+	return strings.TrimSpace(name)
+}
+
+// writeM3U writes an extended M3U playlist in UTF-8.
+func writeM3U(w io.Writer, title string, songs []models.DetailedSong) error {
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintln(bw, "#EXTM3U")
+	if title != "" {
+		fmt.Fprintf(bw, "#PLAYLIST:%s\n", oneLine(title))
+	}
+	for _, song := range songs {
+		fmt.Fprintf(bw, "#EXTINF:-1,%s - %s\n", oneLine(song.Group), oneLine(song.Song.Name))
+		fmt.Fprintf(bw, "#EXTART:%s\n", oneLine(song.Group))
+		if known(song.SongDetails.Album) {
+			fmt.Fprintf(bw, "#EXTALB:%s\n", oneLine(song.SongDetails.Album))
+		}
+		fmt.Fprintln(bw, oneLine(location(song)))
+	}
+
+	return bw.Flush()
+}
+
+// lineBreaks replaces line breaks, which would split an M3U entry.
+var lineBreaks = strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ")
+
+func oneLine(value string) string {
+	return lineBreaks.Replace(value)
+}
@@ -0,0 +1,129 @@
+package playlist
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"path"
+	"strings"
+
+	"github.com/noctusha/music/models"
+)
+
+// Supported playlist formats.
+const (
+	FormatM3U  = "m3u"
+	FormatM3U8 = "m3u8"
+	FormatXSPF = "xspf"
+)
+
+// maxPlaylistSize bounds the size of a parsed playlist.
+const maxPlaylistSize = 16 << 20
+
+// Entry is a single track of a playlist.
+type Entry struct {
+	// Location is the path or URL of the track, empty when the playlist had none.
+	Location string
+	Group    string
+	Title    string
+	Album    string
+	// Duration is the track length in seconds, -1 when unknown.
+	Duration int
+}
+
+// Playlist is an ordered list of tracks.
+type Playlist struct {
+	Title   string
+	Entries []Entry
+}
+
+// Parse reads a playlist in the given format.
+func Parse(r io.Reader, format string) (*Playlist, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxPlaylistSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("error reading playlist: %v", err)
+	}
+	if len(data) > maxPlaylistSize {
+		return nil, fmt.Errorf("playlist is larger than %d bytes", maxPlaylistSize)
+	}
+
+	switch format {
+	case FormatM3U, FormatM3U8:
+		return parseM3U(data, format == FormatM3U8), nil
+	case FormatXSPF:
+		return parseXSPF(data)
+	default:
+		return nil, fmt.Errorf("unsupported playlist format: %v", format)
+	}
+}
+
+// Write writes songs as a playlist in the given format.
+func Write(w io.Writer, format, title string, songs []models.DetailedSong) error {
+	switch format {
+	case FormatM3U, FormatM3U8:
+		return writeM3U(w, title, songs)
+	case FormatXSPF:
+		return writeXSPF(w, title, songs)
+	default:
+		return fmt.Errorf("unsupported playlist format: %v", format)
+	}
+}
+
+// ContentType returns the MIME type of a playlist format.
+func ContentType(format string) string {
+	switch format {
+	case FormatM3U:
+		return "audio/x-mpegurl"
+	case FormatXSPF:
+		return "application/xspf+xml"
+	default:
+		return "audio/x-mpegurl; charset=UTF-8"
+	}
+}
+
+// FormatFromContentType guesses the playlist format from a Content-Type header.
+func FormatFromContentType(contentType string) string {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+
+	switch mediaType {
+	case "audio/x-mpegurl", "audio/mpegurl", "application/x-mpegurl", "application/vnd.apple.mpegurl":
+		if strings.EqualFold(params["charset"], "utf-8") || mediaType == "application/vnd.apple.mpegurl" {
+			return FormatM3U8
+		}
+		return FormatM3U
+	case "application/xspf+xml":
+		return FormatXSPF
+	default:
+		return ""
+	}
+}
+
+// FormatFromFileName guesses the playlist format from a file extension.
+func FormatFromFileName(name string) string {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".m3u":
+		return FormatM3U
+	case ".m3u8":
+		return FormatM3U8
+	case ".xspf":
+		return FormatXSPF
+	default:
+		return ""
+	}
+}
+
+// location returns where a song can be found: its link when known, otherwise a
+// "Group - Song" name that Parse turns back into the group and title.
+func location(song models.DetailedSong) string {
+	if known(song.SongDetails.Link) {
+		return song.SongDetails.Link
+	}
+	return song.Group + " - " + song.Song.Name
+}
+
+func known(value string) bool {
+	return value != "" && value != models.UnknownValue
+}
@@ -0,0 +1,250 @@
+package playlist
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding/charmap"
+
+	"github.com/noctusha/music/models"
+)
+
+func TestParseM3U(t *testing.T) {
+	cp1251, err := charmap.Windows1251.NewEncoder().String("#EXTINF:200,Кино - Группа крови\r\nkino.mp3\r\n")
+	if err != nil {
+		t.Fatalf("error encoding the playlist: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		format   string
+		data     string
+		playlist *Playlist
+	}{
+		{
+			name:   "extended with a byte order mark",
+			format: FormatM3U8,
+			data: "\ufeff#EXTM3U\n#PLAYLIST:Road Trip\n" +
+				"#EXTINF:243,Muse - Uprising\nMusic/Muse/01 Uprising.mp3\n" +
+				"#EXTINF:-1 tvg-name=\"Live, Loud\",The Beatles - Yellow Submarine - Remastered\n#EXTALB:Revolver\nhttps://example.com/ys.mp3\n" +
+				"#EXTINF:180.7,Uprising\n#EXTART:Muse\nuprising.flac\n",
+			playlist: &Playlist{Title: "Road Trip", Entries: []Entry{
+				{Location: "Music/Muse/01 Uprising.mp3", Group: "Muse", Title: "Uprising", Duration: 243},
+				{Location: "https://example.com/ys.mp3", Group: "The Beatles", Title: "Yellow Submarine - Remastered", Album: "Revolver", Duration: -1},
+				{Location: "uprising.flac", Group: "Muse", Title: "Uprising", Duration: 180},
+			}},
+		},
+		{
+			name:   "plain with relative paths and URLs",
+			format: FormatM3U,
+			data: "# a comment\r\n" +
+				"..\\Music\\Kino\\07 - Кино - Группа крови.mp3\r\n" +
+				"Muse/02. Uprising.ogg\r\n" +
+				"\r\n" +
+				"http://example.com/music/05_Yellow%20Submarine.flac\r\n" +
+				"Kino - Звезда по имени Солнце.opus\r\n",
+			playlist: &Playlist{Entries: []Entry{
+				{Location: "..\\Music\\Kino\\07 - Кино - Группа крови.mp3", Group: "Кино", Title: "Группа крови", Duration: -1},
+				{Location: "Muse/02. Uprising.ogg", Title: "Uprising", Duration: -1},
+				{Location: "http://example.com/music/05_Yellow%20Submarine.flac", Title: "Yellow Submarine", Duration: -1},
+				{Location: "Kino - Звезда по имени Солнце.opus", Group: "Kino", Title: "Звезда по имени Солнце", Duration: -1},
+			}},
+		},
+		{
+			name:   "Windows-1251",
+			format: FormatM3U,
+			data:   cp1251,
+			playlist: &Playlist{Entries: []Entry{
+				{Location: "kino.mp3", Group: "Кино", Title: "Группа крови", Duration: 200},
+			}},
+		},
+		{
+			name:     "directives without a location",
+			format:   FormatM3U,
+			data:     "#EXTM3U\n#EXTINF:100,Muse - Uprising\n",
+			playlist: &Playlist{Entries: []Entry{}},
+		},
+	}
+
+	for _, test := range tests {
+		playlist, err := Parse(strings.NewReader(test.data), test.format)
+		if err != nil {
+			t.Errorf("%s: Parse: %v", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(playlist, test.playlist) {
+			t.Errorf("%s: Parse = %+v, want %+v", test.name, playlist, test.playlist)
+		}
+	}
+
+	// An M3U8 playlist is UTF-8 even when it is not valid UTF-8.
+	playlist, err := Parse(strings.NewReader(cp1251), FormatM3U8)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if len(playlist.Entries) != 1 || !strings.Contains(playlist.Entries[0].Group, "�") {
+		t.Errorf("Parse of Windows-1251 as M3U8 = %+v, want replacement characters", playlist)
+	}
+}
+
+func TestParseXSPF(t *testing.T) {
+	cp1251, err := charmap.Windows1251.NewEncoder().String(`<?xml version="1.0" encoding="windows-1251"?>
+<playlist version="1" xmlns="http://xspf.org/ns/0/"><trackList>
+<track><creator>Кино</creator><title>Группа крови</title></track>
+</trackList></playlist>`)
+	if err != nil {
+		t.Fatalf("error encoding the playlist: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		data     string
+		playlist *Playlist
+	}{
+		{
+			name: "titles, locations and escaping",
+			data: `<?xml version="1.0" encoding="UTF-8"?>
+<playlist version="1" xmlns="http://xspf.org/ns/0/">
+  <title> Rock &amp; Roll </title>
+  <trackList>
+    <track>
+      <location>https://example.com/a.mp3?x=1&amp;y=2</location>
+      <location>file:///music/a.mp3</location>
+      <title>Guns N&apos; Roses &lt;Live&gt;</title>
+      <creator>AC/DC &amp; Friends</creator>
+      <album>"Quoted"</album>
+      <duration>243500</duration>
+    </track>
+    <track>
+      <location>file:///music/Muse/03%20-%20Muse%20-%20Uprising.flac</location>
+    </track>
+    <track>
+      <creator>Muse</creator>
+      <location>Muse - Resistance.mp3</location>
+    </track>
+  </trackList>
+</playlist>`,
+			playlist: &Playlist{Title: "Rock & Roll", Entries: []Entry{
+				{Location: "https://example.com/a.mp3?x=1&y=2", Group: "AC/DC & Friends", Title: "Guns N' Roses <Live>", Album: `"Quoted"`, Duration: 243},
+				{Location: "file:///music/Muse/03%20-%20Muse%20-%20Uprising.flac", Group: "Muse", Title: "Uprising", Duration: -1},
+				{Location: "Muse - Resistance.mp3", Group: "Muse", Title: "Resistance", Duration: -1},
+			}},
+		},
+		{
+			name: "Windows-1251",
+			data: cp1251,
+			playlist: &Playlist{Entries: []Entry{
+				{Group: "Кино", Title: "Группа крови", Duration: -1},
+			}},
+		},
+	}
+
+	for _, test := range tests {
+		playlist, err := Parse(strings.NewReader(test.data), FormatXSPF)
+		if err != nil {
+			t.Errorf("%s: Parse: %v", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(playlist, test.playlist) {
+			t.Errorf("%s: Parse = %+v, want %+v", test.name, playlist, test.playlist)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		data   string
+	}{
+		{"unsupported format", "pls", "[playlist]"},
+		{"malformed XSPF", FormatXSPF, "<playlist><trackList><track>"},
+		{"unsupported XSPF charset", FormatXSPF, `<?xml version="1.0" encoding="koi8-r"?><playlist/>`},
+		{"oversize playlist", FormatM3U, strings.Repeat("a.mp3\n", maxPlaylistSize/6+1)},
+	}
+
+	for _, test := range tests {
+		_, err := Parse(strings.NewReader(test.data), test.format)
+		if err == nil {
+			t.Errorf("%s: Parse returned no error", test.name)
+		}
+	}
+}
+
+// testSongs are songs with names that need escaping in every format.
+var testSongs = []models.DetailedSong{
+	{
+		Song:        models.Song{ID: 1, Name: `Guns N' Roses <Live> & "Loud"`},
+		Group:       "AC/DC & Кино",
+		SongDetails: models.SongDetails{Album: "Line\nBreak", Link: "https://example.com/a.mp3?x=1&y=2"},
+	},
+	{
+		Song:        models.Song{ID: 2, Name: "Группа крови"},
+		Group:       "Кино",
+		SongDetails: models.SongDetails{Album: models.UnknownValue, Link: models.UnknownValue},
+	},
+}
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		format  string
+		entries []Entry
+	}{
+		{FormatM3U8, []Entry{
+			{Location: "https://example.com/a.mp3?x=1&y=2", Group: "AC/DC & Кино", Title: `Guns N' Roses <Live> & "Loud"`, Album: "Line Break", Duration: -1},
+			// A song without a link is written as "Group - Song", which is parsed back, and an
+			// unknown album is left out.
+			{Location: "Кино - Группа крови", Group: "Кино", Title: "Группа крови", Duration: -1},
+		}},
+		{FormatXSPF, []Entry{
+			{Location: "https://example.com/a.mp3?x=1&y=2", Group: "AC/DC & Кино", Title: `Guns N' Roses <Live> & "Loud"`, Album: "Line\nBreak", Duration: -1},
+			{Group: "Кино", Title: "Группа крови", Duration: -1},
+		}},
+	}
+
+	for _, test := range tests {
+		var buf bytes.Buffer
+		err := Write(&buf, test.format, "Mixed & Matched", testSongs)
+		if err != nil {
+			t.Fatalf("%s: Write: %v", test.format, err)
+		}
+
+		playlist, err := Parse(&buf, test.format)
+		if err != nil {
+			t.Fatalf("%s: Parse of the written playlist: %v", test.format, err)
+		}
+		want := &Playlist{Title: "Mixed & Matched", Entries: test.entries}
+		if !reflect.DeepEqual(playlist, want) {
+			t.Errorf("%s: round trip = %+v, want %+v", test.format, playlist, want)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	for contentType, format := range map[string]string{
+		"audio/x-mpegurl":                 FormatM3U,
+		"audio/x-mpegurl; charset=UTF-8":  FormatM3U8,
+		"application/vnd.apple.mpegurl":   FormatM3U8,
+		"application/xspf+xml":            FormatXSPF,
+		"text/plain":                      "",
+		"not a ; media type = definitely": "",
+	} {
+		if got := FormatFromContentType(contentType); got != format {
+			t.Errorf("FormatFromContentType(%q) = %q, want %q", contentType, got, format)
+		}
+	}
+
+	for name, format := range map[string]string{
+		"list.M3U":     FormatM3U,
+		"list.m3u8":    FormatM3U8,
+		"list.xspf":    FormatXSPF,
+		"list.pls":     "",
+		"no extension": "",
+	} {
+		if got := FormatFromFileName(name); got != format {
+			t.Errorf("FormatFromFileName(%q) = %q, want %q", name, got, format)
+		}
+	}
+}
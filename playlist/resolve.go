@@ -0,0 +1,195 @@
+package playlist
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/noctusha/music/models"
+)
+
+// Thresholds of the fuzzy match: the average similarity of group and title must
+// reach minScore and neither may fall below minPartScore.
+const (
+	minScore     = 0.8
+	minPartScore = 0.7
+)
+
+// brackets matches the remarks taggers put after a title, e.g. "(Remastered 2011)" or "[Live]".
+var brackets = regexp.MustCompile(`\s*[(\[][^)\]]*[)\]]`)
+
+// featuring matches the guest artists of a track.
+var featuring = regexp.MustCompile(`(?i)\s+(feat\.?|ft\.?|featuring)\s.*$`)
+
+// Resolver matches playlist entries against the songs of the library.
+type Resolver struct {
+	songs []resolverSong
+	exact map[string]int
+	// aliases maps the normalized aliases of groups to their normalized names.
+	aliases map[string]string
+}
+
+type resolverSong struct {
+	name  models.SongName
+	group string
+	title string
+}
+
+// NewResolver creates a Resolver over the given songs.
+func NewResolver(songs []models.SongName) *Resolver {
+	r := &Resolver{songs: make([]resolverSong, 0, len(songs)), exact: make(map[string]int, len(songs))}
+	for _, song := range songs {
+		s := resolverSong{name: song, group: Normalize(song.Group), title: Normalize(song.Name)}
+		key := s.group + "\x00" + s.title
+		if _, ok := r.exact[key]; !ok {
+			r.exact[key] = len(r.songs)
+		}
+		r.songs = append(r.songs, s)
+	}
+	return r
+}
+
+// AddAliases makes the resolver match the other names of groups as the groups themselves.
+func (r *Resolver) AddAliases(aliases []models.GroupAlias) {
+	if r.aliases == nil {
+		r.aliases = make(map[string]string, len(aliases))
+	}
+	for _, alias := range aliases {
+		r.aliases[Normalize(alias.Name)] = Normalize(alias.Group)
+	}
+}
+
+// Resolve matches every entry of a playlist and reports the outcome.
+func (r *Resolver) Resolve(playlist *Playlist) *models.PlaylistImportReport {
+	report := &models.PlaylistImportReport{Title: playlist.Title, Entries: make([]models.PlaylistEntryResult, 0, len(playlist.Entries))}
+	for i, entry := range playlist.Entries {
+		result := r.resolve(entry)
+		result.Position = i + 1
+
+		switch result.Status {
+		case models.PlaylistMatched:
+			report.Matched++
+		case models.PlaylistFuzzy:
+			report.Fuzzy++
+		default:
+			report.Unmatched++
+		}
+		report.Entries = append(report.Entries, result)
+	}
+	return report
+}
+
+// Match matches a single group and title, as the entries of a playlist.
+func (r *Resolver) Match(group, title string) models.PlaylistEntryResult {
+	return r.resolve(Entry{Group: group, Title: title})
+}
+
+// resolve matches an entry by its normalized group and title, falling back to the
+// most similar song. Entries without a group are matched by title alone.
+func (r *Resolver) resolve(entry Entry) models.PlaylistEntryResult {
+	result := models.PlaylistEntryResult{
+		Location: entry.Location,
+		Group:    entry.Group,
+		Song:     entry.Title,
+		Status:   models.PlaylistUnmatched,
+	}
+
+	group, title := Normalize(entry.Group), Normalize(entry.Title)
+	if title == "" {
+		return result
+	}
+	if name, ok := r.aliases[group]; ok {
+		group = name
+	}
+
+	if i, ok := r.exact[group+"\x00"+title]; ok && group != "" {
+		return matched(result, models.PlaylistMatched, r.songs[i].name, 1)
+	}
+
+	best, bestScore := -1, 0.0
+	for i, song := range r.songs {
+		titleScore := Similarity(title, song.title)
+		if titleScore < minPartScore {
+			continue
+		}
+
+		score := titleScore
+		if group != "" {
+			groupScore := Similarity(group, song.group)
+			if groupScore < minPartScore {
+				continue
+			}
+			score = (groupScore + titleScore) / 2
+		}
+
+		if score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+
+	if best < 0 || bestScore < minScore {
+		return result
+	}
+	return matched(result, models.PlaylistFuzzy, r.songs[best].name, bestScore)
+}
+
+func matched(result models.PlaylistEntryResult, status string, song models.SongName, score float64) models.PlaylistEntryResult {
+	result.Status = status
+	result.SongID = song.ID
+	result.MatchedGroup = song.Group
+	result.MatchedSong = song.Name
+	result.Score = float64(int(score*1000)) / 1000
+	return result
+}
+
+// Normalize lowercases a name and drops bracketed remarks, guest artists, punctuation
+// and a leading "the", so that names differing only in those compare equal.
+func Normalize(value string) string {
+	value = strings.ToLower(value)
+	value = strings.ReplaceAll(value, "ё", "е")
+	value = strings.ReplaceAll(value, "&", " and ")
+
+	stripped := featuring.ReplaceAllString(brackets.ReplaceAllString(value, ""), "")
+	if strings.TrimSpace(stripped) != "" {
+		value = stripped
+	}
+
+	value = strings.Join(strings.FieldsFunc(value, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+	return strings.TrimPrefix(value, "the ")
+}
+
+// Similarity returns 1 minus the edit distance of a and b relative to the longer of them.
+func Similarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longest := max(len(ra), len(rb))
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+// levenshtein computes the edit distance of two strings using a single row.
+func levenshtein(a, b []rune) int {
+	row := make([]int, len(b)+1)
+	for j := range row {
+		row[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		diagonal := row[0]
+		row[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			above := row[j]
+			row[j] = min(row[j]+1, row[j-1]+1, diagonal+cost)
+			diagonal = above
+		}
+	}
+
+	return row[len(b)]
+}
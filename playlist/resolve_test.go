@@ -0,0 +1,129 @@
+package playlist
+
+import (
+	"math"
+	"testing"
+
+	"github.com/noctusha/music/models"
+)
+
+func TestNormalize(t *testing.T) {
+	for value, normalized := range map[string]string{
+		"The Beatles":                        "beatles",
+		"Yellow Submarine (Remastered 2009)": "yellow submarine",
+		"Uprising [Live]":                    "uprising",
+		"Song feat. Someone Else":            "song",
+		"Song ft Guest":                      "song",
+		"Guns N' Roses":                      "guns n roses",
+		"Simon & Garfunkel":                  "simon and garfunkel",
+		"Ёлка":                               "елка",
+		"  AC/DC  ":                          "ac dc",
+		"(Intro)":                            "intro",
+		"Theatre":                            "theatre",
+		"":                                   "",
+	} {
+		if got := Normalize(value); got != normalized {
+			t.Errorf("Normalize(%q) = %q, want %q", value, got, normalized)
+		}
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b  string
+		score float64
+	}{
+		{"uprising", "uprising", 1},
+		{"", "", 1},
+		{"abc", "", 0},
+		{"kitten", "sitting", 1 - 3.0/7},
+		{"группа крови", "група крови", 1 - 1.0/12},
+		{"abc", "xyz", 0},
+	}
+
+	for _, test := range tests {
+		got := Similarity(test.a, test.b)
+		if math.Abs(got-test.score) > 1e-9 {
+			t.Errorf("Similarity(%q, %q) = %v, want %v", test.a, test.b, got, test.score)
+		}
+		if reverse := Similarity(test.b, test.a); math.Abs(reverse-got) > 1e-9 {
+			t.Errorf("Similarity(%q, %q) = %v is not symmetric", test.b, test.a, reverse)
+		}
+	}
+}
+
+func TestResolver(t *testing.T) {
+	resolver := NewResolver([]models.SongName{
+		{ID: 1, GroupID: 1, Group: "The Beatles", Name: "Yellow Submarine"},
+		{ID: 2, GroupID: 1, Group: "The Beatles", Name: "Yesterday"},
+		{ID: 3, GroupID: 2, Group: "Muse", Name: "Uprising"},
+		{ID: 4, GroupID: 3, Group: "Кино", Name: "Группа крови"},
+		// A later duplicate of the same name does not replace the first song.
+		{ID: 5, GroupID: 2, Group: "Muse", Name: "uprising!"},
+	})
+	resolver.AddAliases([]models.GroupAlias{{Name: "Kino", Group: "Кино"}})
+
+	tests := []struct {
+		name   string
+		group  string
+		title  string
+		status string
+		songID int
+	}{
+		{"exact", "The Beatles", "Yellow Submarine", models.PlaylistMatched, 1},
+		{"case, article and remarks", "beatles", "YELLOW SUBMARINE (Remastered)", models.PlaylistMatched, 1},
+		{"first of duplicates", "Muse", "Uprising", models.PlaylistMatched, 3},
+		{"alias", "Kino", "Группа крови", models.PlaylistMatched, 4},
+		{"typo", "Beatles", "Yelow Submarine", models.PlaylistFuzzy, 1},
+		{"typo in the group", "Mus", "Uprising", models.PlaylistFuzzy, 3},
+		{"title only", "", "Yesterday", models.PlaylistFuzzy, 2},
+		{"title only with a typo", "", "Группа кров", models.PlaylistFuzzy, 4},
+		{"group below the part threshold", "Metallica", "Uprising", models.PlaylistUnmatched, 0},
+		{"title below the part threshold", "Muse", "Resistance", models.PlaylistUnmatched, 0},
+		{"average below the threshold", "Mus", "Uprisn", models.PlaylistUnmatched, 0},
+		{"no title", "Muse", "", models.PlaylistUnmatched, 0},
+		{"punctuation only", "Muse", "...", models.PlaylistUnmatched, 0},
+	}
+
+	for _, test := range tests {
+		result := resolver.Match(test.group, test.title)
+		if result.Status != test.status || result.SongID != test.songID {
+			t.Errorf("%s: Match(%q, %q) = %s song %d, want %s song %d",
+				test.name, test.group, test.title, result.Status, result.SongID, test.status, test.songID)
+		}
+		if result.Group != test.group || result.Song != test.title {
+			t.Errorf("%s: Match reported %q, %q, want the entry as given", test.name, result.Group, result.Song)
+		}
+		switch {
+		case result.Status == models.PlaylistMatched && result.Score != 1:
+			t.Errorf("%s: exact match scored %v", test.name, result.Score)
+		case result.Status == models.PlaylistFuzzy && (result.Score < minScore || result.Score > 1):
+			t.Errorf("%s: fuzzy match scored %v", test.name, result.Score)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	resolver := NewResolver([]models.SongName{
+		{ID: 1, GroupID: 1, Group: "Muse", Name: "Uprising"},
+		{ID: 2, GroupID: 1, Group: "Muse", Name: "Resistance"},
+	})
+
+	report := resolver.Resolve(&Playlist{Title: "Mix", Entries: []Entry{
+		{Location: "a.mp3", Group: "Muse", Title: "Uprising"},
+		{Location: "b.mp3", Group: "Muse", Title: "Resistanse"},
+		{Location: "c.mp3", Group: "Muse", Title: "Starlight"},
+	}})
+
+	if report.Title != "Mix" || report.Matched != 1 || report.Fuzzy != 1 || report.Unmatched != 1 {
+		t.Errorf("Resolve = %+v, want 1 matched, 1 fuzzy and 1 unmatched", report)
+	}
+	for i, entry := range report.Entries {
+		if entry.Position != i+1 {
+			t.Errorf("entry %d has position %d", i, entry.Position)
+		}
+	}
+	if entry := report.Entries[1]; entry.SongID != 2 || entry.Location != "b.mp3" || entry.MatchedSong != "Resistance" || entry.MatchedGroup != "Muse" {
+		t.Errorf("fuzzy entry = %+v", entry)
+	}
+}
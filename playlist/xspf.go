@@ -0,0 +1,108 @@
+package playlist
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/text/encoding/charmap"
+
+	"github.com/noctusha/music/models"
+)
+
+// xspfNamespace is the XML namespace of XSPF version 1.
+const xspfNamespace = "http://xspf.org/ns/0/"
+
+type xspfPlaylist struct {
+	XMLName   xml.Name    `xml:"playlist"`
+	Namespace string      `xml:"xmlns,attr,omitempty"`
+	Version   string      `xml:"version,attr"`
+	Title     string      `xml:"title,omitempty"`
+	Tracks    []xspfTrack `xml:"trackList>track"`
+}
+
+type xspfTrack struct {
+	Location []string `xml:"location,omitempty"`
+	Title    string   `xml:"title,omitempty"`
+	Creator  string   `xml:"creator,omitempty"`
+	Album    string   `xml:"album,omitempty"`
+	// Duration is in milliseconds.
+	Duration int `xml:"duration,omitempty"`
+}
+
+// parseXSPF reads an XSPF playlist. Tracks without a title are named after their location.
+func parseXSPF(data []byte) (*Playlist, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.CharsetReader = charsetReader
+
+	var doc xspfPlaylist
+	err := decoder.Decode(&doc)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding XSPF: %v", err)
+	}
+
+	playlist := &Playlist{Title: strings.TrimSpace(doc.Title), Entries: make([]Entry, 0, len(doc.Tracks))}
+	for _, track := range doc.Tracks {
+		entry := Entry{
+			Group:    strings.TrimSpace(track.Creator),
+			Title:    strings.TrimSpace(track.Title),
+			Album:    strings.TrimSpace(track.Album),
+			Duration: -1,
+		}
+		if len(track.Location) > 0 {
+			entry.Location = strings.TrimSpace(track.Location[0])
+		}
+		if track.Duration > 0 {
+			entry.Duration = track.Duration / 1000
+		}
+		if entry.Title == "" && entry.Location != "" {
+			entry.Group, entry.Title = splitDisplay(entry.Group, nameFromLocation(entry.Location))
+		}
+		playlist.Entries = append(playlist.Entries, entry)
+	}
+
+	return playlist, nil
+}
+
+// charsetReader decodes the single-byte encodings XSPF files are found in besides UTF-8.
+func charsetReader(label string, input io.Reader) (io.Reader, error) {
+	switch strings.ToLower(label) {
+	case "windows-1251", "cp1251":
+		return charmap.Windows1251.NewDecoder().Reader(input), nil
+	case "iso-8859-1", "latin1":
+		return charmap.ISO8859_1.NewDecoder().Reader(input), nil
+	default:
+		return nil, fmt.Errorf("unsupported charset: %v", label)
+	}
+}
+
+// writeXSPF writes an XSPF playlist. The album and location are only written when they are known.
+func writeXSPF(w io.Writer, title string, songs []models.DetailedSong) error {
+	doc := xspfPlaylist{Namespace: xspfNamespace, Version: "1", Title: title, Tracks: make([]xspfTrack, 0, len(songs))}
+	for _, song := range songs {
+		track := xspfTrack{Title: song.Song.Name, Creator: song.Group}
+		if known(song.SongDetails.Album) {
+			track.Album = song.SongDetails.Album
+		}
+		if known(song.SongDetails.Link) {
+			track.Location = []string{song.SongDetails.Link}
+		}
+		doc.Tracks = append(doc.Tracks, track)
+	}
+
+	_, err := io.WriteString(w, xml.Header)
+	if err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	err = encoder.Encode(doc)
+	if err != nil {
+		return fmt.Errorf("error encoding XSPF: %v", err)
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
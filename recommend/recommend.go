@@ -0,0 +1,253 @@
+// Package recommend finds songs similar to a song by the TF-IDF cosine similarity of their
+// lyrics, combined with a shared group and a close release year.
+package recommend
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/noctusha/music/connection"
+	"github.com/noctusha/music/lyrics"
+	"github.com/noctusha/music/models"
+)
+
+// Weights of the parts of the score. They sum to 1.
+const (
+	lyricsWeight = 0.8
+	groupWeight  = 0.1
+	eraWeight    = 0.1
+)
+
+// eraYears is the largest difference of release years for songs of the same era.
+const eraYears = 5
+
+// Index holds the TF-IDF vectors of all lyrics. The term counts are stored in the
+// database and only recounted for changed lyrics; the vectors are rebuilt from them
+// on every Sync, since a change of any song changes the inverse document frequencies.
+type Index struct {
+	Repo *connection.Repository
+
+	// syncMu serializes Sync, mu guards the vectors.
+	syncMu sync.Mutex
+	mu     sync.RWMutex
+	synced bool
+	docs   map[int]*document
+	// postings lists the songs containing each term.
+	postings map[string][]int
+	byGroup  map[int][]int
+}
+
+type document struct {
+	groupID int
+	year    int
+	// vector is the unit TF-IDF vector of the lyrics, empty without lyrics.
+	vector map[string]float64
+}
+
+// NewIndex creates an empty Index. It is filled by the first Sync.
+func NewIndex(repo *connection.Repository) *Index {
+	return &Index{Repo: repo}
+}
+
+// Sync counts the terms of the lyrics changed since the previous Sync and rebuilds the vectors.
+func (x *Index) Sync() error {
+	x.syncMu.Lock()
+	defer x.syncMu.Unlock()
+
+	texts, err := x.Repo.ChangedSongTexts()
+	if err != nil {
+		return err
+	}
+
+	for _, text := range texts {
+		counts := lyrics.Terms(text.Text)
+
+		terms := make([]string, 0, len(counts))
+		for term := range counts {
+			terms = append(terms, term)
+		}
+		sort.Strings(terms)
+
+		values := make([]int64, len(terms))
+		for i, term := range terms {
+			values[i] = int64(counts[term])
+		}
+
+		err = x.Repo.SaveSongTerms(text.SongID, text.Hash, terms, values)
+		if err != nil {
+			return err
+		}
+	}
+
+	all, err := x.Repo.AllSongTerms()
+	if err != nil {
+		return err
+	}
+
+	docs, postings, byGroup := build(all)
+
+	x.mu.Lock()
+	x.docs, x.postings, x.byGroup, x.synced = docs, postings, byGroup, true
+	x.mu.Unlock()
+
+	return nil
+}
+
+// build computes the unit TF-IDF vectors, weighting a term by 1+ln(count) in the song and
+// ln((1+N)/(1+df))+1 over the N songs, df of which contain it.
+func build(all []models.SongTerms) (map[int]*document, map[string][]int, map[int][]int) {
+	df := make(map[string]int)
+	for _, song := range all {
+		for _, term := range song.Terms {
+			df[term]++
+		}
+	}
+
+	n := float64(len(all))
+	docs := make(map[int]*document, len(all))
+	postings := make(map[string][]int, len(df))
+	byGroup := make(map[int][]int)
+
+	for _, song := range all {
+		doc := &document{groupID: song.GroupID, year: song.Year, vector: make(map[string]float64, len(song.Terms))}
+
+		var norm float64
+		for i, term := range song.Terms {
+			if i >= len(song.Counts) || song.Counts[i] <= 0 {
+				continue
+			}
+			weight := (1 + math.Log(float64(song.Counts[i]))) * (math.Log((1+n)/(1+float64(df[term]))) + 1)
+			doc.vector[term] = weight
+			norm += weight * weight
+		}
+
+		norm = math.Sqrt(norm)
+		for term, weight := range doc.vector {
+			doc.vector[term] = weight / norm
+			postings[term] = append(postings[term], song.SongID)
+		}
+
+		docs[song.SongID] = doc
+		byGroup[song.GroupID] = append(byGroup[song.GroupID], song.SongID)
+	}
+
+	return docs, postings, byGroup
+}
+
+// Similar returns up to limit songs most similar to a song, the most similar first, without
+// names. It syncs the index first if it never was, and returns nil for unknown songs.
+func (x *Index) Similar(songID, limit int) ([]models.SimilarSong, error) {
+	x.mu.RLock()
+	synced := x.synced
+	x.mu.RUnlock()
+
+	if !synced {
+		err := x.Sync()
+		if err != nil {
+			return nil, fmt.Errorf("error building the similarity index: %v", err)
+		}
+	}
+
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	doc, ok := x.docs[songID]
+	if !ok {
+		return nil, nil
+	}
+
+	// Lyrics similarity is the dot product of the unit vectors, summed over the shared terms.
+	lyricsScores := make(map[int]float64)
+	for term, weight := range doc.vector {
+		for _, other := range x.postings[term] {
+			if other != songID {
+				lyricsScores[other] += weight * x.docs[other].vector[term]
+			}
+		}
+	}
+
+	candidates := make(map[int]bool, len(lyricsScores))
+	for other := range lyricsScores {
+		candidates[other] = true
+	}
+	for _, other := range x.byGroup[doc.groupID] {
+		if other != songID {
+			candidates[other] = true
+		}
+	}
+
+	similar := make([]models.SimilarSong, 0, len(candidates))
+	for other := range candidates {
+		otherDoc := x.docs[other]
+		song := models.SimilarSong{
+			SongID:      other,
+			LyricsScore: math.Min(lyricsScores[other], 1),
+			SameGroup:   otherDoc.groupID == doc.groupID,
+			SameEra:     doc.year != 0 && otherDoc.year != 0 && abs(doc.year-otherDoc.year) <= eraYears,
+		}
+
+		song.Score = lyricsWeight * song.LyricsScore
+		if song.SameGroup {
+			song.Score += groupWeight
+		}
+		if song.SameEra {
+			song.Score += eraWeight
+		}
+		similar = append(similar, song)
+	}
+
+	sort.Slice(similar, func(i, j int) bool {
+		if similar[i].Score != similar[j].Score {
+			return similar[i].Score > similar[j].Score
+		}
+		return similar[i].SongID < similar[j].SongID
+	})
+	if len(similar) > limit {
+		similar = similar[:limit]
+	}
+
+	return similar, nil
+}
+
+// LyricsSimilarity returns the cosine similarity of the lyrics of two songs. It syncs the index
+// first if it never was, and reports false when either song is unknown or has no lyrics.
+func (x *Index) LyricsSimilarity(a, b int) (float64, bool, error) {
+	x.mu.RLock()
+	synced := x.synced
+	x.mu.RUnlock()
+
+	if !synced {
+		err := x.Sync()
+		if err != nil {
+			return 0, false, fmt.Errorf("error building the similarity index: %v", err)
+		}
+	}
+
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	docA, okA := x.docs[a]
+	docB, okB := x.docs[b]
+	if !okA || !okB || len(docA.vector) == 0 || len(docB.vector) == 0 {
+		return 0, false, nil
+	}
+
+	if len(docB.vector) < len(docA.vector) {
+		docA, docB = docB, docA
+	}
+
+	var score float64
+	for term, weight := range docA.vector {
+		score += weight * docB.vector[term]
+	}
+	return math.Min(score, 1), true, nil
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,295 @@
+package scanner
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/noctusha/music/audiotag"
+	"github.com/noctusha/music/connection"
+	"github.com/noctusha/music/importer"
+	"github.com/noctusha/music/models"
+)
+
+// Statuses of a scanned file.
+const (
+	StatusCreated   = "created"
+	StatusUpdated   = "updated"
+	StatusUnchanged = "unchanged"
+	StatusConflict  = "conflict"
+	StatusFailed    = "failed"
+)
+
+// Conflict is a field whose tag value differs from the stored one.
+type Conflict struct {
+	Field    string `json:"field"`
+	Existing string `json:"existing"`
+	Tagged   string `json:"tagged"`
+}
+
+// FileResult describes what happened to a single file.
+type FileResult struct {
+	Path      string     `json:"path"`
+	Status    string     `json:"status"`
+	SongID    int        `json:"song_id,omitempty"`
+	Group     string     `json:"group,omitempty"`
+	Song      string     `json:"song,omitempty"`
+	Conflicts []Conflict `json:"conflicts,omitempty"`
+	Error     string     `json:"error,omitempty"`
+}
+
+// Report summarizes a scan.
+type Report struct {
+	DryRun    bool `json:"dry_run"`
+	Created   int  `json:"created"`
+	Updated   int  `json:"updated"`
+	Unchanged int  `json:"unchanged"`
+	Conflicts int  `json:"conflicts"`
+	Failed    int  `json:"failed"`
+	// Skipped counts files that did not change since the previous scan.
+	Skipped int          `json:"skipped"`
+	Files   []FileResult `json:"files"`
+}
+
+// Scanner imports songs from the tags of audio files in a directory tree.
+type Scanner struct {
+	Repo *connection.Repository
+	// State remembers the files of previous scans; files that did not change are skipped.
+	State *State
+	// Full reads every file, including those that did not change since the previous scan.
+	Full bool
+	// DryRun reports what would change without writing to the repository or the state.
+	DryRun bool
+	// Overwrite replaces stored values with tag values instead of reporting conflicts.
+	Overwrite bool
+}
+
+// Scan walks root and imports every supported audio file.
+func (s *Scanner) Scan(root string) (*Report, error) {
+	report := &Report{DryRun: s.DryRun, Files: []FileResult{}}
+
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			report.add(FileResult{Path: path, Status: StatusFailed, Error: err.Error()})
+			if entry != nil && entry.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if entry.IsDir() || !audiotag.Supported(path) {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			report.add(FileResult{Path: path, Status: StatusFailed, Error: err.Error()})
+			return nil
+		}
+
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			abs = path
+		}
+		if s.State != nil && !s.Full && s.State.Unchanged(abs, info) {
+			report.Skipped++
+			return nil
+		}
+
+		result := s.scanFile(path)
+		report.add(result)
+
+		if s.State != nil && !s.DryRun && result.Status != StatusFailed && result.Status != StatusConflict {
+			s.State.Remember(abs, info)
+		}
+		return nil
+	})
+	if err != nil {
+		return report, fmt.Errorf("error walking %s: %v", root, err)
+	}
+
+	return report, nil
+}
+
+// scanFile reads the tags of a file and stores them as a song.
+func (s *Scanner) scanFile(path string) FileResult {
+	result := FileResult{Path: path}
+
+	tags, err := audiotag.ReadFile(path)
+	if err != nil {
+		return failed(result, err)
+	}
+
+	result.Group = tags.Artist
+	result.Song = tags.Title
+	if tags.Artist == "" || tags.Title == "" {
+		return failed(result, fmt.Errorf("missing artist or title tag"))
+	}
+
+	date, err := releaseDate(tags.Date)
+	if err != nil {
+		return failed(result, err)
+	}
+
+	existing, err := s.Repo.FindSong(tags.Artist, tags.Title)
+	if err != nil {
+		return failed(result, err)
+	}
+
+	if existing == nil {
+		return s.create(result, tags, date)
+	}
+	return s.update(result, existing, tags, date)
+}
+
+// create stores a song that is not in the repository yet.
+func (s *Scanner) create(result FileResult, tags audiotag.Tags, date string) FileResult {
+	result.Status = StatusCreated
+	if s.DryRun {
+		return result
+	}
+
+	groupID, err := s.Repo.GetGroupID(tags.Artist)
+	if err != nil {
+		return failed(result, err)
+	}
+	if groupID == 0 {
+		groupID, err = s.Repo.NewGroup(tags.Artist)
+		if err != nil {
+			return failed(result, err)
+		}
+	}
+
+	details := models.SongDetails{
+		ReleaseDate: date,
+		Text:        tags.Lyrics,
+		Link:        models.UnknownValue,
+		Album:       tags.Album,
+	}
+	if details.ReleaseDate == "" {
+		details.ReleaseDate = models.DefaultReleaseDate
+	}
+	if details.Text == "" {
+		details.Text = models.UnknownValue
+	}
+
+	result.SongID, err = s.Repo.CreateSongWithDetails(models.Song{Name: tags.Title, GroupID: groupID}, details)
+	if err != nil {
+		return failed(result, err)
+	}
+	return result
+}
+
+// update fills the details missing from a stored song and reports fields whose
+// stored value differs from the tags, replacing them only with Overwrite.
+func (s *Scanner) update(result FileResult, existing *models.DetailedSong, tags audiotag.Tags, date string) FileResult {
+	result.SongID = existing.Song.ID
+	details := existing.SongDetails
+	changed := false
+
+	merge := func(field string, stored *string, tagged string, equal func(a, b string) bool) {
+		if tagged == "" || equal(*stored, tagged) {
+			return
+		}
+		if known(*stored) && !s.Overwrite {
+			result.Conflicts = append(result.Conflicts, Conflict{Field: field, Existing: *stored, Tagged: tagged})
+			return
+		}
+		*stored = tagged
+		changed = true
+	}
+
+	storedDate := storedReleaseDate(details.ReleaseDate)
+	merge("release_date", &storedDate, date, sameDate)
+	merge("album", &details.Album, tags.Album, sameText)
+	merge("text", &details.Text, tags.Lyrics, sameText)
+	details.ReleaseDate = storedDate
+	if details.ReleaseDate == "" {
+		details.ReleaseDate = models.DefaultReleaseDate
+	}
+
+	switch {
+	case len(result.Conflicts) > 0:
+		result.Status = StatusConflict
+	case changed:
+		result.Status = StatusUpdated
+	default:
+		result.Status = StatusUnchanged
+	}
+
+	if !changed || s.DryRun {
+		return result
+	}
+
+	song := existing.Song
+	err := s.Repo.UpdateSong(&song, &details)
+	if err != nil {
+		return failed(result, err)
+	}
+	return result
+}
+
+func (r *Report) add(result FileResult) {
+	switch result.Status {
+	case StatusCreated:
+		r.Created++
+	case StatusUpdated:
+		r.Updated++
+	case StatusUnchanged:
+		r.Unchanged++
+	case StatusConflict:
+		r.Conflicts++
+	case StatusFailed:
+		r.Failed++
+	}
+	r.Files = append(r.Files, result)
+}
+
+func failed(result FileResult, err error) FileResult {
+	result.Status = StatusFailed
+	result.Error = err.Error()
+	return result
+}
+
+// releaseDate converts a tag date to YYYY-MM-DD. A bare year becomes January 1st.
+func releaseDate(value string) (string, error) {
+	if len(value) == 4 {
+		value += "-01-01"
+	}
+	if len(value) == 7 {
+		value += "-01"
+	}
+	return importer.NormalizeDate(value)
+}
+
+// storedReleaseDate converts a stored release date to YYYY-MM-DD, hiding the default date.
+func storedReleaseDate(value string) string {
+	date, err := time.Parse(time.RFC3339, value)
+	if err == nil {
+		value = date.Format(time.DateOnly)
+	}
+	if value == models.DefaultReleaseDate {
+		return ""
+	}
+	return value
+}
+
+// sameDate compares dates by year when the tag only had a year.
+func sameDate(stored, tagged string) bool {
+	if strings.HasSuffix(tagged, "-01-01") && len(stored) >= 4 {
+		return stored[:4] == tagged[:4]
+	}
+	return stored == tagged
+}
+
+func sameText(stored, tagged string) bool {
+	normalize := func(s string) string {
+		return strings.TrimSpace(strings.ReplaceAll(s, "\r\n", "\n"))
+	}
+	return normalize(stored) == normalize(tagged)
+}
+
+func known(value string) bool {
+	return value != "" && value != models.UnknownValue
+}
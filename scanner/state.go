@@ -0,0 +1,71 @@
+package scanner
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"time"
+)
+
+// fileState is what the scanner remembers about a file.
+type fileState struct {
+	ModTime time.Time `json:"mod_time"`
+	Size    int64     `json:"size"`
+}
+
+// State records the modification times of scanned files so that later scans only
+// read files that changed.
+type State struct {
+	path  string
+	Files map[string]fileState `json:"files"`
+}
+
+// LoadState reads the state file at path. A missing file yields an empty state.
+func LoadState(path string) (*State, error) {
+	state := &State{path: path, Files: make(map[string]fileState)}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading scan state: %v", err)
+	}
+
+	err = json.Unmarshal(data, state)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing scan state: %v", err)
+	}
+	if state.Files == nil {
+		state.Files = make(map[string]fileState)
+	}
+	return state, nil
+}
+
+// Unchanged reports whether the file was scanned before with the same size and modification time.
+func (s *State) Unchanged(path string, info fs.FileInfo) bool {
+	previous, ok := s.Files[path]
+	return ok && previous.Size == info.Size() && previous.ModTime.Equal(info.ModTime())
+}
+
+// Remember records the current size and modification time of a file.
+func (s *State) Remember(path string, info fs.FileInfo) {
+	s.Files[path] = fileState{ModTime: info.ModTime(), Size: info.Size()}
+}
+
+// Save writes the state back to its file.
+func (s *State) Save() error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding scan state: %v", err)
+	}
+
+	tmp := s.path + ".tmp"
+	err = os.WriteFile(tmp, data, 0o644)
+	if err != nil {
+		return fmt.Errorf("error writing scan state: %v", err)
+	}
+	return os.Rename(tmp, s.path)
+}
@@ -0,0 +1,68 @@
+package songbook
+
+import (
+	"html/template"
+	"io"
+)
+
+var htmlTemplate = template.Must(template.New("songbook").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+	body { font-family: Georgia, "Times New Roman", serif; max-width: 42em; margin: 0 auto; padding: 2em; color: #222; }
+	h1 { font-size: 2.6em; text-align: center; margin-top: 30vh; }
+	.cover p { text-align: center; color: #666; }
+	.toc ol { padding-left: 2em; }
+	.toc li, .index li { margin: 0.2em 0; }
+	.toc .group { color: #666; }
+	.index ul { list-style: none; padding-left: 0; }
+	.song h2 { margin-bottom: 0.2em; }
+	.song .meta { color: #666; margin-top: 0; }
+	.song .verse { white-space: pre-line; margin: 0 0 1.2em; }
+	a { color: inherit; }
+	.toc, .index, .song { page-break-before: always; break-before: page; }
+	@media print { a { text-decoration: none; } }
+</style>
+</head>
+<body>
+<section class="cover">
+	<h1>{{.Title}}</h1>
+	<p>{{len .Entries}} songs</p>
+</section>
+<nav class="toc">
+	<h2>Contents</h2>
+	<ol>
+	{{- range .Entries}}
+		<li><a href="#song-{{.Number}}">{{.Name}}</a> <span class="group">{{.Group}}</span></li>
+	{{- end}}
+	</ol>
+</nav>
+{{- range .Entries}}
+<article class="song" id="song-{{.Number}}">
+	<h2>{{.Number}}. {{.Name}}</h2>
+	<p class="meta">{{.Group}}{{if .Album}} &middot; {{.Album}}{{end}}{{if .ReleaseDate}} &middot; {{.ReleaseDate}}{{end}}{{if .Link}} &middot; <a href="{{.Link}}">{{.Link}}</a>{{end}}</p>
+	{{- range .Verses}}
+	<p class="verse">{{.}}</p>
+	{{- else}}
+	<p class="verse">No lyrics available.</p>
+	{{- end}}
+</article>
+{{- end}}
+<section class="index">
+	<h2>Groups</h2>
+	<ul>
+	{{- range .Index}}
+		<li><strong>{{.Group}}</strong>{{range $i, $n := .Entries}}{{if $i}},{{end}} <a href="#song-{{$n}}">{{$n}}</a>{{end}}</li>
+	{{- end}}
+	</ul>
+</section>
+</body>
+</html>
+`))
+
+// WriteHTML renders the songbook as a standalone HTML document with one song per printed page.
+func (b *Songbook) WriteHTML(w io.Writer) error {
+	return htmlTemplate.Execute(w, b)
+}
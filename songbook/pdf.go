@@ -0,0 +1,166 @@
+package songbook
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+const (
+	pdfMargin     = 20.0
+	pdfLineHeight = 5.5
+	pdfTOCHeight  = 7.0
+	pdfPageWidth  = 170.0
+	pdfUTF8Family = "songbook"
+	pdfCoreFamily = "Helvetica"
+)
+
+// WritePDF renders the songbook as a PDF with a title page, table of contents,
+// one song per page and a group index.
+func (b *Songbook) WritePDF(w io.Writer) error {
+	// Page numbers in the contents are only known once the songs are laid out.
+	// The layout does not depend on those numbers, so a first pass collects them
+	// and the second pass renders the final document with the same pagination.
+	pdf, pages := b.renderPDF(make([]int, len(b.Entries)))
+	if err := pdf.Error(); err != nil {
+		return fmt.Errorf("error rendering songbook: %v", err)
+	}
+
+	pdf, _ = b.renderPDF(pages)
+	if err := pdf.Output(w); err != nil {
+		return fmt.Errorf("error writing songbook: %v", err)
+	}
+	return nil
+}
+
+// renderPDF lays out the whole document using pages for the contents and returns
+// the page on which each entry actually starts.
+func (b *Songbook) renderPDF(pages []int) (*gofpdf.Fpdf, []int) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetTitle(b.Title, true)
+	pdf.SetMargins(pdfMargin, pdfMargin, pdfMargin)
+	pdf.SetAutoPageBreak(true, pdfMargin)
+
+	family, translate := b.pdfFont(pdf)
+	pdf.SetFooterFunc(func() {
+		if pdf.PageNo() == 1 {
+			return
+		}
+		pdf.SetY(-15)
+		pdf.SetFont(family, "", 9)
+		pdf.CellFormat(0, 10, fmt.Sprint(pdf.PageNo()), "", 0, "C", false, 0, "")
+	})
+
+	links := make([]int, len(b.Entries))
+	for i := range links {
+		links[i] = pdf.AddLink()
+	}
+
+	pdf.AddPage()
+	pdf.SetY(100)
+	pdf.SetFont(family, "B", 32)
+	pdf.MultiCell(0, 14, translate(b.Title), "", "C", false)
+	pdf.SetFont(family, "", 12)
+	pdf.CellFormat(0, 10, fmt.Sprintf("%d songs", len(b.Entries)), "", 1, "C", false, 0, "")
+
+	pdf.AddPage()
+	pdfHeading(pdf, family, "Contents")
+	pdf.SetFont(family, "", 11)
+	for i, entry := range b.Entries {
+		title := fmt.Sprintf("%d. %s - %s", entry.Number, entry.Name, entry.Group)
+		page := ""
+		if pages[i] > 0 {
+			page = fmt.Sprint(pages[i])
+		}
+		pdf.CellFormat(pdfPageWidth-15, pdfTOCHeight, fitText(pdf, translate, title, pdfPageWidth-20), "", 0, "L", false, links[i], "")
+		pdf.CellFormat(15, pdfTOCHeight, page, "", 1, "R", false, links[i], "")
+	}
+
+	actual := make([]int, len(b.Entries))
+	for i, entry := range b.Entries {
+		pdf.AddPage()
+		actual[i] = pdf.PageNo()
+		pdf.SetLink(links[i], 0, -1)
+
+		pdf.SetFont(family, "B", 18)
+		pdf.MultiCell(0, 9, translate(fmt.Sprintf("%d. %s", entry.Number, entry.Name)), "", "L", false)
+
+		meta := []string{entry.Group}
+		if entry.Album != "" {
+			meta = append(meta, entry.Album)
+		}
+		if entry.ReleaseDate != "" {
+			meta = append(meta, entry.ReleaseDate)
+		}
+		if entry.Link != "" {
+			meta = append(meta, entry.Link)
+		}
+		pdf.SetFont(family, "", 10)
+		pdf.SetTextColor(100, 100, 100)
+		pdf.MultiCell(0, 6, translate(strings.Join(meta, " | ")), "", "L", false)
+		pdf.SetTextColor(0, 0, 0)
+		pdf.Ln(4)
+
+		pdf.SetFont(family, "", 11)
+		if len(entry.Verses) == 0 {
+			pdf.MultiCell(0, pdfLineHeight, "No lyrics available.", "", "L", false)
+		}
+		for _, verse := range entry.Verses {
+			pdf.MultiCell(0, pdfLineHeight, translate(verse), "", "L", false)
+			pdf.Ln(pdfLineHeight)
+		}
+	}
+
+	pdf.AddPage()
+	pdfHeading(pdf, family, "Groups")
+	for _, group := range b.Index() {
+		var refs []string
+		for _, number := range group.Entries {
+			refs = append(refs, fmt.Sprintf("%d (p. %d)", number, actual[number-1]))
+		}
+		pdf.SetFont(family, "B", 11)
+		pdf.MultiCell(0, pdfTOCHeight, translate(group.Group), "", "L", false)
+		pdf.SetFont(family, "", 10)
+		pdf.MultiCell(0, pdfLineHeight, strings.Join(refs, ", "), "", "L", false)
+		pdf.Ln(2)
+	}
+
+	return pdf, actual
+}
+
+// pdfFont registers the songbook font and returns its family together with the
+// translator that converts UTF-8 text into the font's encoding.
+func (b *Songbook) pdfFont(pdf *gofpdf.Fpdf) (string, func(string) string) {
+	if b.FontPath == "" {
+		return pdfCoreFamily, pdf.UnicodeTranslatorFromDescriptor("")
+	}
+
+	// gofpdf resolves font files relative to its font location.
+	pdf.SetFontLocation(filepath.Dir(b.FontPath))
+	pdf.AddUTF8Font(pdfUTF8Family, "", filepath.Base(b.FontPath))
+	pdf.AddUTF8Font(pdfUTF8Family, "B", filepath.Base(b.FontPath))
+	return pdfUTF8Family, func(s string) string { return s }
+}
+
+// pdfHeading writes a section heading.
+func pdfHeading(pdf *gofpdf.Fpdf, family, title string) {
+	pdf.SetFont(family, "B", 20)
+	pdf.CellFormat(0, 12, title, "", 1, "L", false, 0, "")
+	pdf.Ln(4)
+}
+
+// fitText shortens text with an ellipsis until it fits into width and translates it.
+func fitText(pdf *gofpdf.Fpdf, translate func(string) string, text string, width float64) string {
+	if pdf.GetStringWidth(translate(text)) <= width {
+		return translate(text)
+	}
+
+	runes := []rune(text)
+	for len(runes) > 0 && pdf.GetStringWidth(translate(string(runes)+"...")) > width {
+		runes = runes[:len(runes)-1]
+	}
+	return translate(string(runes) + "...")
+}
@@ -0,0 +1,151 @@
+package songbook
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/noctusha/music/models"
+)
+
+// Supported songbook formats.
+const (
+	FormatHTML = "html"
+	FormatPDF  = "pdf"
+)
+
+// MaxSongs is the number of songs rendered when songs are selected by a filter without a limit.
+const MaxSongs = 500
+
+// Songbook is a printable collection of songs with a table of contents and a group index.
+type Songbook struct {
+	Title string
+	// FontPath is an optional TrueType font used by the PDF renderer. Without it the
+	// built-in Helvetica is used, which only covers Western European characters.
+	FontPath string
+	Entries  []Entry
+}
+
+// Entry is a single song of the songbook.
+type Entry struct {
+	Number      int
+	Name        string
+	Group       string
+	ReleaseDate string
+	Album       string
+	Link        string
+	Verses      []string
+}
+
+// GroupIndex lists the entry numbers of the songs that belong to a group.
+type GroupIndex struct {
+	Group   string
+	Entries []int
+}
+
+// New creates a songbook from songs in the given order.
+func New(title string, songs []models.DetailedSong) *Songbook {
+	if title == "" {
+		title = "Songbook"
+	}
+
+	book := &Songbook{Title: title}
+	for i, song := range songs {
+		book.Entries = append(book.Entries, Entry{
+			Number:      i + 1,
+			Name:        song.Song.Name,
+			Group:       song.Group,
+			ReleaseDate: formatReleaseDate(song.SongDetails.ReleaseDate),
+			Album:       knownValue(song.SongDetails.Album),
+			Link:        knownValue(song.SongDetails.Link),
+			Verses:      splitVerses(knownValue(song.SongDetails.Text)),
+		})
+	}
+
+	return book
+}
+
+// Index returns the group index of the songbook sorted by group name.
+func (b *Songbook) Index() []GroupIndex {
+	positions := make(map[string]int)
+	var index []GroupIndex
+
+	for _, entry := range b.Entries {
+		pos, ok := positions[entry.Group]
+		if !ok {
+			pos = len(index)
+			positions[entry.Group] = pos
+			index = append(index, GroupIndex{Group: entry.Group})
+		}
+		index[pos].Entries = append(index[pos].Entries, entry.Number)
+	}
+
+	sort.SliceStable(index, func(i, j int) bool {
+		return strings.ToLower(index[i].Group) < strings.ToLower(index[j].Group)
+	})
+
+	return index
+}
+
+// Write renders the songbook in the given format.
+func (b *Songbook) Write(w io.Writer, format string) error {
+	switch format {
+	case FormatHTML:
+		return b.WriteHTML(w)
+	case FormatPDF:
+		return b.WritePDF(w)
+	default:
+		return fmt.Errorf("unsupported songbook format: %v", format)
+	}
+}
+
+// ContentType returns the MIME type of a songbook format.
+func ContentType(format string) string {
+	if format == FormatPDF {
+		return "application/pdf"
+	}
+	return "text/html; charset=UTF-8"
+}
+
+// knownValue hides the placeholder stored for missing details.
+func knownValue(value string) string {
+	if value == models.UnknownValue {
+		return ""
+	}
+	return strings.TrimSpace(value)
+}
+
+// formatReleaseDate turns the stored release date into YYYY-MM-DD, hiding the default date.
+func formatReleaseDate(value string) string {
+	date, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		date, err = time.Parse(time.DateOnly, value)
+		if err != nil {
+			return knownValue(value)
+		}
+	}
+
+	if date.Equal(time.Unix(0, 0).UTC()) {
+		return ""
+	}
+
+	return date.Format(time.DateOnly)
+}
+
+// splitVerses splits lyrics into verses the same way GetText does.
+func splitVerses(text string) []string {
+	if text == "" {
+		return nil
+	}
+
+	var verses []string
+	for _, verse := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n\n") {
+		verse = strings.Trim(verse, "\n")
+		if verse != "" {
+			verses = append(verses, verse)
+		}
+	}
+	return verses
+}
@@ -0,0 +1,53 @@
+package suggest
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// cyrillic transliterates the lower case Cyrillic letters to Latin, BGN/PCGN style. Hard and
+// soft signs are dropped.
+var cyrillic = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e", 'ж': "zh", 'з': "z",
+	'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o", 'п': "p", 'р': "r",
+	'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch",
+	'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya",
+	'і': "i", 'ї': "i", 'є': "e", 'ґ': "g", 'ў': "u",
+	'ß': "ss",
+}
+
+// Key folds a search the way the search_key function of the database folds the names the trie is
+// keyed by: NFC normalization, lower case, Cyrillic transliterated to Latin, diacritics and
+// apostrophes removed, and any other punctuation and whitespace collapsed into single spaces.
+func Key(name string) string {
+	var transliterated strings.Builder
+	for _, r := range strings.ToLower(norm.NFC.String(name)) {
+		switch latin, ok := cyrillic[r]; {
+		case ok:
+			transliterated.WriteString(latin)
+		case r == '\'' || r == '’' || r == 'ʼ' || r == '`':
+		default:
+			transliterated.WriteRune(r)
+		}
+	}
+
+	var key strings.Builder
+	space := false
+	for _, r := range norm.NFD.String(transliterated.String()) {
+		switch {
+		case r >= '\u0300' && r <= '\u036f':
+			// Combining diacritical marks.
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if space && key.Len() > 0 {
+				key.WriteByte(' ')
+			}
+			space = false
+			key.WriteRune(r)
+		default:
+			space = true
+		}
+	}
+	return key.String()
+}
@@ -0,0 +1,69 @@
+package suggest
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+
+	_ "github.com/lib/pq"
+)
+
+// keyTests are names with the keys that both Key and the search_key function of the database fold them to.
+var keyTests = []struct {
+	name string
+	key  string
+}{
+	{"Кино", "kino"},
+	{"Ёлка", "elka"},
+	{"Щедрость Жёлтый", "shchedrost zheltyy"},
+	{"Объект", "obekt"},
+	{"Хочу Цветы Юнга Ячейка", "khochu tsvety yunga yacheyka"},
+	{"Їжак Євген Ґанок Ўсё", "izhak evgen ganok use"},
+	{"Beyoncé", "beyonce"},
+	{"Béyoncé", "beyonce"},
+	{"Motörhead", "motorhead"},
+	{"Mötley Crüe", "motley crue"},
+	{"  Sigur Rós  ", "sigur ros"},
+	{"L’Âme Immortelle", "lame immortelle"},
+	{"Guns N' Roses", "guns n roses"},
+	{"AC/DC", "ac dc"},
+	{"Straße", "strasse"},
+	{"Die Ärzte — Weißes Album", "die arzte weisses album"},
+	{"t.A.T.u. & Кино, 1989", "t a t u kino 1989"},
+	{"...", ""},
+}
+
+func TestKey(t *testing.T) {
+	for _, test := range keyTests {
+		got := Key(test.name)
+		if got != test.key {
+			t.Errorf("Key(%q) = %q, want %q", test.name, got, test.key)
+		}
+	}
+}
+
+// TestSearchKey checks the search_key function of the database against the same names. It runs when
+// POSTGRES_CONN points to a migrated database.
+func TestSearchKey(t *testing.T) {
+	connStr := os.Getenv("POSTGRES_CONN")
+	if connStr == "" {
+		t.Skip("POSTGRES_CONN is not set")
+	}
+
+	db, err := sql.Open("postgres", connStr)
+	if err != nil {
+		t.Fatalf("error opening database: %v", err)
+	}
+	defer db.Close()
+
+	for _, test := range keyTests {
+		var got string
+		err = db.QueryRow("SELECT search_key($1)", test.name).Scan(&got)
+		if err != nil {
+			t.Fatalf("error selecting search_key(%q): %v", test.name, err)
+		}
+		if got != test.key {
+			t.Errorf("search_key(%q) = %q, want %q", test.name, got, test.key)
+		}
+	}
+}
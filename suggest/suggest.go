@@ -0,0 +1,303 @@
+// Package suggest completes the beginning of a search with groups, songs and albums from an
+// in-memory trie of their search keys.
+package suggest
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/noctusha/music/connection"
+	"github.com/noctusha/music/models"
+)
+
+// Ranks of a match: a suggestion whose name starts with the search comes before one with a later
+// word starting with it.
+const (
+	rankName = iota
+	rankWord
+)
+
+// MaxLimit is the largest number of suggestions returned at once.
+const MaxLimit = 50
+
+// cachedDepth is the length of the longest prefix whose best matches are kept. Shorter prefixes
+// match most of the catalogue, longer ones few enough suggestions to rank them on every search.
+const cachedDepth = 3
+
+// Index is a trie of the search keys of all groups, their aliases, songs and albums. It is rebuilt
+// from the repository by Sync, which the server runs on a schedule, and updated by SyncGroups after
+// changes of the catalogue.
+type Index struct {
+	Repo *connection.Repository
+
+	// syncMu serializes Sync and SyncGroups, mu guards the trie and the cached matches of its nodes.
+	syncMu sync.Mutex
+	mu     sync.Mutex
+	synced bool
+	root   *node
+	// groups lists the suggestions of every group: the group itself, its songs and its albums.
+	groups map[int][]*models.SearchSuggestion
+}
+
+type node struct {
+	children map[rune]*node
+	// matches lists the suggestions whose key, or a word of it, ends at the node.
+	matches []match
+	// best caches the best matches of the subtree, up to MaxLimit, once searched.
+	best []match
+}
+
+type match struct {
+	suggestion *models.SearchSuggestion
+	rank       int
+	length     int
+}
+
+// NewIndex creates an empty Index. It is filled by the first Sync.
+func NewIndex(repo *connection.Repository) *Index {
+	return &Index{Repo: repo}
+}
+
+// Sync rebuilds the trie from the repository.
+func (x *Index) Sync() error {
+	x.syncMu.Lock()
+	defer x.syncMu.Unlock()
+
+	suggestions, err := x.Repo.SearchSuggestions()
+	if err != nil {
+		return err
+	}
+
+	root := &node{}
+	groups := make(map[int][]*models.SearchSuggestion)
+	for i := range suggestions {
+		suggestion := &suggestions[i]
+		root.insert(suggestion)
+		groups[owner(suggestion)] = append(groups[owner(suggestion)], suggestion)
+	}
+
+	x.mu.Lock()
+	x.root, x.groups, x.synced = root, groups, true
+	x.mu.Unlock()
+
+	return nil
+}
+
+// SyncGroups replaces the suggestions of the given groups, their songs and albums with those in the
+// repository. The popularity of the other suggestions is left to the next Sync. An index that was
+// never synced is left empty.
+func (x *Index) SyncGroups(groupIDs ...int) error {
+	x.syncMu.Lock()
+	defer x.syncMu.Unlock()
+
+	x.mu.Lock()
+	synced := x.synced
+	x.mu.Unlock()
+	if !synced || len(groupIDs) == 0 {
+		return nil
+	}
+
+	suggestions, err := x.Repo.SearchSuggestions(groupIDs...)
+	if err != nil {
+		return err
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	for _, id := range groupIDs {
+		for _, suggestion := range x.groups[id] {
+			x.root.remove(suggestion)
+		}
+		delete(x.groups, id)
+	}
+	for i := range suggestions {
+		suggestion := &suggestions[i]
+		x.root.insert(suggestion)
+		x.groups[owner(suggestion)] = append(x.groups[owner(suggestion)], suggestion)
+	}
+
+	return nil
+}
+
+// owner is the ID of the group a suggestion belongs to.
+func owner(suggestion *models.SearchSuggestion) int {
+	if suggestion.Kind == models.SuggestGroup {
+		return suggestion.ID
+	}
+	return suggestion.GroupID
+}
+
+// insert adds a suggestion under each of its keys and under every later word of them.
+func (n *node) insert(suggestion *models.SearchSuggestion) {
+	for _, key := range suggestion.Keys {
+		words(key, func(word string, rank, length int) {
+			n.add(word, match{suggestion: suggestion, rank: rank, length: length})
+		})
+	}
+}
+
+// remove deletes a suggestion inserted before.
+func (n *node) remove(suggestion *models.SearchSuggestion) {
+	for _, key := range suggestion.Keys {
+		words(key, func(word string, _, _ int) {
+			n.delete(word, suggestion)
+		})
+	}
+}
+
+// words calls f with a key and every later word of it to the end of the key, with the rank of the
+// match and the length of the whole key.
+func words(key string, f func(word string, rank, length int)) {
+	if key == "" {
+		return
+	}
+
+	length := utf8.RuneCountInString(key)
+	rank := rankName
+	for {
+		f(key, rank, length)
+
+		space := strings.IndexByte(key, ' ')
+		if space < 0 {
+			return
+		}
+		key, rank = key[space+1:], rankWord
+	}
+}
+
+// add adds a match under key, dropping the cached matches of the nodes on its path.
+func (n *node) add(key string, m match) {
+	n.best = nil
+	for _, r := range key {
+		child, ok := n.children[r]
+		if !ok {
+			if n.children == nil {
+				n.children = make(map[rune]*node)
+			}
+			child = &node{}
+			n.children[r] = child
+		}
+		n = child
+		n.best = nil
+	}
+	n.matches = append(n.matches, m)
+}
+
+// delete removes the matches of a suggestion under key, dropping the cached matches of the nodes on its path.
+func (n *node) delete(key string, suggestion *models.SearchSuggestion) {
+	n.best = nil
+	for _, r := range key {
+		n = n.children[r]
+		if n == nil {
+			return
+		}
+		n.best = nil
+	}
+	n.matches = slices.DeleteFunc(n.matches, func(m match) bool { return m.suggestion == suggestion })
+}
+
+// Suggest returns up to limit groups, songs and albums with a name or a word of it starting with
+// the search, regardless of case, accents and Cyrillic or Latin spelling. Names starting with the
+// search come first, then the more popular and shorter ones. It syncs the index first if it never was.
+func (x *Index) Suggest(search string, limit int) ([]models.SearchSuggestion, error) {
+	x.mu.Lock()
+	synced := x.synced
+	x.mu.Unlock()
+
+	if !synced {
+		err := x.Sync()
+		if err != nil {
+			return nil, fmt.Errorf("error building the suggestion index: %v", err)
+		}
+	}
+
+	suggestions := []models.SearchSuggestion{}
+
+	prefix := Key(search)
+	if prefix == "" {
+		return suggestions, nil
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	n := x.root
+	depth := 0
+	for _, r := range prefix {
+		n = n.children[r]
+		if n == nil {
+			return suggestions, nil
+		}
+		depth++
+	}
+
+	best := n.best
+	if best == nil {
+		best = n.collect()
+		if depth <= cachedDepth {
+			n.best = best
+		}
+	}
+
+	for _, m := range best {
+		if len(suggestions) == limit {
+			break
+		}
+		suggestions = append(suggestions, *m.suggestion)
+	}
+	return suggestions, nil
+}
+
+// collect ranks the matches of the subtree of n and returns up to MaxLimit of them, each suggestion once.
+func (n *node) collect() []match {
+	found := make(map[*models.SearchSuggestion]match)
+
+	var walk func(n *node)
+	walk = func(n *node) {
+		for _, m := range n.matches {
+			if previous, ok := found[m.suggestion]; !ok || better(m, previous) {
+				found[m.suggestion] = m
+			}
+		}
+		for _, child := range n.children {
+			walk(child)
+		}
+	}
+	walk(n)
+
+	matches := make([]match, 0, len(found))
+	for _, m := range found {
+		matches = append(matches, m)
+	}
+	sort.Slice(matches, func(i, j int) bool { return better(matches[i], matches[j]) })
+
+	if len(matches) > MaxLimit {
+		matches = matches[:MaxLimit]
+	}
+	return matches
+}
+
+// better reports whether a ranks before b.
+func better(a, b match) bool {
+	if a.rank != b.rank {
+		return a.rank < b.rank
+	}
+	if a.suggestion.Popularity != b.suggestion.Popularity {
+		return a.suggestion.Popularity > b.suggestion.Popularity
+	}
+	if a.length != b.length {
+		return a.length < b.length
+	}
+	if a.suggestion.Name != b.suggestion.Name {
+		return a.suggestion.Name < b.suggestion.Name
+	}
+	if a.suggestion.Kind != b.suggestion.Kind {
+		return a.suggestion.Kind < b.suggestion.Kind
+	}
+	return a.suggestion.ID < b.suggestion.ID || a.suggestion.ID == b.suggestion.ID && a.suggestion.GroupID < b.suggestion.GroupID
+}